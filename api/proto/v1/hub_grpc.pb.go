@@ -19,7 +19,9 @@ import (
 const _ = grpc.SupportPackageIsVersion8
 
 const (
-	HubService_SendCommand_FullMethodName = "/v1.HubService/SendCommand"
+	HubService_SendCommand_FullMethodName     = "/v1.HubService/SendCommand"
+	HubService_ValidateCommand_FullMethodName = "/v1.HubService/ValidateCommand"
+	HubService_ListCommands_FullMethodName    = "/v1.HubService/ListCommands"
 )
 
 // HubServiceClient is the client API for HubService service.
@@ -31,6 +33,11 @@ type HubServiceClient interface {
 	// SendCommand transmits a command from the Controller to the Hub.
 	// The Hub is then responsible for forwarding this to the target vehicle (e.g. via MQTT).
 	SendCommand(ctx context.Context, in *SendCommandRequest, opts ...grpc.CallOption) (*SendCommandResponse, error)
+	// ValidateCommand runs the checks SendCommand's command must pass (known method, valid parameters,
+	// vehicle registered and reachable) without delivering it. Every failed check is reported.
+	ValidateCommand(ctx context.Context, in *SendCommandRequest, opts ...grpc.CallOption) (*ValidateCommandResponse, error)
+	// ListCommands returns the command history of a vehicle, newest first, one page at a time.
+	ListCommands(ctx context.Context, in *ListCommandsRequest, opts ...grpc.CallOption) (*ListCommandsResponse, error)
 }
 
 type hubServiceClient struct {
@@ -51,6 +58,26 @@ func (c *hubServiceClient) SendCommand(ctx context.Context, in *SendCommandReque
 	return out, nil
 }
 
+func (c *hubServiceClient) ValidateCommand(ctx context.Context, in *SendCommandRequest, opts ...grpc.CallOption) (*ValidateCommandResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateCommandResponse)
+	err := c.cc.Invoke(ctx, HubService_ValidateCommand_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hubServiceClient) ListCommands(ctx context.Context, in *ListCommandsRequest, opts ...grpc.CallOption) (*ListCommandsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCommandsResponse)
+	err := c.cc.Invoke(ctx, HubService_ListCommands_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // HubServiceServer is the server API for HubService service.
 // All implementations must embed UnimplementedHubServiceServer
 // for forward compatibility
@@ -60,6 +87,11 @@ type HubServiceServer interface {
 	// SendCommand transmits a command from the Controller to the Hub.
 	// The Hub is then responsible for forwarding this to the target vehicle (e.g. via MQTT).
 	SendCommand(context.Context, *SendCommandRequest) (*SendCommandResponse, error)
+	// ValidateCommand runs the checks SendCommand's command must pass (known method, valid parameters,
+	// vehicle registered and reachable) without delivering it. Every failed check is reported.
+	ValidateCommand(context.Context, *SendCommandRequest) (*ValidateCommandResponse, error)
+	// ListCommands returns the command history of a vehicle, newest first, one page at a time.
+	ListCommands(context.Context, *ListCommandsRequest) (*ListCommandsResponse, error)
 	mustEmbedUnimplementedHubServiceServer()
 }
 
@@ -70,6 +102,12 @@ type UnimplementedHubServiceServer struct {
 func (UnimplementedHubServiceServer) SendCommand(context.Context, *SendCommandRequest) (*SendCommandResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendCommand not implemented")
 }
+func (UnimplementedHubServiceServer) ValidateCommand(context.Context, *SendCommandRequest) (*ValidateCommandResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateCommand not implemented")
+}
+func (UnimplementedHubServiceServer) ListCommands(context.Context, *ListCommandsRequest) (*ListCommandsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCommands not implemented")
+}
 func (UnimplementedHubServiceServer) mustEmbedUnimplementedHubServiceServer() {}
 
 // UnsafeHubServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -101,6 +139,42 @@ func _HubService_SendCommand_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _HubService_ValidateCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendCommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HubServiceServer).ValidateCommand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HubService_ValidateCommand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HubServiceServer).ValidateCommand(ctx, req.(*SendCommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HubService_ListCommands_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCommandsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HubServiceServer).ListCommands(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HubService_ListCommands_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HubServiceServer).ListCommands(ctx, req.(*ListCommandsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // HubService_ServiceDesc is the grpc.ServiceDesc for HubService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -112,6 +186,14 @@ var HubService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SendCommand",
 			Handler:    _HubService_SendCommand_Handler,
 		},
+		{
+			MethodName: "ValidateCommand",
+			Handler:    _HubService_ValidateCommand_Handler,
+		},
+		{
+			MethodName: "ListCommands",
+			Handler:    _HubService_ListCommands_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/proto/v1/hub.proto",
@@ -12,6 +12,7 @@ import (
 
 type AgentOptions struct {
 	MqttOptions *options.MqttOptions `json:"mqtt" mapstructure:"mqtt"`
+	OTAOptions  *options.OTAOptions  `json:"ota" mapstructure:"ota"`
 	Log         *log.Options         `json:"log" mapstructure:"log"`
 }
 
@@ -20,6 +21,7 @@ var _ app.NamedFlagSetOptions = (*AgentOptions)(nil)
 func NewAgentOptions() *AgentOptions {
 	o := &AgentOptions{
 		MqttOptions: options.NewMqttOptions(),
+		OTAOptions:  options.NewOTAOptions(),
 		Log:         log.NewOptions(),
 	}
 
@@ -29,6 +31,7 @@ func NewAgentOptions() *AgentOptions {
 func (o *AgentOptions) Flags() cliflag.NamedFlagSets {
 	fss := cliflag.NamedFlagSets{}
 	o.MqttOptions.AddFlags(fss.FlagSet("mqtt"))
+	o.OTAOptions.AddFlags(fss.FlagSet("ota"))
 	o.Log.AddFlags(fss.FlagSet("Log"))
 	return fss
 }
@@ -40,6 +43,7 @@ func (o *AgentOptions) Complete() error {
 func (o *AgentOptions) Validate() error {
 	errs := []error{}
 	errs = append(errs, o.MqttOptions.Validate()...)
+	errs = append(errs, o.OTAOptions.Validate()...)
 	errs = append(errs, o.Log.Validate()...)
 	return utilerrors.NewAggregate(errs)
 }
@@ -47,5 +51,6 @@ func (o *AgentOptions) Validate() error {
 func (o *AgentOptions) Config() (*agent.Config, error) {
 	return &agent.Config{
 		MqttOptions: o.MqttOptions,
+		OTAOptions:  o.OTAOptions,
 	}, nil
 }
@@ -11,24 +11,34 @@ import (
 )
 
 type HubOptions struct {
-	KubeOptions *options.KubeOptions `json:"kube" mapstructure:"kube"`
-	HttpOptions *options.HttpOptions `json:"http" mapstructure:"http"`
-	GrpcOptions *options.GrpcOptions `json:"grpc" mapstructure:"grpc"`
-	MqttOptions *options.MqttOptions `json:"mqtt" mapstructure:"mqtt"`
-	S3Options   *options.S3Options   `json:"s3" mapstructure:"s3"`
-	Log         *log.Options
+	KubeOptions     *options.KubeOptions     `json:"kube" mapstructure:"kube"`
+	HttpOptions     *options.HttpOptions     `json:"http" mapstructure:"http"`
+	GrpcOptions     *options.GrpcOptions     `json:"grpc" mapstructure:"grpc"`
+	MqttOptions     *options.MqttOptions     `json:"mqtt" mapstructure:"mqtt"`
+	S3Options       *options.S3Options       `json:"s3" mapstructure:"s3"`
+	StorageOptions  *options.StorageOptions  `json:"storage" mapstructure:"storage"`
+	FaultOptions    *options.FaultOptions    `json:"fault" mapstructure:"fault"`
+	PipelineOptions *options.PipelineOptions `json:"pipeline" mapstructure:"pipeline"`
+	AuditOptions    *options.AuditOptions    `json:"audit" mapstructure:"audit"`
+	ExportOptions   *options.ExportOptions   `json:"export" mapstructure:"export"`
+	Log             *log.Options
 }
 
 var _ app.NamedFlagSetOptions = (*HubOptions)(nil)
 
 func NewHubOptions() *HubOptions {
 	o := &HubOptions{
-		KubeOptions: options.NewKubeOptions(),
-		HttpOptions: options.NewHttpOptions(),
-		GrpcOptions: options.NewGrpcOptions(),
-		MqttOptions: options.NewMqttOptions(),
-		S3Options:   options.NewS3Options(),
-		Log:         log.NewOptions(),
+		KubeOptions:     options.NewKubeOptions(),
+		HttpOptions:     options.NewHttpOptions(),
+		GrpcOptions:     options.NewGrpcOptions(),
+		MqttOptions:     options.NewMqttOptions(),
+		S3Options:       options.NewS3Options(),
+		StorageOptions:  options.NewStorageOptions(),
+		FaultOptions:    options.NewFaultOptions(),
+		PipelineOptions: options.NewPipelineOptions(),
+		AuditOptions:    options.NewAuditOptions(),
+		ExportOptions:   options.NewExportOptions(),
+		Log:             log.NewOptions(),
 	}
 
 	return o
@@ -41,6 +51,11 @@ func (o *HubOptions) Flags() cliflag.NamedFlagSets {
 	o.GrpcOptions.AddFlags(fss.FlagSet("grpc"))
 	o.MqttOptions.AddFlags(fss.FlagSet("mqtt"))
 	o.S3Options.AddFlags(fss.FlagSet("s3"))
+	o.StorageOptions.AddFlags(fss.FlagSet("storage"))
+	o.FaultOptions.AddFlags(fss.FlagSet("fault"))
+	o.PipelineOptions.AddFlags(fss.FlagSet("pipeline"))
+	o.AuditOptions.AddFlags(fss.FlagSet("audit"))
+	o.ExportOptions.AddFlags(fss.FlagSet("export"))
 	o.Log.AddFlags(fss.FlagSet("log"))
 	return fss
 }
@@ -56,16 +71,26 @@ func (o *HubOptions) Validate() error {
 	errs = append(errs, o.GrpcOptions.Validate()...)
 	errs = append(errs, o.MqttOptions.Validate()...)
 	errs = append(errs, o.S3Options.Validate()...)
+	errs = append(errs, o.StorageOptions.Validate()...)
+	errs = append(errs, o.FaultOptions.Validate()...)
+	errs = append(errs, o.PipelineOptions.Validate()...)
+	errs = append(errs, o.AuditOptions.Validate()...)
+	errs = append(errs, o.ExportOptions.Validate()...)
 	errs = append(errs, o.Log.Validate()...)
 	return utilerrors.NewAggregate(errs)
 }
 
 func (o *HubOptions) Config() (*bridge.Config, error) {
 	return &bridge.Config{
-		KubeOptions: o.KubeOptions,
-		HttpOptions: o.HttpOptions,
-		GrpcOptions: o.GrpcOptions,
-		MqttOptions: o.MqttOptions,
-		S3Options:   o.S3Options,
+		KubeOptions:     o.KubeOptions,
+		HttpOptions:     o.HttpOptions,
+		GrpcOptions:     o.GrpcOptions,
+		MqttOptions:     o.MqttOptions,
+		S3Options:       o.S3Options,
+		StorageOptions:  o.StorageOptions,
+		FaultOptions:    o.FaultOptions,
+		PipelineOptions: o.PipelineOptions,
+		AuditOptions:    o.AuditOptions,
+		ExportOptions:   o.ExportOptions,
 	}, nil
 }
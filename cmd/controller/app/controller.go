@@ -12,7 +12,6 @@ import (
 	controllerruntime "sigs.k8s.io/controller-runtime"
 
 	"github.com/autopeer-io/autopeer/cmd/controller/app/options"
-	"github.com/autopeer-io/autopeer/internal/controller"
 	"github.com/autopeer-io/autopeer/pkg/log"
 )
 
@@ -22,6 +21,10 @@ func NewControllerManagerCommand(ctx context.Context) *cobra.Command {
 		Use:  "controller",
 		Long: "The Autopeer Controller Manager is a daemon that embeds the core control loops for the Autopeer platform.",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
 			log.Init(opts.LogOptions)
 			controllerruntime.SetLogger(log.Std().Logr())
 
@@ -32,8 +35,13 @@ func NewControllerManagerCommand(ctx context.Context) *cobra.Command {
 				}
 			}
 
+			cfg, err := opts.Config()
+			if err != nil {
+				return fmt.Errorf("failed to load configuration: %w", err)
+			}
+
 			kubeconfig := controllerruntime.GetConfigOrDie()
-			mgr, err := controller.NewControllerManager(ctx, kubeconfig, opts.HealthProbeBindAddress, opts.MetricsBindAddress, opts.HubAddr)
+			mgr, err := cfg.NewControllerManager(ctx, kubeconfig)
 			if err != nil {
 				log.Error(err, "failed to new controller manager")
 				return err
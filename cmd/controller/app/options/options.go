@@ -1,9 +1,17 @@
 package options
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	cliflag "k8s.io/component-base/cli/flag"
 
+	"github.com/autopeer-io/autopeer/internal/controller"
 	"github.com/autopeer-io/autopeer/pkg/log"
+	"github.com/autopeer-io/autopeer/pkg/options"
 )
 
 type ControllerManagerOptions struct {
@@ -11,7 +19,15 @@ type ControllerManagerOptions struct {
 	HealthProbeBindAddress string
 	MetricsBindAddress     string
 	HubAddr                string
+	HubMaxRetries          int
+	HubTimeout             time.Duration
+	PauseConfigMap         string
 	FeatureGates           []string
+	VehicleOptions         *options.VehicleOptions
+	VehicleCommandOptions  *options.VehicleCommandOptions
+	WebhookOptions         *options.WebhookOptions
+	AuditOptions           *options.AuditOptions
+	NotifyOptions          *options.NotifyOptions
 	LogOptions             *log.Options
 }
 
@@ -21,6 +37,14 @@ func NewControllerManagerOptions() *ControllerManagerOptions {
 		HealthProbeBindAddress: ":9001",
 		MetricsBindAddress:     ":8080",
 		HubAddr:                "bridge.autopeer-io.svc:8091",
+		HubMaxRetries:          3,
+		HubTimeout:             10 * time.Second,
+		PauseConfigMap:         "autopeer-io/autopeer-pause",
+		VehicleOptions:         options.NewVehicleOptions(),
+		VehicleCommandOptions:  options.NewVehicleCommandOptions(),
+		WebhookOptions:         options.NewWebhookOptions(),
+		AuditOptions:           options.NewAuditOptions(),
+		NotifyOptions:          options.NewNotifyOptions(),
 		LogOptions:             log.NewOptions(),
 	}
 }
@@ -31,9 +55,67 @@ func (o *ControllerManagerOptions) Flags() (fss cliflag.NamedFlagSets) {
 	fs.StringVar(&o.HealthProbeBindAddress, "health-probe-bind-address", o.HealthProbeBindAddress, "The TCP address that the controller should bind to for serving health probes.")
 	fs.StringVar(&o.MetricsBindAddress, "metrics-bind-address", o.MetricsBindAddress, "The TCP address that the controller should bind to for serving prometheus metrics.")
 	fs.StringVar(&o.HubAddr, "hub-addr", o.HubAddr, "The gRPC address of the Autopeer Hub.")
+	fs.IntVar(&o.HubMaxRetries, "hub-max-retries", o.HubMaxRetries, "The number of times a failed call to the Hub is retried before the command is reported as unreachable.")
+	fs.DurationVar(&o.HubTimeout, "hub-timeout", o.HubTimeout, "The timeout of a single gRPC call to the Hub.")
+	fs.StringVar(&o.PauseConfigMap, "pause-configmap", o.PauseConfigMap, "The <namespace>/<name> of the ConfigMap whose 'paused: \"true\"' pauses OTA activity cluster-wide.")
 	fs.StringArrayVar(&o.FeatureGates, "feature-gates", o.FeatureGates, "Used to enable some features.")
 
+	o.VehicleOptions.AddFlags(fss.FlagSet("Vehicle"))
+	o.VehicleCommandOptions.AddFlags(fss.FlagSet("Vehicle Command"))
+	o.WebhookOptions.AddFlags(fss.FlagSet("Webhook"))
+	o.AuditOptions.AddFlags(fss.FlagSet("Audit"))
+	o.NotifyOptions.AddFlags(fss.FlagSet("Notify"))
 	o.LogOptions.AddFlags(fss.FlagSet("Log"))
 
 	return fss
 }
+
+func (o *ControllerManagerOptions) Validate() error {
+	errs := []error{}
+	if o.HubMaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("--hub-max-retries must not be negative"))
+	}
+	if o.HubTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("--hub-timeout must be greater than 0"))
+	}
+	if _, err := o.PauseConfigMapKey(); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, o.VehicleOptions.Validate()...)
+	errs = append(errs, o.VehicleCommandOptions.Validate()...)
+	errs = append(errs, o.WebhookOptions.Validate()...)
+	errs = append(errs, o.AuditOptions.Validate()...)
+	errs = append(errs, o.NotifyOptions.Validate()...)
+	errs = append(errs, o.LogOptions.Validate()...)
+	return utilerrors.NewAggregate(errs)
+}
+
+func (o *ControllerManagerOptions) Config() (*controller.Config, error) {
+	pauseConfigMap, err := o.PauseConfigMapKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &controller.Config{
+		HealthProbeBindAddress: o.HealthProbeBindAddress,
+		MetricsBindAddress:     o.MetricsBindAddress,
+		PauseConfigMap:         pauseConfigMap,
+		HubAddr:                o.HubAddr,
+		HubMaxRetries:          o.HubMaxRetries,
+		HubTimeout:             o.HubTimeout,
+		VehicleOptions:         o.VehicleOptions,
+		VehicleCommandOptions:  o.VehicleCommandOptions,
+		WebhookOptions:         o.WebhookOptions,
+		AuditOptions:           o.AuditOptions,
+		NotifyOptions:          o.NotifyOptions,
+	}, nil
+}
+
+// PauseConfigMapKey parses PauseConfigMap into a namespaced name.
+func (o *ControllerManagerOptions) PauseConfigMapKey() (types.NamespacedName, error) {
+	namespace, name, ok := strings.Cut(o.PauseConfigMap, "/")
+	if !ok || namespace == "" || name == "" {
+		return types.NamespacedName{}, fmt.Errorf("--pause-configmap must be in the form <namespace>/<name>, got %q", o.PauseConfigMap)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
@@ -0,0 +1,55 @@
+package options
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateHubOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(o *ControllerManagerOptions)
+		wantErr string
+	}{
+		{name: "defaults", modify: func(o *ControllerManagerOptions) {}},
+		{name: "no retries", modify: func(o *ControllerManagerOptions) { o.HubMaxRetries = 0 }},
+		{name: "negative retries", modify: func(o *ControllerManagerOptions) { o.HubMaxRetries = -1 }, wantErr: "--hub-max-retries must not be negative"},
+		{name: "zero timeout", modify: func(o *ControllerManagerOptions) { o.HubTimeout = 0 }, wantErr: "--hub-timeout must be greater than 0"},
+		{name: "negative timeout", modify: func(o *ControllerManagerOptions) { o.HubTimeout = -1 }, wantErr: "--hub-timeout must be greater than 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewControllerManagerOptions()
+			tt.modify(o)
+
+			err := o.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	o := NewControllerManagerOptions()
+	o.PauseConfigMap = "fleet/ota-pause"
+	o.HubMaxRetries = 5
+
+	cfg, err := o.Config()
+	if err != nil {
+		t.Fatalf("Config() error = %v", err)
+	}
+	if cfg.PauseConfigMap.Namespace != "fleet" || cfg.PauseConfigMap.Name != "ota-pause" {
+		t.Errorf("PauseConfigMap = %v, want fleet/ota-pause", cfg.PauseConfigMap)
+	}
+	if cfg.HubMaxRetries != 5 || cfg.HubTimeout != o.HubTimeout || cfg.HubAddr != o.HubAddr {
+		t.Errorf("hub settings = %q %d %v, want %q 5 %v", cfg.HubAddr, cfg.HubMaxRetries, cfg.HubTimeout, o.HubAddr, o.HubTimeout)
+	}
+}
@@ -58,6 +58,14 @@ func (a *Agent) Run(ctx context.Context) error {
 		_ = a.reportStatus(shutdownCtx, false, "GracefulShutdown")
 	}()
 
+	for _, m := range a.modules {
+		if s, ok := m.(core.Starter); ok {
+			if err := s.Start(ctx); err != nil {
+				return fmt.Errorf("module %s start failed: %w", m.Name(), err)
+			}
+		}
+	}
+
 	go a.confirmSystemHealth(ctx)
 	go a.registerIdentity(ctx)
 
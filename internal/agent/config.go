@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 
@@ -16,6 +17,7 @@ import (
 
 type Config struct {
 	MqttOptions *options.MqttOptions
+	OTAOptions  *options.OTAOptions
 }
 
 func (cfg *Config) NewAgent() (*Agent, error) {
@@ -31,15 +33,32 @@ func (cfg *Config) NewAgent() (*Agent, error) {
 		return nil, fmt.Errorf("failed to init mqtt client")
 	}
 
+	otaOpts := cfg.OTAOptions
+	if otaOpts == nil {
+		otaOpts = options.NewOTAOptions()
+	}
+
+	var publicKey ed25519.PublicKey
+	if otaOpts.PublicKeyFile != "" {
+		if publicKey, err = ota.LoadPublicKey(otaOpts.PublicKeyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	httpClient, err := ota.NewHTTPClient(otaOpts)
+	if err != nil {
+		return nil, err
+	}
+
 	return NewAgent(
 		systemHAL,
 		hub.New(vid, mqttClient, topicBuilder),
-		ota.NewManager(vid),
+		ota.NewManager(vid, publicKey, httpClient, ota.TimeoutsFromOptions(otaOpts), otaOpts.URLTTL, otaOpts.StateDir),
 	), nil
 }
 
 func (cfg *Config) initMqttClientAndTopicBuilder(vid string) (mqtt.Client, *mqtttopic.Builder, error) {
-	topicBuilder := mqtttopic.NewBuilder(cfg.MqttOptions.TopicRoot)
+	topicBuilder := cfg.MqttOptions.ToTopicBuilder()
 
 	mqttConfig := cfg.MqttOptions.ToClientConfig()
 	if mqttConfig.ClientID == "" {
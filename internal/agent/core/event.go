@@ -9,4 +9,10 @@ const (
 	EventOTARequest    EventType = "ota.request"
 	EventOTAResponse   EventType = "ota.response"
 	EventCommandStatus EventType = "command.status"
+
+	EventVehicleRequest         EventType = "request"
+	EventVehicleRequestResponse EventType = "request.response"
+
+	EventResultUploadRequest  EventType = "result.request"
+	EventResultUploadResponse EventType = "result.response"
 )
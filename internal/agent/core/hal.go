@@ -6,6 +6,9 @@ type HAL interface {
 	GetVehicleID() string
 	GetFirmwareVersion() string
 
+	// GetBatteryLevel 返回当前电量百分比 (0-100), 用于执行 OTA 策略的最低电量要求
+	GetBatteryLevel() (int, error)
+
 	// Safety 接口：安全门禁检查 (P档, 零速, 电量等)
 	// 如果不满足安全条件，返回 error
 	CheckSafety() error
@@ -14,12 +17,23 @@ type HAL interface {
 	// MarkBootSuccessful 通知 Bootloader 当前启动成功 (防回滚)
 	MarkBootSuccessful() error
 
+	// ApplyDelta 基于当前运行分区 (Slot A) 和差分包重建完整固件镜像, 写入 outPath
+	ApplyDelta(patchPath string, outPath string) error
+
 	// InstallFirmware 将固件写入闲置分区 (Slot B)
 	InstallFirmware(path string, version string) error
 
 	// SwitchBootSlot 切换启动分区标志位
+	// 新固件启动失败时再次调用, 切回之前的分区
 	SwitchBootSlot() error
 
+	// ConfirmActiveSlot 升级重启后检查当前分区是否健康启动
+	// 返回 error 表示新固件不可用, 需要回滚
+	ConfirmActiveSlot() error
+
 	// Reboot 执行系统重启
 	Reboot() error
+
+	// ApplyConfig 应用下发的车辆配置 (属性名 -> 值)
+	ApplyConfig(properties map[string]string) error
 }
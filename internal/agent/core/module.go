@@ -13,3 +13,9 @@ type Module interface {
 
 	Routes() map[EventType]adapter.HandlerFunc
 }
+
+// Starter is implemented by modules with work to do once the agent is connected to the hub.
+type Starter interface {
+	// Start must not block; long-running work belongs in a goroutine bound to ctx.
+	Start(ctx context.Context) error
+}
@@ -4,6 +4,7 @@ package hal
 
 import (
 	"os"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -31,6 +32,15 @@ func (h *LinuxHAL) GetFirmwareVersion() string {
 	return strings.TrimSpace(string(data))
 }
 
+func (h *LinuxHAL) GetBatteryLevel() (int, error) {
+	// 真实：读取 BMS 上报的电量, 这里读取 power_supply 子系统
+	data, err := os.ReadFile("/sys/class/power_supply/battery/capacity")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
 func (h *LinuxHAL) CheckSafety() error {
 	// 真实：读取 /sys/class/... 下的传感器文件
 	// 或者调用 Cgo 库
@@ -43,6 +53,11 @@ func (h *LinuxHAL) MarkBootSuccessful() error {
 	return nil
 }
 
+func (h *LinuxHAL) ApplyDelta(patchPath string, outPath string) error {
+	// 真实：调用 bspatch / xdelta3, 以当前分区为基准重建镜像
+	return nil
+}
+
 func (h *LinuxHAL) InstallFirmware(path string, version string) error {
 	// 真实：调用 swupdate 或 dd 命令
 	return nil
@@ -53,8 +68,18 @@ func (h *LinuxHAL) SwitchBootSlot() error {
 	return nil
 }
 
+func (h *LinuxHAL) ConfirmActiveSlot() error {
+	// 真实：确认 bootloader 从新分区启动, 且关键服务已就绪
+	return nil // 暂略
+}
+
 func (h *LinuxHAL) Reboot() error {
 	log.Info("System is rebooting NOW...")
 	syscall.Sync()
 	return syscall.Reboot(syscall.LINUX_REBOOT_CMD_RESTART)
 }
+
+func (h *LinuxHAL) ApplyConfig(properties map[string]string) error {
+	// 真实：写入车辆配置服务 (如 /etc/autopeer/config.d), 并通知相关 ECU 重新加载
+	return nil
+}
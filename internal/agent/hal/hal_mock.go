@@ -3,6 +3,7 @@
 package hal
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,10 +16,15 @@ import (
 )
 
 const (
-	fileCurrentVersion = "current_version"
-	filePendingVersion = "pending_version"
+	fileCurrentVersion  = "current_version"
+	filePendingVersion  = "pending_version"
+	filePreviousVersion = "previous_version"
+	fileConfig          = "config.json"
 )
 
+// envBootFailure makes the mock fail the post-update health check, to exercise the rollback.
+const envBootFailure = "AUTOPEER_MOCK_BOOT_FAILURE"
+
 var (
 	count int
 	mu    sync.Mutex
@@ -75,6 +81,10 @@ func (h *MockHAL) GetFirmwareVersion() string {
 	return strings.TrimSpace(string(data))
 }
 
+func (h *MockHAL) GetBatteryLevel() (int, error) {
+	return 80, nil
+}
+
 func (h *MockHAL) CheckSafety() error {
 	log.Info("[HAL-Mock] Checking safety gates... (Gear=P, Speed=0, Battery=80%)")
 	time.Sleep(500 * time.Millisecond) // 模拟传感器读取耗时
@@ -86,6 +96,12 @@ func (h *MockHAL) MarkBootSuccessful() error {
 	return nil
 }
 
+func (h *MockHAL) ApplyDelta(patchPath string, outPath string) error {
+	log.Info("[HAL-Mock] Rebuilding firmware image from active slot and delta patch...", "vid", h.vid, "patch", patchPath, "out", outPath)
+	time.Sleep(1 * time.Second)
+	return nil
+}
+
 func (h *MockHAL) InstallFirmware(path string, version string) error {
 	log.Info("[HAL-Mock] Writing firmware to inactive slot (Slot B)...", "vid", h.vid, "path", path, "version", version)
 	for i := 0; i < 5; i++ {
@@ -98,7 +114,26 @@ func (h *MockHAL) InstallFirmware(path string, version string) error {
 }
 
 func (h *MockHAL) SwitchBootSlot() error {
-	log.Info("[HAL-Mock] Switching active slot to Slot B.")
+	pendingFile := filepath.Join(h.baseDir, filePendingVersion)
+	if _, err := os.Stat(pendingFile); err == nil {
+		log.Info("[HAL-Mock] Switching active slot to Slot B.")
+		return nil
+	}
+
+	// 没有待启动的新固件: 切回上一个分区 (回滚)
+	data, err := os.ReadFile(filepath.Join(h.baseDir, filePreviousVersion))
+	if err != nil {
+		return fmt.Errorf("no previous slot to switch back to: %w", err)
+	}
+	log.Warn("[HAL-Mock] Switching back to the previous slot.", "version", string(data))
+	return os.WriteFile(pendingFile, data, 0644)
+}
+
+func (h *MockHAL) ConfirmActiveSlot() error {
+	if os.Getenv(envBootFailure) == "true" {
+		return fmt.Errorf("simulated boot failure of %s", h.GetFirmwareVersion())
+	}
+	log.Info("[HAL-Mock] Active slot is healthy.", "version", h.GetFirmwareVersion())
 	return nil
 }
 
@@ -113,6 +148,11 @@ func (h *MockHAL) Reboot() error {
 	if data, err := os.ReadFile(pendingFile); err == nil {
 		newVer := string(data)
 
+		// 记住旧分区的版本, 以便回滚
+		if old, err := os.ReadFile(currentFile); err == nil {
+			_ = os.WriteFile(filepath.Join(h.baseDir, filePreviousVersion), old, 0644)
+		}
+
 		if err := os.WriteFile(currentFile, data, 0644); err != nil {
 			log.Error(err, "Bootloader failed to load new kernel")
 			return err
@@ -127,3 +167,12 @@ func (h *MockHAL) Reboot() error {
 
 	return nil
 }
+
+func (h *MockHAL) ApplyConfig(properties map[string]string) error {
+	log.Info("[HAL-Mock] Applying vehicle configuration...", "vid", h.vid, "properties", properties)
+	data, err := json.MarshalIndent(properties, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(h.baseDir, fileConfig), data, 0644)
+}
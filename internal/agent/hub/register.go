@@ -30,4 +30,8 @@ func init() {
 	events[core.EventOTARequest] = paths.OTARequest
 	events[core.EventOTAResponse] = paths.OTAResponse
 	events[core.EventCommandStatus] = paths.CommandAck
+	events[core.EventVehicleRequest] = paths.Request
+	events[core.EventVehicleRequestResponse] = paths.RequestResponse
+	events[core.EventResultUploadRequest] = paths.ResultUploadRequest
+	events[core.EventResultUploadResponse] = paths.ResultUploadResponse
 }
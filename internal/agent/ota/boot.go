@@ -0,0 +1,123 @@
+package ota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/autopeer-io/autopeer/pkg/log"
+)
+
+// bootRecordFile names the record of an update awaiting boot confirmation inside the state directory.
+const bootRecordFile = "pending-boot.json"
+
+// rolledBackMessage is the failure reported when new firmware did not boot healthy.
+const rolledBackMessage = "rolled back after failed boot"
+
+// bootRecord remembers, across the reboot, which command installed which version.
+type bootRecord struct {
+	Command         string `json:"command"`
+	Version         string `json:"version"`
+	PreviousVersion string `json:"previousVersion"`
+}
+
+func (m *Manager) bootRecordPath() string {
+	return filepath.Join(m.stateDir, bootRecordFile)
+}
+
+// saveBootRecord persists rec atomically, so a power loss never leaves a torn record.
+func (m *Manager) saveBootRecord(rec *bootRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.stateDir, 0o755); err != nil {
+		return fmt.Errorf("failed to prepare state directory: %w", err)
+	}
+
+	tmp := m.bootRecordPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.bootRecordPath())
+}
+
+// loadBootRecord returns the pending boot record, or nil if no update awaits confirmation.
+func (m *Manager) loadBootRecord() (*bootRecord, error) {
+	data, err := os.ReadFile(m.bootRecordPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &bootRecord{}
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, fmt.Errorf("corrupt boot record: %w", err)
+	}
+	return rec, nil
+}
+
+func (m *Manager) clearBootRecord() {
+	if err := os.Remove(m.bootRecordPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Error(err, "Failed to remove boot record")
+	}
+}
+
+// confirmBoot finishes an update after the reboot into the new slot. The new firmware must
+// pass the HAL health check within the watchdog window; otherwise the agent switches back
+// to the previous slot, reports the command as failed and reboots.
+func (m *Manager) confirmBoot(ctx context.Context) {
+	rec, err := m.loadBootRecord()
+	if err != nil {
+		log.Error(err, "Failed to read boot record")
+		m.clearBootRecord()
+		return
+	}
+	if rec == nil {
+		return
+	}
+
+	// The bootloader may already have fallen back on its own.
+	if running := m.hal.GetFirmwareVersion(); running != rec.Version {
+		log.Warn("New firmware did not boot", "command", rec.Command, "version", rec.Version, "running", running)
+		m.AckCommand(ctx, rec.Command, "Failed", rolledBackMessage)
+		m.clearBootRecord()
+		return
+	}
+
+	err = runStep(ctx, stepBootConfirm, m.timeouts.BootConfirm, func(context.Context) error {
+		return m.hal.ConfirmActiveSlot()
+	})
+	if err == nil {
+		if err := m.hal.MarkBootSuccessful(); err != nil {
+			log.Error(err, "Failed to mark boot successful")
+		}
+		log.Info("New firmware confirmed", "command", rec.Command, "version", rec.Version)
+		m.AckCommand(ctx, rec.Command, "Succeeded", "Update installed")
+		m.clearBootRecord()
+		return
+	}
+	if ctx.Err() != nil {
+		// The agent is shutting down; confirm on the next start.
+		return
+	}
+
+	log.Error(err, "New firmware failed its health check, rolling back", "command", rec.Command, "version", rec.Version, "previousVersion", rec.PreviousVersion)
+	if err := m.hal.SwitchBootSlot(); err != nil {
+		// Without a commit mark the bootloader falls back on the next reboot anyway.
+		log.Error(err, "Failed to switch back to the previous slot")
+	}
+	m.AckCommand(ctx, rec.Command, "Failed", rolledBackMessage)
+	m.clearBootRecord()
+
+	if err := runStep(ctx, stepReboot, m.timeouts.Reboot, func(context.Context) error {
+		return m.hal.Reboot()
+	}); err != nil {
+		log.Error(err, "Reboot into the previous slot failed")
+	}
+}
@@ -0,0 +1,141 @@
+package ota
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// bootHAL runs version and answers the post-reboot health check with confirmErr,
+// or never if hang is set. It records the slot switches, reboots and commit marks.
+type bootHAL struct {
+	stubHAL
+	version    string
+	confirmErr error
+	hang       bool
+
+	switches, reboots, marks int
+}
+
+func (h *bootHAL) GetFirmwareVersion() string { return h.version }
+func (h *bootHAL) SwitchBootSlot() error      { h.switches++; return nil }
+func (h *bootHAL) Reboot() error              { h.reboots++; return nil }
+func (h *bootHAL) MarkBootSuccessful() error  { h.marks++; return nil }
+
+func (h *bootHAL) ConfirmActiveSlot() error {
+	if h.hang {
+		select {}
+	}
+	return h.confirmErr
+}
+
+func TestConfirmBoot(t *testing.T) {
+	tests := []struct {
+		name       string
+		hal        *bootHAL
+		noRecord   bool
+		wantAck    string
+		wantMsg    string
+		wantSwitch int
+		wantReboot int
+		wantMarks  int
+	}{
+		{
+			name:      "healthy new firmware",
+			hal:       &bootHAL{version: "v1.2.0"},
+			wantAck:   "Succeeded",
+			wantMsg:   "Update installed",
+			wantMarks: 1,
+		},
+		{
+			name:       "health check fails",
+			hal:        &bootHAL{version: "v1.2.0", confirmErr: errors.New("dashboard service down")},
+			wantAck:    "Failed",
+			wantMsg:    rolledBackMessage,
+			wantSwitch: 1,
+			wantReboot: 1,
+		},
+		{
+			name:       "health check exceeds the watchdog window",
+			hal:        &bootHAL{version: "v1.2.0", hang: true},
+			wantAck:    "Failed",
+			wantMsg:    rolledBackMessage,
+			wantSwitch: 1,
+			wantReboot: 1,
+		},
+		{
+			name:    "bootloader already fell back",
+			hal:     &bootHAL{version: "v1.0.0"},
+			wantAck: "Failed",
+			wantMsg: rolledBackMessage,
+		},
+		{
+			name:     "normal boot",
+			hal:      &bootHAL{version: "v1.0.0"},
+			noRecord: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{
+				hal:      tt.hal,
+				timeouts: Timeouts{BootConfirm: 50 * time.Millisecond, Reboot: time.Second},
+				stateDir: t.TempDir(),
+			}
+			sender := &hubSender{m: m}
+			m.sender = sender
+
+			if !tt.noRecord {
+				if err := m.saveBootRecord(&bootRecord{Command: "ota-1", Version: "v1.2.0", PreviousVersion: "v1.0.0"}); err != nil {
+					t.Fatalf("saveBootRecord() error = %v", err)
+				}
+			}
+
+			m.confirmBoot(context.Background())
+
+			if tt.wantAck == "" {
+				if len(sender.acks) != 0 {
+					t.Errorf("acked %v on a boot without a pending update", sender.acks)
+				}
+			} else if ack := sender.lastAck(); ack.CommandName != "ota-1" || ack.Status != tt.wantAck || ack.Message != tt.wantMsg {
+				t.Errorf("last ack = %s %s %q, want ota-1 %s %q", ack.CommandName, ack.Status, ack.Message, tt.wantAck, tt.wantMsg)
+			}
+
+			if tt.hal.switches != tt.wantSwitch || tt.hal.reboots != tt.wantReboot || tt.hal.marks != tt.wantMarks {
+				t.Errorf("switches/reboots/marks = %d/%d/%d, want %d/%d/%d",
+					tt.hal.switches, tt.hal.reboots, tt.hal.marks, tt.wantSwitch, tt.wantReboot, tt.wantMarks)
+			}
+
+			if _, err := os.Stat(m.bootRecordPath()); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("boot record still present after confirmation (stat error %v)", err)
+			}
+		})
+	}
+}
+
+func TestConfirmBootKeepsRecordOnShutdown(t *testing.T) {
+	m := &Manager{
+		hal:      &bootHAL{version: "v1.2.0", hang: true},
+		timeouts: Timeouts{BootConfirm: time.Minute},
+		stateDir: t.TempDir(),
+	}
+	sender := &hubSender{m: m}
+	m.sender = sender
+	if err := m.saveBootRecord(&bootRecord{Command: "ota-1", Version: "v1.2.0"}); err != nil {
+		t.Fatalf("saveBootRecord() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	m.confirmBoot(ctx)
+
+	if len(sender.acks) != 0 {
+		t.Errorf("acked %s %q while shutting down", sender.lastAck().Status, sender.lastAck().Message)
+	}
+	if rec, err := m.loadBootRecord(); err != nil || rec == nil || rec.Command != "ota-1" {
+		t.Errorf("loadBootRecord() = %+v, %v; want the record kept for the next start", rec, err)
+	}
+}
@@ -0,0 +1,128 @@
+package ota
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/autopeer-io/autopeer/pkg/log"
+)
+
+const (
+	// partialSuffix names the file an artifact is downloaded to before it is verified (e.g. firmware.bin.part).
+	// It is kept after a failed transfer, so the next attempt only fetches the missing bytes.
+	partialSuffix = ".part"
+
+	// downloadAttempts bounds the attempts of a single download.
+	downloadAttempts = 3
+
+	// defaultDownloadRetryDelay is the pause between two download attempts.
+	defaultDownloadRetryDelay = 5 * time.Second
+)
+
+// permanentError marks a download failure that a retry cannot fix, e.g. an expired link.
+type permanentError struct{ error }
+
+// download fetches url into part and returns the SHA256 digest of the complete content.
+// If part already holds bytes from an earlier attempt, only the rest is requested with an
+// HTTP Range request; a server that ignores it (200 instead of 206) restarts the file.
+// resumed reports whether the content was continued from an earlier attempt.
+// progress, if not nil, is told about the downloaded bytes.
+func (m *Manager) download(ctx context.Context, url, part string, progress progressFunc) (digest []byte, resumed bool, err error) {
+	h := sha256.New()
+	offset, err := hashPartial(part, h)
+	if err != nil {
+		return nil, false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, permanentError{fmt.Errorf("invalid download URL: %w", err)}
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, false, permanentError{ctx.Err()}
+		}
+		return nil, false, fmt.Errorf("network error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			os.Remove(part)
+			return nil, false, fmt.Errorf("unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+		}
+		log.Info("Resuming firmware download", "offset", offset)
+		resumed = true
+	case resp.StatusCode == http.StatusOK:
+		if offset > 0 {
+			log.Info("Server does not support range requests, downloading from scratch")
+		}
+		h.Reset()
+		offset = 0
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	case offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is not a prefix of this artifact.
+		os.Remove(part)
+		return nil, false, fmt.Errorf("server rejected resume from offset %d", offset)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return nil, false, permanentError{fmt.Errorf("server returned status: %s", resp.Status)}
+	default:
+		return nil, false, fmt.Errorf("server returned status: %s", resp.Status)
+	}
+
+	f, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return nil, false, permanentError{fmt.Errorf("failed to create artifact file: %w", err)}
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+
+	// We hash the stream while writing it to verify integrity.
+	_, err = io.Copy(io.MultiWriter(f, h, newProgressWriter(progress, offset, total)), resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, false, permanentError{ctx.Err()}
+		}
+		return nil, false, fmt.Errorf("failed to download artifact: %w", err)
+	}
+
+	return h.Sum(nil), resumed, nil
+}
+
+// hashPartial feeds the bytes already downloaded to part into h and returns their count.
+func hashPartial(part string, h hash.Hash) (int64, error) {
+	f, err := os.Open(part)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, permanentError{fmt.Errorf("failed to open partial artifact: %w", err)}
+	}
+	defer f.Close()
+
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, permanentError{fmt.Errorf("failed to read partial artifact: %w", err)}
+	}
+	return n, nil
+}
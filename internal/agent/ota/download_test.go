@@ -0,0 +1,162 @@
+package ota
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDownloadResume(t *testing.T) {
+	artifact := bytes.Repeat([]byte("firmware-v1.2.0;"), 1024)
+	digest := sha256.Sum256(artifact)
+	checksum := "sha256:" + hex.EncodeToString(digest[:])
+	half := len(artifact) / 2
+
+	// ranged serves Range requests, plain ignores them.
+	ranged := func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "vehicle.bin", time.Time{}, bytes.NewReader(artifact))
+	}
+	plain := func(w http.ResponseWriter, r *http.Request) {
+		w.Write(artifact)
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		// partial is already on disk from an earlier attempt.
+		partial []byte
+		// cutFirst truncates the first response after half of the artifact.
+		cutFirst    bool
+		wantRanges  []string
+		wantFetched int
+	}{
+		{
+			name:        "fresh download",
+			handler:     ranged,
+			wantRanges:  []string{""},
+			wantFetched: len(artifact),
+		},
+		{
+			name:        "resumes partial file",
+			handler:     ranged,
+			partial:     artifact[:half],
+			wantRanges:  []string{"bytes=" + strconv.Itoa(half) + "-"},
+			wantFetched: len(artifact) - half,
+		},
+		{
+			name:        "retries interrupted transfer with range",
+			handler:     ranged,
+			cutFirst:    true,
+			wantRanges:  []string{"", "bytes=" + strconv.Itoa(half) + "-"},
+			wantFetched: len(artifact),
+		},
+		{
+			name:        "server without range support restarts",
+			handler:     plain,
+			partial:     artifact[:half],
+			wantRanges:  []string{"bytes=" + strconv.Itoa(half) + "-"},
+			wantFetched: len(artifact),
+		},
+		{
+			name:        "stale partial file is discarded",
+			handler:     ranged,
+			partial:     bytes.Repeat([]byte("x"), half),
+			wantRanges:  []string{"bytes=" + strconv.Itoa(half) + "-", ""},
+			wantFetched: len(artifact) - half + len(artifact),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranges []string
+			var fetched atomic.Int64
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ranges = append(ranges, r.Header.Get("Range"))
+				rec := &countingWriter{ResponseWriter: w, n: &fetched}
+				if tt.cutFirst && calls.Add(1) == 1 {
+					// Announce the full length but drop the connection halfway.
+					w.Header().Set("Content-Length", strconv.Itoa(len(artifact)))
+					rec.Write(artifact[:half])
+					return
+				}
+				tt.handler(rec, r)
+			}))
+			defer srv.Close()
+
+			dest := filepath.Join(t.TempDir(), "firmware.bin")
+			if tt.partial != nil {
+				if err := os.WriteFile(dest+partialSuffix, tt.partial, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			m := &Manager{httpClient: srv.Client()}
+			if err := m.downloadAndVerify(context.Background(), srv.URL, dest, checksum, "", nil); err != nil {
+				t.Fatalf("downloadAndVerify() error = %v", err)
+			}
+
+			got, err := os.ReadFile(dest)
+			if err != nil || !bytes.Equal(got, artifact) {
+				t.Fatalf("stored artifact differs (err = %v)", err)
+			}
+			if _, err := os.Stat(dest + partialSuffix); !os.IsNotExist(err) {
+				t.Errorf("partial file should be gone after success")
+			}
+			if len(ranges) != len(tt.wantRanges) {
+				t.Fatalf("requests with Range %q, want %q", ranges, tt.wantRanges)
+			}
+			for i := range ranges {
+				if ranges[i] != tt.wantRanges[i] {
+					t.Errorf("request %d Range = %q, want %q", i, ranges[i], tt.wantRanges[i])
+				}
+			}
+			if got := fetched.Load(); got != int64(tt.wantFetched) {
+				t.Errorf("fetched %d bytes, want %d", got, tt.wantFetched)
+			}
+		})
+	}
+}
+
+func TestDownloadKeepsPartialFileOnFailure(t *testing.T) {
+	artifact := bytes.Repeat([]byte("firmware"), 512)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(artifact)))
+		w.Write(artifact[:100])
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "firmware.bin")
+	m := &Manager{httpClient: srv.Client()}
+	if err := m.downloadAndVerify(context.Background(), srv.URL, dest, "", "", nil); err == nil {
+		t.Fatal("downloadAndVerify() should fail on a truncated transfer")
+	}
+
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("incomplete artifact must not be stored")
+	}
+	if info, err := os.Stat(dest + partialSuffix); err != nil || info.Size() == 0 {
+		t.Errorf("partial file should be kept for the next attempt, got %v, %v", info, err)
+	}
+}
+
+// countingWriter counts the body bytes sent to the client.
+type countingWriter struct {
+	http.ResponseWriter
+	n *atomic.Int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n.Add(int64(n))
+	return n, err
+}
@@ -6,35 +6,126 @@ import (
 	"time"
 
 	pb "github.com/autopeer-io/autopeer/api/proto/v1"
+	"github.com/autopeer-io/autopeer/internal/agent/task"
 	"github.com/autopeer-io/autopeer/pkg/log"
 )
 
+// CommandFunc executes a command other than an OTA. It must return soon after ctx is
+// cancelled, e.g. when a more urgent command preempts it.
+// A non-empty output, e.g. collected logs, is uploaded to object storage and referenced by
+// the command status, as it is too large for the status itself.
+type CommandFunc func(ctx context.Context, cmd *pb.AgentCommand) (output []byte, err error)
+
+// RegisterCommand executes the commands of commandType with fn. They run on the same scheduler
+// as the OTAs, so that by their priority they preempt an OTA or wait for it.
+// It must be called before the agent starts.
+func (m *Manager) RegisterCommand(commandType string, fn CommandFunc) {
+	m.commands[commandType] = fn
+}
+
+// registerBuiltinCommands registers the executors of the command types every agent understands.
+func (m *Manager) registerBuiltinCommands() {
+	m.RegisterCommand("Reboot", func(ctx context.Context, cmd *pb.AgentCommand) ([]byte, error) {
+		m.AckCommand(ctx, cmd.CommandName, "Running", "Rebooting system...")
+		return nil, m.hal.Reboot()
+	})
+	m.RegisterCommand("ApplyConfig", func(ctx context.Context, cmd *pb.AgentCommand) ([]byte, error) {
+		return nil, m.hal.ApplyConfig(cmd.Parameters)
+	})
+}
+
 func (m *Manager) HandleCommand(ctx context.Context, cmd *pb.AgentCommand) error {
 	log.Info(">>> PROCESSING COMMAND <<<",
 		"Type", cmd.CommandType,
 		"ID", cmd.CommandName,
+		"Priority", cmd.Priority,
 		"Params", cmd.Parameters,
 		"Time", time.Unix(cmd.Timestamp, 0).Format(time.RFC3339))
 
-	if cmd.CommandType != "OTA" {
+	fn, ok := m.commands[cmd.CommandType]
+	if !ok && cmd.CommandType != "OTA" {
+		m.AckCommand(ctx, cmd.CommandName, "Failed", fmt.Sprintf("Unsupported command type %q", cmd.CommandType))
+		return nil
+	}
+
+	m.lock.Lock()
+	if _, ok := m.inflight[cmd.CommandName]; ok {
+		m.lock.Unlock()
+		log.Info("Ignoring resent command that is already being executed", "ID", cmd.CommandName)
 		return nil
 	}
+	m.inflight[cmd.CommandName] = struct{}{}
+	m.lock.Unlock()
+
+	t := &task.Task{
+		Name:     cmd.CommandName,
+		Priority: task.Priority(cmd.Priority),
+		Run: func(ctx context.Context) error {
+			return m.runCommand(ctx, cmd, fn)
+		},
+		Done: func(error) {
+			m.lock.Lock()
+			delete(m.inflight, cmd.CommandName)
+			m.lock.Unlock()
+		},
+	}
+	if cmd.CommandType == "OTA" {
+		// 这里是根据架构设计的后续步骤：
+		// 1. "触发一条消息提醒车主" -> Log / UI Event
+		// 2. "车主点击升级" -> 模拟等待或直接调用
+		// An OTA preempted by a more urgent command resumes afterwards; downloads pick up where they stopped.
+		t.Resumable = true
+		t.Run = func(ctx context.Context) error {
+			return m.execute(ctx, cmd)
+		}
+	}
+
+	// Ack before the command waits its turn, so the controller does not resend it meanwhile.
+	m.AckCommand(ctx, cmd.CommandName, "Received", "")
+	m.tasks.Submit(ctx, t)
+
+	return nil
+}
 
-	// 这里是根据架构设计的后续步骤：
-	// 1. "触发一条消息提醒车主" -> Log / UI Event
-	// 2. "车主点击升级" -> 模拟等待或直接调用
-	go m.execute(ctx, cmd)
+// runCommand executes a command other than an OTA with fn.
+// Unlike an OTA, a preempted command is not resumed: it fails.
+func (m *Manager) runCommand(ctx context.Context, cmd *pb.AgentCommand, fn CommandFunc) error {
+	output, err := fn(ctx, cmd)
+	var key string
+	if err == nil && len(output) > 0 {
+		if key, err = m.uploadResult(ctx, cmd.CommandName, output); err != nil {
+			err = fmt.Errorf("result upload failed: %w", err)
+		}
+	}
 
+	switch {
+	case err != nil && task.Preempted(ctx):
+		m.AckCommand(context.WithoutCancel(ctx), cmd.CommandName, "Failed", task.ErrPreempted.Error())
+		return context.Cause(ctx)
+	case err != nil:
+		m.AckCommand(context.WithoutCancel(ctx), cmd.CommandName, "Failed", err.Error())
+	default:
+		m.ackResult(ctx, cmd.CommandName, key)
+	}
 	return nil
 }
 
 func (m *Manager) HandleResponse(ctx context.Context, resp *pb.OTAResponse) error {
 	fmt.Printf("Got URL: %s\n", resp.DownloadUrl)
 	m.lock.Lock()
-	if ch, ok := m.pending[resp.RequestId]; ok {
-		ch <- resp.DownloadUrl
-		delete(m.pending, resp.RequestId) // 清理
+	defer m.lock.Unlock()
+
+	ch, ok := m.pending[resp.RequestId]
+	if !ok {
+		// The request timed out or was already answered (the response topic is retained).
+		log.Info("Ignoring response to an unknown or expired firmware request", "requestID", resp.RequestId)
+		return nil
+	}
+
+	// Deliver at most one response; requestFirmware removes the entry once it is done waiting.
+	select {
+	case ch <- resp:
+	default:
 	}
-	m.lock.Unlock()
 	return nil
 }
@@ -0,0 +1,151 @@
+package ota
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	pb "github.com/autopeer-io/autopeer/api/proto/v1"
+	"github.com/autopeer-io/autopeer/internal/agent/task"
+)
+
+// acked reports whether the sender recorded an ack of command with status and message.
+func (s *hubSender) acked(command, status, message string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ack := range s.acks {
+		if ack.CommandName == command && ack.Status == status && ack.Message == message {
+			return true
+		}
+	}
+	return false
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestUrgentCommandPreemptsOTA(t *testing.T) {
+	hal := &stubHAL{release: make(chan struct{})}
+	defer close(hal.release)
+
+	// The hub does not answer firmware requests: the OTA waits for its download URL.
+	m := NewManager("LSVAU2180N2183294", nil, http.DefaultClient, Timeouts{URL: time.Minute}, 0, t.TempDir())
+	m.confirmDelay = 0
+	sender := &hubSender{m: m}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.Setup(ctx, hal, sender); err != nil {
+		t.Fatal(err)
+	}
+
+	unlocked := make(chan struct{})
+	m.RegisterCommand("RemoteUnlock", func(ctx context.Context, cmd *pb.AgentCommand) ([]byte, error) {
+		close(unlocked)
+		return nil, nil
+	})
+
+	ota := &pb.AgentCommand{CommandName: "ota-1", CommandType: "OTA", Priority: int32(task.PriorityNormal), Parameters: map[string]string{"version": "v1.2.0"}}
+	if err := m.HandleCommand(ctx, ota); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the OTA to request its firmware", func() bool {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		return len(m.pending) == 1
+	})
+
+	unlock := &pb.AgentCommand{CommandName: "unlock-1", CommandType: "RemoteUnlock", Priority: int32(task.PriorityHigh)}
+	if err := m.HandleCommand(ctx, unlock); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-unlocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the urgent command did not run while the OTA was in progress")
+	}
+
+	waitFor(t, "the unlock to succeed", func() bool { return sender.acked("unlock-1", "Succeeded", "") })
+	if !sender.acked("ota-1", "Running", "Paused for a higher-priority command") {
+		t.Error("the preempted OTA was not reported as paused")
+	}
+	waitFor(t, "the OTA to resume", func() bool {
+		return sender.acked("ota-1", "Running", "Resuming after a higher-priority command")
+	})
+}
+
+func TestUnsupportedCommandFails(t *testing.T) {
+	m := NewManager("LSVAU2180N2183294", nil, http.DefaultClient, Timeouts{}, 0, t.TempDir())
+	sender := &hubSender{m: m}
+	if err := m.Setup(context.Background(), &stubHAL{}, sender); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.HandleCommand(context.Background(), &pb.AgentCommand{CommandName: "honk-1", CommandType: "Honk"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the command to fail", func() bool { return sender.acked("honk-1", "Failed", `Unsupported command type "Honk"`) })
+}
+
+func TestQueuedCommandAcknowledged(t *testing.T) {
+	hal := &stubHAL{release: make(chan struct{})}
+	defer close(hal.release)
+
+	m := NewManager("LSVAU2180N2183294", nil, http.DefaultClient, Timeouts{URL: time.Minute}, 0, t.TempDir())
+	m.confirmDelay = 0
+	sender := &hubSender{m: m}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.Setup(ctx, hal, sender); err != nil {
+		t.Fatal(err)
+	}
+
+	ota := &pb.AgentCommand{CommandName: "ota-1", CommandType: "OTA", Priority: int32(task.PriorityNormal), Parameters: map[string]string{"version": "v1.2.0"}}
+	if err := m.HandleCommand(ctx, ota); err != nil {
+		t.Fatal(err)
+	}
+
+	// Same priority as the OTA: the command waits for it.
+	cfg := &pb.AgentCommand{CommandName: "cfg-1", CommandType: "ApplyConfig", Priority: int32(task.PriorityNormal), Parameters: map[string]string{"speedLimit": "120"}}
+	if err := m.HandleCommand(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !sender.acked("cfg-1", "Received", "") {
+		t.Error("the queued command was not acknowledged")
+	}
+	if sender.acked("cfg-1", "Succeeded", "") {
+		t.Error("the queued command ran before the OTA")
+	}
+}
+
+func TestBuiltinCommands(t *testing.T) {
+	hal := &stubHAL{}
+	m := NewManager("LSVAU2180N2183294", nil, http.DefaultClient, Timeouts{}, 0, t.TempDir())
+	sender := &hubSender{m: m}
+	if err := m.Setup(context.Background(), hal, sender); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &pb.AgentCommand{CommandName: "cfg-1", CommandType: "ApplyConfig", Parameters: map[string]string{"speedLimit": "120"}}
+	if err := m.HandleCommand(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the configuration to be applied", func() bool { return sender.acked("cfg-1", "Succeeded", "") })
+	if hal.config["speedLimit"] != "120" {
+		t.Errorf("applied config = %v, want speedLimit=120", hal.config)
+	}
+
+	reboot := &pb.AgentCommand{CommandName: "reboot-1", CommandType: "Reboot"}
+	if err := m.HandleCommand(context.Background(), reboot); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the reboot to succeed", func() bool { return sender.acked("reboot-1", "Succeeded", "") })
+}
@@ -2,28 +2,87 @@ package ota
 
 import (
 	"context"
+	"crypto/ed25519"
+	"net/http"
+	"os"
 	"sync"
+	"time"
 
+	pb "github.com/autopeer-io/autopeer/api/proto/v1"
 	"github.com/autopeer-io/autopeer/internal/agent/core"
+	"github.com/autopeer-io/autopeer/internal/agent/task"
 	"github.com/autopeer-io/autopeer/internal/pkg/mqtt/adapter"
 )
 
 type Manager struct {
 	vid string
 
+	// publicKey verifies firmware signatures; nil disables signature verification.
+	publicKey ed25519.PublicKey
+
+	// httpClient downloads firmware artifacts.
+	httpClient *http.Client
+
+	// downloadRetryDelay is the pause between two attempts of a failed download.
+	downloadRetryDelay time.Duration
+
+	// timeouts bounds each step of an OTA.
+	timeouts Timeouts
+
+	// urlTTL is the lifetime requested for firmware download URLs; zero leaves it to the hub.
+	urlTTL time.Duration
+
+	// confirmDelay simulates the owner confirming the update.
+	confirmDelay time.Duration
+
+	// workDir holds the downloaded artifacts.
+	workDir string
+
+	// stateDir holds the boot record of an update awaiting confirmation; it must survive a reboot.
+	stateDir string
+
 	hal    core.HAL
 	sender core.Sender
 
 	lock    sync.Mutex
-	pending map[string]chan string
+	pending map[string]chan *pb.OTAResponse
+
+	// uploads holds the result upload requests waiting for the hub's answer, by request ID.
+	uploads map[string]chan *pb.ResultUploadResponse
+
+	// tasks runs the commands, letting urgent ones preempt an OTA that is not installing.
+	tasks *task.Scheduler
+
+	// commands holds the executors of the command types other than OTA, by command type.
+	commands map[string]CommandFunc
+
+	// inflight holds the commands being executed, so a command re-sent by the
+	// controller because its acknowledgement was lost is not executed twice.
+	inflight map[string]struct{}
 }
 
-var _ core.Module = (*Manager)(nil)
+var (
+	_ core.Module  = (*Manager)(nil)
+	_ core.Starter = (*Manager)(nil)
+)
 
-func NewManager(vid string) *Manager {
+func NewManager(vid string, publicKey ed25519.PublicKey, httpClient *http.Client, timeouts Timeouts, urlTTL time.Duration, stateDir string) *Manager {
 	return &Manager{
-		vid:     vid,
-		pending: make(map[string]chan string),
+		vid:        vid,
+		publicKey:  publicKey,
+		httpClient: httpClient,
+
+		downloadRetryDelay: defaultDownloadRetryDelay,
+		timeouts:           timeouts,
+		urlTTL:             urlTTL,
+		confirmDelay:       2 * time.Second,
+		workDir:            os.TempDir(),
+		stateDir:           stateDir,
+		tasks:              task.NewScheduler(),
+		commands:           make(map[string]CommandFunc),
+		pending:            make(map[string]chan *pb.OTAResponse),
+		uploads:            make(map[string]chan *pb.ResultUploadResponse),
+		inflight:           make(map[string]struct{}),
 	}
 }
 
@@ -34,6 +93,13 @@ func (m *Manager) Name() string {
 func (m *Manager) Setup(ctx context.Context, hal core.HAL, sender core.Sender) error {
 	m.hal = hal
 	m.sender = sender
+	m.registerBuiltinCommands()
+	return nil
+}
+
+// Start confirms an update that rebooted into new firmware, now that acks can reach the hub.
+func (m *Manager) Start(ctx context.Context) error {
+	go m.confirmBoot(ctx)
 	return nil
 }
 
@@ -41,5 +107,7 @@ func (m *Manager) Routes() map[core.EventType]adapter.HandlerFunc {
 	return map[core.EventType]adapter.HandlerFunc{
 		core.EventOTACommand:  adapter.ProtoHandler(m.HandleCommand),
 		core.EventOTAResponse: adapter.ProtoHandler(m.HandleResponse),
+
+		core.EventResultUploadResponse: adapter.ProtoHandler(m.HandleResultUploadResponse),
 	}
 }
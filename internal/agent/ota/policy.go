@@ -0,0 +1,32 @@
+package ota
+
+import (
+	"fmt"
+	"strconv"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+	"github.com/autopeer-io/autopeer/pkg/log"
+)
+
+// checkOTAPolicy enforces the OTA policy carried by the command parameters.
+// A command without policy parameters only goes through the HAL safety gates.
+func (m *Manager) checkOTAPolicy(params map[string]string) error {
+	raw, ok := params[iovv1alpha2.OTAParamMinBatteryLevel]
+	if !ok {
+		return nil
+	}
+	minLevel, err := strconv.Atoi(raw)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", iovv1alpha2.OTAParamMinBatteryLevel, raw)
+	}
+
+	level, err := m.hal.GetBatteryLevel()
+	if err != nil {
+		return fmt.Errorf("battery level unavailable: %w", err)
+	}
+	log.Info("Checking OTA policy", "minBatteryLevel", minLevel, "batteryLevel", level)
+	if level < minLevel {
+		return fmt.Errorf("battery level %d%% is below the required %d%%", level, minLevel)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package ota
+
+import (
+	"errors"
+	"testing"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// batteryHAL reports level, or err if set.
+type batteryHAL struct {
+	stubHAL
+	level int
+	err   error
+}
+
+func (h *batteryHAL) GetBatteryLevel() (int, error) { return h.level, h.err }
+
+func TestCheckOTAPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		hal     *batteryHAL
+		wantErr bool
+	}{
+		{name: "no policy", params: map[string]string{"version": "v1.2.0"}, hal: &batteryHAL{level: 5}},
+		{name: "above minimum", params: map[string]string{iovv1alpha2.OTAParamMinBatteryLevel: "50"}, hal: &batteryHAL{level: 80}},
+		{name: "below minimum", params: map[string]string{iovv1alpha2.OTAParamMinBatteryLevel: "50"}, hal: &batteryHAL{level: 30}, wantErr: true},
+		// An emergency override lowering the minimum lets the same vehicle through.
+		{name: "override", params: map[string]string{iovv1alpha2.OTAParamMinBatteryLevel: "0"}, hal: &batteryHAL{level: 30}},
+		{name: "invalid value", params: map[string]string{iovv1alpha2.OTAParamMinBatteryLevel: "low"}, hal: &batteryHAL{level: 80}, wantErr: true},
+		{name: "battery unknown", params: map[string]string{iovv1alpha2.OTAParamMinBatteryLevel: "50"}, hal: &batteryHAL{err: errors.New("no sensor")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{hal: tt.hal}
+			if err := m.checkOTAPolicy(tt.params); (err != nil) != tt.wantErr {
+				t.Errorf("checkOTAPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,17 +2,23 @@ package ota
 
 import (
 	"context"
-	"crypto/tls"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"path/filepath"
 	"time"
 
 	pb "github.com/autopeer-io/autopeer/api/proto/v1"
 	"github.com/autopeer-io/autopeer/internal/agent/core"
+	"github.com/autopeer-io/autopeer/internal/agent/task"
 	"github.com/autopeer-io/autopeer/pkg/log"
 )
 
+// File names of the downloaded artifacts inside the manager's work directory.
+const (
+	firmwareImageFile = "firmware.bin"
+	firmwarePatchFile = "firmware.patch"
+)
+
 func (m *Manager) AckCommand(ctx context.Context, name, status, message string) {
 	ack := &pb.AgentCommandStatus{
 		CommandName: name,
@@ -25,131 +31,234 @@ func (m *Manager) AckCommand(ctx context.Context, name, status, message string)
 	}
 }
 
-func (m *Manager) execute(ctx context.Context, cmd *pb.AgentCommand) {
-	// 1. 收到指令
-	m.AckCommand(ctx, cmd.CommandName, "Received", "Security check passed")
-
-	// 模拟：车主等待确认 (例如 2秒)
-	log.Info("[UI] User notification: New firmware available. Click to upgrade.")
-	time.Sleep(2 * time.Second)
-	log.Info("[UI] User clicked 'Upgrade'. Requesting URL...")
-
-	// 2. 请求 URL
-	targetVer := cmd.Parameters["version"]
-	reqID := fmt.Sprintf("req-%d", time.Now().UnixNano())
+// execute runs an OTA. It returns an error wrapping task.ErrPreempted if it paused for a more
+// urgent command and must be resumed; any other outcome has been acked.
+func (m *Manager) execute(ctx context.Context, cmd *pb.AgentCommand) error {
+	// 1. 收到指令; 被抢占后恢复时车主已确认过
+	if task.Resumed(ctx) {
+		m.AckCommand(ctx, cmd.CommandName, "Running", "Resuming after a higher-priority command")
+	} else {
+		m.AckCommand(ctx, cmd.CommandName, "Received", "Security check passed")
 
-	// 创建接收通道
-	respChan := make(chan string, 1)
-	m.lock.Lock()
-	m.pending[reqID] = respChan
-	m.lock.Unlock()
-
-	// 发送请求
-	req := &pb.OTARequest{
-		VehicleId:      m.vid,
-		DesiredVersion: targetVer,
-		RequestId:      reqID,
+		// 模拟：车主等待确认 (例如 2秒)
+		log.Info("[UI] User notification: New firmware available. Click to upgrade.")
+		if err := sleep(ctx, m.confirmDelay); err != nil {
+			return m.fail(ctx, cmd.CommandName, err, err.Error())
+		}
+		log.Info("[UI] User clicked 'Upgrade'. Requesting URL...")
 	}
 
-	err := m.sender.SendProto(ctx, core.EventOTARequest, req)
+	// 2. 请求 URL (携带当前版本, Hub 有差分包时优先下发差分包)
+	targetVer := cmd.Parameters["version"]
+	resp, err := m.requestFirmware(ctx, targetVer, m.hal.GetFirmwareVersion())
 	if err != nil {
-		log.Error(err, "Faile to send OTA request")
-	}
-
-	var downloadURL string
-
-	// 3. 等待响应 (带超时)
-	select {
-	case url := <-respChan:
-		downloadURL = url
-		log.Info("Received Firmware URL", "url", url)
-	case <-time.After(15 * time.Second):
-		log.Error(nil, "Timeout waiting for firmware URL")
-		m.AckCommand(ctx, cmd.CommandName, "Failed", "Timeout fetching URL")
-
-		// 清理 map
-		m.lock.Lock()
-		delete(m.pending, reqID)
-		m.lock.Unlock()
-		return
+		log.Error(err, "Failed to get firmware URL")
+		return m.fail(ctx, cmd.CommandName, err, err.Error())
 	}
 
-	// 4. 开始下载 (Running)
+	// 3. 开始下载 (Running)
 	m.AckCommand(ctx, cmd.CommandName, "Running", "Downloading firmware artifact...")
 
-	// 执行真实的下载校验
-	if err := downloadAndVerify(downloadURL); err != nil {
+	// 4. 下载校验; 差分包失败时回退到完整镜像
+	// The command may pin the checksum of the full image; the hub's value takes precedence.
+	pinned := cmd.Parameters["checksum"]
+	progress := m.reportDownloadProgress(ctx, cmd.CommandName)
+	err = runStep(ctx, stepDownload, m.timeouts.Download, func(ctx context.Context) error {
+		return m.fetchFirmware(ctx, resp, pinned, progress)
+	})
+	if err != nil && resp.BaseVersion != "" && ctx.Err() == nil {
+		log.Warn("Delta update failed, falling back to full image", "baseVersion", resp.BaseVersion, "error", err.Error())
+		m.AckCommand(ctx, cmd.CommandName, "Running", "Delta update failed, downloading full image...")
+
+		if resp, err = m.requestFirmware(ctx, targetVer, ""); err == nil {
+			err = runStep(ctx, stepDownload, m.timeouts.Download, func(ctx context.Context) error {
+				return m.fetchFirmware(ctx, resp, pinned, progress)
+			})
+		}
+	}
+	if err != nil {
 		log.Error(err, "Download failed")
-		m.AckCommand(ctx, cmd.CommandName, "Failed", fmt.Sprintf("Download failed: %v", err))
-		return
+		return m.fail(ctx, cmd.CommandName, err, fmt.Sprintf("Download failed: %v", err))
 	}
 
-	// 5. 安全门禁 (调用 HAL)
+	// 5. 安全门禁 (OTA 策略, 调用 HAL)
 	log.Info("Performing safety checks before installation...")
+	if err := m.checkOTAPolicy(cmd.Parameters); err != nil {
+		log.Error(err, "OTA policy check failed")
+		return m.fail(ctx, cmd.CommandName, err, fmt.Sprintf("OTA policy not met: %v", err))
+	}
 	if err := m.hal.CheckSafety(); err != nil {
 		log.Error(err, "Safety check failed")
-		m.AckCommand(ctx, cmd.CommandName, "Failed", fmt.Sprintf("Safety check failed: %v", err))
-		return
+		return m.fail(ctx, cmd.CommandName, err, fmt.Sprintf("Safety check failed: %v", err))
 	}
 
+	// 6-9. 安装到重启不可被抢占, 保证 OTA 原子性
+	if err := ctx.Err(); err != nil {
+		return m.fail(ctx, cmd.CommandName, err, err.Error())
+	}
+	// A preemption deferred until the install ends must not run the finished OTA again.
+	task.Critical(ctx, func() error {
+		m.install(ctx, cmd.CommandName, targetVer)
+		return nil
+	})
+	return nil
+}
+
+// install writes the downloaded image to the inactive slot, switches to it and reboots.
+// It runs in a critical section, so a more urgent command never interrupts it.
+func (m *Manager) install(ctx context.Context, name, targetVer string) {
 	// 6. 原子安装 (调用 HAL)
-	m.AckCommand(ctx, cmd.CommandName, "Running", "Installing to Slot B...")
-	if err := m.hal.InstallFirmware("/tmp/firmware.bin", targetVer); err != nil {
+	m.AckCommand(ctx, name, "Running", "Installing to Slot B...")
+	if err := runStep(ctx, stepInstall, m.timeouts.Install, func(context.Context) error {
+		return m.hal.InstallFirmware(filepath.Join(m.workDir, firmwareImageFile), targetVer)
+	}); err != nil {
 		log.Error(err, "Installation failed")
-		m.AckCommand(ctx, cmd.CommandName, "Failed", "Write partition failed")
+		_ = m.fail(ctx, name, err, "Write partition failed")
 		return
 	}
 
-	// 7. 切换引导 (调用 HAL)
-	if err := m.hal.SwitchBootSlot(); err != nil {
-		m.AckCommand(ctx, cmd.CommandName, "Failed", "Switch slot failed")
+	// 7. 记录待确认的启动, 然后切换引导 (调用 HAL)
+	if err := m.saveBootRecord(&bootRecord{Command: name, Version: targetVer, PreviousVersion: m.hal.GetFirmwareVersion()}); err != nil {
+		log.Error(err, "Failed to record pending boot")
+		_ = m.fail(ctx, name, err, "Failed to record pending boot")
+		return
+	}
+	if err := runStep(ctx, stepInstall, m.timeouts.Install, func(context.Context) error {
+		return m.hal.SwitchBootSlot()
+	}); err != nil {
+		m.clearBootRecord()
+		_ = m.fail(ctx, name, err, "Switch slot failed")
 		return
 	}
 
 	// 8. 最终确认 & 重启
-	m.AckCommand(ctx, cmd.CommandName, "Running", "Rebooting system...")
+	m.AckCommand(ctx, name, "Running", "Rebooting system...")
 	log.Info("OTA sequence complete. Requesting system reboot.")
 
 	// 给一点时间让 MQTT 消息发出去
-	time.Sleep(1 * time.Second)
+	if err := sleep(ctx, 1*time.Second); err != nil {
+		_ = m.fail(ctx, name, err, err.Error())
+		return
+	}
 
-	if err := m.hal.Reboot(); err != nil {
-		m.AckCommand(ctx, cmd.CommandName, "Failed", "Reboot failed")
+	if err := runStep(ctx, stepReboot, m.timeouts.Reboot, func(context.Context) error {
+		return m.hal.Reboot()
+	}); err != nil {
+		_ = m.fail(ctx, name, err, "Reboot failed")
 		log.Error(err, "Reboot failed")
 		return
 	}
 
-	// 9. 完成
-	m.AckCommand(ctx, cmd.CommandName, "Succeeded", "Update installed")
+	// 9. 新分区启动后确认健康 (真实设备上由重启后的 Start 执行)
+	m.confirmBoot(ctx)
 }
 
-// downloadAndVerify performs a real HTTP GET to validate the URL.
-// In a production agent, this would also verify SHA256 checksums and write to disk.
-func downloadAndVerify(url string) error {
-	client := &http.Client{
-		Timeout: 10 * time.Minute,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		},
+// fail acks a failed OTA step. A step that ran out of time is reported as "<step> timeout",
+// anything else as msg. The ack is sent even if the sequence was cancelled.
+// A sequence preempted by a more urgent command is only paused, not failed: fail then returns
+// the preemption cause, for the scheduler to resume the OTA, and nil otherwise.
+func (m *Manager) fail(ctx context.Context, name string, err error, msg string) error {
+	if task.Preempted(ctx) {
+		m.AckCommand(context.WithoutCancel(ctx), name, "Running", "Paused for a higher-priority command")
+		return context.Cause(ctx)
 	}
 
-	resp, err := client.Get(url)
-	if err != nil {
-		return fmt.Errorf("network error: %w", err)
+	var timeout *stepError
+	switch {
+	case errors.As(err, &timeout):
+		msg = timeout.Error()
+	case errors.Is(err, context.Canceled):
+		msg = "OTA aborted"
 	}
-	defer resp.Body.Close()
+	m.AckCommand(context.WithoutCancel(ctx), name, "Failed", msg)
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("server returned status: %s", resp.Status)
+// requestFirmware asks the hub for a download URL and waits for the response.
+// An empty currentVersion requests the full image.
+func (m *Manager) requestFirmware(ctx context.Context, targetVer, currentVersion string) (*pb.OTAResponse, error) {
+	reqID := fmt.Sprintf("req-%d", time.Now().UnixNano())
+
+	// 创建接收通道
+	respChan := make(chan *pb.OTAResponse, 1)
+	m.lock.Lock()
+	m.pending[reqID] = respChan
+	m.lock.Unlock()
+
+	// Forget the request however it ends, so a late response finds nothing to deliver to.
+	defer func() {
+		m.lock.Lock()
+		delete(m.pending, reqID)
+		m.lock.Unlock()
+	}()
+
+	// 发送请求
+	req := &pb.OTARequest{
+		VehicleId:      m.vid,
+		DesiredVersion: targetVer,
+		RequestId:      reqID,
+		CurrentVersion: currentVersion,
+		UrlTtlSeconds:  int64(m.urlTTL / time.Second),
+	}
+
+	if err := m.sender.SendProto(ctx, core.EventOTARequest, req); err != nil {
+		log.Error(err, "Faile to send OTA request")
+	}
+
+	// 等待响应 (带超时)
+	ctx, cancel := withStepTimeout(ctx, m.timeouts.URL)
+	defer cancel()
+
+	select {
+	case resp := <-respChan:
+		log.Info("Received Firmware URL", "url", resp.DownloadUrl, "checksum", resp.Checksum, "baseVersion", resp.BaseVersion)
+		if resp.ErrorMessage != "" {
+			return nil, fmt.Errorf("hub could not provide firmware URL: %s", resp.ErrorMessage)
+		}
+		return resp, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, &stepError{step: stepURLFetch}
+		}
+		return nil, ctx.Err()
 	}
+}
+
+// fetchFirmware downloads the artifact and, for a delta, rebuilds the full image from it.
+// pinnedChecksum is used for a full image if the hub provided none.
+func (m *Manager) fetchFirmware(ctx context.Context, resp *pb.OTAResponse, pinnedChecksum string, progress progressFunc) error {
+	imagePath := filepath.Join(m.workDir, firmwareImageFile)
+	patchPath := filepath.Join(m.workDir, firmwarePatchFile)
 
-	// Simulate consuming the body (or write to /tmp/firmware.bin)
-	// We just read it to ensure the stream is valid.
-	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
-		return fmt.Errorf("failed to read body: %w", err)
+	if resp.BaseVersion == "" {
+		checksum := resp.Checksum
+		if checksum == "" {
+			checksum = pinnedChecksum
+		}
+		return m.downloadAndVerify(ctx, resp.DownloadUrl, imagePath, checksum, resp.Signature, progress)
 	}
 
+	if err := m.downloadAndVerify(ctx, resp.DownloadUrl, patchPath, resp.Checksum, resp.Signature, progress); err != nil {
+		return err
+	}
+
+	if current := m.hal.GetFirmwareVersion(); current != resp.BaseVersion {
+		return fmt.Errorf("delta base %s does not match running version %s", resp.BaseVersion, current)
+	}
+	if err := m.hal.ApplyDelta(patchPath, imagePath); err != nil {
+		return fmt.Errorf("failed to apply delta: %w", err)
+	}
 	return nil
 }
+
+// reportDownloadProgress returns a progressFunc that acks the download progress of a command,
+// so it shows up in the VehicleCommand's status message.
+func (m *Manager) reportDownloadProgress(ctx context.Context, name string) progressFunc {
+	return func(done, total int64) {
+		if total > 0 {
+			m.AckCommand(ctx, name, "Running", fmt.Sprintf("Downloading firmware artifact... %d%%", done*100/total))
+			return
+		}
+		m.AckCommand(ctx, name, "Running", fmt.Sprintf("Downloading firmware artifact... %.1f MiB", float64(done)/(1<<20)))
+	}
+}
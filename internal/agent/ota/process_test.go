@@ -0,0 +1,233 @@
+package ota
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/autopeer-io/autopeer/api/proto/v1"
+	"github.com/autopeer-io/autopeer/internal/agent/core"
+)
+
+// stubHAL blocks InstallFirmware until release is closed.
+type stubHAL struct {
+	release chan struct{}
+
+	// config is the configuration last applied.
+	config map[string]string
+}
+
+func (h *stubHAL) GetVehicleID() string                       { return "LSVAU2180N2183294" }
+func (h *stubHAL) GetFirmwareVersion() string                 { return "v1.0.0" }
+func (h *stubHAL) GetBatteryLevel() (int, error)              { return 80, nil }
+func (h *stubHAL) CheckSafety() error                         { return nil }
+func (h *stubHAL) MarkBootSuccessful() error                  { return nil }
+func (h *stubHAL) ApplyDelta(patchPath, outPath string) error { return nil }
+func (h *stubHAL) SwitchBootSlot() error                      { return nil }
+func (h *stubHAL) Reboot() error                              { return nil }
+func (h *stubHAL) ConfirmActiveSlot() error                   { return nil }
+
+func (h *stubHAL) ApplyConfig(properties map[string]string) error {
+	h.config = properties
+	return nil
+}
+
+func (h *stubHAL) InstallFirmware(path, version string) error {
+	<-h.release
+	return nil
+}
+
+// hubSender answers firmware URL requests with url and result upload requests with uploadURL
+// (unless empty), and records the firmware requests and the acks.
+type hubSender struct {
+	m         *Manager
+	url       string
+	uploadURL string
+
+	mu       sync.Mutex
+	requests []*pb.OTARequest
+	acks     []*pb.AgentCommandStatus
+}
+
+func (s *hubSender) Send(ctx context.Context, event core.EventType, payload []byte) error {
+	return nil
+}
+
+func (s *hubSender) SendProto(ctx context.Context, event core.EventType, msg proto.Message) error {
+	switch msg := msg.(type) {
+	case *pb.OTARequest:
+		s.mu.Lock()
+		s.requests = append(s.requests, msg)
+		s.mu.Unlock()
+		if s.url != "" {
+			go s.m.HandleResponse(ctx, &pb.OTAResponse{RequestId: msg.RequestId, DownloadUrl: s.url})
+		}
+	case *pb.ResultUploadRequest:
+		if s.uploadURL != "" {
+			key := "results/" + msg.VehicleId + "/" + msg.CommandName
+			go s.m.HandleResultUploadResponse(ctx, &pb.ResultUploadResponse{RequestId: msg.RequestId, UploadUrl: s.uploadURL + "/" + key, ObjectKey: key})
+		}
+	case *pb.AgentCommandStatus:
+		s.mu.Lock()
+		s.acks = append(s.acks, msg)
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+func (s *hubSender) lastAck() *pb.AgentCommandStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.acks[len(s.acks)-1]
+}
+
+func TestExecuteStepTimeouts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("firmware"))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		cancel  bool
+		wantMsg string
+	}{
+		{name: "hub never answers", wantMsg: "url-fetch timeout"},
+		{name: "install hangs", url: srv.URL, wantMsg: "install timeout"},
+		{name: "cancelled during install", url: srv.URL, cancel: true, wantMsg: "OTA aborted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hal := &stubHAL{release: make(chan struct{})}
+			defer close(hal.release)
+
+			m := &Manager{
+				hal:        hal,
+				httpClient: srv.Client(),
+				timeouts:   Timeouts{URL: 50 * time.Millisecond, Install: 50 * time.Millisecond},
+				workDir:    t.TempDir(),
+				pending:    make(map[string]chan *pb.OTAResponse),
+			}
+			sender := &hubSender{m: m, url: tt.url}
+			m.sender = sender
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				m.timeouts.Install = time.Minute
+				time.AfterFunc(100*time.Millisecond, cancel)
+			}
+
+			done := make(chan struct{})
+			go func() {
+				m.execute(ctx, &pb.AgentCommand{CommandName: "ota-1", CommandType: "OTA", Parameters: map[string]string{"version": "v1.2.0"}})
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("execute did not return after the step deadline")
+			}
+
+			if ack := sender.lastAck(); ack.Status != "Failed" || ack.Message != tt.wantMsg {
+				t.Errorf("last ack = %s %q, want Failed %q", ack.Status, ack.Message, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestExecutePolicyNotMet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("firmware"))
+	}))
+	defer srv.Close()
+
+	m := &Manager{
+		vid:        "LSVAU2180N2183294",
+		hal:        &stubHAL{},
+		httpClient: srv.Client(),
+		timeouts:   Timeouts{URL: time.Second},
+		workDir:    t.TempDir(),
+		pending:    make(map[string]chan *pb.OTAResponse),
+	}
+	sender := &hubSender{m: m, url: srv.URL}
+	m.sender = sender
+
+	cmd := &pb.AgentCommand{CommandName: "ota-1", CommandType: "OTA", Parameters: map[string]string{"version": "v1.2.0", "minBatteryLevel": "90"}}
+	if err := m.execute(context.Background(), cmd); err != nil {
+		t.Fatalf("execute() = %v, want nil", err)
+	}
+
+	want := "OTA policy not met: battery level 80% is below the required 90%"
+	if ack := sender.lastAck(); ack.Status != "Failed" || ack.Message != want {
+		t.Errorf("last ack = %s %q, want Failed %q", ack.Status, ack.Message, want)
+	}
+}
+
+func TestRequestFirmwareCleansUpPending(t *testing.T) {
+	m := &Manager{
+		timeouts: Timeouts{URL: 20 * time.Millisecond},
+		pending:  make(map[string]chan *pb.OTAResponse),
+	}
+	sender := &hubSender{m: m, url: "http://hub/firmware"}
+	m.sender = sender
+
+	for i := 0; i < 100; i++ {
+		if _, err := m.requestFirmware(context.Background(), "v1.2.0", ""); err != nil {
+			t.Fatalf("cycle %d: requestFirmware() error = %v", i, err)
+		}
+	}
+
+	// Unanswered requests time out; a late response must not resurrect the entry.
+	sender.url = ""
+	for i := 0; i < 10; i++ {
+		if _, err := m.requestFirmware(context.Background(), "v1.2.0", ""); err == nil {
+			t.Fatalf("cycle %d: expected a timeout", i)
+		}
+	}
+	m.HandleResponse(context.Background(), &pb.OTAResponse{RequestId: "req-late"})
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if len(m.pending) != 0 {
+		t.Errorf("len(m.pending) = %d after all OTA cycles, want 0", len(m.pending))
+	}
+}
+
+func TestRequestFirmwareURLTTL(t *testing.T) {
+	tests := []struct {
+		name   string
+		urlTTL time.Duration
+		want   int64
+	}{
+		{name: "left to the hub", want: 0},
+		{name: "configured", urlTTL: 10 * time.Minute, want: 600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{
+				timeouts: Timeouts{URL: time.Second},
+				urlTTL:   tt.urlTTL,
+				pending:  make(map[string]chan *pb.OTAResponse),
+			}
+			sender := &hubSender{m: m, url: "http://hub/firmware"}
+			m.sender = sender
+
+			if _, err := m.requestFirmware(context.Background(), "v1.2.0", ""); err != nil {
+				t.Fatalf("requestFirmware() error = %v", err)
+			}
+			if got := sender.requests[0].UrlTtlSeconds; got != tt.want {
+				t.Errorf("UrlTtlSeconds = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package ota
+
+import (
+	"time"
+)
+
+const (
+	// progressStep is the download percentage between two progress reports.
+	progressStep = 10
+
+	// progressInterval is how often progress is reported when the artifact size is unknown.
+	progressInterval = 5 * time.Second
+)
+
+// progressFunc receives the downloaded and the total number of bytes; total is -1 if unknown.
+type progressFunc func(done, total int64)
+
+// progressWriter counts downloaded bytes and reports them to a progressFunc, throttled to
+// one report per progressStep percent, or per progressInterval if the total size is unknown.
+// Reports go out over MQTT, so a chatty link must not turn into a flood of acks.
+type progressWriter struct {
+	report progressFunc
+	done   int64
+	total  int64
+
+	lastPercent int64
+	lastReport  time.Time
+
+	// now is overridable for tests.
+	now func() time.Time
+}
+
+func newProgressWriter(report progressFunc, done, total int64) *progressWriter {
+	w := &progressWriter{report: report, done: done, total: total, now: time.Now}
+	if total > 0 {
+		w.lastPercent = done * 100 / total
+	}
+	w.lastReport = w.now()
+	return w
+}
+
+// Write implements io.Writer.
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.done += int64(len(p))
+	if w.report == nil {
+		return len(p), nil
+	}
+
+	if w.total > 0 {
+		percent := w.done * 100 / w.total
+		if percent/progressStep > w.lastPercent/progressStep {
+			w.lastPercent = percent
+			w.report(w.done, w.total)
+		}
+		return len(p), nil
+	}
+
+	if now := w.now(); now.Sub(w.lastReport) >= progressInterval {
+		w.lastReport = now
+		w.report(w.done, -1)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,87 @@
+package ota
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/autopeer-io/autopeer/api/proto/v1"
+	"github.com/autopeer-io/autopeer/internal/agent/core"
+)
+
+// recordingSender records the command acks sent by the manager.
+type recordingSender struct{ acks []*pb.AgentCommandStatus }
+
+func (s *recordingSender) Send(ctx context.Context, event core.EventType, payload []byte) error {
+	return nil
+}
+
+func (s *recordingSender) SendProto(ctx context.Context, event core.EventType, msg proto.Message) error {
+	if ack, ok := msg.(*pb.AgentCommandStatus); ok {
+		s.acks = append(s.acks, ack)
+	}
+	return nil
+}
+
+func TestDownloadReportsProgress(t *testing.T) {
+	artifact := bytes.Repeat([]byte("firmware"), 64*1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Small writes, as a slow link would deliver them.
+		w.Header().Set("Content-Length", fmt.Sprint(len(artifact)))
+		for i := 0; i < len(artifact); i += 4096 {
+			w.Write(artifact[i : i+4096])
+		}
+	}))
+	defer srv.Close()
+
+	sender := &recordingSender{}
+	m := &Manager{httpClient: srv.Client(), sender: sender}
+	dest := filepath.Join(t.TempDir(), "firmware.bin")
+	if err := m.downloadAndVerify(context.Background(), srv.URL, dest, "", "", m.reportDownloadProgress(context.Background(), "ota-1")); err != nil {
+		t.Fatalf("downloadAndVerify() error = %v", err)
+	}
+
+	// At most one ack per 10%, not one per chunk, ending with the complete download.
+	if len(sender.acks) < 2 || len(sender.acks) > 10 {
+		t.Fatalf("got %d progress acks, want between 2 and 10", len(sender.acks))
+	}
+	last := 0
+	for i, ack := range sender.acks {
+		var percent int
+		if _, err := fmt.Sscanf(ack.Message, "Downloading firmware artifact... %d%%", &percent); err != nil {
+			t.Fatalf("ack %d has unexpected message %q", i, ack.Message)
+		}
+		if ack.CommandName != "ota-1" || ack.Status != "Running" || percent/10 <= last/10 {
+			t.Errorf("ack %d = %s/%s %d%%, want ota-1/Running past %d%%", i, ack.CommandName, ack.Status, percent, last)
+		}
+		last = percent
+	}
+	if last != 100 {
+		t.Errorf("last progress = %d%%, want 100%%", last)
+	}
+}
+
+func TestProgressWriterUnknownSize(t *testing.T) {
+	now := time.Now()
+	var reports []int64
+	w := newProgressWriter(func(done, total int64) { reports = append(reports, done) }, 0, -1)
+	w.now = func() time.Time { return now }
+	w.lastReport = now
+
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Second)
+		w.Write(make([]byte, 100))
+	}
+
+	// Reported after 5s and 10s.
+	if len(reports) != 2 || reports[0] != 500 || reports[1] != 1000 {
+		t.Errorf("reports = %v, want [500 1000]", reports)
+	}
+}
@@ -0,0 +1,117 @@
+package ota
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	pb "github.com/autopeer-io/autopeer/api/proto/v1"
+	"github.com/autopeer-io/autopeer/internal/agent/core"
+	"github.com/autopeer-io/autopeer/pkg/log"
+)
+
+// ackResult acks a succeeded command, referencing its uploaded output by key if it has one.
+func (m *Manager) ackResult(ctx context.Context, name, key string) {
+	ack := &pb.AgentCommandStatus{
+		CommandName: name,
+		Status:      "Succeeded",
+		ResultKey:   key,
+	}
+
+	if err := m.sender.SendProto(ctx, core.EventCommandStatus, ack); err != nil {
+		log.Error(err, "Failed to ack command status", "name", name, "status", ack.Status, "resultKey", key)
+	}
+}
+
+// uploadResult uploads the output of a command to the presigned URL the hub issues for it,
+// and returns the object key to report in the command status.
+func (m *Manager) uploadResult(ctx context.Context, name string, output []byte) (string, error) {
+	resp, err := m.requestResultUpload(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	err = runStep(ctx, stepUpload, m.timeouts.Download, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, resp.UploadUrl, bytes.NewReader(output))
+		if err != nil {
+			return err
+		}
+		res, err := m.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return fmt.Errorf("bad status: %s", res.Status)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Info("Uploaded command result", "command", name, "key", resp.ObjectKey, "bytes", len(output))
+	return resp.ObjectKey, nil
+}
+
+// requestResultUpload asks the hub for an upload URL for the result of a command and waits for the response.
+func (m *Manager) requestResultUpload(ctx context.Context, name string) (*pb.ResultUploadResponse, error) {
+	reqID := fmt.Sprintf("upload-%d", time.Now().UnixNano())
+
+	respChan := make(chan *pb.ResultUploadResponse, 1)
+	m.lock.Lock()
+	m.uploads[reqID] = respChan
+	m.lock.Unlock()
+
+	// Forget the request however it ends, so a late response finds nothing to deliver to.
+	defer func() {
+		m.lock.Lock()
+		delete(m.uploads, reqID)
+		m.lock.Unlock()
+	}()
+
+	req := &pb.ResultUploadRequest{
+		VehicleId:   m.vid,
+		RequestId:   reqID,
+		CommandName: name,
+	}
+	if err := m.sender.SendProto(ctx, core.EventResultUploadRequest, req); err != nil {
+		log.Error(err, "Failed to send result upload request")
+	}
+
+	ctx, cancel := withStepTimeout(ctx, m.timeouts.URL)
+	defer cancel()
+
+	select {
+	case resp := <-respChan:
+		if resp.ErrorMessage != "" {
+			return nil, fmt.Errorf("hub could not provide upload URL: %s", resp.ErrorMessage)
+		}
+		return resp, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, &stepError{step: stepURLFetch}
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (m *Manager) HandleResultUploadResponse(ctx context.Context, resp *pb.ResultUploadResponse) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ch, ok := m.uploads[resp.RequestId]
+	if !ok {
+		log.Info("Ignoring response to an unknown or expired result upload request", "requestID", resp.RequestId)
+		return nil
+	}
+
+	select {
+	case ch <- resp:
+	default:
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package ota
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/autopeer-io/autopeer/api/proto/v1"
+)
+
+func TestCommandResultUpload(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		uploaded = make(map[string]string)
+	)
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// An expired presigned URL is rejected.
+		if r.Method != http.MethodPut || strings.HasPrefix(r.URL.Path, "/expired/") {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		uploaded[r.URL.Path] = string(body)
+		mu.Unlock()
+	}))
+	defer storage.Close()
+
+	tests := []struct {
+		name      string
+		uploadURL string
+		output    string
+		wantAck   *pb.AgentCommandStatus
+		wantBody  string
+	}{
+		{
+			name:      "output uploaded and referenced",
+			uploadURL: storage.URL,
+			output:    "kernel: boot ok\n",
+			wantAck:   &pb.AgentCommandStatus{CommandName: "logs-1", Status: "Succeeded", ResultKey: "results/LSVAU2180N2183294/logs-1"},
+			wantBody:  "kernel: boot ok\n",
+		},
+		{
+			name:      "no output",
+			uploadURL: storage.URL,
+			wantAck:   &pb.AgentCommandStatus{CommandName: "logs-1", Status: "Succeeded"},
+		},
+		{
+			name:    "hub does not answer",
+			output:  "kernel: boot ok\n",
+			wantAck: &pb.AgentCommandStatus{CommandName: "logs-1", Status: "Failed", Message: "result upload failed: url-fetch timeout"},
+		},
+		{
+			name:      "storage rejects the upload",
+			uploadURL: storage.URL + "/expired",
+			output:    "kernel: boot ok\n",
+			wantAck:   &pb.AgentCommandStatus{CommandName: "logs-1", Status: "Failed", Message: "result upload failed: bad status: 403 Forbidden"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			clear(uploaded)
+			mu.Unlock()
+
+			m := NewManager("LSVAU2180N2183294", nil, storage.Client(), Timeouts{URL: 50 * time.Millisecond}, 0, t.TempDir())
+			sender := &hubSender{m: m, uploadURL: tt.uploadURL}
+			if err := m.Setup(context.Background(), &stubHAL{}, sender); err != nil {
+				t.Fatal(err)
+			}
+			m.RegisterCommand("UploadLogs", func(ctx context.Context, cmd *pb.AgentCommand) ([]byte, error) {
+				return []byte(tt.output), nil
+			})
+
+			if err := m.HandleCommand(context.Background(), &pb.AgentCommand{CommandName: "logs-1", CommandType: "UploadLogs"}); err != nil {
+				t.Fatal(err)
+			}
+			want := tt.wantAck
+			waitFor(t, "the command to finish", func() bool {
+				sender.mu.Lock()
+				defer sender.mu.Unlock()
+				for _, ack := range sender.acks {
+					if ack.Status == want.Status && ack.Message == want.Message && ack.ResultKey == want.ResultKey {
+						return true
+					}
+				}
+				return false
+			})
+
+			if tt.wantBody != "" {
+				mu.Lock()
+				defer mu.Unlock()
+				if got := uploaded["/"+want.ResultKey]; got != tt.wantBody {
+					t.Errorf("uploaded %q under %s, want %q", got, want.ResultKey, tt.wantBody)
+				}
+			}
+		})
+	}
+}
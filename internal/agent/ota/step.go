@@ -0,0 +1,95 @@
+package ota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+// Timeouts bounds the steps of an OTA sequence. A zero timeout leaves the step unbounded.
+type Timeouts struct {
+	URL      time.Duration
+	Download time.Duration
+	Install  time.Duration
+	Reboot   time.Duration
+
+	// BootConfirm is the watchdog window for new firmware to pass its health check after the reboot.
+	BootConfirm time.Duration
+}
+
+// TimeoutsFromOptions returns the step timeouts configured in opts.
+func TimeoutsFromOptions(opts *options.OTAOptions) Timeouts {
+	return Timeouts{
+		URL:      opts.URLTimeout,
+		Download: opts.DownloadTimeout,
+		Install:  opts.InstallTimeout,
+		Reboot:   opts.RebootTimeout,
+
+		BootConfirm: opts.BootConfirmTimeout,
+	}
+}
+
+// Names of the OTA steps, as reported in "<step> timeout" failures.
+const (
+	stepURLFetch = "url-fetch"
+	stepDownload = "download"
+	stepUpload   = "upload"
+	stepInstall  = "install"
+	stepReboot   = "reboot"
+
+	stepBootConfirm = "boot-confirm"
+)
+
+// stepError is returned when a step did not finish within its timeout.
+type stepError struct{ step string }
+
+func (e *stepError) Error() string { return e.step + " timeout" }
+
+// withStepTimeout returns a context bounded by timeout, or only cancellable if timeout is zero.
+func withStepTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// runStep runs fn with the step's timeout. fn should honor its context, but HAL calls
+// can't: a call still running at the deadline is abandoned, so a hung install never
+// wedges the agent. The step fails with a stepError on timeout and with the parent's
+// error if the parent context is cancelled.
+func runStep(ctx context.Context, step string, timeout time.Duration, fn func(ctx context.Context) error) error {
+	stepCtx, cancel := withStepTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- fn(stepCtx) }()
+
+	select {
+	case err := <-done:
+		if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			return &stepError{step: step}
+		}
+		return err
+	case <-stepCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%s aborted: %w", step, err)
+		}
+		return &stepError{step: step}
+	}
+}
+
+// sleep waits for d, returning early with an error if ctx is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
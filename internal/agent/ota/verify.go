@@ -0,0 +1,157 @@
+package ota
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/autopeer-io/autopeer/pkg/log"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+// LoadPublicKey reads a PEM encoded (PKIX) Ed25519 public key, e.g. as produced by
+// `openssl pkey -pubout` or `cosign generate-key-pair`.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in %s", path)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key in %s is %T, want Ed25519", path, key)
+	}
+	return pub, nil
+}
+
+// NewHTTPClient returns the client used for firmware downloads. It verifies the server
+// certificate against the system roots plus opts.CAFile, unless opts.InsecureSkipVerify is set.
+func NewHTTPClient(opts *options.OTAOptions) (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	switch {
+	case opts.InsecureSkipVerify:
+		log.Warn("TLS verification of firmware downloads is disabled, do not use this in production")
+		tlsConfig.InsecureSkipVerify = true
+	case opts.CAFile != "":
+		bundle, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	// Downloads are bounded by the download step timeout rather than a client timeout.
+	return &http.Client{Transport: transport}, nil
+}
+
+// downloadAndVerify downloads the artifact to dest, resuming a previous partial download
+// when possible, then verifies the SHA256 checksum ("sha256:xxxx") and, if the manager has
+// a public key, the Ed25519 signature over the digest. Transient download errors are retried;
+// on a mismatch nothing is stored at dest and a descriptive error is returned.
+// An empty checksum skips the checksum check. progress, if not nil, is told about the downloaded bytes.
+func (m *Manager) downloadAndVerify(ctx context.Context, url, dest, checksum, signature string, progress progressFunc) error {
+	part := dest + partialSuffix
+
+	var err error
+	for attempt := 1; attempt <= downloadAttempts; attempt++ {
+		if attempt > 1 {
+			log.Warn("Retrying firmware download", "attempt", attempt, "error", err.Error())
+			if err := sleep(ctx, m.downloadRetryDelay); err != nil {
+				return err
+			}
+		}
+
+		var digest []byte
+		var resumed bool
+		if digest, resumed, err = m.download(ctx, url, part, progress); err != nil {
+			var perm permanentError
+			if errors.As(err, &perm) {
+				return err
+			}
+			continue
+		}
+
+		if err = verifyChecksum(digest, checksum); err == nil {
+			err = m.verifySignature(digest, signature)
+		}
+		if err != nil {
+			// Never keep bytes that failed verification around for a later resume.
+			os.Remove(part)
+			if resumed {
+				// The partial file may be left over from another artifact, start over.
+				continue
+			}
+			return err
+		}
+
+		if err := os.Rename(part, dest); err != nil {
+			return fmt.Errorf("failed to store artifact: %w", err)
+		}
+		return nil
+	}
+	return err
+}
+
+func verifyChecksum(digest []byte, checksum string) error {
+	if checksum == "" {
+		log.Warn("No checksum provided by hub, skipping integrity verification")
+		return nil
+	}
+
+	got := hex.EncodeToString(digest)
+	want := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(checksum)), "sha256:")
+	if got != want {
+		return fmt.Errorf("checksum mismatch: got sha256:%s, want sha256:%s", got, want)
+	}
+	return nil
+}
+
+// verifySignature checks the base64 Ed25519 signature over the raw digest.
+// Without a configured public key, signatures are not verified.
+func (m *Manager) verifySignature(digest []byte, signature string) error {
+	if m.publicKey == nil {
+		return nil
+	}
+	if signature == "" {
+		return fmt.Errorf("artifact is not signed, but signature verification is required")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if !ed25519.Verify(m.publicKey, digest, sig) {
+		return fmt.Errorf("signature verification failed: artifact was not signed by the trusted publisher")
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package ota
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+func TestDownloadAndVerify(t *testing.T) {
+	artifact := []byte("firmware v1.2.0")
+	digest := sha256.Sum256(artifact)
+	checksum := "sha256:" + hex.EncodeToString(digest[:])
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, digest[:]))
+
+	_, otherPriv, _ := ed25519.GenerateKey(rand.Reader)
+	foreignSignature := base64.StdEncoding.EncodeToString(ed25519.Sign(otherPriv, digest[:]))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/good.bin":
+			w.Write(artifact)
+		case "/tampered.bin":
+			w.Write([]byte("firmware v1.2.0 + backdoor"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		path      string
+		checksum  string
+		signature string
+		publicKey ed25519.PublicKey
+		wantErr   string
+	}{
+		{name: "good artifact", path: "/good.bin", checksum: checksum},
+		{name: "good signed artifact", path: "/good.bin", checksum: checksum, signature: signature, publicKey: pub},
+		{name: "uppercase checksum", path: "/good.bin", checksum: strings.ToUpper(checksum)},
+		{name: "tampered artifact", path: "/tampered.bin", checksum: checksum, wantErr: "checksum mismatch"},
+		{name: "tampered signed artifact", path: "/tampered.bin", signature: signature, publicKey: pub, wantErr: "signature verification failed"},
+		{name: "foreign signature", path: "/good.bin", checksum: checksum, signature: foreignSignature, publicKey: pub, wantErr: "signature verification failed"},
+		{name: "unsigned artifact", path: "/good.bin", checksum: checksum, publicKey: pub, wantErr: "not signed"},
+		{name: "missing artifact", path: "/missing.bin", checksum: checksum, wantErr: "404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "firmware.bin")
+			m := &Manager{publicKey: tt.publicKey, httpClient: srv.Client()}
+
+			err := m.downloadAndVerify(context.Background(), srv.URL+tt.path, dest, tt.checksum, tt.signature, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("downloadAndVerify() error = %v, want %q", err, tt.wantErr)
+				}
+				if _, statErr := os.Stat(dest); !os.IsNotExist(statErr) {
+					t.Errorf("rejected artifact must not be stored")
+				}
+				if entries, _ := os.ReadDir(filepath.Dir(dest)); len(entries) != 0 {
+					t.Errorf("partial download left behind: %v", entries)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("downloadAndVerify() error = %v", err)
+			}
+			got, err := os.ReadFile(dest)
+			if err != nil || string(got) != string(artifact) {
+				t.Errorf("stored artifact = %q, %v; want %q", got, err, artifact)
+			}
+		})
+	}
+}
+
+func TestLoadPublicKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "cosign.pub")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadPublicKey(path)
+	if err != nil {
+		t.Fatalf("LoadPublicKey() error = %v", err)
+	}
+	if !got.Equal(pub) {
+		t.Errorf("LoadPublicKey() returned a different key")
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("firmware"))
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    *options.OTAOptions
+		wantErr string
+	}{
+		{name: "untrusted certificate rejected by default", opts: options.NewOTAOptions(), wantErr: "certificate"},
+		{name: "trusted through CA bundle", opts: &options.OTAOptions{CAFile: caFile}},
+		{name: "verification explicitly disabled", opts: &options.OTAOptions{InsecureSkipVerify: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewHTTPClient(tt.opts)
+			if err != nil {
+				t.Fatalf("NewHTTPClient() error = %v", err)
+			}
+
+			m := &Manager{httpClient: client}
+			err = m.downloadAndVerify(context.Background(), srv.URL, filepath.Join(t.TempDir(), "firmware.bin"), "", "", nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("downloadAndVerify() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("downloadAndVerify() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Package task runs the agent's commands one at a time, letting urgent commands preempt background work.
+package task
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/autopeer-io/autopeer/pkg/log"
+)
+
+// Priority is the urgency of a task, as in VehicleCommand.Spec.Priority.
+type Priority int32
+
+const (
+	// PriorityLow is for background work, e.g. log upload.
+	PriorityLow Priority = 0
+	// PriorityNormal is the default, e.g. OTA.
+	PriorityNormal Priority = 1
+	// PriorityHigh is for user interactive commands, e.g. remote unlock.
+	PriorityHigh Priority = 2
+)
+
+// ErrPreempted is the cancellation cause of a task that made way for a higher-priority one.
+var ErrPreempted = errors.New("preempted by a higher-priority task")
+
+// Task is a unit of work run by the Scheduler.
+type Task struct {
+	Name     string
+	Priority Priority
+
+	// Resumable tasks are run again once the tasks that preempted them are done;
+	// others are dropped when preempted.
+	Resumable bool
+
+	// Run does the work. It must return soon after its context is cancelled. A task that
+	// stops to make way for a higher-priority one returns an error wrapping ErrPreempted,
+	// e.g. context.Cause(ctx); a task that returns anything else has finished.
+	Run func(ctx context.Context) error
+
+	// Done, if set, is called once the task has finished for good.
+	Done func(err error)
+
+	ctx     context.Context
+	resumed bool
+}
+
+// run is the state of the task currently running.
+type run struct {
+	task   *Task
+	cancel context.CancelCauseFunc
+
+	// critical counts the open critical sections; preemption waits until it drops to zero.
+	critical int
+	// preempt is set when a preemption is waiting for the critical sections to end.
+	preempt bool
+}
+
+// Scheduler runs one task at a time, in priority order. A task of higher priority than the
+// running one preempts it: the running task is cancelled with ErrPreempted, unless it is
+// inside a critical section, in which case it is cancelled as soon as the section ends.
+type Scheduler struct {
+	mu      sync.Mutex
+	queue   []*Task
+	current *run
+}
+
+// NewScheduler creates an idle Scheduler.
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Submit queues t, or starts it right away if nothing is running. ctx bounds the task.
+func (s *Scheduler) Submit(ctx context.Context, t *Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t.ctx = ctx
+	s.enqueue(t)
+
+	if s.current == nil {
+		s.next()
+		return
+	}
+
+	if t.Priority > s.current.task.Priority {
+		log.Info("Preempting task for a higher-priority one", "running", s.current.task.Name, "incoming", t.Name)
+		s.preempt(s.current)
+	}
+}
+
+// enqueue inserts t after every queued task of the same or higher priority.
+func (s *Scheduler) enqueue(t *Task) {
+	i := 0
+	for i < len(s.queue) && s.queue[i].Priority >= t.Priority {
+		i++
+	}
+	s.queue = append(s.queue[:i], append([]*Task{t}, s.queue[i:]...)...)
+}
+
+// requeue inserts a preempted t ahead of queued tasks of the same priority, so it resumes first.
+func (s *Scheduler) requeue(t *Task) {
+	i := 0
+	for i < len(s.queue) && s.queue[i].Priority > t.Priority {
+		i++
+	}
+	s.queue = append(s.queue[:i], append([]*Task{t}, s.queue[i:]...)...)
+}
+
+func (s *Scheduler) preempt(r *run) {
+	if r.critical > 0 {
+		r.preempt = true
+		return
+	}
+	r.cancel(ErrPreempted)
+}
+
+// next starts the first queued task. s.mu must be held.
+func (s *Scheduler) next() {
+	if len(s.queue) == 0 {
+		s.current = nil
+		return
+	}
+
+	t := s.queue[0]
+	s.queue = s.queue[1:]
+
+	ctx, cancel := context.WithCancelCause(t.ctx)
+	r := &run{task: t, cancel: cancel}
+	s.current = r
+
+	go func() {
+		err := t.Run(context.WithValue(ctx, runKey{}, &runRef{s: s, r: r}))
+		// A preemption deferred by a critical section may cancel ctx after the work is done;
+		// only a task that says it stopped is resumed.
+		preempted := errors.Is(err, ErrPreempted)
+		cancel(nil)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		r.preempt = false
+
+		switch {
+		case preempted && t.Resumable:
+			t.resumed = true
+			s.requeue(t)
+		case preempted:
+			log.Info("Dropping preempted task", "task", t.Name)
+			if t.Done != nil {
+				t.Done(err)
+			}
+		default:
+			if t.Done != nil {
+				t.Done(err)
+			}
+		}
+		s.next()
+	}()
+}
+
+type runKey struct{}
+
+type runRef struct {
+	s *Scheduler
+	r *run
+}
+
+// Critical runs fn without being preempted, e.g. to keep an install atomic.
+// A preemption requested meanwhile takes effect when fn returns.
+// Outside of a scheduled task it just runs fn.
+func Critical(ctx context.Context, fn func() error) error {
+	ref, ok := ctx.Value(runKey{}).(*runRef)
+	if !ok {
+		return fn()
+	}
+
+	ref.s.mu.Lock()
+	ref.r.critical++
+	ref.s.mu.Unlock()
+
+	defer func() {
+		ref.s.mu.Lock()
+		defer ref.s.mu.Unlock()
+		ref.r.critical--
+		if ref.r.critical == 0 && ref.r.preempt {
+			ref.r.cancel(ErrPreempted)
+		}
+	}()
+
+	return fn()
+}
+
+// Resumed reports whether the task running with ctx was preempted before and is now resumed.
+func Resumed(ctx context.Context) bool {
+	ref, ok := ctx.Value(runKey{}).(*runRef)
+	return ok && ref.r.task.resumed
+}
+
+// Preempted reports whether ctx was cancelled to make way for a higher-priority task.
+func Preempted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrPreempted)
+}
@@ -0,0 +1,271 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// journal records task events in order.
+type journal struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (j *journal) add(e string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.events = append(j.events, e)
+}
+
+func (j *journal) get() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]string(nil), j.events...)
+}
+
+// blockingTask runs until release is closed or its context is cancelled.
+func blockingTask(name string, p Priority, j *journal, started, release chan struct{}) *Task {
+	return &Task{
+		Name:      name,
+		Priority:  p,
+		Resumable: true,
+		Run: func(ctx context.Context) error {
+			if Resumed(ctx) {
+				j.add(name + " resumed")
+			} else {
+				j.add(name + " started")
+			}
+			if started != nil {
+				started <- struct{}{}
+			}
+			select {
+			case <-release:
+				j.add(name + " finished")
+				return nil
+			case <-ctx.Done():
+				j.add(name + " stopped")
+				return context.Cause(ctx)
+			}
+		},
+	}
+}
+
+func waitDone(t *testing.T, done chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("task did not finish")
+		return nil
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHighPriorityPreemptsBackgroundTask(t *testing.T) {
+	s := NewScheduler()
+	j := &journal{}
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	low := blockingTask("upload-logs", PriorityLow, j, started, release)
+	lowDone := make(chan error, 1)
+	low.Done = func(err error) { lowDone <- err }
+	s.Submit(context.Background(), low)
+	<-started
+
+	highDone := make(chan error, 1)
+	s.Submit(context.Background(), &Task{
+		Name:     "unlock",
+		Priority: PriorityHigh,
+		Run: func(ctx context.Context) error {
+			j.add("unlock ran")
+			return nil
+		},
+		Done: func(err error) { highDone <- err },
+	})
+
+	if err := waitDone(t, highDone); err != nil {
+		t.Fatalf("high-priority task failed: %v", err)
+	}
+	<-started
+	close(release)
+	if err := waitDone(t, lowDone); err != nil {
+		t.Fatalf("resumed task failed: %v", err)
+	}
+
+	want := []string{"upload-logs started", "upload-logs stopped", "unlock ran", "upload-logs resumed", "upload-logs finished"}
+	if got := j.get(); !equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestPreemptedTaskDroppedUnlessResumable(t *testing.T) {
+	s := NewScheduler()
+	j := &journal{}
+
+	started := make(chan struct{}, 1)
+	low := blockingTask("scan", PriorityLow, j, started, make(chan struct{}))
+	low.Resumable = false
+	lowDone := make(chan error, 1)
+	low.Done = func(err error) { lowDone <- err }
+	s.Submit(context.Background(), low)
+	<-started
+
+	s.Submit(context.Background(), &Task{Name: "unlock", Priority: PriorityHigh, Run: func(ctx context.Context) error { return nil }})
+
+	if err := waitDone(t, lowDone); !errors.Is(err, ErrPreempted) {
+		t.Errorf("dropped task Done(%v), want ErrPreempted", err)
+	}
+}
+
+func TestCriticalSectionDefersPreemption(t *testing.T) {
+	s := NewScheduler()
+	j := &journal{}
+
+	inside := make(chan struct{})
+	leave := make(chan struct{})
+	otaDone := make(chan error, 1)
+	s.Submit(context.Background(), &Task{
+		Name:      "ota",
+		Priority:  PriorityNormal,
+		Resumable: true,
+		Run: func(ctx context.Context) error {
+			if Resumed(ctx) {
+				j.add("ota resumed")
+				return nil
+			}
+			Critical(ctx, func() error {
+				j.add("ota installing")
+				close(inside)
+				<-leave
+				if ctx.Err() != nil {
+					t.Error("task was cancelled inside its critical section")
+				}
+				j.add("ota installed")
+				return nil
+			})
+			<-ctx.Done()
+			j.add("ota paused")
+			return context.Cause(ctx)
+		},
+		Done: func(err error) { otaDone <- err },
+	})
+	<-inside
+
+	highDone := make(chan error, 1)
+	s.Submit(context.Background(), &Task{
+		Name:     "unlock",
+		Priority: PriorityHigh,
+		Run: func(ctx context.Context) error {
+			j.add("unlock ran")
+			return nil
+		},
+		Done: func(err error) { highDone <- err },
+	})
+
+	// The urgent task waits for the critical section.
+	time.Sleep(50 * time.Millisecond)
+	close(leave)
+	waitDone(t, highDone)
+	waitDone(t, otaDone)
+
+	want := []string{"ota installing", "ota installed", "ota paused", "unlock ran", "ota resumed"}
+	if got := j.get(); !equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestCompletedTaskNotResumed(t *testing.T) {
+	s := NewScheduler()
+	j := &journal{}
+
+	inside := make(chan struct{})
+	leave := make(chan struct{})
+	otaDone := make(chan error, 1)
+	s.Submit(context.Background(), &Task{
+		Name:      "ota",
+		Priority:  PriorityNormal,
+		Resumable: true,
+		Run: func(ctx context.Context) error {
+			if Resumed(ctx) {
+				j.add("ota resumed")
+				return nil
+			}
+			// The install is the last step: the OTA is done when it ends.
+			return Critical(ctx, func() error {
+				j.add("ota installing")
+				close(inside)
+				<-leave
+				j.add("ota installed")
+				return nil
+			})
+		},
+		Done: func(err error) { otaDone <- err },
+	})
+	<-inside
+
+	highDone := make(chan error, 1)
+	s.Submit(context.Background(), &Task{
+		Name:     "unlock",
+		Priority: PriorityHigh,
+		Run: func(ctx context.Context) error {
+			j.add("unlock ran")
+			return nil
+		},
+		Done: func(err error) { highDone <- err },
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	close(leave)
+	if err := waitDone(t, otaDone); err != nil {
+		t.Errorf("completed task Done(%v), want nil", err)
+	}
+	waitDone(t, highDone)
+
+	// Let a wrongly requeued task run.
+	time.Sleep(50 * time.Millisecond)
+	want := []string{"ota installing", "ota installed", "unlock ran"}
+	if got := j.get(); !equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestSamePriorityDoesNotPreempt(t *testing.T) {
+	s := NewScheduler()
+	j := &journal{}
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	first := blockingTask("first", PriorityNormal, j, started, release)
+	second := blockingTask("second", PriorityNormal, j, started, release)
+	done := make(chan error, 2)
+	first.Done = func(err error) { done <- err }
+	second.Done = func(err error) { done <- err }
+
+	s.Submit(context.Background(), first)
+	<-started
+	s.Submit(context.Background(), second)
+	close(release)
+	waitDone(t, done)
+	waitDone(t, done)
+
+	want := []string{"first started", "first finished", "second started", "second finished"}
+	if got := j.get(); !equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
@@ -2,7 +2,10 @@ package bridge
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/autopeer-io/autopeer/internal/bridge/core"
 	"github.com/autopeer-io/autopeer/internal/bridge/k8s"
 	"github.com/autopeer-io/autopeer/internal/bridge/server"
 	"github.com/autopeer-io/autopeer/pkg/log"
@@ -12,11 +15,22 @@ import (
 type CloudHubServer struct {
 	serverManager *server.Manager
 	k8sPipeline   *k8s.StatusPipeline
+	storage       core.Storage
+
+	// checkBucketAttempts and checkBucketInterval bound the startup storage check.
+	checkBucketAttempts int
+	checkBucketInterval time.Duration
 }
 
 // Run starts the application components.
 func (a *CloudHubServer) Run(ctx context.Context) error {
 	log.Info("Starting CloudHub Application...")
+
+	// 0. 确认对象存储可用 (带重试，容忍启动顺序问题)
+	if err := checkBucketWithRetry(ctx, a.storage, a.checkBucketAttempts, a.checkBucketInterval); err != nil {
+		return err
+	}
+
 	// 1. 启动 Pipeline (后台)
 	go a.k8sPipeline.Start(ctx)
 
@@ -30,3 +44,32 @@ func (a *CloudHubServer) Run(ctx context.Context) error {
 
 	return err
 }
+
+// checkBucketWithRetry calls CheckBucket up to attempts times, waiting interval between tries.
+// It gives up early if the context is cancelled.
+func checkBucketWithRetry(ctx context.Context, storage core.Storage, attempts int, interval time.Duration) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = storage.CheckBucket(ctx); err == nil {
+			log.Info("Object storage is ready", "attempt", attempt)
+			return nil
+		}
+
+		log.Warn("Object storage not ready", "attempt", attempt, "maxAttempts", attempts, "error", err.Error())
+		if attempt == attempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+
+	return fmt.Errorf("object storage unavailable after %d attempts: %w", attempts, err)
+}
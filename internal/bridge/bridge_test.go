@@ -0,0 +1,86 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+)
+
+type flakyStorage struct {
+	failures int
+	calls    int
+}
+
+func (s *flakyStorage) CheckBucket(ctx context.Context) error {
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (s *flakyStorage) GeneratePresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "", nil
+}
+
+func (s *flakyStorage) GeneratePresignedPutURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "", nil
+}
+
+func (s *flakyStorage) GetObjectRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (s *flakyStorage) PutObject(ctx context.Context, key string, data []byte, contentType string) error {
+	return nil
+}
+
+func (s *flakyStorage) StatObject(ctx context.Context, key string) (*model.ObjectInfo, error) {
+	return &model.ObjectInfo{Key: key}, nil
+}
+
+func TestCheckBucketWithRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		attempts  int
+		wantErr   bool
+		wantCalls int
+	}{
+		{"succeeds after two failures", 2, 5, false, 3},
+		{"gives up after max attempts", 10, 3, true, 3},
+		{"succeeds immediately", 0, 1, false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &flakyStorage{failures: tt.failures}
+			err := checkBucketWithRetry(context.Background(), s, tt.attempts, time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s.calls != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, s.calls)
+			}
+		})
+	}
+}
+
+func TestCheckBucketWithRetryContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := &flakyStorage{failures: 10}
+	err := checkBucketWithRetry(ctx, s, 5, time.Hour)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if s.calls != 1 {
+		t.Errorf("expected 1 call before cancellation, got %d", s.calls)
+	}
+}
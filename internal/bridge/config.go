@@ -2,7 +2,9 @@ package bridge
 
 import (
 	"fmt"
+	nethttp "net/http"
 
+	"github.com/autopeer-io/autopeer/internal/bridge/core"
 	"github.com/autopeer-io/autopeer/internal/bridge/core/service"
 	"github.com/autopeer-io/autopeer/internal/bridge/k8s"
 	"github.com/autopeer-io/autopeer/internal/bridge/notifier"
@@ -11,17 +13,24 @@ import (
 	"github.com/autopeer-io/autopeer/internal/bridge/server/http"
 	"github.com/autopeer-io/autopeer/internal/bridge/server/mqtt"
 	"github.com/autopeer-io/autopeer/internal/bridge/storage"
+	"github.com/autopeer-io/autopeer/internal/pkg/audit"
+	"github.com/autopeer-io/autopeer/internal/pkg/fault"
+	"github.com/autopeer-io/autopeer/pkg/log"
 	pkgmqtt "github.com/autopeer-io/autopeer/pkg/mqtt"
-	"github.com/autopeer-io/autopeer/pkg/mqtt/topic"
 	"github.com/autopeer-io/autopeer/pkg/options"
 )
 
 type Config struct {
-	KubeOptions *options.KubeOptions
-	HttpOptions *options.HttpOptions
-	GrpcOptions *options.GrpcOptions
-	MqttOptions *options.MqttOptions
-	S3Options   *options.S3Options
+	KubeOptions     *options.KubeOptions
+	HttpOptions     *options.HttpOptions
+	GrpcOptions     *options.GrpcOptions
+	MqttOptions     *options.MqttOptions
+	S3Options       *options.S3Options
+	StorageOptions  *options.StorageOptions
+	FaultOptions    *options.FaultOptions
+	PipelineOptions *options.PipelineOptions
+	AuditOptions    *options.AuditOptions
+	ExportOptions   *options.ExportOptions
 }
 
 func (cfg *Config) NewHubServer() (*CloudHubServer, error) {
@@ -30,9 +39,15 @@ func (cfg *Config) NewHubServer() (*CloudHubServer, error) {
 		return nil, err
 	}
 
-	pipeline := k8s.NewPipeline(cfg.KubeOptions.Namespace, k8sClient)
+	pipeline := k8s.NewPipeline(cfg.KubeOptions.Namespace, k8sClient,
+		k8s.WithFlushInterval(cfg.PipelineOptions.MinFlushInterval, cfg.PipelineOptions.MaxFlushInterval))
+	// Command history queries are served from an indexed cache instead of listing the API server.
+	commandCache, err := k8s.NewCommandCache(cfg.KubeOptions.Namespace)
+	if err != nil {
+		return nil, err
+	}
 	// k8sRepo implements both VehicleRepository and CommandRepository
-	k8sRepo := k8s.NewRepository(cfg.KubeOptions.Namespace, k8sClient, pipeline)
+	k8sRepo := k8s.NewRepository(cfg.KubeOptions.Namespace, k8sClient, commandCache, pipeline)
 
 	// Use the shared MQTT client factory from pkg/mqtt
 	mqttClient, err := pkgmqtt.NewClient(cfg.MqttOptions.ToClientConfig())
@@ -40,10 +55,10 @@ func (cfg *Config) NewHubServer() (*CloudHubServer, error) {
 		return nil, fmt.Errorf("failed to init mqtt client: %w", err)
 	}
 
-	topicBuilder := topic.NewBuilder(cfg.MqttOptions.TopicRoot)
+	topicBuilder := cfg.MqttOptions.ToTopicBuilder()
 
 	// Infrastructure: Storage (Secondary Adapter)
-	storageAdapter, err := storage.NewMinIO(cfg.S3Options)
+	storageAdapter, err := cfg.newStorage()
 	if err != nil {
 		return nil, err
 	}
@@ -54,9 +69,30 @@ func (cfg *Config) NewHubServer() (*CloudHubServer, error) {
 		return nil, fmt.Errorf("failed to init notifier: %w", err)
 	}
 
+	// Chaos testing: wrap the Secondary Adapters with fault injection (explicitly opt-in)
+	var notifierPort core.CommandNotifier = notifierAdapter
+	storagePort := storageAdapter
+	if cfg.FaultOptions != nil && cfg.FaultOptions.Enabled {
+		injector, err := fault.NewInjector(cfg.FaultOptions.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init fault injector: %w", err)
+		}
+		log.Warn("Fault injection is enabled", "rules", cfg.FaultOptions.Rules)
+		notifierPort = &faultyNotifier{CommandNotifier: notifierAdapter, injector: injector}
+		storagePort = &faultyStorage{Storage: storageAdapter, injector: injector}
+	}
+
 	// Core Domain Service (The Business Logic)
 	// Injecting all Secondary Adapters into the Core
-	svc := service.New(k8sRepo, notifierAdapter, storageAdapter)
+	auditSink, err := audit.NewSink(cfg.AuditOptions.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := service.New(k8sRepo, notifierPort, storagePort,
+		service.WithMaxURLExpiry(cfg.S3Options.MaxURLExpiry),
+		service.WithAuditSink(auditSink),
+	)
 
 	// Ingress Servers (Primary Adapters)
 	// Injecting the Core Service into the Servers
@@ -64,12 +100,31 @@ func (cfg *Config) NewHubServer() (*CloudHubServer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to init grpc server: %w", err)
 	}
-	mqttServer := mqtt.NewServer(mqttClient, topicBuilder, svc)
+	mqttServer := mqtt.NewServer(cfg.MqttOptions, mqttClient, topicBuilder, svc)
 	httpServer := http.NewServer(cfg.HttpOptions)
-	srvManager := server.NewManager(mqttServer, grpcServer, httpServer)
+	if handler, ok := storageAdapter.(nethttp.Handler); ok {
+		// The filesystem backend serves its own signed download links.
+		httpServer.Handle(storage.FirmwarePathPrefix, handler)
+	}
+	servers := []server.Server{mqttServer, grpcServer, httpServer, commandCache}
+	if cfg.ExportOptions != nil && cfg.ExportOptions.Interval > 0 {
+		servers = append(servers, newSnapshotExporter(cfg.ExportOptions, svc))
+	}
+	srvManager := server.NewManager(servers...)
 
 	return &CloudHubServer{
-		serverManager: srvManager,
-		k8sPipeline:   pipeline,
+		serverManager:       srvManager,
+		k8sPipeline:         pipeline,
+		storage:             storagePort,
+		checkBucketAttempts: cfg.S3Options.CheckBucketAttempts,
+		checkBucketInterval: cfg.S3Options.CheckBucketInterval,
 	}, nil
 }
+
+// newStorage creates the firmware storage backend selected by StorageOptions.
+func (cfg *Config) newStorage() (core.Storage, error) {
+	if cfg.StorageOptions != nil && cfg.StorageOptions.Backend == options.StorageBackendFileSystem {
+		return storage.NewFileSystem(cfg.StorageOptions)
+	}
+	return storage.NewMinIO(cfg.S3Options)
+}
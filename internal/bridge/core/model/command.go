@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"slices"
+	"strings"
+	"time"
+)
 
 // CommandType defines the type of command.
 type CommandType string
@@ -8,9 +12,13 @@ type CommandType string
 const (
 	CommandTypeOTA    CommandType = "OTA"
 	CommandTypeReboot CommandType = "Reboot"
+
+	// CommandTypeApplyConfig delivers the desired configuration (Spec.Properties) to a vehicle.
+	CommandTypeApplyConfig CommandType = "ApplyConfig"
 )
 
 // CommandStatus defines the execution status of a command.
+// Received is the agent's ack; it is stored as the v1alpha2 Acknowledged phase.
 type CommandStatus string
 
 const (
@@ -20,8 +28,43 @@ const (
 	CommandStatusRunning   CommandStatus = "Running"
 	CommandStatusSucceeded CommandStatus = "Succeeded"
 	CommandStatusFailed    CommandStatus = "Failed"
+
+	// CommandStatusTimeout is set by the controller when the vehicle did not finish in time.
+	CommandStatusTimeout CommandStatus = "Timeout"
 )
 
+// CommandResultObjectKey is the key of the command result entry referencing an output
+// the vehicle uploaded to object storage (see ResultUpload).
+const CommandResultObjectKey = "objectKey"
+
+// commandStatusOrder ranks the lifecycle phases. A command only moves forward through them.
+var commandStatusOrder = map[CommandStatus]int{
+	CommandStatusPending:   0,
+	CommandStatusSent:      1,
+	CommandStatusReceived:  2,
+	CommandStatusRunning:   3,
+	CommandStatusSucceeded: 4,
+	CommandStatusFailed:    4,
+	CommandStatusTimeout:   4,
+}
+
+// IsTerminal reports whether the command has finished.
+func (s CommandStatus) IsTerminal() bool {
+	return s == CommandStatusSucceeded || s == CommandStatusFailed || s == CommandStatusTimeout
+}
+
+// CanTransitionTo reports whether a command in phase s may move to next.
+// A finished command never changes, and no phase moves back to an earlier one,
+// so late or duplicated reports cannot regress a command. Unknown phases are not ordered.
+func (s CommandStatus) CanTransitionTo(next CommandStatus) bool {
+	if s.IsTerminal() {
+		return false
+	}
+	from, knownFrom := commandStatusOrder[s]
+	to, knownTo := commandStatusOrder[next]
+	return !knownFrom || !knownTo || to >= from
+}
+
 // Command represents an instruction sent to a vehicle.
 type Command struct {
 	// ID is the unique trace ID (corresponds to K8s CRD Name).
@@ -36,9 +79,82 @@ type Command struct {
 	// Parameters contains specific arguments for the command.
 	Parameters map[string]string
 
+	// RequestID traces the command back to the request that caused it, if any.
+	RequestID string
+
+	// Priority is the urgency of the command (0: Low, 1: Normal, 2: High).
+	Priority int32
+
 	// Status represents the current lifecycle phase.
 	Status CommandStatus
 
 	// CreatedAt is when the command was issued.
 	CreatedAt time.Time
+
+	// Message is the latest status message.
+	Message string
+
+	// CompletedAt is when the command finished; zero while it is in flight.
+	CompletedAt time.Time
+}
+
+// CommandFilter selects the commands of a vehicle's history. Zero fields match every command.
+type CommandFilter struct {
+	// Statuses keeps the commands in one of these phases.
+	Statuses []CommandStatus
+
+	// CreatedAfter and CreatedBefore bound the creation time: [CreatedAfter, CreatedBefore).
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// Matches reports whether cmd passes the filter.
+func (f *CommandFilter) Matches(cmd *Command) bool {
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, cmd.Status) {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && cmd.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+	return f.CreatedBefore.IsZero() || cmd.CreatedAt.Before(f.CreatedBefore)
+}
+
+// CompareNewestFirst orders commands by creation time, newest first, then by ID.
+// It is the order of a vehicle's command history.
+func CompareNewestFirst(a, b *Command) int {
+	if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// CommandPage is one page of a vehicle's command history.
+type CommandPage struct {
+	// Commands are ordered newest first.
+	Commands []*Command
+
+	// NextPageToken fetches the next page; empty on the last page.
+	NextPageToken string
+}
+
+// ResultUpload is where a vehicle uploads the output of a command.
+// Outputs are too large for the command status, which only references Key.
+type ResultUpload struct {
+	// URL accepts the output with an HTTP PUT until it expires.
+	URL string
+
+	// Key is the object key the output is stored under.
+	Key string
+}
+
+// ValidationIssue describes one check a command failed.
+type ValidationIssue struct {
+	// Field is the command field the issue is about (e.g., "vehicle_id", "parameters.version").
+	Field string
+
+	// Reason is a machine-readable code (e.g., "Required", "NotFound", "Offline").
+	Reason string
+
+	// Message explains the issue.
+	Message string
 }
@@ -0,0 +1,33 @@
+package model
+
+// ObjectInfo describes a stored firmware object.
+type ObjectInfo struct {
+	// Key is the object key in the storage backend.
+	Key string
+
+	// Size is the object size in bytes.
+	Size int64
+
+	// Checksum is the authoritative digest of the object (e.g., "sha256:xxxx").
+	// It is empty if the backend has no checksum for the object.
+	Checksum string
+
+	// Signature is the base64 Ed25519 signature over the raw SHA256 digest, if the publisher signed the object.
+	Signature string
+}
+
+// FirmwareDownload is what a vehicle needs to fetch and verify a firmware bundle.
+type FirmwareDownload struct {
+	// URL is the temporary download link.
+	URL string
+
+	// Checksum is the digest the vehicle must verify the download against (e.g., "sha256:xxxx").
+	Checksum string
+
+	// Signature is the publisher's signature over the digest; empty if unsigned.
+	Signature string
+
+	// BaseVersion is set when URL points at a delta patch instead of a full image.
+	// The patch must be applied on top of this version.
+	BaseVersion string
+}
@@ -0,0 +1,34 @@
+package model
+
+// RequestType defines the kind of action a vehicle asks for.
+type RequestType string
+
+const (
+	// RequestTypeConfig asks the hub to push the vehicle's desired configuration (Spec.Properties).
+	RequestTypeConfig RequestType = "Config"
+)
+
+// VehicleRequest is an action initiated by a vehicle.
+type VehicleRequest struct {
+	// ID is the vehicle-chosen request ID. Retries reuse it.
+	ID string
+
+	// VehicleID is the requesting vehicle.
+	VehicleID string
+
+	Type RequestType
+
+	// Parameters contains request-specific arguments.
+	Parameters map[string]string
+}
+
+// RequestResult is the hub's decision on a VehicleRequest.
+type RequestResult struct {
+	Accepted bool
+
+	// Message is the rejection reason, or a summary of the action taken.
+	Message string
+
+	// CommandID is the command created for an accepted request.
+	CommandID string
+}
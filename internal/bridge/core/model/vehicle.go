@@ -22,6 +22,25 @@ type Vehicle struct {
 	// This comes from the Spec.
 	DesiredVersion string
 
+	// DesiredChecksum is the expected firmware checksum from the Spec (e.g., "sha256:xxxx").
+	DesiredChecksum string
+
+	// Properties is the desired configuration of the vehicle's dynamic properties (Spec.Properties).
+	Properties map[string]string
+
+	// ModelRef is the name of the VehicleModel the vehicle belongs to, if any.
+	ModelRef string
+
+	// Phase is the phase of the vehicle's OTA process (Status.UpgradeStatus.Phase, e.g., "Pending").
+	Phase string
+
+	// MaintenanceMode excludes the vehicle from automated commands (Spec.MaintenanceMode).
+	MaintenanceMode bool
+
+	// Decommissioned means the vehicle is no longer managed (Spec.Lifecycle Retired).
+	// Its record and history are kept, but it is not sent commands.
+	Decommissioned bool
+
 	IsRegister bool
 }
 
@@ -31,4 +50,22 @@ type VehicleStatusUpdate struct {
 	VIN               string
 	Online            bool
 	LastHeartbeatTime time.Time
+
+	// FirmwareVersion is the version reported by the vehicle.
+	// Empty means the update carries no version, and the stored one must be kept.
+	FirmwareVersion string
+
+	// Properties are reported property values. Properties not listed are left untouched.
+	Properties map[string]string
+}
+
+// VehicleModel is the schema shared by the vehicles of a model.
+type VehicleModel struct {
+	Name string
+
+	// Properties lists the names of the dynamic properties a vehicle of this model may report.
+	Properties []string
+
+	// Methods lists the command methods the model declares (VehicleModel.Spec.Methods).
+	Methods []string
 }
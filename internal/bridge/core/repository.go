@@ -25,10 +25,25 @@ type VehicleRepository interface {
 	// BatchUpdateStatus updates the status fields (Online, LastSeen, Version) of a vehicle.
 	// Note: Implementations should handle high-concurrency batching/buffering.
 	BatchUpdateStatus(ctx context.Context, update *model.VehicleStatusUpdate) error
+
+	// GetModel retrieves a VehicleModel by its name.
+	GetModel(ctx context.Context, name string) (*model.VehicleModel, error)
+
+	// List returns all vehicles of the fleet.
+	List(ctx context.Context) ([]*model.Vehicle, error)
 }
 
 // CommandRepository defines the interface for interacting with command persistent data.
 type CommandRepository interface {
+	// Create issues a new command. It returns util.ErrAlreadyExists if a command with the same ID exists.
+	Create(ctx context.Context, cmd *model.Command) error
+
 	// UpdateStatus updates the lifecycle phase of a command (e.g., Received -> Running).
-	UpdateStatus(ctx context.Context, cmdID string, status model.CommandStatus, message string) error
+	// Entries of result, if any, are merged into the command result.
+	// It returns util.ErrNotFound if the command does not exist, and util.ErrStale
+	// if the command is already past status (see model.CommandStatus.CanTransitionTo).
+	UpdateStatus(ctx context.Context, cmdID string, status model.CommandStatus, message string, result map[string]string) error
+
+	// ListByVehicle returns the commands of a vehicle that match filter, newest first.
+	ListByVehicle(ctx context.Context, vin string, filter model.CommandFilter) ([]*model.Command, error)
 }
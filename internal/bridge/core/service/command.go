@@ -2,24 +2,60 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/audit"
+	"github.com/autopeer-io/autopeer/internal/pkg/util"
+	"github.com/autopeer-io/autopeer/pkg/log"
 )
 
 // UpdateCommandStatus handles status reports from the vehicle agent regarding a specific command.
 // e.g., Agent reports "I have received command cmd-123" or "I have finished command cmd-123".
-func (s *Service) UpdateCommandStatus(ctx context.Context, cmdID string, status model.CommandStatus, message string) error {
+// A non-empty resultKey references the output the agent uploaded (see GetResultUpload);
+// it is recorded in the command result only if it was issued for this command.
+func (s *Service) UpdateCommandStatus(ctx context.Context, cmdID string, status model.CommandStatus, message, resultKey string) error {
 	if cmdID == "" {
 		return nil // Ignore invalid status reports
 	}
 
+	var result map[string]string
+	if resultKey != "" {
+		if isResultKeyOf(resultKey, cmdID) {
+			result = map[string]string{model.CommandResultObjectKey: resultKey}
+		} else {
+			log.Warn("Dropped result key not issued for the command", "command", cmdID, "resultKey", resultKey)
+		}
+	}
+
 	// Delegate to the repository
 	// The repository implementation (K8s adapter) will map this to a CRD Status update.
-	if err := s.command.UpdateStatus(ctx, cmdID, status, message); err != nil {
+	if err := s.command.UpdateStatus(ctx, cmdID, status, message, result); err != nil {
+		if errors.Is(err, util.ErrStale) {
+			// MQTT does not order deliveries across retries: a late report must not move the command back.
+			log.Info("Dropped stale command status report", "command", cmdID, "status", status, "reason", err.Error())
+			return nil
+		}
+		if errors.Is(err, util.ErrNotFound) {
+			// Nothing to patch: usually a malformed report or a command deleted meanwhile.
+			log.Warn("Dropped status report for unknown command", "command", cmdID, "status", status, "message", message)
+			return nil
+		}
 		return fmt.Errorf("failed to update command status for %s: %w", cmdID, err)
 	}
 
+	// Audit only persisted transitions; a failing sink does not reject the report.
+	err := s.audit.Record(ctx, &audit.Record{
+		Actor:   audit.ActorVehicleAgent,
+		Command: cmdID,
+		ToPhase: string(status),
+		Message: message,
+	})
+	if err != nil {
+		log.Error(err, "Failed to audit command status", "command", cmdID, "status", status)
+	}
+
 	return nil
 }
 
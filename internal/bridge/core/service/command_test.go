@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core"
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/audit"
+	"github.com/autopeer-io/autopeer/internal/pkg/util"
+)
+
+// stubCommandRepo fails status updates of the command named "broken".
+type stubCommandRepo struct{}
+
+func (r *stubCommandRepo) Vehicle() core.VehicleRepository { return nil }
+func (r *stubCommandRepo) Command() core.CommandRepository { return r }
+
+func (r *stubCommandRepo) Create(ctx context.Context, cmd *model.Command) error { return nil }
+
+func (r *stubCommandRepo) ListByVehicle(ctx context.Context, vin string, filter model.CommandFilter) ([]*model.Command, error) {
+	return nil, nil
+}
+
+func (r *stubCommandRepo) UpdateStatus(ctx context.Context, cmdID string, status model.CommandStatus, message string, result map[string]string) error {
+	switch cmdID {
+	case "broken":
+		return errors.New("apiserver unavailable")
+	case "finished":
+		return util.ErrStale
+	case "typo":
+		return util.ErrNotFound
+	}
+	return nil
+}
+
+type recordingSink struct{ records []audit.Record }
+
+func (s *recordingSink) Record(ctx context.Context, r *audit.Record) error {
+	s.records = append(s.records, *r)
+	return nil
+}
+
+func TestUpdateCommandStatusAudit(t *testing.T) {
+	sink := &recordingSink{}
+	svc := New(&stubCommandRepo{}, nil, nil, WithAuditSink(sink))
+
+	lifecycle := []model.CommandStatus{model.CommandStatusReceived, model.CommandStatusRunning, model.CommandStatusSucceeded}
+	for _, status := range lifecycle {
+		if err := svc.UpdateCommandStatus(context.Background(), "ota-vh-1", status, "ok", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A report that could not be persisted must not be audited.
+	if err := svc.UpdateCommandStatus(context.Background(), "broken", model.CommandStatusFailed, "", ""); err == nil {
+		t.Fatal("expected an error for the failing repository")
+	}
+
+	// A stale report is dropped silently and not audited either.
+	if err := svc.UpdateCommandStatus(context.Background(), "finished", model.CommandStatusRunning, "", ""); err != nil {
+		t.Fatalf("stale report: error = %v, want it dropped", err)
+	}
+
+	// So is a report for a command that does not exist.
+	if err := svc.UpdateCommandStatus(context.Background(), "typo", model.CommandStatusSucceeded, "", ""); err != nil {
+		t.Fatalf("unknown command: error = %v, want it dropped", err)
+	}
+
+	if len(sink.records) != len(lifecycle) {
+		t.Fatalf("got %d audit records, want %d: %+v", len(sink.records), len(lifecycle), sink.records)
+	}
+	for i, status := range lifecycle {
+		got := sink.records[i]
+		if got.ToPhase != string(status) || got.Command != "ota-vh-1" || got.Actor != audit.ActorVehicleAgent {
+			t.Errorf("record %d = %+v, want %s by %s", i, got, status, audit.ActorVehicleAgent)
+		}
+	}
+}
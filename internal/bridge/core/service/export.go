@@ -0,0 +1,115 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+// fleetSnapshot is the exported summary of the fleet status at one point in time.
+type fleetSnapshot struct {
+	Time   time.Time `json:"time"`
+	Total  int       `json:"total"`
+	Online int       `json:"online"`
+
+	// Versions counts the vehicles by reported firmware version.
+	Versions map[string]int `json:"versions"`
+
+	// Phases counts the vehicles by OTA phase.
+	Phases map[string]int `json:"phases"`
+
+	Vehicles []vehicleSnapshot `json:"vehicles"`
+}
+
+// vehicleSnapshot is the exported status of one vehicle.
+type vehicleSnapshot struct {
+	VIN               string     `json:"vin"`
+	ReportedVersion   string     `json:"reportedVersion,omitempty"`
+	DesiredVersion    string     `json:"desiredVersion,omitempty"`
+	Phase             string     `json:"phase,omitempty"`
+	Online            bool       `json:"online"`
+	LastHeartbeatTime *time.Time `json:"lastHeartbeatTime,omitempty"`
+}
+
+// ExportFleetSnapshot writes a snapshot of the fleet status to object storage and returns its key.
+// The key is prefix followed by the snapshot time, so snapshots sort chronologically.
+func (s *Service) ExportFleetSnapshot(ctx context.Context, prefix, format string, now time.Time) (string, error) {
+	vehicles, err := s.vehicle.List(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	snap := newFleetSnapshot(vehicles, now.UTC())
+	data, contentType, err := encodeSnapshot(snap, format)
+	if err != nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("%s%s.%s", prefix, snap.Time.Format("20060102T150405Z"), format)
+	if err := s.storage.PutObject(ctx, key, data, contentType); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func newFleetSnapshot(vehicles []*model.Vehicle, now time.Time) *fleetSnapshot {
+	snap := &fleetSnapshot{
+		Time:     now,
+		Total:    len(vehicles),
+		Versions: map[string]int{},
+		Phases:   map[string]int{},
+		Vehicles: make([]vehicleSnapshot, 0, len(vehicles)),
+	}
+
+	for _, v := range vehicles {
+		if v.Online {
+			snap.Online++
+		}
+		snap.Versions[v.ReportedVersion]++
+		snap.Phases[v.Phase]++
+
+		vs := vehicleSnapshot{
+			VIN:             v.VIN,
+			ReportedVersion: v.ReportedVersion,
+			DesiredVersion:  v.DesiredVersion,
+			Phase:           v.Phase,
+			Online:          v.Online,
+		}
+		if !v.LastHeartbeatTime.IsZero() {
+			t := v.LastHeartbeatTime.UTC()
+			vs.LastHeartbeatTime = &t
+		}
+		snap.Vehicles = append(snap.Vehicles, vs)
+	}
+
+	return snap
+}
+
+// encodeSnapshot encodes the snapshot in format and returns the matching content type.
+// The ndjson format holds one line per vehicle; the aggregates are left to the consumer.
+func encodeSnapshot(snap *fleetSnapshot, format string) ([]byte, string, error) {
+	switch format {
+	case options.ExportFormatJSON:
+		data, err := json.Marshal(snap)
+		return data, "application/json", err
+	case options.ExportFormatNDJSON:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, v := range snap.Vehicles {
+			if err := enc.Encode(struct {
+				Time time.Time `json:"time"`
+				vehicleSnapshot
+			}{snap.Time, v}); err != nil {
+				return nil, "", err
+			}
+		}
+		return buf.Bytes(), "application/x-ndjson", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported snapshot format %q", format)
+	}
+}
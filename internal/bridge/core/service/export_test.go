@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+// memoryStorage keeps the objects written through PutObject.
+type memoryStorage struct {
+	stubStorage
+	objects      map[string][]byte
+	contentTypes map[string]string
+}
+
+func (s *memoryStorage) PutObject(ctx context.Context, key string, data []byte, contentType string) error {
+	s.objects[key] = data
+	s.contentTypes[key] = contentType
+	return nil
+}
+
+func TestExportFleetSnapshot(t *testing.T) {
+	heartbeat := time.Date(2026, 3, 1, 11, 59, 0, 0, time.UTC)
+	repo := &twinRepo{vehicles: map[string]*model.Vehicle{
+		"VH1": {VIN: "VH1", ReportedVersion: "1.0.0", DesiredVersion: "1.1.0", Phase: "Pending", Online: true, LastHeartbeatTime: heartbeat},
+		"VH2": {VIN: "VH2", ReportedVersion: "1.1.0", DesiredVersion: "1.1.0", Phase: "Succeeded", Online: true, LastHeartbeatTime: heartbeat},
+		"VH3": {VIN: "VH3", ReportedVersion: "1.1.0", DesiredVersion: "1.1.0", Phase: "Succeeded"},
+	}}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("json", func(t *testing.T) {
+		storage := &memoryStorage{objects: map[string][]byte{}, contentTypes: map[string]string{}}
+		svc := New(repo, nil, storage)
+
+		key, err := svc.ExportFleetSnapshot(context.Background(), "snapshots/", options.ExportFormatJSON, now)
+		if err != nil {
+			t.Fatalf("ExportFleetSnapshot() error = %v", err)
+		}
+		if key != "snapshots/20260301T120000Z.json" {
+			t.Errorf("key = %q", key)
+		}
+		if storage.contentTypes[key] != "application/json" {
+			t.Errorf("content type = %q", storage.contentTypes[key])
+		}
+
+		var got fleetSnapshot
+		if err := json.Unmarshal(storage.objects[key], &got); err != nil {
+			t.Fatalf("snapshot is not valid JSON: %v", err)
+		}
+		if !got.Time.Equal(now) || got.Total != 3 || got.Online != 2 {
+			t.Errorf("time/total/online = %v/%d/%d, want %v/3/2", got.Time, got.Total, got.Online, now)
+		}
+		if got.Versions["1.0.0"] != 1 || got.Versions["1.1.0"] != 2 {
+			t.Errorf("versions = %v", got.Versions)
+		}
+		if got.Phases["Pending"] != 1 || got.Phases["Succeeded"] != 2 {
+			t.Errorf("phases = %v", got.Phases)
+		}
+		if len(got.Vehicles) != 3 {
+			t.Errorf("got %d vehicles, want 3", len(got.Vehicles))
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		storage := &memoryStorage{objects: map[string][]byte{}, contentTypes: map[string]string{}}
+		svc := New(repo, nil, storage)
+
+		key, err := svc.ExportFleetSnapshot(context.Background(), "snapshots/", options.ExportFormatNDJSON, now)
+		if err != nil {
+			t.Fatalf("ExportFleetSnapshot() error = %v", err)
+		}
+		if key != "snapshots/20260301T120000Z.ndjson" {
+			t.Errorf("key = %q", key)
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(storage.objects[key])), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("got %d lines, want one per vehicle", len(lines))
+		}
+		vehicles := map[string]vehicleSnapshot{}
+		for _, line := range lines {
+			var v vehicleSnapshot
+			if err := json.Unmarshal([]byte(line), &v); err != nil {
+				t.Fatalf("line %q is not valid JSON: %v", line, err)
+			}
+			vehicles[v.VIN] = v
+		}
+		if v := vehicles["VH1"]; v.ReportedVersion != "1.0.0" || v.Phase != "Pending" || !v.Online || v.LastHeartbeatTime == nil {
+			t.Errorf("VH1 = %+v", v)
+		}
+		if v := vehicles["VH3"]; v.Online || v.LastHeartbeatTime != nil {
+			t.Errorf("VH3 = %+v", v)
+		}
+	})
+}
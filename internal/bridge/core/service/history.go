@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+)
+
+const (
+	// defaultCommandPageSize is the page size of ListCommands when none is requested.
+	defaultCommandPageSize = 50
+
+	// maxCommandPageSize caps the page size of ListCommands.
+	maxCommandPageSize = 500
+)
+
+// ErrInvalidPageToken is returned by ListCommands for a page token it did not issue.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// ListCommands returns a page of the command history of a vehicle, newest first.
+// A zero pageSize selects the default; larger values are clamped. pageToken is the
+// NextPageToken of the previous page, or empty for the first page.
+func (s *Service) ListCommands(ctx context.Context, vin string, filter model.CommandFilter, pageSize int, pageToken string) (*model.CommandPage, error) {
+	if vin == "" {
+		return nil, errors.New("vehicle id is required")
+	}
+	if pageSize <= 0 {
+		pageSize = defaultCommandPageSize
+	}
+	pageSize = min(pageSize, maxCommandPageSize)
+
+	var cursor *model.Command
+	if pageToken != "" {
+		c, err := decodePageToken(pageToken)
+		if err != nil {
+			return nil, err
+		}
+		cursor = c
+	}
+
+	cmds, err := s.command.ListByVehicle(ctx, vin, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token holds the position of the last command returned rather than an offset,
+	// so commands created between two calls do not shift the following pages.
+	if cursor != nil {
+		start, found := slices.BinarySearchFunc(cmds, cursor, model.CompareNewestFirst)
+		if found {
+			start++
+		}
+		cmds = cmds[start:]
+	}
+
+	page := &model.CommandPage{Commands: cmds}
+	if len(cmds) > pageSize {
+		page.Commands = cmds[:pageSize]
+		page.NextPageToken = encodePageToken(page.Commands[pageSize-1])
+	}
+	return page, nil
+}
+
+// encodePageToken returns the opaque token of the position after cmd.
+func encodePageToken(cmd *model.Command) string {
+	raw := strconv.FormatInt(cmd.CreatedAt.UnixNano(), 10) + "/" + cmd.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodePageToken returns the command position held by a token of encodePageToken.
+func decodePageToken(token string) (*model.Command, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPageToken, err)
+	}
+	nanos, id, ok := strings.Cut(string(raw), "/")
+	if !ok || id == "" {
+		return nil, ErrInvalidPageToken
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPageToken, err)
+	}
+	return &model.Command{ID: id, CreatedAt: time.Unix(0, n)}, nil
+}
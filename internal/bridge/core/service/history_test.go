@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+)
+
+func TestListCommandsPagination(t *testing.T) {
+	repo := newRequestRepo()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		id := fmt.Sprintf("cmd-%d", i)
+		repo.commands[id] = &model.Command{ID: id, VehicleID: "VH1", Status: model.CommandStatusSucceeded, CreatedAt: base.Add(time.Duration(i) * time.Minute)}
+	}
+	// Created at the same time as cmd-4: ordered by ID.
+	repo.commands["cmd-5"] = &model.Command{ID: "cmd-5", VehicleID: "VH1", Status: model.CommandStatusFailed, CreatedAt: base.Add(4 * time.Minute)}
+	repo.commands["other"] = &model.Command{ID: "other", VehicleID: "VH2", CreatedAt: base}
+	svc := New(repo, nil, nil)
+	ctx := context.Background()
+
+	var got []string
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not end")
+		}
+		page, err := svc.ListCommands(ctx, "VH1", model.CommandFilter{}, 2, token)
+		if err != nil {
+			t.Fatalf("ListCommands() error = %v", err)
+		}
+		for _, cmd := range page.Commands {
+			got = append(got, cmd.ID)
+		}
+		if token = page.NextPageToken; token == "" {
+			break
+		}
+		// A command issued between two calls does not shift the next pages.
+		id := fmt.Sprintf("new-%d", pages)
+		repo.commands[id] = &model.Command{ID: id, VehicleID: "VH1", CreatedAt: base.Add(time.Hour)}
+	}
+	if want := fmt.Sprint([]string{"cmd-4", "cmd-5", "cmd-3", "cmd-2", "cmd-1", "cmd-0"}); fmt.Sprint(got) != want {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+
+	filter := model.CommandFilter{Statuses: []model.CommandStatus{model.CommandStatusSucceeded}, CreatedAfter: base.Add(time.Minute), CreatedBefore: base.Add(4 * time.Minute)}
+	page, err := svc.ListCommands(ctx, "VH1", filter, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Commands) != 3 || page.Commands[0].ID != "cmd-3" || page.NextPageToken != "" {
+		t.Errorf("filtered page = %+v", page)
+	}
+
+	if _, err := svc.ListCommands(ctx, "VH1", model.CommandFilter{}, 0, "not a token"); !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("ListCommands(bad token) error = %v, want ErrInvalidPageToken", err)
+	}
+	if _, err := svc.ListCommands(ctx, "", model.CommandFilter{}, 0, ""); err == nil {
+		t.Error("ListCommands without vehicle succeeded, want error")
+	}
+}
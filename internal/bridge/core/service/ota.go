@@ -2,24 +2,148 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/util"
+	"github.com/autopeer-io/autopeer/pkg/log"
 )
 
-// GetFirmwareDownloadURL generates a secure, temporary URL for the vehicle to download firmware.
-// This decouples the vehicle from the underlying storage details (S3/MinIO).
-func (s *Service) GetFirmwareDownloadURL(ctx context.Context, firmwarePath string) (string, error) {
-	// Hardcoded bucket name for now, in production this could come from config or the Firmware CRD.
-	const urlExpiry = 1 * time.Hour
+const (
+	// defaultURLExpiry is used when the agent does not request a specific URL lifetime.
+	defaultURLExpiry = 1 * time.Hour
+
+	// minURLExpiry is the shortest URL lifetime granted; a shorter one would expire before
+	// the agent could start the transfer.
+	minURLExpiry = 1 * time.Minute
+)
+
+// ErrChecksumMismatch is returned when the stored firmware does not match the checksum declared on the Vehicle.
+var ErrChecksumMismatch = errors.New("firmware checksum mismatch")
+
+// FirmwareKey returns the object key of the full firmware image for a version.
+func FirmwareKey(version string) string {
+	return fmt.Sprintf("%s/vehicle.bin", version)
+}
+
+// DeltaKey returns the object key of the delta patch that upgrades from one version to another.
+// Deltas are stored next to the full image of the target version: {to}/deltas/{from}.patch.
+func DeltaKey(from, to string) string {
+	return fmt.Sprintf("%s/deltas/%s.patch", to, from)
+}
+
+// GetFirmwareUpdate resolves the cheapest download that takes a vehicle from currentVersion
+// to desiredVersion. If storage holds a delta for that version pair, a link to the delta is
+// returned with BaseVersion set; otherwise it falls back to the full image.
+// An empty currentVersion always selects the full image.
+func (s *Service) GetFirmwareUpdate(ctx context.Context, vin, desiredVersion, currentVersion string, ttl time.Duration) (*model.FirmwareDownload, error) {
+	if desiredVersion == "" {
+		return nil, fmt.Errorf("desired version is empty")
+	}
+
+	if currentVersion != "" && currentVersion != desiredVersion {
+		download, err := s.getDeltaDownload(ctx, currentVersion, desiredVersion, ttl)
+		if err == nil {
+			return download, nil
+		}
+		log.Debug("No usable delta, falling back to full image",
+			"vin", vin, "from", currentVersion, "to", desiredVersion, "reason", err.Error())
+	}
+
+	return s.GetFirmwareDownload(ctx, vin, FirmwareKey(desiredVersion), ttl)
+}
+
+// getDeltaDownload returns a link to the delta patch between two versions.
+// The Vehicle's declared checksum describes the full image, so the delta is verified
+// against the checksum stored with the patch only.
+func (s *Service) getDeltaDownload(ctx context.Context, from, to string, ttl time.Duration) (*model.FirmwareDownload, error) {
+	key := DeltaKey(from, to)
+
+	info, err := s.storage.StatObject(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat delta: %w", err)
+	}
+
+	url, err := s.storage.GeneratePresignedURL(ctx, key, s.urlExpiry(ttl))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate delta URL: %w", err)
+	}
 
+	return &model.FirmwareDownload{URL: url, Checksum: info.Checksum, Signature: info.Signature, BaseVersion: from}, nil
+}
+
+// GetFirmwareDownload generates a secure, temporary URL for the vehicle to download firmware,
+// together with the checksum the vehicle must verify the download against.
+// This decouples the vehicle from the underlying storage details (S3/MinIO).
+// A zero ttl selects the default expiry; values above the configured maximum are clamped.
+//
+// The checksum is read from the storage metadata, which is authoritative. If the Vehicle
+// declares a checksum in its Spec, both must match; if storage has none, the Spec value is used.
+func (s *Service) GetFirmwareDownload(ctx context.Context, vin, firmwarePath string, ttl time.Duration) (*model.FirmwareDownload, error) {
 	if firmwarePath == "" {
-		return "", fmt.Errorf("firmware path is empty")
+		return nil, fmt.Errorf("firmware path is empty")
+	}
+
+	info, err := s.storage.StatObject(ctx, firmwarePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat firmware: %w", err)
+	}
+
+	checksum := info.Checksum
+	if expected := s.desiredChecksum(ctx, vin); expected != "" {
+		switch {
+		case checksum == "":
+			checksum = expected
+		case !sameChecksum(checksum, expected):
+			return nil, fmt.Errorf("%w: storage has %s, vehicle %s expects %s", ErrChecksumMismatch, checksum, vin, expected)
+		}
+	}
+
+	url, err := s.storage.GeneratePresignedURL(ctx, firmwarePath, s.urlExpiry(ttl))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate firmware URL: %w", err)
 	}
 
-	url, err := s.storage.GeneratePresignedURL(ctx, firmwarePath, urlExpiry)
+	return &model.FirmwareDownload{URL: url, Checksum: checksum, Signature: info.Signature}, nil
+}
+
+// desiredChecksum returns the firmware checksum declared on the Vehicle Spec, if any.
+func (s *Service) desiredChecksum(ctx context.Context, vin string) string {
+	if vin == "" {
+		return ""
+	}
+
+	v, err := s.vehicle.Get(ctx, vin)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate firmware URL: %w", err)
+		if !errors.Is(err, util.ErrNotFound) {
+			log.Warn("Failed to get vehicle for checksum lookup", "vin", vin, "error", err.Error())
+		}
+		return ""
 	}
+	return v.DesiredChecksum
+}
 
-	return url, nil
+// sameChecksum compares two checksums, ignoring case and an optional "sha256:" prefix.
+func sameChecksum(a, b string) bool {
+	normalize := func(s string) string {
+		return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "sha256:")
+	}
+	return normalize(a) == normalize(b)
+}
+
+// urlExpiry resolves the requested ttl against the default, the minimum and the server-side maximum.
+func (s *Service) urlExpiry(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		ttl = defaultURLExpiry
+	}
+	if ttl < minURLExpiry {
+		ttl = minURLExpiry
+	}
+	if s.maxURLExpiry > 0 && ttl > s.maxURLExpiry {
+		ttl = s.maxURLExpiry
+	}
+	return ttl
 }
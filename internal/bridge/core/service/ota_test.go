@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core"
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/util"
+)
+
+type stubStorage struct {
+	checksum string
+
+	// expiry is the lifetime of the last presigned download URL.
+	expiry time.Duration
+
+	// objects, if set, restricts StatObject to these keys.
+	objects map[string]string
+}
+
+func (s *stubStorage) GeneratePresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	s.expiry = expiry
+	return "https://s3.example.com/" + key, nil
+}
+
+func (s *stubStorage) GeneratePresignedPutURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "https://s3.example.com/" + key + "?upload", nil
+}
+
+func (s *stubStorage) StatObject(ctx context.Context, key string) (*model.ObjectInfo, error) {
+	if s.objects == nil {
+		return &model.ObjectInfo{Key: key, Checksum: s.checksum}, nil
+	}
+	sum, ok := s.objects[key]
+	if !ok {
+		return nil, errors.New("object not found")
+	}
+	return &model.ObjectInfo{Key: key, Checksum: sum}, nil
+}
+
+func (s *stubStorage) GetObjectRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubStorage) PutObject(ctx context.Context, key string, data []byte, contentType string) error {
+	return errors.New("not implemented")
+}
+
+func (s *stubStorage) CheckBucket(ctx context.Context) error { return nil }
+
+// stubVehicleRepo returns a single vehicle with the given desired checksum.
+type stubVehicleRepo struct {
+	core.VehicleRepository
+	checksum string
+}
+
+func (r *stubVehicleRepo) Vehicle() core.VehicleRepository { return r }
+func (r *stubVehicleRepo) Command() core.CommandRepository { return nil }
+
+func (r *stubVehicleRepo) Get(ctx context.Context, vin string) (*model.Vehicle, error) {
+	if vin != "LSVAU2180N2183294" {
+		return nil, util.ErrNotFound
+	}
+	return &model.Vehicle{VIN: vin, DesiredChecksum: r.checksum}, nil
+}
+
+func TestGetFirmwareDownloadChecksum(t *testing.T) {
+	const sum = "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	tests := []struct {
+		name           string
+		storedChecksum string
+		specChecksum   string
+		wantChecksum   string
+		wantErr        error
+	}{
+		{name: "matching checksums", storedChecksum: sum, specChecksum: "SHA256:9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08", wantChecksum: sum},
+		{name: "mismatching checksums", storedChecksum: sum, specChecksum: "sha256:deadbeef", wantErr: ErrChecksumMismatch},
+		{name: "storage only", storedChecksum: sum, wantChecksum: sum},
+		{name: "spec only", specChecksum: sum, wantChecksum: sum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := New(&stubVehicleRepo{checksum: tt.specChecksum}, nil, &stubStorage{checksum: tt.storedChecksum})
+
+			download, err := svc.GetFirmwareDownload(context.Background(), "LSVAU2180N2183294", "1.2.0/vehicle.bin", 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetFirmwareDownload() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if download.Checksum != tt.wantChecksum {
+				t.Errorf("checksum = %q, want %q", download.Checksum, tt.wantChecksum)
+			}
+			if download.URL == "" {
+				t.Errorf("expected a download URL")
+			}
+		})
+	}
+}
+
+func TestGetFirmwareUpdateDelta(t *testing.T) {
+	storage := &stubStorage{objects: map[string]string{
+		"v1.2.0/vehicle.bin":         "sha256:full",
+		"v1.2.0/deltas/v1.1.0.patch": "sha256:delta",
+		"v1.1.0/vehicle.bin":         "sha256:old",
+	}}
+	svc := New(&stubVehicleRepo{}, nil, storage)
+
+	tests := []struct {
+		name           string
+		currentVersion string
+		wantKey        string
+		wantChecksum   string
+		wantBase       string
+	}{
+		{name: "delta available", currentVersion: "v1.1.0", wantKey: "v1.2.0/deltas/v1.1.0.patch", wantChecksum: "sha256:delta", wantBase: "v1.1.0"},
+		{name: "no delta for version pair", currentVersion: "v1.0.0", wantKey: "v1.2.0/vehicle.bin", wantChecksum: "sha256:full"},
+		{name: "unknown current version", currentVersion: "", wantKey: "v1.2.0/vehicle.bin", wantChecksum: "sha256:full"},
+		{name: "already on target", currentVersion: "v1.2.0", wantKey: "v1.2.0/vehicle.bin", wantChecksum: "sha256:full"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			download, err := svc.GetFirmwareUpdate(context.Background(), "LSVAU2180N2183294", "v1.2.0", tt.currentVersion, 0)
+			if err != nil {
+				t.Fatalf("GetFirmwareUpdate() error = %v", err)
+			}
+			if download.URL != "https://s3.example.com/"+tt.wantKey {
+				t.Errorf("URL = %q, want key %q", download.URL, tt.wantKey)
+			}
+			if download.Checksum != tt.wantChecksum {
+				t.Errorf("checksum = %q, want %q", download.Checksum, tt.wantChecksum)
+			}
+			if download.BaseVersion != tt.wantBase {
+				t.Errorf("base version = %q, want %q", download.BaseVersion, tt.wantBase)
+			}
+		})
+	}
+}
+
+func TestFirmwareDownloadURLExpiry(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{name: "unset", ttl: 0, want: defaultURLExpiry},
+		{name: "negative", ttl: -time.Minute, want: defaultURLExpiry},
+		{name: "below minimum", ttl: time.Second, want: minURLExpiry},
+		{name: "within bounds", ttl: 6 * time.Hour, want: 6 * time.Hour},
+		{name: "at maximum", ttl: 24 * time.Hour, want: 24 * time.Hour},
+		{name: "above maximum", ttl: 48 * time.Hour, want: 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &stubStorage{}
+			svc := New(&stubVehicleRepo{}, nil, storage, WithMaxURLExpiry(24*time.Hour))
+
+			if _, err := svc.GetFirmwareDownload(context.Background(), "LSVAU2180N2183294", "v1.2.0/vehicle.bin", tt.ttl); err != nil {
+				t.Fatalf("GetFirmwareDownload() error = %v", err)
+			}
+			if storage.expiry != tt.want {
+				t.Errorf("expiry = %v, want %v", storage.expiry, tt.want)
+			}
+		})
+	}
+}
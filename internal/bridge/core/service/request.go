@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/util"
+	"github.com/autopeer-io/autopeer/pkg/log"
+)
+
+// minRequestInterval is the shortest time between two accepted requests of the same type from one vehicle.
+// It keeps a misbehaving agent from flooding the control plane with commands.
+// The limit is kept per bridge replica: requests reach the replicas through a shared
+// subscription, so with N replicas a vehicle may get up to N requests of a type accepted per
+// interval. Retries are still safe, as a request ID always maps to the same command.
+const minRequestInterval = time.Minute
+
+// requestPlanner turns a vehicle request into the command that carries it out.
+// It returns a nil command and the reason if the request cannot be honored.
+type requestPlanner func(v *model.Vehicle, req *model.VehicleRequest) (*model.Command, string)
+
+// requestPlanners lists the request types vehicles may send. Anything else is rejected.
+var requestPlanners = map[model.RequestType]requestPlanner{
+	model.RequestTypeConfig: planConfigRequest,
+}
+
+// HandleVehicleRequest decides on a vehicle-initiated request and, if the policy allows it,
+// creates the command that carries it out.
+// Policy rejections are reported in the result; an error means the request could not be
+// evaluated and the vehicle may retry it with the same ID.
+func (s *Service) HandleVehicleRequest(ctx context.Context, req *model.VehicleRequest) (*model.RequestResult, error) {
+	plan, ok := requestPlanners[req.Type]
+	if !ok {
+		return rejectRequest("unsupported request type %q", req.Type), nil
+	}
+
+	v, err := s.vehicle.Get(ctx, req.VehicleID)
+	if errors.Is(err, util.ErrNotFound) {
+		return rejectRequest("vehicle %s is not registered", req.VehicleID), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vehicle %s: %w", req.VehicleID, err)
+	}
+
+	if v.Decommissioned {
+		return rejectRequest("vehicle %s is decommissioned", req.VehicleID), nil
+	}
+	if v.MaintenanceMode {
+		return rejectRequest("vehicle %s is in maintenance mode", req.VehicleID), nil
+	}
+
+	cmd, reason := plan(v, req)
+	if cmd == nil {
+		return rejectRequest("%s", reason), nil
+	}
+	cmd.ID = requestCommandID(req)
+	cmd.VehicleID = req.VehicleID
+	cmd.RequestID = req.ID
+
+	if !s.requests.acquire(req) {
+		return rejectRequest("too many %s requests, retry in %s", req.Type, minRequestInterval), nil
+	}
+
+	err = s.command.Create(ctx, cmd)
+	switch {
+	case errors.Is(err, util.ErrAlreadyExists):
+		// A retry of a request that was already acted on.
+		log.Info("Vehicle request already handled", "vehicleID", req.VehicleID, "requestID", req.ID, "command", cmd.ID)
+	case err != nil:
+		s.requests.release(req)
+		return nil, fmt.Errorf("failed to create command for %s request: %w", req.Type, err)
+	}
+
+	return &model.RequestResult{
+		Accepted:  true,
+		Message:   fmt.Sprintf("Created %s command", cmd.Type),
+		CommandID: cmd.ID,
+	}, nil
+}
+
+// planConfigRequest sends the vehicle its desired configuration.
+func planConfigRequest(v *model.Vehicle, req *model.VehicleRequest) (*model.Command, string) {
+	if len(v.Properties) == 0 {
+		return nil, "no configuration is defined for the vehicle"
+	}
+
+	return &model.Command{
+		Type:       model.CommandTypeApplyConfig,
+		Parameters: maps.Clone(v.Properties),
+		Priority:   1,
+	}, ""
+}
+
+func rejectRequest(format string, args ...any) *model.RequestResult {
+	return &model.RequestResult{Message: fmt.Sprintf(format, args...)}
+}
+
+// requestCommandID derives the command name from the request, so a retried request maps to the same command.
+func requestCommandID(req *model.VehicleRequest) string {
+	sum := sha256.Sum256([]byte(req.ID))
+	return fmt.Sprintf("req-%s-%s-%s", strings.ToLower(req.VehicleID), strings.ToLower(string(req.Type)), hex.EncodeToString(sum[:5]))
+}
+
+// requestLimiter enforces minRequestInterval per vehicle and request type.
+// It holds its state in memory, so it only sees the requests handled by this replica.
+type requestLimiter struct {
+	mu      sync.Mutex
+	entries map[string]requestLimiterEntry
+
+	// now is overridable for tests.
+	now func() time.Time
+}
+
+type requestLimiterEntry struct {
+	requestID string
+	at        time.Time
+}
+
+func newRequestLimiter() *requestLimiter {
+	return &requestLimiter{
+		entries: make(map[string]requestLimiterEntry),
+		now:     time.Now,
+	}
+}
+
+// acquire reports whether the request may be acted on. A retry of the last accepted request always may.
+func (l *requestLimiter) acquire(req *model.VehicleRequest) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := req.VehicleID + "/" + string(req.Type)
+	now := l.now()
+	if e, ok := l.entries[key]; ok && e.requestID != req.ID && now.Sub(e.at) < minRequestInterval {
+		return false
+	}
+
+	// Drop stale entries now and then, so retired vehicles don't pile up.
+	if len(l.entries) >= 1024 {
+		for k, e := range l.entries {
+			if now.Sub(e.at) >= minRequestInterval {
+				delete(l.entries, k)
+			}
+		}
+	}
+	l.entries[key] = requestLimiterEntry{requestID: req.ID, at: now}
+	return true
+}
+
+// release forgets a request that could not be acted on, so the vehicle can retry right away.
+func (l *requestLimiter) release(req *model.VehicleRequest) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := req.VehicleID + "/" + string(req.Type)
+	if e, ok := l.entries[key]; ok && e.requestID == req.ID {
+		delete(l.entries, key)
+	}
+}
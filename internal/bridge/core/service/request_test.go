@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core"
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/util"
+)
+
+// requestRepo serves the vehicles of a twinRepo and records the created commands.
+type requestRepo struct {
+	twinRepo
+	commands map[string]*model.Command
+	failures int
+}
+
+func (r *requestRepo) Vehicle() core.VehicleRepository { return &r.twinRepo }
+func (r *requestRepo) Command() core.CommandRepository { return r }
+
+func (r *requestRepo) Create(ctx context.Context, cmd *model.Command) error {
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("apiserver unavailable")
+	}
+	if _, ok := r.commands[cmd.ID]; ok {
+		return util.ErrAlreadyExists
+	}
+	r.commands[cmd.ID] = cmd
+	return nil
+}
+
+func (r *requestRepo) UpdateStatus(ctx context.Context, cmdID string, status model.CommandStatus, message string, result map[string]string) error {
+	return nil
+}
+
+func (r *requestRepo) ListByVehicle(ctx context.Context, vin string, filter model.CommandFilter) ([]*model.Command, error) {
+	var cmds []*model.Command
+	for _, cmd := range r.commands {
+		if cmd.VehicleID == vin && filter.Matches(cmd) {
+			cmds = append(cmds, cmd)
+		}
+	}
+	slices.SortFunc(cmds, model.CompareNewestFirst)
+	return cmds, nil
+}
+
+func newRequestRepo() *requestRepo {
+	return &requestRepo{
+		twinRepo: twinRepo{vehicles: map[string]*model.Vehicle{
+			"CONFIGURED":   {VIN: "CONFIGURED", Properties: map[string]string{"drive_mode": "eco", "max_speed": "120"}},
+			"UNCONFIGURED": {VIN: "UNCONFIGURED"},
+			"IN-SERVICE":   {VIN: "IN-SERVICE", Properties: map[string]string{"drive_mode": "eco"}, MaintenanceMode: true},
+			"RETIRED":      {VIN: "RETIRED", Properties: map[string]string{"drive_mode": "eco"}, Decommissioned: true},
+		}},
+		commands: make(map[string]*model.Command),
+	}
+}
+
+func TestHandleConfigRequest(t *testing.T) {
+	repo := newRequestRepo()
+	svc := New(repo, nil, nil)
+
+	req := &model.VehicleRequest{ID: "r-1", VehicleID: "CONFIGURED", Type: model.RequestTypeConfig}
+	result, err := svc.HandleVehicleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("HandleVehicleRequest() error = %v", err)
+	}
+	if !result.Accepted {
+		t.Fatalf("request rejected: %s", result.Message)
+	}
+
+	cmd, ok := repo.commands[result.CommandID]
+	if !ok {
+		t.Fatalf("command %q was not created", result.CommandID)
+	}
+	if cmd.Type != model.CommandTypeApplyConfig || cmd.VehicleID != "CONFIGURED" || cmd.RequestID != "r-1" {
+		t.Errorf("command = %+v, want ApplyConfig for CONFIGURED from r-1", cmd)
+	}
+	if want := repo.vehicles["CONFIGURED"].Properties; !maps.Equal(cmd.Parameters, want) {
+		t.Errorf("parameters = %v, want desired config %v", cmd.Parameters, want)
+	}
+	if !strings.HasPrefix(cmd.ID, "req-configured-config-") {
+		t.Errorf("command ID = %q, want a DNS-safe name derived from the request", cmd.ID)
+	}
+
+	// A retry of the same request maps to the same command and is not rate limited.
+	retry, err := svc.HandleVehicleRequest(context.Background(), req)
+	if err != nil || !retry.Accepted || retry.CommandID != result.CommandID {
+		t.Errorf("retry = %+v, %v; want accepted with command %q", retry, err, result.CommandID)
+	}
+	if len(repo.commands) != 1 {
+		t.Errorf("created %d commands, want 1", len(repo.commands))
+	}
+}
+
+func TestHandleVehicleRequestPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *model.VehicleRequest
+		wantMsg string
+	}{
+		{
+			name:    "unsupported type",
+			req:     &model.VehicleRequest{ID: "r-1", VehicleID: "CONFIGURED", Type: "SelfDestruct"},
+			wantMsg: "unsupported request type",
+		},
+		{
+			name:    "unregistered vehicle",
+			req:     &model.VehicleRequest{ID: "r-1", VehicleID: "UNKNOWN", Type: model.RequestTypeConfig},
+			wantMsg: "not registered",
+		},
+		{
+			name:    "nothing to send",
+			req:     &model.VehicleRequest{ID: "r-1", VehicleID: "UNCONFIGURED", Type: model.RequestTypeConfig},
+			wantMsg: "no configuration",
+		},
+		{
+			name:    "maintenance mode",
+			req:     &model.VehicleRequest{ID: "r-1", VehicleID: "IN-SERVICE", Type: model.RequestTypeConfig},
+			wantMsg: "maintenance mode",
+		},
+		{
+			name:    "decommissioned",
+			req:     &model.VehicleRequest{ID: "r-1", VehicleID: "RETIRED", Type: model.RequestTypeConfig},
+			wantMsg: "decommissioned",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRequestRepo()
+			svc := New(repo, nil, nil)
+
+			result, err := svc.HandleVehicleRequest(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("HandleVehicleRequest() error = %v", err)
+			}
+			if result.Accepted || !strings.Contains(result.Message, tt.wantMsg) {
+				t.Errorf("result = %+v, want rejection containing %q", result, tt.wantMsg)
+			}
+			if len(repo.commands) != 0 {
+				t.Errorf("created %d commands for a rejected request", len(repo.commands))
+			}
+		})
+	}
+}
+
+func TestHandleVehicleRequestRateLimit(t *testing.T) {
+	repo := newRequestRepo()
+	svc := New(repo, nil, nil)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc.requests.now = func() time.Time { return now }
+
+	handle := func(id string) *model.RequestResult {
+		t.Helper()
+		result, err := svc.HandleVehicleRequest(context.Background(), &model.VehicleRequest{ID: id, VehicleID: "CONFIGURED", Type: model.RequestTypeConfig})
+		if err != nil {
+			t.Fatalf("HandleVehicleRequest(%s) error = %v", id, err)
+		}
+		return result
+	}
+
+	if r := handle("r-1"); !r.Accepted {
+		t.Fatalf("first request rejected: %s", r.Message)
+	}
+	if r := handle("r-2"); r.Accepted {
+		t.Error("second request within the interval was accepted")
+	}
+
+	now = now.Add(minRequestInterval)
+	if r := handle("r-3"); !r.Accepted {
+		t.Errorf("request after the interval rejected: %s", r.Message)
+	}
+
+	// A request that failed to be acted on does not count against the limit.
+	now = now.Add(minRequestInterval)
+	repo.failures = 1
+	if _, err := svc.HandleVehicleRequest(context.Background(), &model.VehicleRequest{ID: "r-4", VehicleID: "CONFIGURED", Type: model.RequestTypeConfig}); err == nil {
+		t.Fatal("expected an error when the command cannot be created")
+	}
+	if r := handle("r-5"); !r.Accepted {
+		t.Errorf("request after a failed one rejected: %s", r.Message)
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/util"
+)
+
+// resultKeyPrefix is the storage prefix command outputs are uploaded under.
+const resultKeyPrefix = "results/"
+
+// ResultKey returns the object key the output of a command is uploaded to: results/{vin}/{command}.
+func ResultKey(vin, cmdID string) string {
+	return fmt.Sprintf("%s%s/%s", resultKeyPrefix, vin, cmdID)
+}
+
+// isResultKeyOf reports whether key is the result key issued for the command by some vehicle.
+func isResultKeyOf(key, cmdID string) bool {
+	rest, ok := strings.CutPrefix(key, resultKeyPrefix)
+	if !ok || path.Clean(key) != key {
+		return false
+	}
+	vin, name, _ := strings.Cut(rest, "/")
+	return vin != "" && name == cmdID
+}
+
+// GetResultUpload issues a presigned URL the vehicle uploads the output of a command to.
+// Outputs such as collected logs are too large for the command status; once uploaded,
+// the vehicle reports the key with the command status, which only references it.
+// A zero ttl selects the default expiry; values above the configured maximum are clamped.
+func (s *Service) GetResultUpload(ctx context.Context, vin, cmdID string, ttl time.Duration) (*model.ResultUpload, error) {
+	if vin == "" || cmdID == "" || strings.Contains(vin, "/") || strings.Contains(cmdID, "/") {
+		return nil, fmt.Errorf("invalid vehicle %q or command %q", vin, cmdID)
+	}
+
+	if _, err := s.vehicle.Get(ctx, vin); err != nil {
+		if errors.Is(err, util.ErrNotFound) {
+			return nil, fmt.Errorf("vehicle %s is not registered: %w", vin, err)
+		}
+		return nil, fmt.Errorf("failed to get vehicle %s: %w", vin, err)
+	}
+
+	key := ResultKey(vin, cmdID)
+	url, err := s.storage.GeneratePresignedPutURL(ctx, key, s.urlExpiry(ttl))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate result upload URL: %w", err)
+	}
+
+	return &model.ResultUpload{URL: url, Key: key}, nil
+}
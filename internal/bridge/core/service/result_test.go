@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core"
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+)
+
+// resultRepo serves the vehicles of a twinRepo and records the command results it is given.
+type resultRepo struct {
+	twinRepo
+	results map[string]map[string]string
+}
+
+func (r *resultRepo) Vehicle() core.VehicleRepository { return &r.twinRepo }
+func (r *resultRepo) Command() core.CommandRepository { return r }
+
+func (r *resultRepo) Create(ctx context.Context, cmd *model.Command) error { return nil }
+
+func (r *resultRepo) ListByVehicle(ctx context.Context, vin string, filter model.CommandFilter) ([]*model.Command, error) {
+	return nil, nil
+}
+
+func (r *resultRepo) UpdateStatus(ctx context.Context, cmdID string, status model.CommandStatus, message string, result map[string]string) error {
+	r.results[cmdID] = result
+	return nil
+}
+
+func TestResultUploadFlow(t *testing.T) {
+	repo := &resultRepo{
+		twinRepo: twinRepo{vehicles: map[string]*model.Vehicle{"VH1": {VIN: "VH1"}}},
+		results:  make(map[string]map[string]string),
+	}
+	svc := New(repo, nil, &stubStorage{})
+	ctx := context.Background()
+
+	upload, err := svc.GetResultUpload(ctx, "VH1", "logs-vh1", 0)
+	if err != nil {
+		t.Fatalf("GetResultUpload() error = %v", err)
+	}
+	if upload.Key != "results/VH1/logs-vh1" || upload.URL != "https://s3.example.com/results/VH1/logs-vh1?upload" {
+		t.Fatalf("upload = %+v", upload)
+	}
+
+	// The agent uploads, then reports the key with the final status.
+	if err := svc.UpdateCommandStatus(ctx, "logs-vh1", model.CommandStatusSucceeded, "uploaded", upload.Key); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{model.CommandResultObjectKey: upload.Key}; !maps.Equal(repo.results["logs-vh1"], want) {
+		t.Errorf("result = %v, want %v", repo.results["logs-vh1"], want)
+	}
+
+	// A key issued for another command, or outside the result prefix, is not recorded.
+	for _, key := range []string{"results/VH1/other", "v1.2.0/vehicle.bin", "results/../logs-vh2", "results//logs-vh2"} {
+		if err := svc.UpdateCommandStatus(ctx, "logs-vh2", model.CommandStatusSucceeded, "", key); err != nil {
+			t.Fatal(err)
+		}
+		if result := repo.results["logs-vh2"]; result != nil {
+			t.Errorf("key %q: result = %v, want none", key, result)
+		}
+	}
+
+	for _, tc := range []struct{ vin, cmd string }{{"UNKNOWN", "logs"}, {"VH1", ""}, {"VH1", "../firmware"}} {
+		if _, err := svc.GetResultUpload(ctx, tc.vin, tc.cmd, 0); err == nil {
+			t.Errorf("GetResultUpload(%q, %q) succeeded, want error", tc.vin, tc.cmd)
+		}
+	}
+}
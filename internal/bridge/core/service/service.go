@@ -1,7 +1,10 @@
 package service
 
 import (
+	"time"
+
 	"github.com/autopeer-io/autopeer/internal/bridge/core"
+	"github.com/autopeer-io/autopeer/internal/pkg/audit"
 )
 
 // Service implements the core business logic (Use Cases) for CloudHub.
@@ -11,6 +14,35 @@ type Service struct {
 	command  core.CommandRepository
 	notifier core.CommandNotifier
 	storage  core.Storage
+
+	// maxURLExpiry caps the lifetime of firmware download URLs requested by agents.
+	maxURLExpiry time.Duration
+
+	// audit records the command status changes reported by vehicles.
+	audit audit.Sink
+
+	// properties caches the properties each vehicle may report as telemetry.
+	properties *propertyCache
+
+	// requests rate-limits vehicle-initiated requests, per bridge replica.
+	requests *requestLimiter
+}
+
+// Option configures optional behavior of the Service.
+type Option func(*Service)
+
+// WithMaxURLExpiry sets the upper bound for presigned firmware URL lifetimes.
+func WithMaxURLExpiry(d time.Duration) Option {
+	return func(s *Service) {
+		s.maxURLExpiry = d
+	}
+}
+
+// WithAuditSink sets the sink receiving command status audit records.
+func WithAuditSink(sink audit.Sink) Option {
+	return func(s *Service) {
+		s.audit = sink
+	}
 }
 
 // New creates a new instance of the CloudHub core service.
@@ -19,11 +51,22 @@ func New(
 	repo core.Repository,
 	notifier core.CommandNotifier,
 	storage core.Storage,
+	opts ...Option,
 ) *Service {
-	return &Service{
-		vehicle:  repo.Vehicle(),
-		command:  repo.Command(),
-		notifier: notifier,
-		storage:  storage,
+	s := &Service{
+		vehicle:      repo.Vehicle(),
+		command:      repo.Command(),
+		notifier:     notifier,
+		storage:      storage,
+		maxURLExpiry: defaultURLExpiry,
+		audit:        audit.Discard,
+		properties:   newPropertyCache(),
+		requests:     newRequestLimiter(),
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
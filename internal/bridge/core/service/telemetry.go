@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/util"
+)
+
+// ErrUnknownProperty is returned when a vehicle reports properties its VehicleModel does not declare.
+var ErrUnknownProperty = errors.New("property not declared by vehicle model")
+
+// propertyCacheTTL is how long the properties a vehicle may report are cached.
+// Telemetry arrives far more often than models change, so this saves two apiserver reads per report.
+const propertyCacheTTL = time.Minute
+
+// ReportTelemetry writes the reported property values to the vehicle twin through the buffered pipeline.
+// Properties the vehicle's VehicleModel does not declare are dropped and reported as ErrUnknownProperty;
+// a vehicle without a VehicleModel may report any property.
+func (s *Service) ReportTelemetry(ctx context.Context, vin string, properties map[string]string) error {
+	if vin == "" || len(properties) == 0 {
+		return nil
+	}
+
+	allowed, err := s.allowedProperties(ctx, vin)
+	if err != nil {
+		return fmt.Errorf("failed to resolve vehicle model of %s: %w", vin, err)
+	}
+
+	accepted := properties
+	var rejected []string
+	if allowed != nil {
+		accepted = make(map[string]string, len(properties))
+		for name, value := range properties {
+			if _, ok := allowed[name]; ok {
+				accepted[name] = value
+			} else {
+				rejected = append(rejected, name)
+			}
+		}
+	}
+
+	// A telemetry report also proves the vehicle is online.
+	if len(accepted) > 0 {
+		err := s.batchUpdateStatus(ctx, &model.VehicleStatusUpdate{
+			VIN:               vin,
+			Online:            true,
+			LastHeartbeatTime: time.Now(),
+			Properties:        accepted,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(rejected) > 0 {
+		slices.Sort(rejected)
+		return fmt.Errorf("%w: %s", ErrUnknownProperty, strings.Join(rejected, ", "))
+	}
+	return nil
+}
+
+// allowedProperties returns the set of properties a vehicle may report, or nil if any is allowed.
+func (s *Service) allowedProperties(ctx context.Context, vin string) (map[string]struct{}, error) {
+	if allowed, ok := s.properties.get(vin); ok {
+		return allowed, nil
+	}
+
+	v, err := s.vehicle.Get(ctx, vin)
+	if err != nil {
+		return nil, err
+	}
+
+	var allowed map[string]struct{}
+	if v.ModelRef != "" {
+		m, err := s.vehicle.GetModel(ctx, v.ModelRef)
+		switch {
+		case errors.Is(err, util.ErrNotFound):
+			// The controller flags the dangling reference; until it is fixed nothing is accepted.
+			allowed = map[string]struct{}{}
+		case err != nil:
+			return nil, err
+		default:
+			allowed = make(map[string]struct{}, len(m.Properties))
+			for _, name := range m.Properties {
+				allowed[name] = struct{}{}
+			}
+		}
+	}
+
+	s.properties.put(vin, allowed)
+	return allowed, nil
+}
+
+// propertyCache caches the properties each vehicle may report, keyed by VIN.
+type propertyCache struct {
+	mu      sync.Mutex
+	entries map[string]propertyCacheEntry
+
+	// now is overridable for tests.
+	now func() time.Time
+}
+
+type propertyCacheEntry struct {
+	allowed map[string]struct{}
+	expires time.Time
+}
+
+func newPropertyCache() *propertyCache {
+	return &propertyCache{
+		entries: make(map[string]propertyCacheEntry),
+		now:     time.Now,
+	}
+}
+
+func (c *propertyCache) get(vin string) (map[string]struct{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[vin]
+	if !ok || c.now().After(e.expires) {
+		return nil, false
+	}
+	return e.allowed, true
+}
+
+func (c *propertyCache) put(vin string, allowed map[string]struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop expired entries now and then, so retired vehicles don't pile up.
+	now := c.now()
+	if len(c.entries) >= 1024 {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[vin] = propertyCacheEntry{allowed: allowed, expires: now.Add(propertyCacheTTL)}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core"
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/util"
+)
+
+// twinRepo serves fixed vehicles and models and records the buffered status updates.
+type twinRepo struct {
+	vehicles map[string]*model.Vehicle
+	models   map[string]*model.VehicleModel
+	updates  []*model.VehicleStatusUpdate
+	reads    int
+}
+
+func (r *twinRepo) Vehicle() core.VehicleRepository { return r }
+func (r *twinRepo) Command() core.CommandRepository { return nil }
+
+func (r *twinRepo) Get(ctx context.Context, vin string) (*model.Vehicle, error) {
+	r.reads++
+	if v, ok := r.vehicles[vin]; ok {
+		return v, nil
+	}
+	return nil, util.ErrNotFound
+}
+
+func (r *twinRepo) GetModel(ctx context.Context, name string) (*model.VehicleModel, error) {
+	r.reads++
+	if m, ok := r.models[name]; ok {
+		return m, nil
+	}
+	return nil, util.ErrNotFound
+}
+
+func (r *twinRepo) Create(ctx context.Context, v *model.Vehicle) error       { return nil }
+func (r *twinRepo) UpdateStatus(ctx context.Context, v *model.Vehicle) error { return nil }
+
+func (r *twinRepo) List(ctx context.Context) ([]*model.Vehicle, error) {
+	var vehicles []*model.Vehicle
+	for _, v := range r.vehicles {
+		vehicles = append(vehicles, v)
+	}
+	return vehicles, nil
+}
+
+func (r *twinRepo) BatchUpdateStatus(ctx context.Context, update *model.VehicleStatusUpdate) error {
+	r.updates = append(r.updates, update)
+	return nil
+}
+
+func TestReportTelemetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		vin        string
+		properties map[string]string
+		want       map[string]string
+		wantErr    error
+	}{
+		{
+			name:       "declared properties",
+			vin:        "MODELED",
+			properties: map[string]string{"battery_level": "80", "cabin_temp": "21.5"},
+			want:       map[string]string{"battery_level": "80", "cabin_temp": "21.5"},
+		},
+		{
+			name:       "undeclared properties are dropped",
+			vin:        "MODELED",
+			properties: map[string]string{"battery_level": "80", "tire_pressure": "2.4"},
+			want:       map[string]string{"battery_level": "80"},
+			wantErr:    ErrUnknownProperty,
+		},
+		{
+			name:       "only undeclared properties",
+			vin:        "MODELED",
+			properties: map[string]string{"tire_pressure": "2.4"},
+			wantErr:    ErrUnknownProperty,
+		},
+		{
+			name:       "vehicle without model accepts anything",
+			vin:        "UNMODELED",
+			properties: map[string]string{"tire_pressure": "2.4"},
+			want:       map[string]string{"tire_pressure": "2.4"},
+		},
+		{
+			name:       "dangling model reference accepts nothing",
+			vin:        "DANGLING",
+			properties: map[string]string{"battery_level": "80"},
+			wantErr:    ErrUnknownProperty,
+		},
+		{
+			name:       "unknown vehicle",
+			vin:        "GHOST",
+			properties: map[string]string{"battery_level": "80"},
+			wantErr:    util.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &twinRepo{
+				vehicles: map[string]*model.Vehicle{
+					"MODELED":   {VIN: "MODELED", ModelRef: "model-3"},
+					"UNMODELED": {VIN: "UNMODELED"},
+					"DANGLING":  {VIN: "DANGLING", ModelRef: "deleted"},
+				},
+				models: map[string]*model.VehicleModel{
+					"model-3": {Name: "model-3", Properties: []string{"battery_level", "cabin_temp"}},
+				},
+			}
+			svc := New(repo, nil, nil)
+
+			err := svc.ReportTelemetry(context.Background(), tt.vin, tt.properties)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReportTelemetry() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.want == nil {
+				if len(repo.updates) != 0 {
+					t.Errorf("expected no twin update, got %+v", repo.updates)
+				}
+				return
+			}
+			if len(repo.updates) != 1 {
+				t.Fatalf("expected 1 twin update, got %d", len(repo.updates))
+			}
+			update := repo.updates[0]
+			if !maps.Equal(update.Properties, tt.want) || !update.Online || update.VIN != tt.vin {
+				t.Errorf("update = %+v, want online update of %v", update, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportTelemetryCachesModel(t *testing.T) {
+	repo := &twinRepo{
+		vehicles: map[string]*model.Vehicle{"MODELED": {VIN: "MODELED", ModelRef: "model-3"}},
+		models:   map[string]*model.VehicleModel{"model-3": {Name: "model-3", Properties: []string{"battery_level"}}},
+	}
+	svc := New(repo, nil, nil)
+
+	for i := 0; i < 5; i++ {
+		if err := svc.ReportTelemetry(context.Background(), "MODELED", map[string]string{"battery_level": "80"}); err != nil {
+			t.Fatalf("ReportTelemetry() error = %v", err)
+		}
+	}
+
+	if repo.reads != 2 {
+		t.Errorf("expected the vehicle and its model to be read once, got %d reads", repo.reads)
+	}
+	if len(repo.updates) != 5 {
+		t.Errorf("expected every report to update the twin, got %d updates", len(repo.updates))
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/util"
+)
+
+// builtinCommandTypes are the command types every agent understands, whatever its VehicleModel declares.
+var builtinCommandTypes = []model.CommandType{
+	model.CommandTypeOTA,
+	model.CommandTypeReboot,
+	model.CommandTypeApplyConfig,
+}
+
+// ValidateCommand runs the checks a command must pass to be delivered, without dispatching it.
+// It returns every failed check; an error means the command could not be evaluated.
+//
+// A method is known if it is a built-in command type or declared by the vehicle's model.
+// Vehicles whose model declares no methods accept any method.
+func (s *Service) ValidateCommand(ctx context.Context, cmd *model.Command) ([]model.ValidationIssue, error) {
+	var issues []model.ValidationIssue
+	issue := func(field, reason, format string, args ...any) {
+		issues = append(issues, model.ValidationIssue{Field: field, Reason: reason, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if cmd.Type == "" {
+		issue("command_type", "Required", "command type is required")
+	}
+	if cmd.Priority < 0 || cmd.Priority > 2 {
+		issue("priority", "OutOfRange", "priority must be between 0 and 2, got %d", cmd.Priority)
+	}
+	if cmd.Type == model.CommandTypeOTA && cmd.Parameters["version"] == "" {
+		issue("parameters.version", "Required", "OTA commands need the target firmware version")
+	}
+
+	if cmd.VehicleID == "" {
+		issue("vehicle_id", "Required", "vehicle ID is required")
+		return issues, nil
+	}
+
+	v, err := s.vehicle.Get(ctx, cmd.VehicleID)
+	if errors.Is(err, util.ErrNotFound) {
+		issue("vehicle_id", "NotFound", "vehicle %s is not registered", cmd.VehicleID)
+		return issues, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vehicle %s: %w", cmd.VehicleID, err)
+	}
+
+	if v.Decommissioned {
+		issue("vehicle_id", "Decommissioned", "vehicle %s is decommissioned", cmd.VehicleID)
+	}
+	if v.MaintenanceMode {
+		issue("vehicle_id", "MaintenanceMode", "vehicle %s is in maintenance mode", cmd.VehicleID)
+	}
+	if !v.Online {
+		issue("vehicle_id", "Offline", "vehicle %s is not connected", cmd.VehicleID)
+	}
+
+	if v.ModelRef == "" {
+		return issues, nil
+	}
+	m, err := s.vehicle.GetModel(ctx, v.ModelRef)
+	if errors.Is(err, util.ErrNotFound) {
+		issue("vehicle_id", "ModelNotFound", "vehicle model %s of vehicle %s does not exist", v.ModelRef, cmd.VehicleID)
+		return issues, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vehicle model %s: %w", v.ModelRef, err)
+	}
+
+	if cmd.Type != "" && len(m.Methods) > 0 && !slices.Contains(builtinCommandTypes, cmd.Type) && !slices.Contains(m.Methods, string(cmd.Type)) {
+		issue("command_type", "UnknownMethod", "method %s is not declared by vehicle model %s", cmd.Type, m.Name)
+	}
+	if cmd.Type == model.CommandTypeApplyConfig {
+		for _, name := range slices.Sorted(maps.Keys(cmd.Parameters)) {
+			if !slices.Contains(m.Properties, name) {
+				issue("parameters."+name, "UnknownProperty", "property %s is not declared by vehicle model %s", name, m.Name)
+			}
+		}
+	}
+
+	return issues, nil
+}
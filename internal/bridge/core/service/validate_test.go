@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+)
+
+func TestValidateCommand(t *testing.T) {
+	repo := &twinRepo{
+		vehicles: map[string]*model.Vehicle{
+			"ONLINE":      {VIN: "ONLINE", Online: true, ModelRef: "model-3"},
+			"OFFLINE":     {VIN: "OFFLINE", ModelRef: "model-3"},
+			"MAINTENANCE": {VIN: "MAINTENANCE", Online: true, MaintenanceMode: true},
+			"RETIRED":     {VIN: "RETIRED", Online: true, ModelRef: "model-3", Decommissioned: true},
+			"UNMODELED":   {VIN: "UNMODELED", Online: true},
+			"DANGLING":    {VIN: "DANGLING", Online: true, ModelRef: "missing"},
+		},
+		models: map[string]*model.VehicleModel{
+			"model-3": {Name: "model-3", Properties: []string{"eco_mode"}, Methods: []string{"UploadLogs"}},
+		},
+	}
+	svc := New(repo, nil, nil)
+
+	tests := []struct {
+		name string
+		cmd  model.Command
+		want []string // field/reason of the expected issues
+	}{
+		{
+			name: "valid",
+			cmd:  model.Command{VehicleID: "ONLINE", Type: model.CommandTypeOTA, Parameters: map[string]string{"version": "2.0.0"}, Priority: 1},
+		},
+		{
+			name: "declared method",
+			cmd:  model.Command{VehicleID: "ONLINE", Type: "UploadLogs"},
+		},
+		{
+			name: "any method without model",
+			cmd:  model.Command{VehicleID: "UNMODELED", Type: "OpenTrunk"},
+		},
+		{
+			name: "missing type",
+			cmd:  model.Command{VehicleID: "ONLINE"},
+			want: []string{"command_type/Required"},
+		},
+		{
+			name: "priority out of range",
+			cmd:  model.Command{VehicleID: "ONLINE", Type: model.CommandTypeReboot, Priority: 3},
+			want: []string{"priority/OutOfRange"},
+		},
+		{
+			name: "OTA without version",
+			cmd:  model.Command{VehicleID: "ONLINE", Type: model.CommandTypeOTA},
+			want: []string{"parameters.version/Required"},
+		},
+		{
+			name: "missing vehicle ID",
+			cmd:  model.Command{Type: model.CommandTypeReboot},
+			want: []string{"vehicle_id/Required"},
+		},
+		{
+			name: "unknown vehicle",
+			cmd:  model.Command{VehicleID: "NOPE", Type: model.CommandTypeReboot},
+			want: []string{"vehicle_id/NotFound"},
+		},
+		{
+			name: "offline",
+			cmd:  model.Command{VehicleID: "OFFLINE", Type: model.CommandTypeReboot},
+			want: []string{"vehicle_id/Offline"},
+		},
+		{
+			name: "maintenance mode",
+			cmd:  model.Command{VehicleID: "MAINTENANCE", Type: model.CommandTypeReboot},
+			want: []string{"vehicle_id/MaintenanceMode"},
+		},
+		{
+			name: "decommissioned",
+			cmd:  model.Command{VehicleID: "RETIRED", Type: model.CommandTypeReboot},
+			want: []string{"vehicle_id/Decommissioned"},
+		},
+		{
+			name: "dangling model",
+			cmd:  model.Command{VehicleID: "DANGLING", Type: model.CommandTypeReboot},
+			want: []string{"vehicle_id/ModelNotFound"},
+		},
+		{
+			name: "unknown method",
+			cmd:  model.Command{VehicleID: "ONLINE", Type: "OpenTrunk"},
+			want: []string{"command_type/UnknownMethod"},
+		},
+		{
+			name: "undeclared config property",
+			cmd:  model.Command{VehicleID: "ONLINE", Type: model.CommandTypeApplyConfig, Parameters: map[string]string{"eco_mode": "on", "turbo": "on"}},
+			want: []string{"parameters.turbo/UnknownProperty"},
+		},
+		{
+			name: "all issues reported",
+			cmd:  model.Command{VehicleID: "OFFLINE", Type: model.CommandTypeOTA, Priority: -1},
+			want: []string{"priority/OutOfRange", "parameters.version/Required", "vehicle_id/Offline"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := svc.ValidateCommand(context.Background(), &tt.cmd)
+			if err != nil {
+				t.Fatalf("ValidateCommand() error = %v", err)
+			}
+
+			var got []string
+			for _, i := range issues {
+				if i.Message == "" {
+					t.Errorf("issue %s/%s has no message", i.Field, i.Reason)
+				}
+				got = append(got, i.Field+"/"+i.Reason)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("issues = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
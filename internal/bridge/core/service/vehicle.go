@@ -14,43 +14,52 @@ import (
 // Flow:
 // 1. Check if vehicle exists in K8s (via Repo).
 // 2. If not found, create a new Vehicle CRD.
-// 3. If found, assume it's a reconnection (logic can be extended to update firmware version here).
+// 3. If found (or created concurrently by another registration), treat it as a reconnection
+// and refresh its heartbeat.
 func (s *Service) RegisterVehicle(ctx context.Context, v *model.Vehicle) error {
 	// Default to Online=true upon registration
 	v.Online = true
 	v.LastHeartbeatTime = time.Now()
 
 	// Check existence
-	existing, err := s.vehicle.Get(ctx, v.VIN)
+	_, err := s.vehicle.Get(ctx, v.VIN)
 	if err != nil {
-		if errors.Is(err, util.ErrNotFound) {
-			// Create new vehicle
-			if err = s.vehicle.Create(ctx, v); err != nil {
-				return fmt.Errorf("failed to create vehicle: %w", err)
-			}
+		if !errors.Is(err, util.ErrNotFound) {
+			return err
 		}
-		return err
-	}
 
-	if existing != nil {
-		// Vehicle exists.
-		// Optional: We could update the Description or FirmwareVersion if changed.
-		// For high concurrency, we might skip heavy updates here unless necessary.
-		return nil
+		// Create new vehicle
+		err = s.vehicle.Create(ctx, v)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, util.ErrAlreadyExists) {
+			return fmt.Errorf("failed to create vehicle: %w", err)
+		}
+		// Another registration message (burst or retry) won the race; fall through.
 	}
 
-	return nil
+	// Vehicle exists.
+	// For high concurrency, we refresh the heartbeat and reported version via the buffered pipeline.
+	return s.batchUpdateStatus(ctx, &model.VehicleStatusUpdate{
+		VIN:               v.VIN,
+		Online:            true,
+		LastHeartbeatTime: v.LastHeartbeatTime,
+		FirmwareVersion:   v.ReportedVersion,
+	})
 }
 
 // UpdateOnlineStatus processes heartbeat or connection state changes (Online/Offline).
 // This is a high-frequency operation.
 func (s *Service) UpdateOnlineStatus(ctx context.Context, vehicleID string, online bool) error {
-	update := &model.VehicleStatusUpdate{
+	return s.batchUpdateStatus(ctx, &model.VehicleStatusUpdate{
 		VIN:               vehicleID,
 		Online:            online,
 		LastHeartbeatTime: time.Now(),
-	}
+	})
+}
 
+func (s *Service) batchUpdateStatus(ctx context.Context, update *model.VehicleStatusUpdate) error {
 	// This calls the Repository's optimized (buffered) update method.
 	if err := s.vehicle.BatchUpdateStatus(ctx, update); err != nil {
 		return fmt.Errorf("failed to update online status: %w", err)
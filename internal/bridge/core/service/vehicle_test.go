@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core"
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/util"
+)
+
+// racyVehicleRepo simulates a stale read: Get never sees vehicles created by concurrent callers.
+type racyVehicleRepo struct {
+	mu         sync.Mutex
+	created    map[string]*model.Vehicle
+	heartbeats int
+}
+
+func (r *racyVehicleRepo) Vehicle() core.VehicleRepository { return r }
+func (r *racyVehicleRepo) Command() core.CommandRepository { return nil }
+
+func (r *racyVehicleRepo) Get(ctx context.Context, vin string) (*model.Vehicle, error) {
+	return nil, util.ErrNotFound
+}
+
+func (r *racyVehicleRepo) Create(ctx context.Context, v *model.Vehicle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.created[v.VIN]; ok {
+		return util.ErrAlreadyExists
+	}
+	r.created[v.VIN] = v
+	return nil
+}
+
+func (r *racyVehicleRepo) UpdateStatus(ctx context.Context, v *model.Vehicle) error { return nil }
+
+func (r *racyVehicleRepo) List(ctx context.Context) ([]*model.Vehicle, error) { return nil, nil }
+
+func (r *racyVehicleRepo) BatchUpdateStatus(ctx context.Context, update *model.VehicleStatusUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.heartbeats++
+	return nil
+}
+
+func (r *racyVehicleRepo) GetModel(ctx context.Context, name string) (*model.VehicleModel, error) {
+	return nil, util.ErrNotFound
+}
+
+func TestRegisterVehicleConcurrent(t *testing.T) {
+	repo := &racyVehicleRepo{created: map[string]*model.Vehicle{}}
+	svc := New(repo, nil, nil)
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- svc.RegisterVehicle(context.Background(), &model.Vehicle{VIN: "LSVAU2180N2183294", IsRegister: true})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("registration should be idempotent, got: %v", err)
+		}
+	}
+	if len(repo.created) != 1 {
+		t.Errorf("expected exactly 1 vehicle created, got %d", len(repo.created))
+	}
+	if repo.heartbeats != n-1 {
+		t.Errorf("expected %d heartbeat updates for the losing registrations, got %d", n-1, repo.heartbeats)
+	}
+}
@@ -2,14 +2,32 @@ package core
 
 import (
 	"context"
+	"io"
 	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
 )
 
 // Storage defines the interface for object storage operations.
 type Storage interface {
 	// GeneratePresignedURL generates a temporary URL for downloading a file (firmware).
+	// The URL honors HTTP Range requests, so agents can fetch only part of the file.
 	GeneratePresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
 
+	// GeneratePresignedPutURL generates a temporary URL for uploading an object (e.g., a command result)
+	// with an HTTP PUT. An existing object is replaced.
+	GeneratePresignedPutURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+
+	// GetObjectRange reads length bytes of an object starting at offset (e.g., a firmware header).
+	// The caller must close the returned reader.
+	GetObjectRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+
+	// StatObject returns the metadata of a stored object, including its checksum.
+	StatObject(ctx context.Context, key string) (*model.ObjectInfo, error)
+
+	// PutObject stores data under key, replacing any existing object.
+	PutObject(ctx context.Context, key string, data []byte, contentType string) error
+
 	// CheckBucket for initial
 	CheckBucket(ctx context.Context) error
 }
@@ -0,0 +1,51 @@
+package bridge
+
+import (
+	"context"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/service"
+	"github.com/autopeer-io/autopeer/pkg/log"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+// snapshotExporter periodically writes a fleet status snapshot to object storage for analytics.
+// It runs next to the protocol servers and stops with them.
+type snapshotExporter struct {
+	svc      *service.Service
+	interval time.Duration
+	format   string
+	prefix   string
+}
+
+func newSnapshotExporter(opts *options.ExportOptions, svc *service.Service) *snapshotExporter {
+	return &snapshotExporter{
+		svc:      svc,
+		interval: opts.Interval,
+		format:   opts.Format,
+		prefix:   opts.Prefix,
+	}
+}
+
+// Start exports a snapshot every interval until the context is cancelled.
+// A failed export is logged and retried at the next tick.
+func (e *snapshotExporter) Start(ctx context.Context) error {
+	log.Info("Starting fleet snapshot exporter", "interval", e.interval, "format", e.format)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			key, err := e.svc.ExportFleetSnapshot(ctx, e.prefix, e.format, now)
+			if err != nil {
+				log.Error(err, "Failed to export fleet snapshot")
+				continue
+			}
+			log.Info("Exported fleet snapshot", "key", key)
+		}
+	}
+}
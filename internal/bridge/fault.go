@@ -0,0 +1,50 @@
+package bridge
+
+import (
+	"context"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core"
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/fault"
+)
+
+// faultyStorage injects faults into storage calls before delegating to the real backend.
+type faultyStorage struct {
+	core.Storage
+	injector *fault.Injector
+}
+
+func (s *faultyStorage) GeneratePresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if err := s.injector.Inject(ctx, fault.PointStoragePresign); err != nil {
+		return "", err
+	}
+	return s.Storage.GeneratePresignedURL(ctx, key, expiry)
+}
+
+func (s *faultyStorage) GeneratePresignedPutURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if err := s.injector.Inject(ctx, fault.PointStoragePresign); err != nil {
+		return "", err
+	}
+	return s.Storage.GeneratePresignedPutURL(ctx, key, expiry)
+}
+
+func (s *faultyStorage) CheckBucket(ctx context.Context) error {
+	if err := s.injector.Inject(ctx, fault.PointStorageCheckBucket); err != nil {
+		return err
+	}
+	return s.Storage.CheckBucket(ctx)
+}
+
+// faultyNotifier injects faults into MQTT command publishes.
+type faultyNotifier struct {
+	core.CommandNotifier
+	injector *fault.Injector
+}
+
+func (n *faultyNotifier) Notify(ctx context.Context, cmd *model.Command) error {
+	if err := n.injector.Inject(ctx, fault.PointMQTTPublish); err != nil {
+		return err
+	}
+	return n.CommandNotifier.Notify(ctx, cmd)
+}
@@ -0,0 +1,34 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/fault"
+)
+
+func TestFaultyStorage(t *testing.T) {
+	injector, err := fault.NewInjector([]string{fault.PointStorageCheckBucket + "=error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &flakyStorage{}
+	s := &faultyStorage{Storage: backend, injector: injector}
+
+	// An injected CheckBucket error makes the startup check fail like a real outage.
+	err = checkBucketWithRetry(context.Background(), s, 2, time.Millisecond)
+	if !errors.Is(err, fault.ErrInjected) {
+		t.Fatalf("expected injected error, got %v", err)
+	}
+	if backend.calls != 0 {
+		t.Errorf("backend should not be reached, got %d calls", backend.calls)
+	}
+
+	// Points without a rule pass through.
+	if _, err := s.GeneratePresignedURL(context.Background(), "fw.bin", time.Minute); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
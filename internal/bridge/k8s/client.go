@@ -1,11 +1,15 @@
 package k8s
 
 import (
+	"context"
+	"fmt"
+
 	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/scheme"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 
@@ -51,12 +55,7 @@ func InitializeK8sClient() (controllerclient.Client, error) {
 		return nil, err
 	}
 
-	// Create a new scheme and add all our API types and standard types
-	autopeerscheme := runtime.NewScheme()
-	utilruntime.Must(scheme.AddToScheme(autopeerscheme)) // Add standard schemes like v1.Pod, etc.
-	utilruntime.Must(iovv1alpha2.AddToScheme(autopeerscheme))
-
-	k8sclient, err := controllerclient.New(k8sconfig, controllerclient.Options{Scheme: autopeerscheme})
+	k8sclient, err := controllerclient.New(k8sconfig, controllerclient.Options{Scheme: newScheme()})
 	if err != nil {
 		log.Error(err, "failed to create kubernetes client")
 		return nil, err
@@ -64,3 +63,33 @@ func InitializeK8sClient() (controllerclient.Client, error) {
 
 	return k8sclient, nil
 }
+
+// NewCommandCache creates an informer cache of the VehicleCommands in namespace, indexed by
+// CommandVehicleIndex. It must be started; reads block until it has synced.
+func NewCommandCache(namespace string) (cache.Cache, error) {
+	k8sconfig, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := cache.New(k8sconfig, cache.Options{
+		Scheme:            newScheme(),
+		DefaultNamespaces: map[string]cache.Config{namespace: {}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create command cache: %w", err)
+	}
+
+	if err := c.IndexField(context.Background(), &iovv1alpha2.VehicleCommand{}, CommandVehicleIndex, IndexCommandVehicle); err != nil {
+		return nil, fmt.Errorf("failed to index commands by vehicle: %w", err)
+	}
+	return c, nil
+}
+
+// newScheme returns a scheme with the standard types and our API types.
+func newScheme() *runtime.Scheme {
+	autopeerscheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(autopeerscheme)) // Add standard schemes like v1.Pod, etc.
+	utilruntime.Must(iovv1alpha2.AddToScheme(autopeerscheme))
+	return autopeerscheme
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 
 	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
 	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
@@ -19,9 +20,27 @@ func ToModel(crd *iovv1alpha2.Vehicle) *model.Vehicle {
 		Online:            crd.Status.Online,
 		LastHeartbeatTime: extractTime(crd.Status.LastHeartbeatTime),
 		DesiredVersion:    crd.Spec.Profile.Firmware.Version,
+		DesiredChecksum:   crd.Spec.Profile.Firmware.Checksum,
+		Properties:        crd.Spec.Properties,
+		ModelRef:          crd.Spec.VehicleModelRef,
+		Phase:             string(crd.Status.UpgradeStatus.Phase),
+		MaintenanceMode:   crd.Spec.MaintenanceMode,
+		Decommissioned:    crd.Spec.Lifecycle == iovv1alpha2.VehicleLifecycleRetired,
 	}
 }
 
+// ToVehicleModel converts a VehicleModel CRD to a Core Model entity.
+func ToVehicleModel(crd *iovv1alpha2.VehicleModel) *model.VehicleModel {
+	m := &model.VehicleModel{Name: crd.Name}
+	for _, p := range crd.Spec.Properties {
+		m.Properties = append(m.Properties, p.Name)
+	}
+	for _, cm := range crd.Spec.Methods {
+		m.Methods = append(m.Methods, cm.Name)
+	}
+	return m
+}
+
 // ToCRD converts a Core Model entity to a K8s CRD object (for creation).
 func ToCRD(ns string, v *model.Vehicle) *iovv1alpha2.Vehicle {
 	return &iovv1alpha2.Vehicle{
@@ -53,6 +72,39 @@ func ToCRD(ns string, v *model.Vehicle) *iovv1alpha2.Vehicle {
 	}
 }
 
+// commandToModel converts a VehicleCommand CRD to the domain model.
+func commandToModel(crd *iovv1alpha2.VehicleCommand) *model.Command {
+	return &model.Command{
+		ID:          crd.Name,
+		VehicleID:   crd.Spec.VehicleName,
+		Type:        model.CommandType(crd.Spec.Method),
+		Parameters:  crd.Spec.Parameters,
+		RequestID:   crd.Spec.RequestID,
+		Priority:    ptr.Deref(crd.Spec.Priority, 1),
+		Status:      commandStatusFromPhase(crd.Status.Phase),
+		CreatedAt:   crd.CreationTimestamp.Time,
+		Message:     crd.Status.Message,
+		CompletedAt: extractTime(crd.Status.CompletionTime),
+	}
+}
+
+// commandStatusFromPhase maps a VehicleCommand phase to the model status.
+// The agents and the hub API keep the v1alpha1 vocabulary, in which v1alpha2 Acknowledged is Received.
+func commandStatusFromPhase(phase iovv1alpha2.CommandPhase) model.CommandStatus {
+	if phase == iovv1alpha2.CommandPhaseAcknowledged {
+		return model.CommandStatusReceived
+	}
+	return model.CommandStatus(phase)
+}
+
+// commandPhaseFromStatus maps a model status to the VehicleCommand phase, see commandStatusFromPhase.
+func commandPhaseFromStatus(status model.CommandStatus) iovv1alpha2.CommandPhase {
+	if status == model.CommandStatusReceived {
+		return iovv1alpha2.CommandPhaseAcknowledged
+	}
+	return iovv1alpha2.CommandPhase(status)
+}
+
 func vinToMetaName(vin string) string {
 	return strings.ToLower(vin)
 }
@@ -3,16 +3,31 @@ package k8s
 import (
 	"context"
 	"encoding/json"
+	"maps"
 	"time"
 
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/metrics"
 	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
 	"github.com/autopeer-io/autopeer/pkg/log"
 )
 
+const (
+	// maxFlushRetries is how many more flushes a failed update is retried in before it is dead-lettered.
+	maxFlushRetries = 3
+
+	// maxBufferSize forces a flush before the ticker fires.
+	maxBufferSize = 1000
+
+	// A flush of at least busyFlushSize updates widens the adaptive interval,
+	// one of at most quietFlushSize updates narrows it.
+	busyFlushSize  = maxBufferSize / 2
+	quietFlushSize = maxBufferSize / 20
+)
+
 // StatusPipeline implements a write-merging buffer for K8s status updates.
 // It protects the K8s API server from being overwhelmed by high-frequency heartbeat events.
 type StatusPipeline struct {
@@ -26,19 +41,54 @@ type StatusPipeline struct {
 	// Map Key: Vehicle ID
 	buffer map[string]*model.VehicleStatusUpdate
 
+	// retries counts the failed flush attempts of the update currently buffered for a vehicle.
+	// Map Key: Vehicle ID
+	retries map[string]int
+
 	// flushInterval determines how often we flush the aggregated state to K8s.
-	flushInterval time.Duration
+	// It moves between minFlushInterval and maxFlushInterval depending on load.
+	flushInterval    time.Duration
+	minFlushInterval time.Duration
+	maxFlushInterval time.Duration
+}
+
+// PipelineOption configures optional behavior of the StatusPipeline.
+type PipelineOption func(*StatusPipeline)
+
+// WithFlushInterval sets the bounds of the flush interval.
+// If max is greater than min, the interval adapts to load: it doubles (up to max) after a
+// busy flush and halves (down to min) after a quiet one. Longer intervals merge more
+// heartbeats per patch, which smooths apiserver load at the cost of status freshness.
+func WithFlushInterval(minInterval, maxInterval time.Duration) PipelineOption {
+	return func(p *StatusPipeline) {
+		if minInterval > 0 {
+			p.minFlushInterval = minInterval
+		}
+		p.maxFlushInterval = maxInterval
+		if p.maxFlushInterval < p.minFlushInterval {
+			p.maxFlushInterval = p.minFlushInterval
+		}
+	}
 }
 
 // NewPipeline creates a new write-merging pipeline.
-func NewPipeline(ns string, c client.Client) *StatusPipeline {
-	return &StatusPipeline{
-		namespace:     ns,
-		client:        c,
-		inputCh:       make(chan *model.VehicleStatusUpdate, 5000), // Large buffer
-		buffer:        make(map[string]*model.VehicleStatusUpdate),
-		flushInterval: 1 * time.Second, // Aggregate 1s worth of data
+func NewPipeline(ns string, c client.Client, opts ...PipelineOption) *StatusPipeline {
+	p := &StatusPipeline{
+		namespace:        ns,
+		client:           c,
+		inputCh:          make(chan *model.VehicleStatusUpdate, 5000), // Large buffer
+		buffer:           make(map[string]*model.VehicleStatusUpdate),
+		retries:          make(map[string]int),
+		minFlushInterval: 1 * time.Second, // Aggregate 1s worth of data
+		maxFlushInterval: 1 * time.Second,
 	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.flushInterval = p.minFlushInterval
+
+	return p
 }
 
 // Start begins the background worker that processes the pipeline.
@@ -47,26 +97,37 @@ func (p *StatusPipeline) Start(ctx context.Context) {
 	ticker := time.NewTicker(p.flushInterval)
 	defer ticker.Stop()
 
-	log.Info("K8s Status Pipeline started", "Interval", p.flushInterval)
+	log.Info("K8s Status Pipeline started", "Interval", p.flushInterval, "MaxInterval", p.maxFlushInterval)
+
+	// flushed counts the updates written since the last tick, including forced flushes.
+	flushed := 0
 
 	for {
 		select {
 		case update := <-p.inputCh:
-			// MERGE STRATEGY: Last Write Wins (in memory)
-			// We only keep the latest update for each vehicle in the buffer map.
-			p.buffer[update.VIN] = update
+			p.merge(update)
 
 			// Optimization: If buffer gets too large, force flush immediately
-			if len(p.buffer) >= 1000 {
+			if len(p.buffer) >= maxBufferSize {
+				flushed += len(p.buffer)
 				p.flush(ctx)
 			}
 
 		case <-ticker.C:
 			// Time to sync to K8s
 			if len(p.buffer) > 0 {
+				flushed += len(p.buffer)
 				p.flush(ctx)
 			}
 
+			next := p.nextInterval(flushed)
+			if next != p.flushInterval {
+				log.Debug("Adjusting pipeline flush interval", "from", p.flushInterval, "to", next, "flushed", flushed)
+				p.flushInterval = next
+				ticker.Reset(next)
+			}
+			flushed = 0
+
 		case <-ctx.Done():
 			// Flush remaining data before exit
 			p.flush(context.Background())
@@ -75,15 +136,72 @@ func (p *StatusPipeline) Start(ctx context.Context) {
 	}
 }
 
+// nextInterval returns the flush interval to use after `flushed` updates were written during the current one.
+// The count is scaled to one minFlushInterval, so a wide interval is not kept wide just because it
+// collects more updates than a narrow one.
+func (p *StatusPipeline) nextInterval(flushed int) time.Duration {
+	rate := float64(flushed) * float64(p.minFlushInterval) / float64(p.flushInterval)
+
+	switch {
+	case rate >= busyFlushSize:
+		return min(p.flushInterval*2, p.maxFlushInterval)
+	case rate <= quietFlushSize:
+		return max(p.flushInterval/2, p.minFlushInterval)
+	default:
+		return p.flushInterval
+	}
+}
+
+// merge buffers an update.
+// MERGE STRATEGY: Last Write Wins (in memory), ordered by heartbeat time.
+// We only keep the latest update for each vehicle in the buffer map, so a newer
+// update always replaces a re-enqueued (stale) one, but never the other way round.
+func (p *StatusPipeline) merge(update *model.VehicleStatusUpdate) {
+	existing, ok := p.buffer[update.VIN]
+	if ok && existing.LastHeartbeatTime.After(update.LastHeartbeatTime) {
+		if existing.FirmwareVersion == "" {
+			existing.FirmwareVersion = update.FirmwareVersion
+		}
+		existing.Properties = mergeProperties(update.Properties, existing.Properties)
+		return
+	}
+	// Heartbeats don't carry a version; don't lose one that is still waiting to be flushed.
+	if ok && update.FirmwareVersion == "" {
+		update.FirmwareVersion = existing.FirmwareVersion
+	}
+	// Likewise, keep the pending values of properties this update doesn't report.
+	if ok {
+		update.Properties = mergeProperties(existing.Properties, update.Properties)
+	}
+
+	p.buffer[update.VIN] = update
+	// A fresh update gets a fresh retry budget.
+	delete(p.retries, update.VIN)
+}
+
+// mergeProperties returns the union of older and newer, newer values winning.
+func mergeProperties(older, newer map[string]string) map[string]string {
+	if len(older) == 0 {
+		return newer
+	}
+	merged := make(map[string]string, len(older)+len(newer))
+	maps.Copy(merged, older)
+	maps.Copy(merged, newer)
+	return merged
+}
+
 // Push adds an update to the pipeline. It is non-blocking.
 func (p *StatusPipeline) Push(update *model.VehicleStatusUpdate) {
+	metrics.PipelineUpdatesReceived.Inc()
+
 	select {
 	case p.inputCh <- update:
 		// Success
 	default:
 		// Buffer full: Drop the heartbeat to protect the system (Load Shedding).
 		// For status updates, dropping a frame is better than crashing OOM.
-		log.Warn("Status pipeline full! Dropping update for vehicle: %s", update.VIN)
+		metrics.PipelineUpdatesDropped.Inc()
+		log.Warn("Status pipeline full, dropping update", "vin", update.VIN)
 	}
 }
 
@@ -91,40 +209,42 @@ func (p *StatusPipeline) Push(update *model.VehicleStatusUpdate) {
 // Note: K8s currently doesn't support bulk updates for different resources.
 // We still have to make N requests, BUT we saved M (M >> N) redundant requests via merging.
 func (p *StatusPipeline) flush(ctx context.Context) {
+	start := time.Now()
+	defer func() { metrics.PipelineFlushDuration.Observe(time.Since(start).Seconds()) }()
+
+	metrics.PipelineBufferSize.Set(float64(len(p.buffer)))
+
 	count := 0
+	failed := make(map[string]*model.VehicleStatusUpdate)
 	for vin, update := range p.buffer {
 		if err := p.patchStatus(ctx, vin, update); err != nil {
-			log.Error(err, "Failed to patch vehicle status", "vin", vin)
+			metrics.PipelineFlushErrors.Inc()
+
+			// Keep the update for the next flush, unless it has used up its retries (dead letter).
+			if p.retries[vin] < maxFlushRetries {
+				log.Warn("Failed to patch vehicle status, will retry", "vin", vin, "attempt", p.retries[vin]+1, "error", err.Error())
+				p.retries[vin]++
+				failed[vin] = update
+			} else {
+				log.Error(err, "Failed to patch vehicle status, dropping update", "vin", vin, "attempts", p.retries[vin]+1)
+				metrics.PipelineUpdatesDeadLettered.Inc()
+				delete(p.retries, vin)
+			}
+			continue
 		}
+		delete(p.retries, vin)
 		count++
 	}
 
-	// Reset buffer after flush
-	p.buffer = make(map[string]*model.VehicleStatusUpdate)
+	// Reset buffer after flush, re-enqueueing the updates to retry
+	p.buffer = failed
 
-	log.Debug("Pipeline flushed %d updates to K8s", count)
+	log.Debug("Pipeline flushed updates to K8s", "count", count)
 }
 
 // patchStatus performs a lightweight MergePatch on the Status subresource.
 func (p *StatusPipeline) patchStatus(ctx context.Context, vin string, update *model.VehicleStatusUpdate) error {
-	// Construct a raw JSON patch for efficiency.
-	// We only want to touch specific fields in .status
-	// structure: {"status": {"online": true, "lastSeenTime": "..."}}
-	patchMap := map[string]any{
-		"apiVersion": "iov.autopeer.io/v1alpha2",
-		"kind":       "Vehicle",
-		"metadata": map[string]any{
-			"name":      vinToMetaName(vin),
-			"namespace": p.namespace,
-		},
-		"status": map[string]any{
-			"online":            update.Online,
-			"lastHeartbeatTime": update.LastHeartbeatTime, // 确保这里序列化符合 RFC3339
-			// "reportedFirmwareVersion": update.FirmwareVersion,
-		},
-	}
-
-	patchData, err := json.Marshal(patchMap)
+	patchData, err := statusPatch(update)
 	if err != nil {
 		return err
 	}
@@ -135,7 +255,34 @@ func (p *StatusPipeline) patchStatus(ctx context.Context, vin string, update *mo
 	obj.SetName(vinToMetaName(vin))
 	obj.SetNamespace(p.namespace)
 
-	patch := client.RawPatch(types.ApplyPatchType, patchData)
+	// A merge patch leaves fields it doesn't mention untouched, so heartbeats without
+	// a version never clear the reported firmware version.
+	patch := client.RawPatch(types.MergePatchType, patchData)
 	owner := client.FieldOwner("autopeer-bridge")
-	return p.client.Status().Patch(ctx, obj, patch, owner, client.ForceOwnership)
+	return p.client.Status().Patch(ctx, obj, patch, owner)
+}
+
+// statusPatch builds the raw JSON merge patch for an update.
+// We only want to touch specific fields in .status
+// structure: {"status": {"online": true, "lastHeartbeatTime": "...", "profile": {"firmware": {"version": "..."}}, "properties": {...}}}
+func statusPatch(update *model.VehicleStatusUpdate) ([]byte, error) {
+	status := map[string]any{
+		"online":            update.Online,
+		"lastHeartbeatTime": update.LastHeartbeatTime, // 确保这里序列化符合 RFC3339
+	}
+
+	// The reported version feeds the Spec.Profile vs Status.Profile diff behind the Synced condition.
+	// Only set it when reported, so an empty string never overwrites a known version.
+	if update.FirmwareVersion != "" {
+		status["profile"] = map[string]any{
+			"firmware": map[string]any{"version": update.FirmwareVersion},
+		}
+	}
+
+	// A merge patch merges maps key by key, so only the reported properties are overwritten.
+	if len(update.Properties) > 0 {
+		status["properties"] = update.Properties
+	}
+
+	return json.Marshal(map[string]any{"status": status})
 }
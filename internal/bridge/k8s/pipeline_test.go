@@ -0,0 +1,225 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/metrics"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// newFailingClient returns a client whose status patches fail for the first `failures` calls.
+func newFailingClient(t *testing.T, failures int, calls *int) client.Client {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		SubResourcePatch: func(ctx context.Context, c client.Client, subResource string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+			*calls++
+			if *calls <= failures {
+				return errors.New("apiserver unavailable")
+			}
+			return nil
+		},
+	}).Build()
+}
+
+func TestPushDropsWhenFull(t *testing.T) {
+	p := &StatusPipeline{inputCh: make(chan *model.VehicleStatusUpdate, 2)}
+
+	received := testutil.ToFloat64(metrics.PipelineUpdatesReceived)
+	dropped := testutil.ToFloat64(metrics.PipelineUpdatesDropped)
+
+	for i := 0; i < 5; i++ {
+		p.Push(&model.VehicleStatusUpdate{VIN: "LSVAU2180N2183294"})
+	}
+
+	if got := testutil.ToFloat64(metrics.PipelineUpdatesReceived) - received; got != 5 {
+		t.Errorf("received counter increased by %v, want 5", got)
+	}
+	if got := testutil.ToFloat64(metrics.PipelineUpdatesDropped) - dropped; got != 3 {
+		t.Errorf("dropped counter increased by %v, want 3", got)
+	}
+}
+
+func TestFlushRetriesFailedPatch(t *testing.T) {
+	var calls int
+	p := NewPipeline("default", newFailingClient(t, 1, &calls))
+	p.merge(&model.VehicleStatusUpdate{VIN: "LSVAU2180N2183294", Online: true, LastHeartbeatTime: time.Now()})
+
+	flushErrors := testutil.ToFloat64(metrics.PipelineFlushErrors)
+
+	// First flush fails: the update must stay buffered.
+	p.flush(context.Background())
+	if _, ok := p.buffer["LSVAU2180N2183294"]; !ok {
+		t.Fatalf("failed update was not re-enqueued")
+	}
+	if got := testutil.ToFloat64(metrics.PipelineFlushErrors) - flushErrors; got != 1 {
+		t.Errorf("flush errors counter increased by %v, want 1", got)
+	}
+
+	// Second flush succeeds: the buffer is drained.
+	p.flush(context.Background())
+	if len(p.buffer) != 0 || len(p.retries) != 0 {
+		t.Errorf("buffer not drained after successful retry: buffer=%d retries=%d", len(p.buffer), len(p.retries))
+	}
+	if calls != 2 {
+		t.Errorf("patch called %d times, want 2", calls)
+	}
+}
+
+func TestFlushDeadLettersAfterMaxRetries(t *testing.T) {
+	var calls int
+	p := NewPipeline("default", newFailingClient(t, 100, &calls))
+	p.merge(&model.VehicleStatusUpdate{VIN: "LSVAU2180N2183294", LastHeartbeatTime: time.Now()})
+
+	for i := 0; i <= maxFlushRetries; i++ {
+		p.flush(context.Background())
+	}
+
+	if len(p.buffer) != 0 {
+		t.Errorf("update should be dropped after %d retries", maxFlushRetries)
+	}
+	if calls != maxFlushRetries+1 {
+		t.Errorf("patch called %d times, want %d", calls, maxFlushRetries+1)
+	}
+}
+
+func TestMergeKeepsNewestUpdate(t *testing.T) {
+	p := NewPipeline("default", nil)
+	now := time.Now()
+
+	p.merge(&model.VehicleStatusUpdate{VIN: "LSVAU2180N2183294", Online: true, LastHeartbeatTime: now})
+	p.merge(&model.VehicleStatusUpdate{VIN: "LSVAU2180N2183294", Online: false, LastHeartbeatTime: now.Add(-time.Minute)})
+
+	if !p.buffer["LSVAU2180N2183294"].Online {
+		t.Errorf("stale update overwrote a newer one")
+	}
+}
+
+func TestStatusPatchFirmwareVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		wantVersion bool
+	}{
+		{name: "version reported", version: "v1.2.0", wantVersion: true},
+		{name: "heartbeat without version", version: "", wantVersion: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := statusPatch(&model.VehicleStatusUpdate{VIN: "LSVAU2180N2183294", Online: true, LastHeartbeatTime: time.Now(), FirmwareVersion: tt.version})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var patch struct {
+				Status iovv1alpha2.VehicleStatus `json:"status"`
+			}
+			if err := json.Unmarshal(data, &patch); err != nil {
+				t.Fatal(err)
+			}
+
+			if !patch.Status.Online || patch.Status.LastHeartbeatTime == nil {
+				t.Errorf("patch is missing heartbeat fields: %s", data)
+			}
+			if got := patch.Status.Profile.Firmware.Version; got != tt.version {
+				t.Errorf("reported version = %q, want %q", got, tt.version)
+			}
+			if has := strings.Contains(string(data), `"profile"`); has != tt.wantVersion {
+				t.Errorf("patch contains profile = %v, want %v: %s", has, tt.wantVersion, data)
+			}
+		})
+	}
+}
+
+func TestMergeKeepsReportedVersion(t *testing.T) {
+	p := NewPipeline("default", nil)
+	now := time.Now()
+
+	p.merge(&model.VehicleStatusUpdate{VIN: "LSVAU2180N2183294", LastHeartbeatTime: now, FirmwareVersion: "v1.2.0"})
+	p.merge(&model.VehicleStatusUpdate{VIN: "LSVAU2180N2183294", LastHeartbeatTime: now.Add(time.Second)})
+
+	if got := p.buffer["LSVAU2180N2183294"].FirmwareVersion; got != "v1.2.0" {
+		t.Errorf("heartbeat dropped the pending reported version, got %q", got)
+	}
+}
+
+func TestFlushMergesReportedProperties(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	vehicle := &iovv1alpha2.Vehicle{
+		ObjectMeta: metav1.ObjectMeta{Name: "lsvau2180n2183294", Namespace: "default"},
+		Status:     iovv1alpha2.VehicleStatus{Properties: map[string]string{"cabin_temp": "21.5", "battery_level": "75"}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vehicle).WithStatusSubresource(vehicle).Build()
+
+	p := NewPipeline("default", c)
+	now := time.Now()
+	p.merge(&model.VehicleStatusUpdate{VIN: "LSVAU2180N2183294", Online: true, LastHeartbeatTime: now, Properties: map[string]string{"battery_level": "80"}})
+	p.merge(&model.VehicleStatusUpdate{VIN: "LSVAU2180N2183294", Online: true, LastHeartbeatTime: now.Add(time.Second), Properties: map[string]string{"odometer": "1200"}})
+	p.flush(context.Background())
+
+	got := &iovv1alpha2.Vehicle{}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(vehicle), got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"cabin_temp": "21.5", "battery_level": "80", "odometer": "1200"}
+	if !maps.Equal(got.Status.Properties, want) {
+		t.Errorf("reported properties = %v, want %v", got.Status.Properties, want)
+	}
+}
+
+func TestAdaptiveFlushInterval(t *testing.T) {
+	p := NewPipeline("default", nil, WithFlushInterval(time.Second, 8*time.Second))
+
+	// Sustained burst: the interval doubles per busy flush, capped at the max.
+	// Updates per flush grow with the interval, i.e. the ingress rate stays constant.
+	for _, want := range []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second} {
+		p.flushInterval = p.nextInterval(int(800 * p.flushInterval / time.Second))
+		if p.flushInterval != want {
+			t.Fatalf("under load: interval = %v, want %v", p.flushInterval, want)
+		}
+	}
+
+	// Moderate traffic keeps the current interval.
+	if got := p.nextInterval(int(200 * p.flushInterval / time.Second)); got != 8*time.Second {
+		t.Errorf("moderate load: interval = %v, want 8s", got)
+	}
+
+	// Quiet again: the interval halves back down to the min.
+	for _, want := range []time.Duration{4 * time.Second, 2 * time.Second, time.Second, time.Second} {
+		p.flushInterval = p.nextInterval(int(10 * p.flushInterval / time.Second))
+		if p.flushInterval != want {
+			t.Fatalf("quiet: interval = %v, want %v", p.flushInterval, want)
+		}
+	}
+}
+
+func TestFixedFlushIntervalByDefault(t *testing.T) {
+	p := NewPipeline("default", nil)
+
+	if got := p.nextInterval(5000); got != time.Second {
+		t.Errorf("interval = %v, want the fixed 1s default", got)
+	}
+}
@@ -12,13 +12,17 @@ var _ core.Repository = (*repository)(nil)
 type repository struct {
 	namespace string
 	client    client.Client
+	commands  client.Reader
 	pipeline  *StatusPipeline
 }
 
-func NewRepository(ns string, c client.Client, p *StatusPipeline) *repository {
+// NewRepository creates the repository of namespace ns. Command history queries are served
+// by commands, which must be indexed by CommandVehicleIndex.
+func NewRepository(ns string, c client.Client, commands client.Reader, p *StatusPipeline) *repository {
 	return &repository{
 		namespace: ns,
 		client:    c,
+		commands:  commands,
 		pipeline:  p,
 	}
 }
@@ -28,5 +32,5 @@ func (r *repository) Vehicle() core.VehicleRepository {
 }
 
 func (r *repository) Command() core.CommandRepository {
-	return newCommandRepository(r.namespace, r.client)
+	return newCommandRepository(r.namespace, r.client, r.commands)
 }
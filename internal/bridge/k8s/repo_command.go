@@ -3,49 +3,136 @@ package k8s
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"slices"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/util"
 	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
 )
 
+// CommandVehicleIndex is the field index of VehicleCommands by Spec.VehicleName.
+// The reader given to the repository must have it (see NewCommandCache).
+const CommandVehicleIndex = "spec.vehicleName"
+
+// IndexCommandVehicle extracts the CommandVehicleIndex value of a VehicleCommand.
+func IndexCommandVehicle(obj client.Object) []string {
+	cmd, ok := obj.(*iovv1alpha2.VehicleCommand)
+	if !ok || cmd.Spec.VehicleName == "" {
+		return nil
+	}
+	return []string{cmd.Spec.VehicleName}
+}
+
 type commandRepository struct {
 	namespace string
 	client    client.Client
+
+	// reader serves command history queries, usually from an indexed cache.
+	reader client.Reader
 }
 
-func newCommandRepository(ns string, c client.Client) *commandRepository {
-	return &commandRepository{namespace: ns, client: c}
+func newCommandRepository(ns string, c client.Client, reader client.Reader) *commandRepository {
+	return &commandRepository{namespace: ns, client: c, reader: reader}
+}
+
+// Create implements core.CommandRepository.
+// The VehicleCommand controller picks the new command up and sends it like any other.
+func (r *commandRepository) Create(ctx context.Context, cmd *model.Command) error {
+	crd := &iovv1alpha2.VehicleCommand{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cmd.ID,
+			Namespace: r.namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "autopeer-bridge",
+			},
+		},
+		Spec: iovv1alpha2.VehicleCommandSpec{
+			VehicleName: vinToMetaName(cmd.VehicleID),
+			Method:      string(cmd.Type),
+			RequestID:   cmd.RequestID,
+			Priority:    ptr.To(cmd.Priority),
+			Parameters:  cmd.Parameters,
+		},
+	}
+
+	if err := r.client.Create(ctx, crd); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return util.ErrAlreadyExists
+		}
+		return err
+	}
+	return nil
 }
 
 // UpdateStatus implements core.CommandRepository.
 // It maps the model status to the K8s CRD status.
-func (r *commandRepository) UpdateStatus(ctx context.Context, cmdID string, status model.CommandStatus, message string) error {
-	// In a real high-concurrency scenario, this should also use the Pipeline (Buffer).
-	// For simplicity in this MVP, we use direct Patch, but leveraging Server-Side Apply or MergePatch.
+// The patch carries the resourceVersion the transition was checked against, so a concurrent
+// report cannot slip in between; on a conflict the check is repeated on the fresh object.
+func (r *commandRepository) UpdateStatus(ctx context.Context, cmdID string, status model.CommandStatus, message string, result map[string]string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj := &iovv1alpha2.VehicleCommand{}
+		if err := r.client.Get(ctx, types.NamespacedName{Namespace: r.namespace, Name: cmdID}, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				return util.ErrNotFound
+			}
+			return err
+		}
+
+		if current := commandStatusFromPhase(obj.Status.Phase); !current.CanTransitionTo(status) {
+			return fmt.Errorf("command %s is %s, cannot move to %s: %w", cmdID, current, status, util.ErrStale)
+		}
 
-	patchMap := map[string]any{
-		"status": map[string]any{
-			"phase":   status,
+		statusPatch := map[string]any{
+			"phase":   commandPhaseFromStatus(status),
 			"message": message,
 
 			// "lastUpdateTime": "",
 			// TODO: AcknowledgeTime, CompletionTime
-		},
-	}
+		}
+		if len(result) > 0 {
+			// A merge patch merges map entries, so other result entries are kept.
+			statusPatch["result"] = result
+		}
 
-	patchData, err := json.Marshal(patchMap)
-	if err != nil {
-		return err
-	}
+		patchMap := map[string]any{
+			"metadata": map[string]any{
+				"resourceVersion": obj.ResourceVersion,
+			},
+			"status": statusPatch,
+		}
 
-	obj := &iovv1alpha2.VehicleCommand{}
-	obj.SetName(cmdID)
-	obj.SetNamespace(r.namespace)
+		patchData, err := json.Marshal(patchMap)
+		if err != nil {
+			return err
+		}
 
-	// Use MergePatchType to avoid conflicts
-	patch := client.RawPatch(types.MergePatchType, patchData)
-	return r.client.Status().Patch(ctx, obj, patch)
+		// The resourceVersion turns a concurrent change into a conflict instead of a lost check.
+		patch := client.RawPatch(types.MergePatchType, patchData)
+		return r.client.Status().Patch(ctx, obj, patch)
+	})
+}
+
+// ListByVehicle implements core.CommandRepository.
+func (r *commandRepository) ListByVehicle(ctx context.Context, vin string, filter model.CommandFilter) ([]*model.Command, error) {
+	list := &iovv1alpha2.VehicleCommandList{}
+	if err := r.reader.List(ctx, list, client.InNamespace(r.namespace), client.MatchingFields{CommandVehicleIndex: vinToMetaName(vin)}); err != nil {
+		return nil, fmt.Errorf("failed to list commands of vehicle %s: %w", vin, err)
+	}
+
+	var cmds []*model.Command
+	for i := range list.Items {
+		if cmd := commandToModel(&list.Items[i]); filter.Matches(cmd) {
+			cmds = append(cmds, cmd)
+		}
+	}
+	slices.SortFunc(cmds, model.CompareNewestFirst)
+	return cmds, nil
 }
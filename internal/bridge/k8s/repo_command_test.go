@@ -0,0 +1,186 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/pkg/util"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func TestCommandUpdateStatusOutOfOrder(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &iovv1alpha2.VehicleCommand{
+		ObjectMeta: metav1.ObjectMeta{Name: "ota-vh-1", Namespace: "default"},
+		Status:     iovv1alpha2.VehicleCommandStatus{Phase: iovv1alpha2.CommandPhaseSent},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cmd).WithStatusSubresource(cmd).Build()
+	repo := newCommandRepository("default", cli, cli)
+
+	// Running overtakes the delayed Received, then Succeeded arrives before a redelivered Running.
+	reports := []struct {
+		status    model.CommandStatus
+		wantStale bool
+	}{
+		{status: model.CommandStatusRunning},
+		{status: model.CommandStatusReceived, wantStale: true},
+		{status: model.CommandStatusRunning},
+		{status: model.CommandStatusSucceeded},
+		{status: model.CommandStatusRunning, wantStale: true},
+		{status: model.CommandStatusFailed, wantStale: true},
+		{status: model.CommandStatusSucceeded, wantStale: true},
+	}
+
+	for i, r := range reports {
+		err := repo.UpdateStatus(context.Background(), "ota-vh-1", r.status, string(r.status), nil)
+		if r.wantStale != errors.Is(err, util.ErrStale) {
+			t.Fatalf("report %d (%s): error = %v, wantStale %v", i, r.status, err, r.wantStale)
+		}
+		if !r.wantStale && err != nil {
+			t.Fatalf("report %d (%s): error = %v", i, r.status, err)
+		}
+	}
+
+	var got iovv1alpha2.VehicleCommand
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(cmd), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != iovv1alpha2.CommandPhaseSucceeded || got.Status.Message != "Succeeded" {
+		t.Errorf("status = %s (%q), want the terminal Succeeded to stick", got.Status.Phase, got.Status.Message)
+	}
+
+	if err := repo.UpdateStatus(context.Background(), "missing", model.CommandStatusRunning, "", nil); !errors.Is(err, util.ErrNotFound) {
+		t.Errorf("unknown command: error = %v, want util.ErrNotFound", err)
+	}
+}
+
+func TestCommandUpdateStatusReceived(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &iovv1alpha2.VehicleCommand{
+		ObjectMeta: metav1.ObjectMeta{Name: "ota-vh-1", Namespace: "default"},
+		Status:     iovv1alpha2.VehicleCommandStatus{Phase: iovv1alpha2.CommandPhaseSent},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cmd).WithStatusSubresource(cmd).Build()
+	repo := newCommandRepository("default", cli, cli)
+	ctx := context.Background()
+
+	// The agent acks with "Received": the v1alpha2 command is Acknowledged.
+	if err := repo.UpdateStatus(ctx, "ota-vh-1", model.CommandStatusReceived, "Security check passed", nil); err != nil {
+		t.Fatalf("UpdateStatus(Received) error = %v", err)
+	}
+	var got iovv1alpha2.VehicleCommand
+	if err := cli.Get(ctx, client.ObjectKeyFromObject(cmd), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != iovv1alpha2.CommandPhaseAcknowledged {
+		t.Errorf("phase = %s, want %s", got.Status.Phase, iovv1alpha2.CommandPhaseAcknowledged)
+	}
+	if status := commandToModel(&got).Status; status != model.CommandStatusReceived {
+		t.Errorf("model status = %s, want %s", status, model.CommandStatusReceived)
+	}
+
+	// Acknowledged ranks as Received: a redelivered Sent is stale, Running moves on.
+	if err := repo.UpdateStatus(ctx, "ota-vh-1", model.CommandStatusSent, "", nil); !errors.Is(err, util.ErrStale) {
+		t.Errorf("UpdateStatus(Sent) error = %v, want util.ErrStale", err)
+	}
+	if err := repo.UpdateStatus(ctx, "ota-vh-1", model.CommandStatusRunning, "", nil); err != nil {
+		t.Errorf("UpdateStatus(Running) error = %v", err)
+	}
+}
+
+func TestCommandUpdateStatusMergesResult(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &iovv1alpha2.VehicleCommand{
+		ObjectMeta: metav1.ObjectMeta{Name: "logs-vh-1", Namespace: "default"},
+		Status: iovv1alpha2.VehicleCommandStatus{
+			Phase:  iovv1alpha2.CommandPhaseRunning,
+			Result: map[string]string{"lines": "1200"},
+		},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cmd).WithStatusSubresource(cmd).Build()
+	repo := newCommandRepository("default", cli, cli)
+
+	result := map[string]string{model.CommandResultObjectKey: "results/VH1/logs-vh-1"}
+	if err := repo.UpdateStatus(context.Background(), "logs-vh-1", model.CommandStatusSucceeded, "", result); err != nil {
+		t.Fatal(err)
+	}
+
+	var got iovv1alpha2.VehicleCommand
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(cmd), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Result["objectKey"] != "results/VH1/logs-vh-1" || got.Status.Result["lines"] != "1200" {
+		t.Errorf("result = %v, want the object key merged into the existing entries", got.Status.Result)
+	}
+}
+
+func TestCommandListByVehicle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newCmd := func(name, vehicle string, phase iovv1alpha2.CommandPhase, age time.Duration) *iovv1alpha2.VehicleCommand {
+		return &iovv1alpha2.VehicleCommand{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", CreationTimestamp: metav1.NewTime(base.Add(-age))},
+			Spec:       iovv1alpha2.VehicleCommandSpec{VehicleName: vehicle, Method: "OTA"},
+			Status:     iovv1alpha2.VehicleCommandStatus{Phase: phase, Message: string(phase)},
+		}
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).
+		WithIndex(&iovv1alpha2.VehicleCommand{}, CommandVehicleIndex, IndexCommandVehicle).
+		WithObjects(
+			newCmd("ota-1", "vh-1", iovv1alpha2.CommandPhaseSucceeded, 3*time.Hour),
+			newCmd("ota-2", "vh-1", iovv1alpha2.CommandPhaseFailed, 2*time.Hour),
+			newCmd("ota-3", "vh-1", iovv1alpha2.CommandPhaseSucceeded, time.Hour),
+			newCmd("ota-4", "vh-2", iovv1alpha2.CommandPhaseSucceeded, time.Hour),
+		).Build()
+	repo := newCommandRepository("default", cli, cli)
+
+	// The VIN is matched against the metadata name form of the vehicle.
+	cmds, err := repo.ListByVehicle(context.Background(), "VH-1", model.CommandFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, cmd := range cmds {
+		got = append(got, cmd.ID)
+	}
+	if want := []string{"ota-3", "ota-2", "ota-1"}; !slices.Equal(got, want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+	if cmds[1].Status != model.CommandStatusFailed || cmds[1].Message != "Failed" || cmds[1].Priority != 1 {
+		t.Errorf("command = %+v", cmds[1])
+	}
+
+	filter := model.CommandFilter{Statuses: []model.CommandStatus{model.CommandStatusSucceeded}, CreatedAfter: base.Add(-2 * time.Hour)}
+	cmds, err = repo.ListByVehicle(context.Background(), "VH-1", filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 1 || cmds[0].ID != "ota-3" {
+		t.Errorf("filtered history = %v, want [ota-3]", cmds)
+	}
+}
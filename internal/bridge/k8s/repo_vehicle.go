@@ -43,7 +43,8 @@ func (r *vehicleRepository) Create(ctx context.Context, v *model.Vehicle) error
 	crd := ToCRD(r.namespace, v)
 	if err := r.client.Create(ctx, crd); err != nil {
 		if apierrors.IsAlreadyExists(err) {
-			return nil // Idempotency
+			// Let the caller treat it as an existing vehicle (e.g. concurrent registration).
+			return util.ErrAlreadyExists
 		}
 		return err
 	}
@@ -71,6 +72,40 @@ func (r *vehicleRepository) UpdateStatus(ctx context.Context, v *model.Vehicle)
 	return nil
 }
 
+func (r *vehicleRepository) GetModel(ctx context.Context, name string) (*model.VehicleModel, error) {
+	crd := &iovv1alpha2.VehicleModel{}
+	key := types.NamespacedName{Name: name, Namespace: r.namespace}
+
+	if err := r.client.Get(ctx, key, crd); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, util.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return ToVehicleModel(crd), nil
+}
+
+// listPageSize bounds the number of vehicles fetched per List request.
+const listPageSize = 500
+
+func (r *vehicleRepository) List(ctx context.Context) ([]*model.Vehicle, error) {
+	var vehicles []*model.Vehicle
+	list := &iovv1alpha2.VehicleList{}
+	for {
+		err := r.client.List(ctx, list, client.InNamespace(r.namespace), client.Limit(listPageSize), client.Continue(list.Continue))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list vehicles: %w", err)
+		}
+		for i := range list.Items {
+			vehicles = append(vehicles, ToModel(&list.Items[i]))
+		}
+		if list.Continue == "" {
+			return vehicles, nil
+		}
+	}
+}
+
 // UpdateStatus delegates the update to the async pipeline.
 // This returns immediately, ensuring high throughput for the caller.
 func (r *vehicleRepository) BatchUpdateStatus(ctx context.Context, update *model.VehicleStatusUpdate) error {
@@ -30,6 +30,7 @@ func (n *MQTTNotifier) Notify(ctx context.Context, cmd *model.Command) error {
 		CommandType: string(cmd.Type),
 		Parameters:  cmd.Parameters,
 		Timestamp:   cmd.CreatedAt.Unix(),
+		Priority:    cmd.Priority,
 	}
 
 	payload, err := json.Marshal(agentCmd)
@@ -2,10 +2,14 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"net"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/autopeer-io/autopeer/api/proto/v1"
 	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
@@ -67,6 +71,7 @@ func (s *Server) SendCommand(ctx context.Context, req *pb.SendCommandRequest) (*
 		VehicleID:  req.VehicleId,
 		Type:       model.CommandType(req.CommandType),
 		Parameters: req.Parameters,
+		Priority:   req.Priority,
 		Status:     model.CommandStatusPending,
 	}
 
@@ -90,6 +95,85 @@ func (s *Server) SendCommand(ctx context.Context, req *pb.SendCommandRequest) (*
 	}, nil
 }
 
+// ValidateCommand implements v1.HubServiceServer.
+// It runs the dispatch checks on a command without sending it (dry run).
+func (s *Server) ValidateCommand(ctx context.Context, req *pb.SendCommandRequest) (*pb.ValidateCommandResponse, error) {
+	issues, err := s.svc.ValidateCommand(ctx, &model.Command{
+		ID:         req.CommandName,
+		VehicleID:  req.VehicleId,
+		Type:       model.CommandType(req.CommandType),
+		Parameters: req.Parameters,
+		Priority:   req.Priority,
+	})
+	if err != nil {
+		log.Error(err, "Failed to validate command", "id", req.CommandName)
+		return nil, status.Errorf(codes.Unavailable, "failed to validate command: %v", err)
+	}
+
+	resp := &pb.ValidateCommandResponse{Valid: len(issues) == 0}
+	for _, i := range issues {
+		resp.Issues = append(resp.Issues, &pb.ValidationIssue{Field: i.Field, Reason: i.Reason, Message: i.Message})
+	}
+	return resp, nil
+}
+
+// ListCommands implements v1.HubServiceServer.
+// It serves a page of a vehicle's command history.
+func (s *Server) ListCommands(ctx context.Context, req *pb.ListCommandsRequest) (*pb.ListCommandsResponse, error) {
+	if req.VehicleId == "" {
+		return nil, status.Error(codes.InvalidArgument, "vehicle_id is required")
+	}
+
+	filter := model.CommandFilter{
+		CreatedAfter:  unixTime(req.CreatedAfter),
+		CreatedBefore: unixTime(req.CreatedBefore),
+	}
+	for _, phase := range req.Phases {
+		filter.Statuses = append(filter.Statuses, model.CommandStatus(phase))
+	}
+
+	page, err := s.svc.ListCommands(ctx, req.VehicleId, filter, int(req.PageSize), req.PageToken)
+	if errors.Is(err, service.ErrInvalidPageToken) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		log.Error(err, "Failed to list commands", "vehicle", req.VehicleId)
+		return nil, status.Errorf(codes.Unavailable, "failed to list commands: %v", err)
+	}
+
+	resp := &pb.ListCommandsResponse{NextPageToken: page.NextPageToken}
+	for _, cmd := range page.Commands {
+		resp.Commands = append(resp.Commands, &pb.CommandRecord{
+			CommandName: cmd.ID,
+			CommandType: string(cmd.Type),
+			Parameters:  cmd.Parameters,
+			Priority:    cmd.Priority,
+			Phase:       string(cmd.Status),
+			Message:     cmd.Message,
+			CreatedAt:   unixSeconds(cmd.CreatedAt),
+			CompletedAt: unixSeconds(cmd.CompletedAt),
+			RequestId:   cmd.RequestID,
+		})
+	}
+	return resp, nil
+}
+
+// unixTime converts optional Unix seconds; zero is the zero time.
+func unixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// unixSeconds converts an optional time to Unix seconds; the zero time is zero.
+func unixSeconds(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
 // SendCommand implements the gRPC method defined in hub.proto
 // func (h *grpcHandler) SendCommand(ctx context.Context, req *pb.SendCommandRequest) (*pb.SendCommandResponse, error) {
 // 	log.Info("Hub received gRPC Command",
@@ -5,12 +5,14 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/autopeer-io/autopeer/internal/pkg/metrics"
 	"github.com/autopeer-io/autopeer/pkg/log"
 	"github.com/autopeer-io/autopeer/pkg/options"
 )
 
 type Server struct {
 	server  *http.Server
+	mux     *http.ServeMux
 	options *options.HttpOptions
 }
 
@@ -29,15 +31,25 @@ func NewServer(opts *options.HttpOptions) *Server {
 		w.Write([]byte("ok"))
 	})
 
+	// Prometheus metrics (e.g. status pipeline load shedding)
+	mux.Handle("/metrics", metrics.Handler())
+
 	return &Server{
 		server: &http.Server{
 			Addr:    opts.Addr,
 			Handler: mux,
 		},
+		mux:     mux,
 		options: opts,
 	}
 }
 
+// Handle registers an additional handler (e.g. firmware downloads) on the server.
+// It must be called before Start.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
 func (s *Server) Start(ctx context.Context) error {
 	log.Info("Starting HTTP Server", "addr", s.server.Addr)
 
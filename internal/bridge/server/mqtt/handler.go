@@ -2,10 +2,13 @@ package mqtt
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	pb "github.com/autopeer-io/autopeer/api/proto/v1"
 	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/bridge/core/service"
 	"github.com/autopeer-io/autopeer/internal/pkg/mqtt/paths"
 	"github.com/autopeer-io/autopeer/pkg/log"
 	"google.golang.org/protobuf/encoding/protojson"
@@ -34,7 +37,19 @@ func (s *Server) handleRegister(ctx context.Context, req *pb.RegisterVehicleRequ
 	return nil
 }
 
+// handleOnline also receives the agents' last-will messages: an agent registers an offline
+// OnlineStatus on this topic as its will, which the broker publishes as soon as the agent
+// drops without disconnecting, long before any heartbeat-based detection would notice.
 func (s *Server) handleOnline(ctx context.Context, req *pb.OnlineStatus) error {
+	if req.VehicleId == "" {
+		log.Warn("Received online status without vehicleID")
+		return nil
+	}
+
+	if !req.Online {
+		log.Info("Vehicle went offline", "vehicleID", req.VehicleId, "reason", req.Reason)
+	}
+
 	if err := s.svc.UpdateOnlineStatus(ctx, req.VehicleId, req.Online); err != nil {
 		log.Error(err, "Failed to update online status", "id", req.VehicleId, "online", req.Online)
 	}
@@ -43,12 +58,34 @@ func (s *Server) handleOnline(ctx context.Context, req *pb.OnlineStatus) error {
 }
 
 func (s *Server) handleCommandAck(ctx context.Context, req *pb.AgentCommandStatus) error {
+	// Unknown fields are discarded, so any JSON object parses; a message without the ack
+	// fields was published on this topic by mistake and is skipped, not reported as an error.
+	if req.CommandName == "" || req.Status == "" {
+		log.Debug("Skipping message without command name or status on the ack topic")
+		return nil
+	}
+
 	log.Info("Received Status Report",
 		"commandName", req.CommandName,
 		"status", req.Status,
 		"msg", req.Message)
 
-	return s.svc.UpdateCommandStatus(ctx, req.CommandName, model.CommandStatus(req.Status), req.Message)
+	return s.svc.UpdateCommandStatus(ctx, req.CommandName, model.CommandStatus(req.Status), req.Message, req.ResultKey)
+}
+
+func (s *Server) handleTelemetry(ctx context.Context, req *pb.TelemetryReport) error {
+	if req.VehicleId == "" {
+		log.Warn("Received telemetry without vehicleID")
+		return nil
+	}
+
+	err := s.svc.ReportTelemetry(ctx, req.VehicleId, req.Properties)
+	if errors.Is(err, service.ErrUnknownProperty) {
+		// The declared properties were still recorded; the rest is a device misconfiguration.
+		log.Warn("Dropped undeclared telemetry properties", "vehicleID", req.VehicleId, "error", err.Error())
+		return nil
+	}
+	return err
 }
 
 func (s *Server) handleOTARequest(ctx context.Context, req *pb.OTARequest) error {
@@ -59,16 +96,21 @@ func (s *Server) handleOTARequest(ctx context.Context, req *pb.OTARequest) error
 
 	resp := &pb.OTAResponse{RequestId: req.RequestId}
 
-	// 假设固件文件在存储桶中的路径格式为: {version}/vehicle.bin
-	// 在真实场景中，这里应该查询数据库或 K8s 获取该版本对应的真实 ObjectKey
-	objectKey := fmt.Sprintf("%s/vehicle.bin", req.DesiredVersion)
-
-	downloadURL, err := s.svc.GetFirmwareDownloadURL(ctx, objectKey)
-	if err != nil {
+	// 固件在存储桶中的路径格式: {version}/vehicle.bin, 差分包: {version}/deltas/{from}.patch
+	ttl := time.Duration(req.UrlTtlSeconds) * time.Second
+	download, err := s.svc.GetFirmwareUpdate(ctx, req.VehicleId, req.DesiredVersion, req.CurrentVersion, ttl)
+	switch {
+	case errors.Is(err, service.ErrChecksumMismatch):
+		log.Error(err, "Refusing to serve firmware with mismatching checksum")
+		resp.ErrorMessage = "Firmware checksum mismatch"
+	case err != nil:
 		log.Error(err, "Failed to get firmware download URL")
 		resp.ErrorMessage = "Internal Server Error: DownloadUrl unavailable"
-	} else {
-		resp.DownloadUrl = downloadURL
+	default:
+		resp.DownloadUrl = download.URL
+		resp.Checksum = download.Checksum
+		resp.BaseVersion = download.BaseVersion
+		resp.Signature = download.Signature
 	}
 
 	// 发送响应
@@ -81,6 +123,70 @@ func (s *Server) handleOTARequest(ctx context.Context, req *pb.OTARequest) error
 		return err
 	}
 
-	log.Info("Sent Firmware URL", "url", resp.DownloadUrl)
+	log.Info("Sent Firmware URL", "url", resp.DownloadUrl, "baseVersion", resp.BaseVersion)
+	return nil
+}
+
+func (s *Server) handleResultUploadRequest(ctx context.Context, req *pb.ResultUploadRequest) error {
+	if req.VehicleId == "" || req.RequestId == "" {
+		return fmt.Errorf("either VehicleId[%s] or RequestId[%s] is empty", req.VehicleId, req.RequestId)
+	}
+
+	resp := &pb.ResultUploadResponse{RequestId: req.RequestId}
+
+	ttl := time.Duration(req.UrlTtlSeconds) * time.Second
+	upload, err := s.svc.GetResultUpload(ctx, req.VehicleId, req.CommandName, ttl)
+	if err != nil {
+		log.Error(err, "Failed to get result upload URL", "vehicleID", req.VehicleId, "command", req.CommandName)
+		resp.ErrorMessage = "Internal Server Error: UploadUrl unavailable"
+	} else {
+		resp.UploadUrl = upload.URL
+		resp.ObjectKey = upload.Key
+	}
+
+	respBytes, _ := protojson.Marshal(resp)
+	topicPath := s.topics.Build(paths.ResultUploadResponse, req.VehicleId)
+	if err := s.client.Publish(ctx, topicPath, 1, false, respBytes); err != nil {
+		log.Error(err, "Failed to publish result upload response")
+		return err
+	}
+
+	log.Info("Sent result upload URL", "vehicleID", req.VehicleId, "command", req.CommandName, "key", resp.ObjectKey)
+	return nil
+}
+
+func (s *Server) handleVehicleRequest(ctx context.Context, req *pb.VehicleRequest) error {
+	if req.VehicleId == "" || req.RequestId == "" {
+		return fmt.Errorf("either VehicleId[%s] or RequestId[%s] is empty", req.VehicleId, req.RequestId)
+	}
+
+	log.Info("Received vehicle request", "vehicleID", req.VehicleId, "requestID", req.RequestId, "type", req.Type)
+
+	result, err := s.svc.HandleVehicleRequest(ctx, &model.VehicleRequest{
+		ID:         req.RequestId,
+		VehicleID:  req.VehicleId,
+		Type:       model.RequestType(req.Type),
+		Parameters: req.Parameters,
+	})
+	if err != nil {
+		// Let the vehicle retry; the response carries no decision.
+		log.Error(err, "Failed to handle vehicle request", "vehicleID", req.VehicleId, "requestID", req.RequestId)
+		result = &model.RequestResult{Message: "Internal Server Error: request not handled, retry later"}
+	}
+
+	resp := &pb.VehicleRequestResponse{
+		RequestId:   req.RequestId,
+		Accepted:    result.Accepted,
+		Message:     result.Message,
+		CommandName: result.CommandID,
+	}
+	respBytes, _ := protojson.Marshal(resp)
+	topicPath := s.topics.Build(paths.RequestResponse, req.VehicleId)
+	if err := s.client.Publish(ctx, topicPath, 1, false, respBytes); err != nil {
+		log.Error(err, "Failed to publish vehicle request response")
+		return err
+	}
+
+	log.Info("Answered vehicle request", "requestID", req.RequestId, "accepted", result.Accepted, "message", result.Message)
 	return nil
 }
@@ -0,0 +1,234 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/autopeer-io/autopeer/api/proto/v1"
+	"github.com/autopeer-io/autopeer/internal/bridge/core"
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/internal/bridge/core/service"
+	"github.com/autopeer-io/autopeer/internal/pkg/mqtt/adapter"
+	"github.com/autopeer-io/autopeer/internal/pkg/mqtt/paths"
+	pkgmqtt "github.com/autopeer-io/autopeer/pkg/mqtt"
+	"github.com/autopeer-io/autopeer/pkg/mqtt/topic"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+// statusRepo records the buffered vehicle status updates.
+type statusRepo struct {
+	updates []*model.VehicleStatusUpdate
+	acks    []string
+}
+
+func (r *statusRepo) Vehicle() core.VehicleRepository { return r }
+func (r *statusRepo) Command() core.CommandRepository { return (*commandRepo)(r) }
+
+func (r *statusRepo) Get(ctx context.Context, vin string) (*model.Vehicle, error) { return nil, nil }
+func (r *statusRepo) Create(ctx context.Context, v *model.Vehicle) error          { return nil }
+func (r *statusRepo) UpdateStatus(ctx context.Context, v *model.Vehicle) error    { return nil }
+func (r *statusRepo) GetModel(ctx context.Context, name string) (*model.VehicleModel, error) {
+	return nil, nil
+}
+func (r *statusRepo) List(ctx context.Context) ([]*model.Vehicle, error) { return nil, nil }
+
+func (r *statusRepo) BatchUpdateStatus(ctx context.Context, update *model.VehicleStatusUpdate) error {
+	r.updates = append(r.updates, update)
+	return nil
+}
+
+// commandRepo records the command status updates of a statusRepo.
+type commandRepo statusRepo
+
+func (r *commandRepo) Create(ctx context.Context, cmd *model.Command) error { return nil }
+
+func (r *commandRepo) ListByVehicle(ctx context.Context, vin string, filter model.CommandFilter) ([]*model.Command, error) {
+	return nil, nil
+}
+
+func (r *commandRepo) UpdateStatus(ctx context.Context, cmdID string, status model.CommandStatus, message string, result map[string]string) error {
+	r.acks = append(r.acks, cmdID+"="+string(status))
+	return nil
+}
+
+func TestWillMessageMarksVehicleOffline(t *testing.T) {
+	repo := &statusRepo{}
+	builder := topic.NewBuilder("iov/v1")
+	s := NewServer(options.NewMqttOptions(), nil, builder, service.New(repo, nil, nil))
+
+	// The agent registers this will (see agent.Config); the broker publishes it when the agent drops.
+	willTopic := builder.Build(paths.Online, "VH1")
+	willPayload, _ := json.Marshal(&pb.OnlineStatus{VehicleId: "VH1", Reason: "UnexpectedDisconnect"})
+
+	subscription := s.subscriptionTopic(paths.Online)
+	if want := strings.TrimSuffix(strings.TrimPrefix(subscription, "$share/autopeer-bridge/"), "+"); !strings.HasPrefix(willTopic, want) {
+		t.Fatalf("hub subscription %q does not cover the will topic %q", subscription, willTopic)
+	}
+
+	before := time.Now()
+	if err := adapter.ProtoHandler(s.handleOnline)(context.Background(), willPayload); err != nil {
+		t.Fatalf("handler error = %v", err)
+	}
+
+	if len(repo.updates) != 1 {
+		t.Fatalf("got %d status updates, want 1", len(repo.updates))
+	}
+	u := repo.updates[0]
+	if u.VIN != "VH1" || u.Online {
+		t.Errorf("update = %+v, want VH1 offline", u)
+	}
+	if u.LastHeartbeatTime.Before(before) {
+		t.Errorf("LastHeartbeatTime = %v, want the time the will was received", u.LastHeartbeatTime)
+	}
+}
+
+// vehicleRepo knows every vehicle, with no desired checksum.
+type vehicleRepo struct {
+	statusRepo
+}
+
+func (r *vehicleRepo) Vehicle() core.VehicleRepository { return r }
+
+func (r *vehicleRepo) Get(ctx context.Context, vin string) (*model.Vehicle, error) {
+	return &model.Vehicle{VIN: vin}, nil
+}
+
+// expiryStorage holds every firmware image and records the lifetime of the last download URL.
+type expiryStorage struct {
+	core.Storage
+	expiry time.Duration
+}
+
+func (s *expiryStorage) StatObject(ctx context.Context, key string) (*model.ObjectInfo, error) {
+	return &model.ObjectInfo{Key: key}, nil
+}
+
+func (s *expiryStorage) GeneratePresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	s.expiry = expiry
+	return "https://s3.example.com/" + key, nil
+}
+
+// publishClient records the published messages.
+type publishClient struct {
+	pkgmqtt.Client
+	published []string
+}
+
+func (c *publishClient) Publish(ctx context.Context, topic string, qos int, retain bool, payload []byte) error {
+	c.published = append(c.published, topic)
+	return nil
+}
+
+func TestOTARequestURLTTLClamped(t *testing.T) {
+	tests := []struct {
+		name       string
+		ttlSeconds int64
+		want       time.Duration
+	}{
+		{name: "left to the hub", ttlSeconds: 0, want: time.Hour},
+		{name: "below minimum", ttlSeconds: 30, want: time.Minute},
+		{name: "within bounds", ttlSeconds: 600, want: 10 * time.Minute},
+		{name: "above maximum", ttlSeconds: 48 * 3600, want: 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &expiryStorage{}
+			client := &publishClient{}
+			svc := service.New(&vehicleRepo{}, nil, storage, service.WithMaxURLExpiry(24*time.Hour))
+			s := NewServer(options.NewMqttOptions(), client, topic.NewBuilder("iov/v1"), svc)
+
+			payload, _ := json.Marshal(&pb.OTARequest{VehicleId: "VH1", RequestId: "req-1", DesiredVersion: "v1.2.0", UrlTtlSeconds: tt.ttlSeconds})
+			if err := adapter.ProtoHandler(s.handleOTARequest)(context.Background(), payload); err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+			if storage.expiry != tt.want {
+				t.Errorf("expiry = %v, want %v", storage.expiry, tt.want)
+			}
+			if len(client.published) != 1 {
+				t.Errorf("published %d responses, want 1", len(client.published))
+			}
+		})
+	}
+}
+
+func TestCommandAckForwardCompatibility(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		wantAcks []string
+	}{
+		{
+			name:     "newer agent schema",
+			payload:  `{"command_name":"ota-vh-1","status":"Running","message":"50%","progress":{"percent":50}}`,
+			wantAcks: []string{"ota-vh-1=Running"},
+		},
+		{
+			name:    "misrouted firmware request",
+			payload: `{"vehicle_id":"VH1","request_id":"req-1","firmware_version":"2.0.0"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &statusRepo{}
+			s := NewServer(options.NewMqttOptions(), nil, topic.NewBuilder("iov/v1"), service.New(repo, nil, nil))
+
+			if err := adapter.ProtoHandler(s.handleCommandAck)(context.Background(), []byte(tt.payload)); err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+			if !slices.Equal(repo.acks, tt.wantAcks) {
+				t.Errorf("acks = %v, want %v", repo.acks, tt.wantAcks)
+			}
+		})
+	}
+}
+
+// slowRepo simulates an apiserver that does not answer: lookups block until the context ends.
+type slowRepo struct {
+	statusRepo
+	err error
+}
+
+func (r *slowRepo) Vehicle() core.VehicleRepository { return r }
+
+func (r *slowRepo) Get(ctx context.Context, vin string) (*model.Vehicle, error) {
+	<-ctx.Done()
+	r.err = ctx.Err()
+	return nil, r.err
+}
+
+func TestHandlerDeadline(t *testing.T) {
+	repo := &slowRepo{}
+	opts := options.NewMqttOptions()
+	opts.HandlerTimeout = 50 * time.Millisecond
+	s := NewServer(opts, nil, topic.NewBuilder("iov/v1"), service.New(repo, nil, nil))
+
+	handle := s.messageHandler("iov/v1/register/+", adapter.ProtoHandler(s.handleRegister))
+	payload, _ := json.Marshal(&pb.RegisterVehicleRequest{VehicleId: "VH1", FirmwareVersion: "1.0.0"})
+
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		handle(context.Background(), "iov/v1/register/VH1", payload)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not return after its deadline")
+	}
+
+	if elapsed := time.Since(start); elapsed < opts.HandlerTimeout {
+		t.Errorf("handler returned after %v, before its deadline", elapsed)
+	}
+	if !errors.Is(repo.err, context.DeadlineExceeded) {
+		t.Errorf("repository saw %v, want the handler deadline", repo.err)
+	}
+}
@@ -11,21 +11,33 @@ import (
 	"github.com/autopeer-io/autopeer/pkg/log"
 	pkgmqtt "github.com/autopeer-io/autopeer/pkg/mqtt"
 	"github.com/autopeer-io/autopeer/pkg/mqtt/topic"
+	"github.com/autopeer-io/autopeer/pkg/options"
 )
 
 // Server implements the MQTT ingress layer.
+//
+// When sharedGroup is set, the hub subscribes through $share/<group>/, so with several hub
+// replicas the broker delivers each vehicle message to exactly one of them (at-most-one
+// replica processes it). Without it, every replica processes every message.
+//
+// Every message is handled under a deadline (handlerTimeout), so that a slow apiserver
+// cannot block handlers indefinitely; the router starts a goroutine per message.
 type Server struct {
-	client pkgmqtt.Client
-	topics *topic.Builder
-	svc    *service.Service
+	client         pkgmqtt.Client
+	topics         *topic.Builder
+	sharedGroup    string
+	handlerTimeout time.Duration
+	svc            *service.Service
 }
 
 // NewServer creates a new MQTT server (client).
-func NewServer(client pkgmqtt.Client, builder *topic.Builder, svc *service.Service) *Server {
+func NewServer(opts *options.MqttOptions, client pkgmqtt.Client, builder *topic.Builder, svc *service.Service) *Server {
 	return &Server{
-		client: client,
-		topics: builder,
-		svc:    svc,
+		client:         client,
+		topics:         builder,
+		sharedGroup:    opts.SharedGroup,
+		handlerTimeout: opts.HandlerTimeout,
+		svc:            svc,
 	}
 }
 
@@ -65,8 +77,6 @@ func (s *Server) Start(ctx context.Context) error {
 }
 
 func (s *Server) initMQTTSubscriptions(ctx context.Context) error {
-	// Define shared subscription group prefix
-	const groupName = "autopeer-bridge"
 	const qos = 1
 
 	subscriptions := map[string]adapter.HandlerFunc{
@@ -74,18 +84,38 @@ func (s *Server) initMQTTSubscriptions(ctx context.Context) error {
 		paths.Online:     adapter.ProtoHandler(s.handleOnline),
 		paths.CommandAck: adapter.ProtoHandler(s.handleCommandAck),
 		paths.OTARequest: adapter.ProtoHandler(s.handleOTARequest),
+		paths.Telemetry:  adapter.ProtoHandler(s.handleTelemetry),
+		paths.Request:    adapter.ProtoHandler(s.handleVehicleRequest),
+
+		paths.ResultUploadRequest: adapter.ProtoHandler(s.handleResultUploadRequest),
 	}
 
 	for segment, handler := range subscriptions {
-		fullTopic := s.topics.Shared(groupName).BuildWildcard(segment)
-		if err := s.client.Subscribe(ctx, fullTopic, qos, func(c context.Context, _ string, p []byte) {
-			if handleErr := handler(c, p); handleErr != nil {
-				log.Error(handleErr, "Handler execution failed", "topic", fullTopic)
-			}
-		}); err != nil {
+		fullTopic := s.subscriptionTopic(segment)
+		if err := s.client.Subscribe(ctx, fullTopic, qos, s.messageHandler(fullTopic, handler)); err != nil {
 			return fmt.Errorf("failed to subscribe to topic: %s, err: %w", fullTopic, err)
 		}
 	}
 
 	return nil
 }
+
+// messageHandler adapts a handler to the MQTT client, running it under the handler deadline.
+func (s *Server) messageHandler(subscription string, handler adapter.HandlerFunc) pkgmqtt.MessageHandler {
+	return func(c context.Context, _ string, p []byte) {
+		ctx, cancel := context.WithTimeout(c, s.handlerTimeout)
+		defer cancel()
+
+		if handleErr := handler(ctx, p); handleErr != nil {
+			log.Error(handleErr, "Handler execution failed", "topic", subscription)
+		}
+	}
+}
+
+// subscriptionTopic returns the hub's subscription for a topic segment, shared if a group is configured.
+func (s *Server) subscriptionTopic(segment string) string {
+	if s.sharedGroup == "" {
+		return s.topics.BuildWildcard(segment)
+	}
+	return s.topics.Shared(s.sharedGroup).BuildWildcard(segment)
+}
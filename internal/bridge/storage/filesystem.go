@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
+	"github.com/autopeer-io/autopeer/pkg/log"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+// FirmwarePathPrefix is the HTTP route under which the filesystem backend serves firmware files.
+const FirmwarePathPrefix = "/firmware/"
+
+// FileSystem serves firmware from a local directory for deployments without S3.
+// Download and upload links point at the hub's own HTTP server and carry an HMAC-signed expiry,
+// which makes them behave like S3 presigned URLs.
+type FileSystem struct {
+	rootDir   string
+	publicURL string
+	key       []byte
+
+	// now is overridable for tests.
+	now func() time.Time
+}
+
+// NewFileSystem creates a filesystem-backed storage.
+func NewFileSystem(opts *options.StorageOptions) (*FileSystem, error) {
+	if opts.SigningKey == "" {
+		return nil, fmt.Errorf("signing key is required for filesystem storage")
+	}
+
+	return &FileSystem{
+		rootDir:   opts.RootDir,
+		publicURL: strings.TrimSuffix(opts.PublicURL, "/"),
+		key:       []byte(opts.SigningKey),
+		now:       time.Now,
+	}, nil
+}
+
+// CheckBucket ensures the firmware root directory exists.
+func (p *FileSystem) CheckBucket(ctx context.Context) error {
+	if err := os.MkdirAll(p.rootDir, 0o755); err != nil {
+		return fmt.Errorf("failed to prepare firmware directory: %w", err)
+	}
+	return nil
+}
+
+// GeneratePresignedURL returns a link to the hub HTTP server that is valid until now+expiry.
+func (p *FileSystem) GeneratePresignedURL(ctx context.Context, objectKey string, expiry time.Duration) (string, error) {
+	return p.presign(http.MethodGet, objectKey, expiry)
+}
+
+// GeneratePresignedPutURL returns an upload link to the hub HTTP server that is valid until now+expiry.
+func (p *FileSystem) GeneratePresignedPutURL(ctx context.Context, objectKey string, expiry time.Duration) (string, error) {
+	return p.presign(http.MethodPut, objectKey, expiry)
+}
+
+func (p *FileSystem) presign(method, objectKey string, expiry time.Duration) (string, error) {
+	key := cleanKey(objectKey)
+	if key == "" {
+		return "", fmt.Errorf("invalid object key %q", objectKey)
+	}
+
+	expires := p.now().Add(expiry).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", p.sign(method, key, expires))
+
+	return fmt.Sprintf("%s%s%s?%s", p.publicURL, FirmwarePathPrefix, key, query.Encode()), nil
+}
+
+// signatureSuffix names the sidecar file holding a firmware file's signature (e.g. vehicle.bin.sig).
+const signatureSuffix = ".sig"
+
+// StatObject returns the size and SHA256 checksum of a firmware file.
+// The checksum is computed from the file content, so it is always authoritative.
+// The signature, if any, is read from the sidecar file next to it.
+func (p *FileSystem) StatObject(ctx context.Context, objectKey string) (*model.ObjectInfo, error) {
+	key := cleanKey(objectKey)
+	if key == "" {
+		return nil, fmt.Errorf("invalid object key %q", objectKey)
+	}
+
+	f, err := os.Open(filepath.Join(p.rootDir, filepath.FromSlash(key)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat object %s: %w", key, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", key, err)
+	}
+
+	var signature string
+	if sig, err := os.ReadFile(filepath.Join(p.rootDir, filepath.FromSlash(key)) + signatureSuffix); err == nil {
+		signature = strings.TrimSpace(string(sig))
+	}
+
+	return &model.ObjectInfo{
+		Key:       key,
+		Size:      size,
+		Checksum:  "sha256:" + hex.EncodeToString(h.Sum(nil)),
+		Signature: signature,
+	}, nil
+}
+
+// GetObjectRange reads the byte range [offset, offset+length) of a firmware file.
+// A range past the end of the file is truncated, like an HTTP Range request.
+func (p *FileSystem) GetObjectRange(ctx context.Context, objectKey string, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 || length <= 0 {
+		return nil, fmt.Errorf("invalid range offset=%d length=%d", offset, length)
+	}
+
+	key := cleanKey(objectKey)
+	if key == "" {
+		return nil, fmt.Errorf("invalid object key %q", objectKey)
+	}
+
+	f, err := os.Open(filepath.Join(p.rootDir, filepath.FromSlash(key)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open object %s: %w", key, err)
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(f, offset, length), f}, nil
+}
+
+// PutObject writes data to a file below the root directory. The file is replaced
+// atomically, so concurrent downloads never see a partial object.
+func (p *FileSystem) PutObject(ctx context.Context, objectKey string, data []byte, contentType string) error {
+	key := cleanKey(objectKey)
+	if key == "" {
+		return fmt.Errorf("invalid object key %q", objectKey)
+	}
+	return p.writeObject(key, bytes.NewReader(data))
+}
+
+// writeObject atomically replaces the file of a cleaned key with the content of r.
+func (p *FileSystem) writeObject(key string, r io.Reader) error {
+	name := filepath.Join(p.rootDir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("failed to put object %s: %w", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to put object %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to put object %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to put object %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("failed to put object %s: %w", key, err)
+	}
+	return nil
+}
+
+// ServeHTTP serves a firmware file after validating its signed token, or stores an upload (PUT).
+// Range requests are supported (http.ServeFile), so a signed link can also be used for partial reads.
+// It is mounted on the hub HTTP server under FirmwarePathPrefix.
+func (p *FileSystem) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := cleanKey(strings.TrimPrefix(r.URL.Path, FirmwarePathPrefix))
+	if key == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	method := r.Method
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		method = http.MethodGet
+	case http.MethodPut:
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	if err := p.verify(method, key, query.Get("expires"), query.Get("signature")); err != nil {
+		log.Warn("Rejected object request", "method", r.Method, "key", key, "reason", err.Error())
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	if method == http.MethodGet {
+		http.ServeFile(w, r, filepath.Join(p.rootDir, filepath.FromSlash(key)))
+		return
+	}
+
+	if err := p.writeObject(key, r.Body); err != nil {
+		log.Error(err, "Failed to store upload", "key", key)
+		http.Error(w, "failed to store object", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify checks that the signature matches the method and key and that the token has not expired.
+func (p *FileSystem) verify(method, key, expiresParam, signature string) error {
+	expires, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid expiry")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(p.sign(method, key, expires))) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	if p.now().Unix() > expires {
+		return fmt.Errorf("link expired")
+	}
+
+	return nil
+}
+
+// sign computes the token of a link. Upload links are signed differently from download
+// links, so a download link cannot be used to overwrite the object.
+func (p *FileSystem) sign(method, key string, expires int64) string {
+	mac := hmac.New(sha256.New, p.key)
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	if method == http.MethodPut {
+		fmt.Fprint(mac, "\nPUT")
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// cleanKey normalizes an object key and prevents it from escaping the root directory.
+func cleanKey(key string) string {
+	return strings.TrimPrefix(path.Clean("/"+key), "/")
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+func newTestFileSystem(t *testing.T) *FileSystem {
+	t.Helper()
+
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "v1.2.0"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "v1.2.0", "vehicle.bin"), []byte("firmware"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, err := NewFileSystem(&options.StorageOptions{
+		RootDir:    root,
+		PublicURL:  "http://hub.local:8001/",
+		SigningKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func download(fs *FileSystem, rawURL string) *httptest.ResponseRecorder {
+	u, _ := url.Parse(rawURL)
+	rec := httptest.NewRecorder()
+	fs.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+	return rec
+}
+
+func TestFileSystemPresignedURL(t *testing.T) {
+	fs := newTestFileSystem(t)
+	now := time.Unix(1700000000, 0)
+	fs.now = func() time.Time { return now }
+
+	link, err := fs.GeneratePresignedURL(context.Background(), "v1.2.0/vehicle.bin", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "hub.local:8001" || u.Path != "/firmware/v1.2.0/vehicle.bin" {
+		t.Fatalf("unexpected link: %s", link)
+	}
+	if u.Query().Get("expires") != "1700003600" {
+		t.Errorf("unexpected expiry: %s", u.Query().Get("expires"))
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		rec := download(fs, link)
+		if rec.Code != http.StatusOK || rec.Body.String() != "firmware" {
+			t.Fatalf("expected firmware body, got %d %q", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("tampered key", func(t *testing.T) {
+		tampered := *u
+		tampered.Path = "/firmware/v9.9.9/vehicle.bin"
+		if rec := download(fs, tampered.String()); rec.Code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d", rec.Code)
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		now = now.Add(2 * time.Hour)
+		if rec := download(fs, link); rec.Code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d", rec.Code)
+		}
+	})
+}
+
+func TestCleanKey(t *testing.T) {
+	tests := map[string]string{
+		"v1/vehicle.bin":       "v1/vehicle.bin",
+		"/v1/vehicle.bin":      "v1/vehicle.bin",
+		"../../etc/passwd":     "etc/passwd",
+		"v1/../../vehicle.bin": "vehicle.bin",
+		"":                     "",
+	}
+	for in, want := range tests {
+		if got := cleanKey(in); got != want {
+			t.Errorf("cleanKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFileSystemGetObjectRange(t *testing.T) {
+	fs := newTestFileSystem(t)
+
+	tests := []struct {
+		name    string
+		offset  int64
+		length  int64
+		want    string
+		wantErr bool
+	}{
+		{name: "header", offset: 0, length: 4, want: "firm"},
+		{name: "middle", offset: 4, length: 3, want: "war"},
+		{name: "past end is truncated", offset: 6, length: 100, want: "re"},
+		{name: "negative offset", offset: -1, length: 4, wantErr: true},
+		{name: "zero length", offset: 0, length: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, err := fs.GetObjectRange(context.Background(), "v1.2.0/vehicle.bin", tt.offset, tt.length)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetObjectRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			defer rc.Close()
+
+			got, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("GetObjectRange() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileSystemPresignedURLRange(t *testing.T) {
+	fs := newTestFileSystem(t)
+
+	link, err := fs.GeneratePresignedURL(context.Background(), "v1.2.0/vehicle.bin", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(link)
+	req := httptest.NewRequest(http.MethodGet, u.RequestURI(), nil)
+	req.Header.Set("Range", "bytes=0-3")
+	rec := httptest.NewRecorder()
+	fs.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "firm" {
+		t.Fatalf("expected 206 with header bytes, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestFileSystemPresignedPutURL(t *testing.T) {
+	fs := newTestFileSystem(t)
+
+	putLink, err := fs.GeneratePresignedPutURL(context.Background(), "results/VH1/logs-vh1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	getLink, err := fs.GeneratePresignedURL(context.Background(), "results/VH1/logs-vh1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upload := func(rawURL, body string) int {
+		u, _ := url.Parse(rawURL)
+		rec := httptest.NewRecorder()
+		fs.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, u.RequestURI(), strings.NewReader(body)))
+		return rec.Code
+	}
+
+	if code := upload(getLink, "forged"); code != http.StatusForbidden {
+		t.Fatalf("upload with a download link: expected 403, got %d", code)
+	}
+	if code := upload(putLink, "log lines"); code != http.StatusOK {
+		t.Fatalf("upload: expected 200, got %d", code)
+	}
+	if rec := download(fs, putLink); rec.Code != http.StatusForbidden {
+		t.Fatalf("download with an upload link: expected 403, got %d", rec.Code)
+	}
+	if rec := download(fs, getLink); rec.Code != http.StatusOK || rec.Body.String() != "log lines" {
+		t.Fatalf("expected uploaded body, got %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -1,16 +1,22 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 
+	"github.com/autopeer-io/autopeer/internal/bridge/core/model"
 	"github.com/autopeer-io/autopeer/pkg/log"
 	"github.com/autopeer-io/autopeer/pkg/options"
 )
@@ -79,3 +85,75 @@ func (p *MinIO) GeneratePresignedURL(ctx context.Context, objectKey string, expi
 
 	return presignedURL.String(), nil
 }
+
+// GeneratePresignedPutURL generates a presigned URL the holder can upload the object to with an HTTP PUT.
+func (p *MinIO) GeneratePresignedPutURL(ctx context.Context, objectKey string, expiry time.Duration) (string, error) {
+	presignedURL, err := p.client.PresignedPutObject(ctx, p.bucketName, objectKey, expiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned put url: %w", err)
+	}
+	return presignedURL.String(), nil
+}
+
+const (
+	// checksumMetadataKey is the user metadata (x-amz-meta-sha256) publishers set on firmware objects.
+	checksumMetadataKey = "Sha256"
+
+	// signatureMetadataKey is the user metadata (x-amz-meta-signature) holding the publisher's signature.
+	signatureMetadataKey = "Signature"
+)
+
+// StatObject returns the object metadata. The checksum is read from the
+// x-amz-meta-sha256 user metadata, or from the S3 SHA256 checksum if the object was uploaded with one.
+func (p *MinIO) StatObject(ctx context.Context, objectKey string) (*model.ObjectInfo, error) {
+	info, err := p.client.StatObject(ctx, p.bucketName, objectKey, minio.StatObjectOptions{Checksum: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat object %s: %w", objectKey, err)
+	}
+
+	checksum := ""
+	if sum := info.UserMetadata[checksumMetadataKey]; sum != "" {
+		checksum = "sha256:" + strings.ToLower(strings.TrimPrefix(sum, "sha256:"))
+	} else if info.ChecksumSHA256 != "" {
+		// S3 reports additional checksums base64 encoded
+		if raw, err := base64.StdEncoding.DecodeString(info.ChecksumSHA256); err == nil {
+			checksum = "sha256:" + hex.EncodeToString(raw)
+		}
+	}
+
+	return &model.ObjectInfo{
+		Key:       objectKey,
+		Size:      info.Size,
+		Checksum:  checksum,
+		Signature: info.UserMetadata[signatureMetadataKey],
+	}, nil
+}
+
+// PutObject uploads data as an object, replacing any existing one.
+func (p *MinIO) PutObject(ctx context.Context, objectKey string, data []byte, contentType string) error {
+	_, err := p.client.PutObject(ctx, p.bucketName, objectKey, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("failed to put object %s: %w", objectKey, err)
+	}
+	return nil
+}
+
+// GetObjectRange reads the byte range [offset, offset+length) of an object.
+// Presigned URLs support the same through the HTTP Range header.
+func (p *MinIO) GetObjectRange(ctx context.Context, objectKey string, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 || length <= 0 {
+		return nil, fmt.Errorf("invalid range offset=%d length=%d", offset, length)
+	}
+
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(offset, offset+length-1); err != nil {
+		return nil, fmt.Errorf("invalid range: %w", err)
+	}
+
+	obj, err := p.client.GetObject(ctx, p.bucketName, objectKey, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object %s: %w", objectKey, err)
+	}
+	return obj, nil
+}
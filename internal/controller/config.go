@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/autopeer-io/autopeer/internal/controller/vehiclecommand"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+type Config struct {
+	HealthProbeBindAddress string
+	MetricsBindAddress     string
+
+	// PauseConfigMap is the ConfigMap whose 'paused: "true"' pauses OTA activity cluster-wide.
+	PauseConfigMap types.NamespacedName
+
+	HubAddr       string
+	HubMaxRetries int
+	HubTimeout    time.Duration
+
+	VehicleOptions        *options.VehicleOptions
+	VehicleCommandOptions *options.VehicleCommandOptions
+	WebhookOptions        *options.WebhookOptions
+	AuditOptions          *options.AuditOptions
+	NotifyOptions         *options.NotifyOptions
+}
+
+// hubClientOptions returns the options of the gRPC client the VehicleCommand controller calls the Hub with.
+func (cfg *Config) hubClientOptions() []vehiclecommand.HubClientOption {
+	return []vehiclecommand.HubClientOption{
+		vehiclecommand.WithMaxRetries(cfg.HubMaxRetries),
+		vehiclecommand.WithTimeout(cfg.HubTimeout),
+	}
+}
@@ -11,9 +11,16 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	"github.com/autopeer-io/autopeer/internal/controller/pause"
+	"github.com/autopeer-io/autopeer/internal/controller/scheduledcommand"
 	"github.com/autopeer-io/autopeer/internal/controller/vehicle"
 	"github.com/autopeer-io/autopeer/internal/controller/vehiclecommand"
+	"github.com/autopeer-io/autopeer/internal/controller/vehiclegroup"
+	"github.com/autopeer-io/autopeer/internal/pkg/audit"
+	"github.com/autopeer-io/autopeer/internal/pkg/notify"
+	iovv1alpha1 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha1"
 	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
 	"github.com/autopeer-io/autopeer/pkg/log"
 )
@@ -22,6 +29,7 @@ var autopeerScheme = runtime.NewScheme()
 
 func init() {
 	utilruntime.Must(scheme.AddToScheme(autopeerScheme))
+	utilruntime.Must(iovv1alpha1.AddToScheme(autopeerScheme))
 	utilruntime.Must(iovv1alpha2.AddToScheme(autopeerScheme))
 }
 
@@ -29,11 +37,25 @@ type Controller interface {
 	SetupWithManager(ctx context.Context, mgr ctrl.Manager) error
 }
 
-func NewControllerManager(ctx context.Context, kubeconfig *rest.Config, healthProbe string, metricsAddr string, hubAddr string) (manager.Manager, error) {
+// NewControllerManager creates the controller manager with all controllers and the enabled webhooks registered.
+func (cfg *Config) NewControllerManager(ctx context.Context, kubeconfig *rest.Config) (manager.Manager, error) {
+	auditSink, err := audit.NewSink(cfg.AuditOptions.Path)
+	if err != nil {
+		log.Error(err, "failed to open audit log")
+		return nil, err
+	}
+
+	notifier, err := notify.NewNotifier(cfg.NotifyOptions)
+	if err != nil {
+		log.Error(err, "failed to set up milestone notifications")
+		return nil, err
+	}
+
 	mgr, err := ctrl.NewManager(kubeconfig, ctrl.Options{
 		Scheme:                 autopeerScheme,
-		Metrics:                server.Options{BindAddress: metricsAddr},
-		HealthProbeBindAddress: healthProbe,
+		Metrics:                server.Options{BindAddress: cfg.MetricsBindAddress},
+		HealthProbeBindAddress: cfg.HealthProbeBindAddress,
+		WebhookServer:          webhook.NewServer(webhook.Options{Port: cfg.WebhookOptions.Port, CertDir: cfg.WebhookOptions.CertDir}),
 	})
 	if err != nil {
 		log.Error(err, "failed to create controller manager")
@@ -50,7 +72,11 @@ func NewControllerManager(ctx context.Context, kubeconfig *rest.Config, healthPr
 		return nil, err
 	}
 
-	if err := setupControllers(ctx, mgr, hubAddr); err != nil {
+	if err := cfg.setupControllers(ctx, mgr, auditSink, notifier); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.setupWebhooks(mgr); err != nil {
 		return nil, err
 	}
 
@@ -58,18 +84,24 @@ func NewControllerManager(ctx context.Context, kubeconfig *rest.Config, healthPr
 }
 
 // setupControllers initializes and registers all controllers with the manager.
-func setupControllers(ctx context.Context, mgr manager.Manager, hubAddr string) error {
+func (cfg *Config) setupControllers(ctx context.Context, mgr manager.Manager, auditSink audit.Sink, notifier notify.Notifier) error {
 	cli := mgr.GetClient()
 	sche := mgr.GetScheme()
 
+	// The global pause switch is shared by all controllers.
+	// Read it uncached, so that the manager does not watch every ConfigMap in the cluster.
+	pauseSwitch := pause.NewConfigMapSwitch(mgr.GetAPIReader(), cfg.PauseConfigMap)
+
 	// EventRecorders for the controllers.
 	vehicleRecorder := mgr.GetEventRecorderFor("autopeer-vehicle-controller")
 	commandRecorder := mgr.GetEventRecorderFor("autopeer-command-controller")
 
 	// Register Controllers
 	controllers := []Controller{
-		vehicle.NewReconciler(cli, sche, vehicleRecorder),
-		vehiclecommand.NewReconciler(cli, sche, commandRecorder, hubAddr),
+		vehicle.NewReconciler(cli, sche, vehicleRecorder, pauseSwitch, notifier, cfg.VehicleOptions),
+		vehiclecommand.NewReconciler(cli, sche, commandRecorder, pauseSwitch, auditSink, cfg.VehicleCommandOptions, cfg.HubAddr, cfg.hubClientOptions()...),
+		vehiclegroup.NewReconciler(cli, sche),
+		scheduledcommand.NewReconciler(cli, sche),
 	}
 
 	for _, ctl := range controllers {
@@ -81,3 +113,26 @@ func setupControllers(ctx context.Context, mgr manager.Manager, hubAddr string)
 
 	return nil
 }
+
+// setupWebhooks registers the enabled admission webhooks. The webhook server only runs if one is.
+func (cfg *Config) setupWebhooks(mgr manager.Manager) error {
+	if cfg.WebhookOptions.Conversion {
+		// Serves /convert for the Vehicle versions, which convert through the v1alpha2 hub.
+		if err := ctrl.NewWebhookManagedBy(mgr).For(&iovv1alpha2.Vehicle{}).Complete(); err != nil {
+			log.Error(err, "failed to setup webhook", "webhook", "vehicle-conversion")
+			return err
+		}
+	}
+
+	// Validated with the options.
+	allowlist, _ := cfg.VehicleCommandOptions.MethodAllowlist()
+	if len(allowlist) == 0 {
+		return nil
+	}
+
+	if err := vehiclecommand.NewMethodAuthorizer(mgr.GetAPIReader(), allowlist).SetupWebhookWithManager(mgr); err != nil {
+		log.Error(err, "failed to setup webhook", "webhook", "vehiclecommand-method-authorizer")
+		return err
+	}
+	return nil
+}
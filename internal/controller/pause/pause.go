@@ -0,0 +1,109 @@
+// Package pause implements the cluster-wide maintenance switch of the controllers.
+//
+// While paused, controllers keep aggregating status (conditions, timestamps) but stop
+// advancing state machines: no OTA commands are created or sent and no phase transitions
+// are triggered. Resources are left untouched, so resuming continues where it stopped.
+//
+// The switch is a ConfigMap (by default autopeer-io/autopeer-pause):
+//
+//	kubectl -n autopeer-io create configmap autopeer-pause --from-literal=paused=true
+//	kubectl -n autopeer-io delete configmap autopeer-pause
+package pause
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// PausedKey is the ConfigMap data key holding the switch ("true" pauses).
+	PausedKey = "paused"
+
+	// RecheckInterval is how often a paused resource is requeued to notice a resume.
+	// The ConfigMap is not watched, so this bounds the delay after a resume.
+	RecheckInterval = 30 * time.Second
+
+	// cacheTTL bounds the apiserver reads: the switch is read at most once per TTL.
+	cacheTTL = 5 * time.Second
+)
+
+// Switch reports whether reconciliation is paused cluster-wide.
+type Switch interface {
+	Paused(ctx context.Context) bool
+}
+
+// Never is a Switch that is never paused.
+var Never Switch = never{}
+
+type never struct{}
+
+func (never) Paused(context.Context) bool { return false }
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
+
+// ConfigMapSwitch reads the switch from a ConfigMap.
+// A missing ConfigMap means not paused. If the ConfigMap cannot be read,
+// the last known state is kept.
+type ConfigMapSwitch struct {
+	reader client.Reader
+	key    types.NamespacedName
+
+	// now is overridable for tests.
+	now func() time.Time
+
+	mu        sync.Mutex
+	paused    bool
+	checkedAt time.Time
+}
+
+// NewConfigMapSwitch creates a Switch backed by the ConfigMap `key`.
+// The reader should not be cache-backed (e.g. mgr.GetAPIReader()) to avoid
+// watching all ConfigMaps in the cluster.
+func NewConfigMapSwitch(reader client.Reader, key types.NamespacedName) *ConfigMapSwitch {
+	return &ConfigMapSwitch{
+		reader: reader,
+		key:    key,
+		now:    time.Now,
+	}
+}
+
+// Paused implements Switch.
+func (s *ConfigMapSwitch) Paused(ctx context.Context) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.checkedAt.IsZero() && s.now().Sub(s.checkedAt) < cacheTTL {
+		return s.paused
+	}
+
+	var cm corev1.ConfigMap
+	err := s.reader.Get(ctx, s.key, &cm)
+	switch {
+	case apierrors.IsNotFound(err):
+		s.set(false)
+	case err != nil:
+		log.FromContext(ctx).Error(err, "Failed to read pause switch, keeping last known state", "configMap", s.key, "paused", s.paused)
+		return s.paused
+	default:
+		paused, _ := strconv.ParseBool(cm.Data[PausedKey])
+		s.set(paused)
+	}
+
+	return s.paused
+}
+
+func (s *ConfigMapSwitch) set(paused bool) {
+	if paused != s.paused {
+		log.Log.Info("Reconciliation pause switch changed", "configMap", s.key, "paused", paused)
+	}
+	s.paused = paused
+	s.checkedAt = s.now()
+}
@@ -0,0 +1,73 @@
+package pause
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var key = types.NamespacedName{Namespace: "autopeer-io", Name: "autopeer-pause"}
+
+func TestConfigMapSwitch(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]string
+		want bool
+	}{
+		{name: "paused", data: map[string]string{PausedKey: "true"}, want: true},
+		{name: "explicitly resumed", data: map[string]string{PausedKey: "false"}, want: false},
+		{name: "invalid value", data: map[string]string{PausedKey: "maybe"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}, Data: tt.data}
+			s := NewConfigMapSwitch(fake.NewClientBuilder().WithObjects(cm).Build(), key)
+
+			if got := s.Paused(context.Background()); got != tt.want {
+				t.Errorf("Paused() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing configmap", func(t *testing.T) {
+		s := NewConfigMapSwitch(fake.NewClientBuilder().Build(), key)
+		if s.Paused(context.Background()) {
+			t.Errorf("Paused() = true without a ConfigMap")
+		}
+	})
+}
+
+func TestConfigMapSwitchKeepsLastStateOnError(t *testing.T) {
+	now := time.Now()
+	fail := false
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}, Data: map[string]string{PausedKey: "true"}}
+	cli := fake.NewClientBuilder().WithObjects(cm).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, k client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if fail {
+				return errors.New("apiserver unavailable")
+			}
+			return c.Get(ctx, k, obj, opts...)
+		},
+	}).Build()
+
+	s := NewConfigMapSwitch(cli, key)
+	s.now = func() time.Time { return now }
+	if !s.Paused(context.Background()) {
+		t.Fatalf("expected paused")
+	}
+
+	fail = true
+	now = now.Add(cacheTTL)
+	if !s.Paused(context.Background()) {
+		t.Errorf("a read error must not resume reconciliation")
+	}
+}
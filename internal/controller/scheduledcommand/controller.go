@@ -0,0 +1,200 @@
+package scheduledcommand
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+const (
+	// ReasonScheduled means commands are created on schedule.
+	ReasonScheduled = "Scheduled"
+
+	// ReasonSuspended means Spec.Suspend stops creating commands.
+	ReasonSuspended = "Suspended"
+
+	// ReasonInvalidSpec means Spec.Schedule or Spec.Selector cannot be parsed.
+	ReasonInvalidSpec = "InvalidSpec"
+)
+
+// Reconciler creates the VehicleCommands of a ScheduledVehicleCommand when they are due,
+// and requeues itself for the next scheduled time.
+type Reconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// now is overridable for tests.
+	now func() time.Time
+}
+
+// NewReconciler creates a new Reconciler for ScheduledVehicleCommand.
+func NewReconciler(cli client.Client, sche *runtime.Scheme) *Reconciler {
+	return &Reconciler{Client: cli, Scheme: sche, now: time.Now}
+}
+
+//+kubebuilder:rbac:groups=iov.autopeer.io,resources=scheduledvehiclecommands,verbs=get;list;watch
+//+kubebuilder:rbac:groups=iov.autopeer.io,resources=scheduledvehiclecommands/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=iov.autopeer.io,resources=vehiclecommands,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=iov.autopeer.io,resources=vehicles,verbs=get;list;watch
+
+// Reconcile creates the commands for the most recent missed scheduled time, if any.
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var sc iovv1alpha2.ScheduledVehicleCommand
+	if err := r.Get(ctx, req.NamespacedName, &sc); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	original := sc.DeepCopy()
+
+	sched, err := parseSchedule(sc.Spec.Schedule)
+	if err != nil {
+		return ctrl.Result{}, r.invalid(ctx, &sc, original, fmt.Errorf("invalid schedule: %w", err))
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&sc.Spec.Selector)
+	if err != nil {
+		return ctrl.Result{}, r.invalid(ctx, &sc, original, fmt.Errorf("invalid selector: %w", err))
+	}
+
+	now := r.now()
+	if sc.Spec.Suspend {
+		sc.Status.NextScheduleTime = nil
+		setReadyCondition(&sc, metav1.ConditionFalse, ReasonSuspended, "Schedule is suspended")
+		return ctrl.Result{}, r.patchStatus(ctx, &sc, original)
+	}
+
+	if scheduled, ok := mostRecent(sched, earliest(&sc, now), now); ok {
+		count, err := r.createCommands(ctx, &sc, selector, scheduled)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		log.FromContext(ctx).Info("Created scheduled commands", "scheduledTime", scheduled, "count", count)
+		sc.Status.LastScheduleTime = &metav1.Time{Time: scheduled}
+		sc.Status.LastScheduledCount = int32(count)
+	}
+
+	next := sched.next(now)
+	if next.IsZero() {
+		sc.Status.NextScheduleTime = nil
+		setReadyCondition(&sc, metav1.ConditionFalse, ReasonInvalidSpec, "Schedule never fires")
+		return ctrl.Result{}, r.patchStatus(ctx, &sc, original)
+	}
+	sc.Status.NextScheduleTime = &metav1.Time{Time: next}
+	setReadyCondition(&sc, metav1.ConditionTrue, ReasonScheduled, fmt.Sprintf("Next run at %s", next.Format(time.RFC3339)))
+	if err := r.patchStatus(ctx, &sc, original); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+}
+
+// earliest returns the time after which scheduled times are still due: the last scheduled
+// time (or the creation time), but no earlier than the starting deadline.
+func earliest(sc *iovv1alpha2.ScheduledVehicleCommand, now time.Time) time.Time {
+	last := sc.CreationTimestamp.Time
+	if sc.Status.LastScheduleTime != nil {
+		last = sc.Status.LastScheduleTime.Time
+	}
+	if d := sc.Spec.StartingDeadlineSeconds; d != nil {
+		if deadline := now.Add(-time.Duration(*d) * time.Second); deadline.After(last) {
+			last = deadline
+		}
+	}
+	return last
+}
+
+// mostRecent returns the latest scheduled time in (after, now]. Older missed times are
+// skipped: a recurring command only needs to run once to catch up.
+func mostRecent(sched *schedule, after, now time.Time) (time.Time, bool) {
+	var found time.Time
+	for t := sched.next(after); !t.IsZero() && !t.After(now); t = sched.next(t) {
+		found = t
+	}
+	return found, !found.IsZero()
+}
+
+// createCommands creates one VehicleCommand per selected vehicle for the scheduled time.
+// Command names are derived from the scheduled time, so retrying after a partial failure
+// does not create duplicates. Vehicles in maintenance mode or decommissioned (Lifecycle Retired)
+// are excluded from automated commands and skipped.
+func (r *Reconciler) createCommands(ctx context.Context, sc *iovv1alpha2.ScheduledVehicleCommand, selector labels.Selector, scheduled time.Time) (int, error) {
+	var vehicles iovv1alpha2.VehicleList
+	if err := r.List(ctx, &vehicles, client.InNamespace(sc.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, v := range vehicles.Items {
+		if v.Spec.MaintenanceMode || v.Spec.Lifecycle == iovv1alpha2.VehicleLifecycleRetired {
+			log.FromContext(ctx).V(1).Info("Skipping vehicle excluded from automated commands", "vehicle", v.Name,
+				"maintenanceMode", v.Spec.MaintenanceMode, "lifecycle", v.Spec.Lifecycle)
+			continue
+		}
+
+		cmd := &iovv1alpha2.VehicleCommand{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%d-%s", sc.Name, scheduled.Unix()/60, v.Name),
+				Namespace: sc.Namespace,
+				Labels:    map[string]string{iovv1alpha2.ScheduledCommandLabel: sc.Name},
+			},
+			Spec: iovv1alpha2.VehicleCommandSpec{
+				VehicleName:    v.Name,
+				Method:         sc.Spec.Method,
+				TemplateRef:    sc.Spec.TemplateRef,
+				Priority:       sc.Spec.Priority,
+				Parameters:     maps.Clone(sc.Spec.Parameters),
+				TimeoutSeconds: sc.Spec.TimeoutSeconds,
+			},
+		}
+		if err := controllerutil.SetControllerReference(sc, cmd, r.Scheme); err != nil {
+			return 0, err
+		}
+		if err := r.Create(ctx, cmd); err != nil && !apierrors.IsAlreadyExists(err) {
+			return 0, fmt.Errorf("failed to create command for vehicle %s: %w", v.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// invalid records a spec error. It is not retried: fixing the spec triggers a new reconcile.
+func (r *Reconciler) invalid(ctx context.Context, sc, original *iovv1alpha2.ScheduledVehicleCommand, err error) error {
+	sc.Status.NextScheduleTime = nil
+	setReadyCondition(sc, metav1.ConditionFalse, ReasonInvalidSpec, err.Error())
+	return r.patchStatus(ctx, sc, original)
+}
+
+func (r *Reconciler) patchStatus(ctx context.Context, sc, original *iovv1alpha2.ScheduledVehicleCommand) error {
+	if equality.Semantic.DeepEqual(original.Status, sc.Status) {
+		return nil
+	}
+	return r.Status().Patch(ctx, sc, client.MergeFrom(original))
+}
+
+func setReadyCondition(sc *iovv1alpha2.ScheduledVehicleCommand, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&sc.Status.Conditions, metav1.Condition{
+		Type:               iovv1alpha2.ConditionTypeReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: sc.Generation,
+	})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&iovv1alpha2.ScheduledVehicleCommand{}).
+		Complete(r)
+}
@@ -0,0 +1,182 @@
+package scheduledcommand
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func TestReconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	nightly := time.Date(2026, 1, 1, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		now          time.Time
+		suspend      bool
+		deadline     *int64
+		wantCommands int
+		wantLast     *time.Time
+		wantRequeue  time.Duration
+		wantReason   string
+	}{
+		{
+			name:        "not due yet",
+			now:         nightly.Add(-time.Hour),
+			wantRequeue: time.Hour,
+			wantReason:  ReasonScheduled,
+		},
+		{
+			name:         "due",
+			now:          nightly.Add(30 * time.Second),
+			wantCommands: 2,
+			wantLast:     &nightly,
+			wantRequeue:  24*time.Hour - 30*time.Second,
+			wantReason:   ReasonScheduled,
+		},
+		{
+			name:         "missed runs collapse into the latest",
+			now:          nightly.Add(72*time.Hour + time.Hour),
+			wantCommands: 2,
+			wantLast:     ptr.To(nightly.Add(72 * time.Hour)),
+			wantRequeue:  23 * time.Hour,
+			wantReason:   ReasonScheduled,
+		},
+		{
+			name:        "missed starting deadline",
+			now:         nightly.Add(30 * time.Minute),
+			deadline:    ptr.To[int64](600),
+			wantRequeue: 23*time.Hour + 30*time.Minute,
+			wantReason:  ReasonScheduled,
+		},
+		{
+			name:       "suspended",
+			now:        nightly.Add(30 * time.Second),
+			suspend:    true,
+			wantReason: ReasonSuspended,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := &iovv1alpha2.ScheduledVehicleCommand{
+				ObjectMeta: metav1.ObjectMeta{Name: "nightly-logs", Namespace: "default", UID: "sc-uid", CreationTimestamp: metav1.NewTime(created)},
+				Spec: iovv1alpha2.ScheduledVehicleCommandSpec{
+					Schedule:                "0 2 * * *",
+					Suspend:                 tt.suspend,
+					StartingDeadlineSeconds: tt.deadline,
+					Selector:                metav1.LabelSelector{MatchLabels: map[string]string{"fleet": "test"}},
+					Method:                  "UploadLogs",
+					Parameters:              map[string]string{"since": "24h"},
+				},
+			}
+			vehicles := []client.Object{
+				&iovv1alpha2.Vehicle{ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Labels: map[string]string{"fleet": "test"}}},
+				&iovv1alpha2.Vehicle{ObjectMeta: metav1.ObjectMeta{Name: "vh-2", Namespace: "default", Labels: map[string]string{"fleet": "test"}}},
+				&iovv1alpha2.Vehicle{ObjectMeta: metav1.ObjectMeta{Name: "vh-3", Namespace: "default", Labels: map[string]string{"fleet": "prod"}}},
+				// Selected, but excluded from automated commands.
+				&iovv1alpha2.Vehicle{
+					ObjectMeta: metav1.ObjectMeta{Name: "vh-4", Namespace: "default", Labels: map[string]string{"fleet": "test"}},
+					Spec:       iovv1alpha2.VehicleSpec{MaintenanceMode: true},
+				},
+				&iovv1alpha2.Vehicle{
+					ObjectMeta: metav1.ObjectMeta{Name: "vh-5", Namespace: "default", Labels: map[string]string{"fleet": "test"}},
+					Spec:       iovv1alpha2.VehicleSpec{Lifecycle: iovv1alpha2.VehicleLifecycleRetired},
+				},
+			}
+			cli := fake.NewClientBuilder().WithScheme(scheme).
+				WithObjects(vehicles...).WithObjects(sc).WithStatusSubresource(sc).Build()
+
+			r := NewReconciler(cli, scheme)
+			r.now = func() time.Time { return tt.now }
+			reconcile := func() ctrl.Result {
+				t.Helper()
+				res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(sc)})
+				if err != nil {
+					t.Fatalf("Reconcile() error = %v", err)
+				}
+				return res
+			}
+
+			if res := reconcile(); res.RequeueAfter != tt.wantRequeue {
+				t.Errorf("RequeueAfter = %v, want %v", res.RequeueAfter, tt.wantRequeue)
+			}
+			// A second reconcile at the same time must not schedule again.
+			reconcile()
+
+			var cmds iovv1alpha2.VehicleCommandList
+			if err := cli.List(context.Background(), &cmds, client.MatchingLabels{iovv1alpha2.ScheduledCommandLabel: sc.Name}); err != nil {
+				t.Fatal(err)
+			}
+			if len(cmds.Items) != tt.wantCommands {
+				t.Fatalf("commands = %d, want %d", len(cmds.Items), tt.wantCommands)
+			}
+			for _, c := range cmds.Items {
+				if c.Spec.VehicleName == "vh-4" || c.Spec.VehicleName == "vh-5" {
+					t.Errorf("command %s created for a vehicle excluded from automated commands", c.Name)
+				}
+				if c.Spec.Method != "UploadLogs" || c.Spec.Parameters["since"] != "24h" || !metav1.IsControlledBy(&c, sc) {
+					t.Errorf("command %s = %+v", c.Name, c.Spec)
+				}
+			}
+
+			var got iovv1alpha2.ScheduledVehicleCommand
+			if err := cli.Get(context.Background(), client.ObjectKeyFromObject(sc), &got); err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.wantLast == nil && got.Status.LastScheduleTime != nil:
+				t.Errorf("LastScheduleTime = %v, want unset", got.Status.LastScheduleTime)
+			case tt.wantLast != nil && (got.Status.LastScheduleTime == nil || !got.Status.LastScheduleTime.Time.Equal(*tt.wantLast)):
+				t.Errorf("LastScheduleTime = %v, want %v", got.Status.LastScheduleTime, tt.wantLast)
+			}
+			if got.Status.LastScheduledCount != int32(tt.wantCommands) {
+				t.Errorf("LastScheduledCount = %d, want %d", got.Status.LastScheduledCount, tt.wantCommands)
+			}
+			if cond := meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeReady); cond == nil || cond.Reason != tt.wantReason {
+				t.Errorf("Ready condition = %+v, want reason %s", cond, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestReconcileInvalidSchedule(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	sc := &iovv1alpha2.ScheduledVehicleCommand{
+		ObjectMeta: metav1.ObjectMeta{Name: "typo", Namespace: "default"},
+		Spec:       iovv1alpha2.ScheduledVehicleCommandSpec{Schedule: "0 25 * * *", Method: "UploadLogs"},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sc).WithStatusSubresource(sc).Build()
+
+	res, err := NewReconciler(cli, scheme).Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(sc)})
+	if err != nil || res.RequeueAfter != 0 {
+		t.Fatalf("Reconcile() = %+v, %v; want no requeue", res, err)
+	}
+
+	var got iovv1alpha2.ScheduledVehicleCommand
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(sc), &got); err != nil {
+		t.Fatal(err)
+	}
+	if cond := meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeReady); cond == nil || cond.Reason != ReasonInvalidSpec {
+		t.Errorf("Ready condition = %+v, want reason %s", cond, ReasonInvalidSpec)
+	}
+}
@@ -0,0 +1,61 @@
+package scheduledcommand
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@every 5m",
+	} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Errorf("parseSchedule(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// Thursday, 2026-01-01 10:30 UTC.
+	from := time.Date(2026, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{spec: "* * * * *", want: time.Date(2026, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{spec: "30 10 * * *", want: time.Date(2026, 1, 2, 10, 30, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", want: time.Date(2026, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{spec: "0 2 * * *", want: time.Date(2026, 1, 2, 2, 0, 0, 0, time.UTC)},
+		{spec: "0 9-17/4 * * *", want: time.Date(2026, 1, 1, 13, 0, 0, 0, time.UTC)},
+		{spec: "0 0 * * 1,3", want: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 * * 7", want: time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 15 * 1", want: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)}, // day-of-month OR day-of-week
+		{spec: "0 0 29 2 *", want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "@monthly", want: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "@hourly", want: time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{spec: "0 0 30 2 *", want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := parseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("parseSchedule() error = %v", err)
+			}
+			if got := s.next(from); !got.Equal(tt.want) {
+				t.Errorf("next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package vehicle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+const (
+	// ReasonCanarySoaking means the vehicle waits for the canaries to prove the new firmware.
+	ReasonCanarySoaking = "CanarySoaking"
+
+	// ReasonCanaryUnhealthy means a canary is unhealthy on the new firmware and the rollout is halted.
+	ReasonCanaryUnhealthy = "CanaryUnhealthy"
+)
+
+// canaryRecheckInterval is how often a held vehicle looks at the canaries again
+// when the remaining wait is not known (canary still updating, or unhealthy).
+const canaryRecheckInterval = time.Minute
+
+// CanaryGate holds the rollout of a firmware version to a model's fleet until the
+// canaries of that model (vehicles labelled with CanaryLabel) have run it healthily
+// for the soak period: installed, online, and sending heartbeats again.
+// Models without canaries targeting the version are not gated.
+//
+// A nil *CanaryGate admits everything.
+type CanaryGate struct {
+	reader client.Reader
+	soak   time.Duration
+
+	// now is overridable for tests.
+	now func() time.Time
+}
+
+// canaryVerdict is the outcome of a CanaryGate check. A zero verdict admits the vehicle.
+type canaryVerdict struct {
+	reason  string
+	message string
+	wait    time.Duration
+}
+
+// NewCanaryGate creates a gate requiring soak of healthy canary runtime.
+// A non-positive soak disables the gate.
+func NewCanaryGate(reader client.Reader, soak time.Duration) *CanaryGate {
+	if soak <= 0 {
+		return nil
+	}
+	return &CanaryGate{reader: reader, soak: soak, now: time.Now}
+}
+
+// Check reports whether v may start updating to its desired firmware.
+func (g *CanaryGate) Check(ctx context.Context, v *iovv1alpha2.Vehicle) (canaryVerdict, error) {
+	if g == nil || v.Labels[iovv1alpha2.CanaryLabel] == "true" {
+		return canaryVerdict{}, nil
+	}
+
+	var canaries iovv1alpha2.VehicleList
+	if err := g.reader.List(ctx, &canaries, client.InNamespace(v.Namespace), client.MatchingLabels{iovv1alpha2.CanaryLabel: "true"}); err != nil {
+		return canaryVerdict{}, err
+	}
+
+	version := v.Spec.Profile.Firmware.Version
+	now := g.now()
+
+	var installing bool
+	var wait time.Duration
+	for i := range canaries.Items {
+		c := &canaries.Items[i]
+		if c.Spec.VehicleModelRef != v.Spec.VehicleModelRef || c.Spec.Profile.Firmware.Version != version {
+			continue
+		}
+
+		if c.Status.UpgradeStatus.Phase == iovv1alpha2.VehiclePhaseFailed {
+			return unhealthyCanary(c, "failed to install %s", version), nil
+		}
+
+		if c.Status.Profile.Firmware.Version != version {
+			installing = true
+			wait = max(wait, canaryRecheckInterval)
+			continue
+		}
+
+		if !c.Status.Online {
+			return unhealthyCanary(c, "went offline on %s", version), nil
+		}
+
+		installed := canaryInstalledAt(c)
+		if remaining := installed.Add(g.soak).Sub(now); remaining > 0 {
+			wait = max(wait, remaining)
+			continue
+		}
+
+		if hb := c.Status.LastHeartbeatTime; hb == nil || hb.Time.Before(installed) {
+			return unhealthyCanary(c, "has not sent a heartbeat since installing %s", version), nil
+		}
+	}
+
+	if wait == 0 {
+		return canaryVerdict{}, nil
+	}
+
+	// Keep the message constant while waiting: a changing message would patch the status and retrigger the reconcile.
+	msg := fmt.Sprintf("Waiting for the canaries to run %s healthily for %s", version, g.soak)
+	if installing {
+		msg = fmt.Sprintf("Waiting for the canaries to install %s", version)
+	}
+	return canaryVerdict{reason: ReasonCanarySoaking, message: msg, wait: wait}, nil
+}
+
+// canaryInstalledAt returns when the canary finished installing its current firmware,
+// or the zero time if it has been running it since before this controller tracked it.
+func canaryInstalledAt(c *iovv1alpha2.Vehicle) time.Time {
+	cond := meta.FindStatusCondition(c.Status.Conditions, iovv1alpha2.ConditionTypeSynced)
+	if cond == nil || cond.Status != metav1.ConditionTrue {
+		return time.Time{}
+	}
+	return cond.LastTransitionTime.Time
+}
+
+func unhealthyCanary(c *iovv1alpha2.Vehicle, format string, args ...any) canaryVerdict {
+	return canaryVerdict{
+		reason:  ReasonCanaryUnhealthy,
+		message: fmt.Sprintf("Rollout halted: canary %s "+format, append([]any{c.Name}, args...)...),
+		wait:    canaryRecheckInterval,
+	}
+}
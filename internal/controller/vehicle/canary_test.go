@@ -0,0 +1,140 @@
+package vehicle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/notify"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+func TestReconcileCanaryGate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	ago := func(d time.Duration) *metav1.Time { return &metav1.Time{Time: now.Add(-d)} }
+
+	// canary returns a canary of model-3 that finished installing 2.0.0 `installed` ago.
+	canary := func(installed time.Duration, mutate func(c *iovv1alpha2.Vehicle)) *iovv1alpha2.Vehicle {
+		c := &iovv1alpha2.Vehicle{
+			ObjectMeta: metav1.ObjectMeta{Name: "canary-1", Namespace: "default", Labels: map[string]string{iovv1alpha2.CanaryLabel: "true"}},
+			Spec: iovv1alpha2.VehicleSpec{
+				VehicleModelRef: "model-3",
+				Profile:         iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "2.0.0"}},
+			},
+			Status: iovv1alpha2.VehicleStatus{
+				Online:            true,
+				LastHeartbeatTime: ago(time.Minute),
+				Profile:           iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "2.0.0"}},
+				UpgradeStatus:     iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhaseIdle},
+				Conditions: []metav1.Condition{{
+					Type: iovv1alpha2.ConditionTypeSynced, Status: metav1.ConditionTrue, Reason: "Synced", LastTransitionTime: *ago(installed),
+				}},
+			},
+		}
+		if mutate != nil {
+			mutate(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name       string
+		canary     *iovv1alpha2.Vehicle
+		wantReason string
+	}{
+		{name: "healthy canary after soak", canary: canary(2*time.Hour, nil)},
+		{name: "canary still soaking", canary: canary(10*time.Minute, nil), wantReason: ReasonCanarySoaking},
+		{
+			name: "canary still installing",
+			canary: canary(0, func(c *iovv1alpha2.Vehicle) {
+				c.Status.Profile.Firmware.Version = "1.0.0"
+				c.Status.UpgradeStatus.Phase = iovv1alpha2.VehiclePhasePending
+			}),
+			wantReason: ReasonCanarySoaking,
+		},
+		{
+			name:       "canary offline despite version match",
+			canary:     canary(2*time.Hour, func(c *iovv1alpha2.Vehicle) { c.Status.Online = false }),
+			wantReason: ReasonCanaryUnhealthy,
+		},
+		{
+			name:       "canary silent since the update",
+			canary:     canary(2*time.Hour, func(c *iovv1alpha2.Vehicle) { c.Status.LastHeartbeatTime = ago(3 * time.Hour) }),
+			wantReason: ReasonCanaryUnhealthy,
+		},
+		{
+			name: "canary failed to install",
+			canary: canary(0, func(c *iovv1alpha2.Vehicle) {
+				c.Status.Profile.Firmware.Version = "1.0.0"
+				c.Status.UpgradeStatus.Phase = iovv1alpha2.VehiclePhaseFailed
+			}),
+			wantReason: ReasonCanaryUnhealthy,
+		},
+		{
+			name:   "canary of another model",
+			canary: canary(10*time.Minute, func(c *iovv1alpha2.Vehicle) { c.Spec.VehicleModelRef = "model-y" }),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vehicle := &iovv1alpha2.Vehicle{
+				ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+				Spec: iovv1alpha2.VehicleSpec{
+					VehicleModelRef: "model-3",
+					Profile:         iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "2.0.0"}},
+				},
+				Status: iovv1alpha2.VehicleStatus{
+					Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}},
+					UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhaseIdle},
+				},
+			}
+			model := &iovv1alpha2.VehicleModel{ObjectMeta: metav1.ObjectMeta{Name: "model-3", Namespace: "default"}}
+
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(model, vehicle, tt.canary).
+				WithStatusSubresource(&iovv1alpha2.Vehicle{}).Build()
+			r := NewReconciler(cli, scheme, record.NewFakeRecorder(10), staticSwitch(false), notify.Discard, &options.VehicleOptions{CanarySoak: time.Hour})
+
+			res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(vehicle)})
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			var got iovv1alpha2.Vehicle
+			if err := cli.Get(context.Background(), client.ObjectKeyFromObject(vehicle), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantReason == "" {
+				if got.Status.UpgradeStatus.Phase != iovv1alpha2.VehiclePhasePending {
+					t.Errorf("phase = %s, want Pending", got.Status.UpgradeStatus.Phase)
+				}
+				return
+			}
+
+			if got.Status.UpgradeStatus.Phase != iovv1alpha2.VehiclePhaseIdle {
+				t.Errorf("phase = %s, want Idle while held", got.Status.UpgradeStatus.Phase)
+			}
+			if cond := meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeSynced); cond == nil || cond.Reason != tt.wantReason {
+				t.Errorf("Synced condition = %+v, want reason %s", cond, tt.wantReason)
+			}
+			if res.RequeueAfter <= 0 {
+				t.Error("a held vehicle must be requeued")
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package vehicle
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -11,9 +13,14 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/autopeer-io/autopeer/internal/controller/pause"
+	"github.com/autopeer-io/autopeer/internal/pkg/notify"
 	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+	"github.com/autopeer-io/autopeer/pkg/options"
 )
 
 type Reconciler struct {
@@ -24,24 +31,41 @@ type Reconciler struct {
 	// subReconcilers is the chain of business logic plugins.
 	// They are executed sequentially on each reconciliation.
 	subReconcilers []SubReconciler
+
+	// guard is shared with the state machine, to give back the rollout slot of an
+	// OTA start whose status patch failed.
+	guard *RolloutGuard
+
+	// offlineThreshold and offlineScanInterval configure the OnlineReaper.
+	offlineThreshold    time.Duration
+	offlineScanInterval time.Duration
 }
 
 // NewReconciler creates a new vehicle Reconciler.
 // This constructor follows the "encapsulated" pattern (vs. dependency injection)
 // by instantiating its own sub-reconciler chain. This simplifies
 // the registration in manager.go.
-func NewReconciler(cli client.Client, sche *runtime.Scheme, recorder record.EventRecorder) *Reconciler {
+func NewReconciler(cli client.Client, sche *runtime.Scheme, recorder record.EventRecorder, sw pause.Switch, notifier notify.Notifier, opts *options.VehicleOptions) *Reconciler {
 	r := &Reconciler{
 		Client:   cli,
 		Scheme:   sche,
 		Recorder: recorder,
+		guard:    NewRolloutGuard(cli, opts.RolloutLimit, opts.RolloutWindow),
+
+		offlineThreshold:    opts.OfflineThreshold,
+		offlineScanInterval: opts.OfflineScanInterval,
 	}
 
 	// This is the "plugin" registration.
 	// We can add more sub-reconcilers here (e.g., NewConfigReconciler())
 	// and they will be executed in order.
 	r.subReconcilers = []SubReconciler{
-		NewSubStateMachine(cli),
+		NewSubLifecycleGate(recorder),
+		NewSubModelValidator(cli),
+		NewSubPauseGate(sw),
+		NewSubMaintenanceGate(recorder),
+		NewSubStateMachine(cli, recorder, r.guard, NewCanaryGate(cli, opts.CanarySoak), notifier,
+			RetryBackoff{Limit: opts.RetryLimit, BaseDelay: opts.RetryBaseDelay, MaxDelay: opts.RetryMaxDelay}),
 	}
 
 	return r
@@ -51,6 +75,7 @@ func NewReconciler(cli client.Client, sche *runtime.Scheme, recorder record.Even
 // +kubebuilder:rbac:groups=iov.autopeer.io,resources=vehicles,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=iov.autopeer.io,resources=vehicles/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=iov.autopeer.io,resources=vehicles/finalizers,verbs=update
+// +kubebuilder:rbac:groups=iov.autopeer.io,resources=vehiclemodels,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is the core logic for the Vehicle controller.
@@ -89,13 +114,9 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	// This is the best practice for using r.Status().Patch().
 	// client.MergeFrom() will calculate the "diff" between originalVehicle
 	// and the modified 'vehicle' object.
+	// A RetryCount reset to 0 is omitted from the status (omitempty), so the merge patch removes the field.
 	originalVehicle := vehicle.DeepCopy()
 
-	// 【Hack】Force patch generation for RetryCount=0.
-	if originalVehicle.Status.UpgradeStatus.RetryCount == 0 {
-		originalVehicle.Status.UpgradeStatus.RetryCount = -1
-	}
-
 	// Handle Finalizer logic
 	if !vehicle.ObjectMeta.DeletionTimestamp.IsZero() {
 		return r.handleVehicleDeletion(ctx, logger, &vehicle, originalVehicle)
@@ -111,7 +132,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	var aggregatedResult ctrl.Result
 	for _, sub := range r.subReconcilers {
 		result, err := sub.Reconcile(ctx, &vehicle)
-		if err != nil {
+		if err != nil && !errors.Is(err, ErrHaltChain) {
 			logger.Error(err, "Sub-reconciler failed", "subReconciler", sub)
 			// Create a Kubernetes event to broadcast the failure
 			r.Recorder.Event(&vehicle, corev1.EventTypeWarning, "ReconcileFailed", err.Error())
@@ -128,6 +149,10 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 				aggregatedResult = result
 			}
 		}
+
+		if errors.Is(err, ErrHaltChain) {
+			break
+		}
 	}
 
 	// Compare and Patch Spec (if changed)
@@ -152,6 +177,12 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 
 		if err := r.Status().Patch(ctx, &vehicle, client.MergeFrom(originalVehicle)); err != nil {
 			logger.Error(err, "Failed to patch Vehicle Status")
+			started := oldPhase == iovv1alpha2.VehiclePhaseIdle ||
+				oldPhase == iovv1alpha2.VehiclePhaseFailed && newVersionRequested(originalVehicle)
+			if started && newPhase == iovv1alpha2.VehiclePhasePending {
+				// The OTA start was not persisted; the retry takes a new slot.
+				r.guard.Release(&vehicle)
+			}
 			return ctrl.Result{}, err
 		}
 
@@ -238,8 +269,34 @@ func (r *Reconciler) clearVehicle(ctx context.Context, v *iovv1alpha2.Vehicle) e
 }
 
 func (r *Reconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	if r.offlineThreshold > 0 {
+		reaper := NewOnlineReaper(mgr.GetClient(), mgr.GetLogger().WithName("online-reaper"), r.offlineThreshold, r.offlineScanInterval)
+		if err := mgr.Add(reaper); err != nil {
+			return err
+		}
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&iovv1alpha2.Vehicle{}).
 		Owns(&iovv1alpha2.VehicleCommand{}).
+		Watches(&iovv1alpha2.VehicleModel{}, handler.EnqueueRequestsFromMapFunc(r.vehiclesForModel)).
 		Complete(r)
 }
+
+// vehiclesForModel maps a VehicleModel event to the Vehicles that reference it,
+// so that they are re-validated when the model changes.
+func (r *Reconciler) vehiclesForModel(ctx context.Context, obj client.Object) []reconcile.Request {
+	var vehicles iovv1alpha2.VehicleList
+	if err := r.List(ctx, &vehicles, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list Vehicles for VehicleModel", "model", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, v := range vehicles.Items {
+		if v.Spec.VehicleModelRef == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&v)})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,124 @@
+package vehicle
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/notify"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+func TestReconcileStatusPatchRetryCount(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	newVehicle := func(phase iovv1alpha2.VehiclePhase, retryCount int32) *iovv1alpha2.Vehicle {
+		return &iovv1alpha2.Vehicle{
+			ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Generation: 1, Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+			Spec: iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{
+				Firmware:  iovv1alpha2.FirmwareConfig{Version: "3.0.0"},
+				OTAPolicy: iovv1alpha2.OTAPolicy{RetryLimit: ptr.To[int32](0)},
+			}},
+			Status: iovv1alpha2.VehicleStatus{
+				Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}},
+				UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: phase, RetryCount: retryCount, Path: []string{"2.0.0", "3.0.0"}},
+				Conditions: []metav1.Condition{{
+					Type: iovv1alpha2.ConditionTypeSynced, Status: metav1.ConditionFalse, Reason: "Updating",
+					ObservedGeneration: 1, LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Hour)),
+				}},
+			},
+		}
+	}
+	newCommand := func(name string, phase iovv1alpha2.CommandPhase) *iovv1alpha2.VehicleCommand {
+		return &iovv1alpha2.VehicleCommand{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     iovv1alpha2.VehicleCommandStatus{Phase: phase},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		vehicle        *iovv1alpha2.Vehicle
+		command        *iovv1alpha2.VehicleCommand
+		wantPatches    int
+		wantRetryCount int32
+	}{
+		{
+			// Only the condition changes while the command runs.
+			name:        "retry count 0, other fields change",
+			vehicle:     newVehicle(iovv1alpha2.VehiclePhasePending, 0),
+			command:     newCommand("ota-vh-1-2.0.0-0", iovv1alpha2.CommandPhaseRunning),
+			wantPatches: 1,
+		},
+		{
+			// The intermediate step is installed: the retry count of the next step starts over.
+			name:        "retry count reset to 0",
+			vehicle:     newVehicle(iovv1alpha2.VehiclePhasePending, 2),
+			command:     newCommand("ota-vh-1-2.0.0-2", iovv1alpha2.CommandPhaseSucceeded),
+			wantPatches: 1,
+		},
+		{
+			// Retries are disabled: the failed vehicle stays as it is.
+			name:    "retry count 0, nothing changes",
+			vehicle: newVehicle(iovv1alpha2.VehiclePhaseFailed, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patches []string
+			builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.vehicle).
+				WithStatusSubresource(&iovv1alpha2.Vehicle{}).
+				WithInterceptorFuncs(interceptor.Funcs{
+					SubResourcePatch: func(ctx context.Context, c client.Client, subResource string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+						data, err := patch.Data(obj)
+						if err != nil {
+							return err
+						}
+						patches = append(patches, string(data))
+						return c.SubResource(subResource).Patch(ctx, obj, patch, opts...)
+					},
+				})
+			if tt.command != nil {
+				builder = builder.WithObjects(tt.command)
+			}
+			cli := builder.Build()
+
+			r := NewReconciler(cli, scheme, record.NewFakeRecorder(10), staticSwitch(false), notify.Discard, &options.VehicleOptions{})
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(tt.vehicle)}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			if len(patches) != tt.wantPatches {
+				t.Fatalf("status patches = %q, want %d", patches, tt.wantPatches)
+			}
+			for _, p := range patches {
+				if strings.Contains(p, `"retryCount":-1`) {
+					t.Errorf("status patch %s writes a bogus retry count", p)
+				}
+			}
+
+			var got iovv1alpha2.Vehicle
+			if err := cli.Get(context.Background(), client.ObjectKeyFromObject(tt.vehicle), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.UpgradeStatus.RetryCount != tt.wantRetryCount {
+				t.Errorf("retry count = %d, want %d", got.Status.UpgradeStatus.RetryCount, tt.wantRetryCount)
+			}
+		})
+	}
+}
@@ -3,10 +3,14 @@ package vehicle
 import (
 	"context"
 	"fmt"
+	"maps"
 
 	"github.com/looplab/fsm"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/autopeer-io/autopeer/internal/pkg/metrics"
 	fsmutil "github.com/autopeer-io/autopeer/internal/pkg/util/fsm"
 	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
 )
@@ -24,35 +28,70 @@ const (
 	EventFinalize = "event_finalize"
 )
 
+// GuardReasonUpToDate means the desired firmware is already the reported one.
+const GuardReasonUpToDate = "UpToDate"
+
 type FiniteStateMachine struct {
 	*fsm.FSM
 }
 
-func NewFiniteStateMachine(initialstate string) *FiniteStateMachine {
-	f := &FiniteStateMachine{}
+// fsmDefinition is the set of events and callbacks a FiniteStateMachine is built from.
+type fsmDefinition struct {
+	events    fsm.Events
+	callbacks fsm.Callbacks
+}
+
+// FSMOption layers additional states, transitions or callbacks over the default state machine.
+type FSMOption func(*fsmDefinition)
 
-	events := fsm.Events{
-		{Name: EventUpdate, Src: []string{string(iovv1alpha2.VehiclePhaseIdle)}, Dst: string(iovv1alpha2.VehiclePhasePending)},
-		{Name: EventSuccess, Src: []string{string(iovv1alpha2.VehiclePhasePending)}, Dst: string(iovv1alpha2.VehiclePhaseSucceeded)},
-		{Name: EventFail, Src: []string{string(iovv1alpha2.VehiclePhasePending)}, Dst: string(iovv1alpha2.VehiclePhaseFailed)},
-		{Name: EventFinalize, Src: []string{string(iovv1alpha2.VehiclePhaseSucceeded)}, Dst: string(iovv1alpha2.VehiclePhaseIdle)},
+// WithEvents adds transitions. An event that already exists gains the new source states.
+func WithEvents(events ...fsm.EventDesc) FSMOption {
+	return func(d *fsmDefinition) {
+		d.events = append(d.events, events...)
+	}
+}
 
-		// Failed retry
-		{Name: EventRetry, Src: []string{string(iovv1alpha2.VehiclePhaseFailed)}, Dst: string(iovv1alpha2.VehiclePhasePending)},
+// WithCallbacks adds callbacks, replacing the default ones registered under the same key.
+func WithCallbacks(callbacks fsm.Callbacks) FSMOption {
+	return func(d *fsmDefinition) {
+		maps.Copy(d.callbacks, callbacks)
 	}
+}
 
-	callbacks := fsm.Callbacks{
-		// Guards (before_...): Decide if a transition is allowed
-		"before_" + EventUpdate: fsmutil.WrapEvent(f.GuardUpdateRequired),
+// NewFiniteStateMachine creates the upgrade state machine in initialstate. Without options
+// it has the default phases; opts layer extra states and transitions over them.
+func NewFiniteStateMachine(initialstate string, opts ...FSMOption) *FiniteStateMachine {
+	f := &FiniteStateMachine{}
 
-		// Side-Effects (enter_...): Set fields upon entering a state
-		"enter_" + string(iovv1alpha2.VehiclePhasePending):   fsmutil.WrapEvent(f.ActionEnterPending),
-		"enter_" + string(iovv1alpha2.VehiclePhaseSucceeded): fsmutil.WrapEvent(f.ActionEnterSucceeded),
-		"enter_" + string(iovv1alpha2.VehiclePhaseFailed):    fsmutil.WrapEvent(f.ActionEnterFailed),
-		"enter_" + string(iovv1alpha2.VehiclePhaseIdle):      fsmutil.WrapEvent(f.ActionEnterIdle),
+	d := &fsmDefinition{
+		events: fsm.Events{
+			{Name: EventUpdate, Src: []string{string(iovv1alpha2.VehiclePhaseIdle)}, Dst: string(iovv1alpha2.VehiclePhasePending)},
+			{Name: EventSuccess, Src: []string{string(iovv1alpha2.VehiclePhasePending)}, Dst: string(iovv1alpha2.VehiclePhaseSucceeded)},
+			{Name: EventFail, Src: []string{string(iovv1alpha2.VehiclePhasePending)}, Dst: string(iovv1alpha2.VehiclePhaseFailed)},
+			{Name: EventFinalize, Src: []string{string(iovv1alpha2.VehiclePhaseSucceeded)}, Dst: string(iovv1alpha2.VehiclePhaseIdle)},
+
+			// Failed retry
+			{Name: EventRetry, Src: []string{string(iovv1alpha2.VehiclePhaseFailed)}, Dst: string(iovv1alpha2.VehiclePhasePending)},
+		},
+		callbacks: fsm.Callbacks{
+			// Guards (before_...): Decide if a transition is allowed
+			"before_" + EventUpdate: fsmutil.WrapEvent(f.GuardUpdateRequired),
+
+			// Side-Effects (enter_...): Set fields upon entering a state
+			"enter_" + string(iovv1alpha2.VehiclePhasePending):   fsmutil.WrapEvent(f.ActionEnterPending),
+			"enter_" + string(iovv1alpha2.VehiclePhaseSucceeded): fsmutil.WrapEvent(f.ActionEnterSucceeded),
+			"enter_" + string(iovv1alpha2.VehiclePhaseFailed):    fsmutil.WrapEvent(f.ActionEnterFailed),
+			"enter_" + string(iovv1alpha2.VehiclePhaseIdle):      fsmutil.WrapEvent(f.ActionEnterIdle),
+
+			// Runs after the enter_<state> callbacks, for every transition.
+			"enter_state": fsmutil.WrapEvent(f.ActionRecordTransition),
+		},
+	}
+	for _, opt := range opts {
+		opt(d)
 	}
 
-	f.FSM = fsm.NewFSM(initialstate, events, callbacks)
+	f.FSM = fsm.NewFSM(initialstate, d.events, d.callbacks)
 	return f
 }
 
@@ -62,11 +101,19 @@ func (f *FiniteStateMachine) GuardUpdateRequired(ctx context.Context, e *fsm.Eve
 	v := e.Args[0].(*iovv1alpha2.Vehicle)
 	if !(isNewVersion(v)) {
 		// No update needed. Cancel the transition.
-		e.Cancel(fsm.NoTransitionError{})
+		cancelTransition(ctx, e, GuardReasonUpToDate)
 	}
 	return nil
 }
 
+// cancelTransition cancels the event from a guard. Cancellations are not errors (see
+// isFsmRealError), so they are counted and logged here to show why a vehicle does not advance.
+func cancelTransition(ctx context.Context, e *fsm.Event, reason string) {
+	metrics.VehicleFSMGuardCancellations.WithLabelValues(e.Event, reason).Inc()
+	log.FromContext(ctx).V(1).Info("FSM transition canceled by guard", "event", e.Event, "from", e.Src, "to", e.Dst, "reason", reason)
+	e.Cancel(fsm.NoTransitionError{})
+}
+
 // ActionEnterPending is a "Side-Effect" callback.
 // It resets the status for a new update attempt (either new or retry).
 func (f *FiniteStateMachine) ActionEnterPending(ctx context.Context, e *fsm.Event) error {
@@ -75,7 +122,9 @@ func (f *FiniteStateMachine) ActionEnterPending(ctx context.Context, e *fsm.Even
 	switch e.Event {
 	case EventUpdate:
 		// This is a NEW update (from Idle)
+		now := metav1.Now()
 		v.Status.UpgradeStatus.RetryCount = 0
+		v.Status.UpgradeStatus.StartTime = &now
 	case EventRetry:
 		// This is a RETRY (from Failed)
 		v.Status.UpgradeStatus.RetryCount++
@@ -91,7 +140,10 @@ func (f *FiniteStateMachine) ActionEnterPending(ctx context.Context, e *fsm.Even
 func (f *FiniteStateMachine) ActionEnterSucceeded(ctx context.Context, e *fsm.Event) error {
 	v := e.Args[0].(*iovv1alpha2.Vehicle)
 
+	recordAttempt(v, iovv1alpha2.VehiclePhaseSucceeded, "Firmware update applied successfully")
 	v.Status.Profile.Firmware.Version = v.Spec.Profile.Firmware.Version
+	v.Status.UpgradeStatus.Path = nil
+	v.Status.UpgradeStatus.Step = 0
 	SetCondition(v, iovv1alpha2.ConditionTypeReady, metav1.ConditionTrue, "Succeeded", "Firmware update applied successfully")
 	SetCondition(v, iovv1alpha2.ConditionTypeSynced, metav1.ConditionTrue, "Synced", fmt.Sprintf("Version %s is active", v.Spec.Profile.Firmware.Version))
 	return nil
@@ -110,11 +162,24 @@ func (f *FiniteStateMachine) ActionEnterFailed(ctx context.Context, e *fsm.Event
 	}
 	// We embed the spec version in the error message for the Reconcile loop's retry logic.
 	msg := fmt.Sprintf("Failed on version %s: %s", v.Spec.Profile.Firmware.Version, errMsg)
+	recordAttempt(v, iovv1alpha2.VehiclePhaseFailed, errMsg)
 	SetCondition(v, iovv1alpha2.ConditionTypeReady, metav1.ConditionFalse, "Failed", msg)
 	SetCondition(v, iovv1alpha2.ConditionTypeSynced, metav1.ConditionFalse, "SyncFailed", msg)
 	return nil
 }
 
+// ActionRecordTransition is a "Side-Effect" callback.
+// It appends the transition to the status, with the Ready message the state's callback set.
+func (f *FiniteStateMachine) ActionRecordTransition(ctx context.Context, e *fsm.Event) error {
+	v := e.Args[0].(*iovv1alpha2.Vehicle)
+	var msg string
+	if cond := meta.FindStatusCondition(v.Status.Conditions, iovv1alpha2.ConditionTypeReady); cond != nil {
+		msg = cond.Message
+	}
+	recordTransition(v, iovv1alpha2.VehiclePhase(e.Src), iovv1alpha2.VehiclePhase(e.Dst), e.Event, msg)
+	return nil
+}
+
 // ActionEnterIdle is a "Side-Effect" callback.
 func (f *FiniteStateMachine) ActionEnterIdle(ctx context.Context, e *fsm.Event) error {
 	v := e.Args[0].(*iovv1alpha2.Vehicle)
@@ -0,0 +1,256 @@
+package vehicle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/looplab/fsm"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/metrics"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func TestGuardCancellationCounted(t *testing.T) {
+	ctx := context.Background()
+	counter := metrics.VehicleFSMGuardCancellations.WithLabelValues(EventUpdate, GuardReasonUpToDate)
+	before := testutil.ToFloat64(counter)
+
+	v := &iovv1alpha2.Vehicle{
+		Spec:   iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}}},
+		Status: iovv1alpha2.VehicleStatus{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}}},
+	}
+	f := NewFiniteStateMachine(string(iovv1alpha2.VehiclePhaseIdle))
+	err := f.Event(ctx, EventUpdate, v)
+	if err == nil || isFsmRealError(err) {
+		t.Fatalf("Event() error = %v, want a guard cancellation", err)
+	}
+	if f.Current() != string(iovv1alpha2.VehiclePhaseIdle) {
+		t.Errorf("state = %s, want Idle", f.Current())
+	}
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("guard cancellations = %v, want 1", got)
+	}
+
+	// A transition the guard allows is not counted.
+	v.Spec.Profile.Firmware.Version = "2.0.0"
+	if err := f.Event(ctx, EventUpdate, v); err != nil {
+		t.Fatalf("Event() error = %v", err)
+	}
+	if f.Current() != string(iovv1alpha2.VehiclePhasePending) {
+		t.Errorf("state = %s, want Pending", f.Current())
+	}
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("guard cancellations = %v, want 1", got)
+	}
+}
+
+func TestFiniteStateMachineWithAddedState(t *testing.T) {
+	const (
+		paused      = "Paused"
+		eventPause  = "event_pause"
+		eventResume = "event_resume"
+	)
+	ctx := context.Background()
+
+	var entered int
+	f := NewFiniteStateMachine(string(iovv1alpha2.VehiclePhaseIdle),
+		WithEvents(
+			fsm.EventDesc{Name: eventPause, Src: []string{string(iovv1alpha2.VehiclePhasePending)}, Dst: paused},
+			fsm.EventDesc{Name: eventResume, Src: []string{paused}, Dst: string(iovv1alpha2.VehiclePhasePending)},
+		),
+		WithCallbacks(fsm.Callbacks{
+			"enter_" + paused: func(context.Context, *fsm.Event) { entered++ },
+		}),
+	)
+
+	v := &iovv1alpha2.Vehicle{
+		Spec: iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "2.0.0"}}},
+	}
+	// The default transitions and callbacks are kept.
+	for _, step := range []struct{ event, want string }{
+		{EventUpdate, string(iovv1alpha2.VehiclePhasePending)},
+		{eventPause, paused},
+		{eventResume, string(iovv1alpha2.VehiclePhasePending)},
+		{EventSuccess, string(iovv1alpha2.VehiclePhaseSucceeded)},
+	} {
+		if err := f.Event(ctx, step.event, v); err != nil {
+			t.Fatalf("Event(%s) error = %v", step.event, err)
+		}
+		if f.Current() != step.want {
+			t.Fatalf("after %s: state = %s, want %s", step.event, f.Current(), step.want)
+		}
+	}
+	if entered != 1 {
+		t.Errorf("enter_%s called %d times, want 1", paused, entered)
+	}
+	if v.Status.Profile.Firmware.Version != "2.0.0" {
+		t.Errorf("reported version = %q, want the default success callback to run", v.Status.Profile.Firmware.Version)
+	}
+
+	// The added event is only allowed from its source state.
+	if err := f.Event(ctx, eventPause, v); err == nil {
+		t.Error("pause from Succeeded succeeded, want error")
+	}
+}
+
+func TestUpgradeHistory(t *testing.T) {
+	ctx := context.Background()
+	v := &iovv1alpha2.Vehicle{
+		Spec:   iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "2.0.0"}}},
+		Status: iovv1alpha2.VehicleStatus{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}}},
+	}
+
+	// A failure and a successful retry are both recorded.
+	f := NewFiniteStateMachine(string(iovv1alpha2.VehiclePhaseIdle))
+	for _, step := range []struct {
+		event string
+		args  []any
+	}{
+		{EventUpdate, nil},
+		{EventFail, []any{"download failed"}},
+		{EventRetry, nil},
+		{EventSuccess, nil},
+	} {
+		if err := f.Event(ctx, step.event, append([]any{v}, step.args...)...); err != nil {
+			t.Fatalf("Event(%s) error = %v", step.event, err)
+		}
+	}
+	history := v.Status.UpgradeStatus.History
+	if len(history) != 2 {
+		t.Fatalf("history = %+v, want 2 attempts", history)
+	}
+	if got := history[0]; got.Outcome != iovv1alpha2.VehiclePhaseFailed || got.Message != "download failed" || got.FromVersion != "1.0.0" || got.ToVersion != "2.0.0" || got.StartTime == nil {
+		t.Errorf("first attempt = %+v", got)
+	}
+	if got := history[1]; got.Outcome != iovv1alpha2.VehiclePhaseSucceeded || got.RetryCount != 1 || got.FromVersion != "1.0.0" {
+		t.Errorf("second attempt = %+v", got)
+	}
+
+	// The history is capped, keeping the newest attempts.
+	for i := range iovv1alpha2.MaxUpgradeHistory + 3 {
+		v.Spec.Profile.Firmware.Version = fmt.Sprintf("3.0.%d", i)
+		recordAttempt(v, iovv1alpha2.VehiclePhaseFailed, "")
+	}
+	history = v.Status.UpgradeStatus.History
+	if len(history) != iovv1alpha2.MaxUpgradeHistory {
+		t.Fatalf("history has %d attempts, want %d", len(history), iovv1alpha2.MaxUpgradeHistory)
+	}
+	if first, last := history[0].ToVersion, history[len(history)-1].ToVersion; first != "3.0.3" || last != "3.0.12" {
+		t.Errorf("history spans %s..%s, want 3.0.3..3.0.12", first, last)
+	}
+}
+
+func TestPhaseTransitions(t *testing.T) {
+	ctx := context.Background()
+	v := &iovv1alpha2.Vehicle{
+		Spec:   iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "2.0.0"}}},
+		Status: iovv1alpha2.VehicleStatus{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}}},
+	}
+
+	f := NewFiniteStateMachine(string(iovv1alpha2.VehiclePhaseIdle))
+	for _, step := range []struct {
+		event string
+		args  []any
+	}{
+		{EventUpdate, nil},
+		{EventFail, []any{"download failed"}},
+		{EventRetry, nil},
+		{EventSuccess, nil},
+		{EventFinalize, nil},
+	} {
+		if err := f.Event(ctx, step.event, append([]any{v}, step.args...)...); err != nil {
+			t.Fatalf("Event(%s) error = %v", step.event, err)
+		}
+	}
+	// A canceled transition is not recorded.
+	if err := f.Event(ctx, EventUpdate, v); isFsmRealError(err) || err == nil {
+		t.Fatalf("Event(%s) error = %v, want a guard cancellation", EventUpdate, err)
+	}
+
+	var got []string
+	for _, tr := range v.Status.UpgradeStatus.Transitions {
+		got = append(got, fmt.Sprintf("%s->%s/%s", tr.From, tr.To, tr.Reason))
+	}
+	want := []string{
+		"Idle->Pending/" + EventUpdate,
+		"Pending->Failed/" + EventFail,
+		"Failed->Pending/" + EventRetry,
+		"Pending->Succeeded/" + EventSuccess,
+		"Succeeded->Idle/" + EventFinalize,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+	transitions := v.Status.UpgradeStatus.Transitions
+	if failed := transitions[1]; failed.Message != "Failed on version 2.0.0: download failed" || failed.Time.IsZero() {
+		t.Errorf("failure transition = %+v", failed)
+	}
+	for i := 1; i < len(transitions); i++ {
+		if transitions[i].Time.Before(&transitions[i-1].Time) {
+			t.Errorf("transition %d is older than the one before it", i)
+		}
+	}
+
+	// The transitions are capped, keeping the newest ones.
+	for i := range iovv1alpha2.MaxPhaseTransitions {
+		recordTransition(v, iovv1alpha2.VehiclePhaseFailed, iovv1alpha2.VehiclePhasePending, fmt.Sprintf("event_%d", i), "")
+	}
+	transitions = v.Status.UpgradeStatus.Transitions
+	if len(transitions) != iovv1alpha2.MaxPhaseTransitions {
+		t.Fatalf("got %d transitions, want %d", len(transitions), iovv1alpha2.MaxPhaseTransitions)
+	}
+	if first, last := transitions[0].Reason, transitions[len(transitions)-1].Reason; first != "event_0" || last != fmt.Sprintf("event_%d", iovv1alpha2.MaxPhaseTransitions-1) {
+		t.Errorf("transitions span %s..%s", first, last)
+	}
+}
+
+func TestFiniteStateMachineTransitionTable(t *testing.T) {
+	const (
+		idle      = string(iovv1alpha2.VehiclePhaseIdle)
+		pending   = string(iovv1alpha2.VehiclePhasePending)
+		succeeded = string(iovv1alpha2.VehiclePhaseSucceeded)
+		failed    = string(iovv1alpha2.VehiclePhaseFailed)
+	)
+	// want maps each state and event to the resulting state; a missing entry is an invalid event.
+	want := map[string]map[string]string{
+		idle:      {EventUpdate: pending},
+		pending:   {EventSuccess: succeeded, EventFail: failed},
+		succeeded: {EventFinalize: idle},
+		failed:    {EventRetry: pending},
+	}
+	events := []string{EventUpdate, EventSuccess, EventFail, EventRetry, EventFinalize}
+
+	for src, dsts := range want {
+		for _, event := range events {
+			t.Run(src+"/"+event, func(t *testing.T) {
+				v := &iovv1alpha2.Vehicle{
+					Spec:   iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "2.0.0"}}},
+					Status: iovv1alpha2.VehicleStatus{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}}},
+				}
+				f := NewFiniteStateMachine(src)
+				err := f.Event(context.Background(), event, v)
+
+				dst, ok := dsts[event]
+				if !ok {
+					var invalid fsm.InvalidEventError
+					if !errors.As(err, &invalid) || f.Current() != src {
+						t.Errorf("Event() error = %v, state %s; want an invalid event in %s", err, f.Current(), src)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Event() error = %v", err)
+				}
+				if f.Current() != dst {
+					t.Errorf("state = %s, want %s", f.Current(), dst)
+				}
+				if got := string(v.Status.UpgradeStatus.Transitions[0].To); got != dst {
+					t.Errorf("recorded transition to %s, want %s", got, dst)
+				}
+			})
+		}
+	}
+}
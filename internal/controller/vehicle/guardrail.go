@@ -0,0 +1,119 @@
+package vehicle
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// ReasonRolloutThrottled means the vehicle waits for the fleet-wide rollout guardrail.
+const ReasonRolloutThrottled = "RolloutThrottled"
+
+// RolloutGuard limits how many vehicles may move into an active OTA within a
+// sliding window, across all vehicles handled by this controller. It protects
+// the fleet against an accidental mass rollout (e.g. a bulk Spec edit).
+//
+// The window is kept in memory. After a controller restart it is rebuilt from the
+// persisted Status.UpgradeStatus.StartTime of the fleet, so a restart mid-rollout
+// does not reopen the window. A nil *RolloutGuard admits everything.
+type RolloutGuard struct {
+	reader client.Reader
+	limit  int
+	window time.Duration
+
+	// now is overridable for tests.
+	now func() time.Time
+
+	mu     sync.Mutex
+	starts []rolloutStart
+	seeded bool
+}
+
+// rolloutStart is one OTA start counted by the guard.
+type rolloutStart struct {
+	vehicle client.ObjectKey
+	at      time.Time
+}
+
+// NewRolloutGuard creates a guard admitting at most limit starts per window.
+// A non-positive limit disables the guard.
+func NewRolloutGuard(reader client.Reader, limit int, window time.Duration) *RolloutGuard {
+	if limit <= 0 {
+		return nil
+	}
+	return &RolloutGuard{reader: reader, limit: limit, window: window, now: time.Now}
+}
+
+// Admit reserves a slot for one OTA start. If the window is full, it returns
+// false and how long to wait until a slot frees up.
+// Vehicles annotated with RolloutOverrideAnnotation are always admitted, but still count.
+func (g *RolloutGuard) Admit(ctx context.Context, v *iovv1alpha2.Vehicle) (bool, time.Duration, error) {
+	if g == nil {
+		return true, 0, nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.seeded {
+		if err := g.seed(ctx); err != nil {
+			return false, 0, err
+		}
+	}
+
+	now := g.now()
+	cutoff := now.Add(-g.window)
+	for len(g.starts) > 0 && !g.starts[0].at.After(cutoff) {
+		g.starts = g.starts[1:]
+	}
+
+	if len(g.starts) >= g.limit && v.Annotations[iovv1alpha2.RolloutOverrideAnnotation] != "true" {
+		return false, g.starts[0].at.Sub(cutoff), nil
+	}
+
+	g.starts = append(g.starts, rolloutStart{vehicle: client.ObjectKeyFromObject(v), at: now})
+	return true, 0, nil
+}
+
+// Release gives back the latest slot reserved for the vehicle, for a start that did not
+// happen after all (e.g. the reconcile failed before the Pending phase was persisted).
+func (g *RolloutGuard) Release(v *iovv1alpha2.Vehicle) {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := client.ObjectKeyFromObject(v)
+	for i := len(g.starts) - 1; i >= 0; i-- {
+		if g.starts[i].vehicle == key {
+			g.starts = slices.Delete(g.starts, i, i+1)
+			return
+		}
+	}
+}
+
+// seed fills the window with the OTA starts persisted before this guard was created.
+func (g *RolloutGuard) seed(ctx context.Context) error {
+	var list iovv1alpha2.VehicleList
+	if err := g.reader.List(ctx, &list); err != nil {
+		return fmt.Errorf("failed to list vehicles for the rollout guardrail: %w", err)
+	}
+
+	cutoff := g.now().Add(-g.window)
+	for _, v := range list.Items {
+		if start := v.Status.UpgradeStatus.StartTime; start != nil && start.Time.After(cutoff) {
+			g.starts = append(g.starts, rolloutStart{vehicle: client.ObjectKeyFromObject(&v), at: start.Time})
+		}
+	}
+	slices.SortFunc(g.starts, func(a, b rolloutStart) int { return a.at.Compare(b.at) })
+	g.seeded = true
+	return nil
+}
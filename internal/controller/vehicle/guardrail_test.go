@@ -0,0 +1,299 @@
+package vehicle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/notify"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+func TestRolloutGuardWindow(t *testing.T) {
+	now := time.Now()
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	g := NewRolloutGuard(fake.NewClientBuilder().WithScheme(scheme).Build(), 2, time.Minute)
+	g.now = func() time.Time { return now }
+	v := &iovv1alpha2.Vehicle{}
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := g.Admit(context.Background(), v); !ok {
+			t.Fatalf("start %d should be admitted", i+1)
+		}
+	}
+
+	now = now.Add(20 * time.Second)
+	ok, wait, _ := g.Admit(context.Background(), v)
+	if ok || wait != 40*time.Second {
+		t.Fatalf("Admit() = %v, %v; want throttled for 40s", ok, wait)
+	}
+
+	// The first starts leave the window.
+	now = now.Add(wait)
+	if ok, _, _ := g.Admit(context.Background(), v); !ok {
+		t.Errorf("start should be admitted once the window slid")
+	}
+}
+
+func TestRolloutGuardDisabled(t *testing.T) {
+	g := NewRolloutGuard(nil, 0, time.Minute)
+	for i := 0; i < 1000; i++ {
+		if ok, _, _ := g.Admit(context.Background(), &iovv1alpha2.Vehicle{}); !ok {
+			t.Fatalf("a disabled guard must admit everything")
+		}
+	}
+}
+
+func TestRolloutGuardSurvivesRestart(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	// Before the restart, two vehicles started within the window and one long before it.
+	// Persisted timestamps have second precision.
+	now := time.Now().Truncate(time.Second)
+	started := func(name string, ago time.Duration) *iovv1alpha2.Vehicle {
+		start := metav1.NewTime(now.Add(-ago))
+		return &iovv1alpha2.Vehicle{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     iovv1alpha2.VehicleStatus{UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhasePending, StartTime: &start}},
+		}
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		started("vh-0", 50*time.Second),
+		started("vh-1", 30*time.Second),
+		started("vh-old", time.Hour),
+	).Build()
+
+	// The restarted controller builds a fresh guard.
+	g := NewRolloutGuard(cli, 2, time.Minute)
+	g.now = func() time.Time { return now }
+
+	ok, wait, err := g.Admit(context.Background(), &iovv1alpha2.Vehicle{})
+	if err != nil {
+		t.Fatalf("Admit() error = %v", err)
+	}
+	if ok || wait != 10*time.Second {
+		t.Fatalf("Admit() = %v, %v; want throttled for 10s by the starts before the restart", ok, wait)
+	}
+
+	now = now.Add(wait)
+	if ok, _, _ := g.Admit(context.Background(), &iovv1alpha2.Vehicle{}); !ok {
+		t.Errorf("start should be admitted once the oldest persisted start left the window")
+	}
+}
+
+func TestReconcileThrottlesFleetRollout(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	// A bulk edit moves 5 vehicles to v2.0.0; one of them is explicitly overridden.
+	var objs []client.Object
+	for i := 0; i < 5; i++ {
+		v := &iovv1alpha2.Vehicle{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("vh-%d", i), Namespace: "default", Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+			Spec:       iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v2.0.0"}}},
+			Status: iovv1alpha2.VehicleStatus{
+				Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v1.0.0"}},
+				UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhaseIdle},
+			},
+		}
+		if i == 4 {
+			v.Annotations = map[string]string{iovv1alpha2.RolloutOverrideAnnotation: "true"}
+		}
+		objs = append(objs, v)
+	}
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(&iovv1alpha2.Vehicle{}).Build()
+	recorder := record.NewFakeRecorder(20)
+	r := NewReconciler(cli, scheme, recorder, staticSwitch(false), notify.Discard, &options.VehicleOptions{RolloutLimit: 2, RolloutWindow: time.Hour})
+
+	for _, obj := range objs {
+		res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+		if err != nil {
+			t.Fatalf("Reconcile(%s) error = %v", obj.GetName(), err)
+		}
+
+		var v iovv1alpha2.Vehicle
+		if err := cli.Get(context.Background(), client.ObjectKeyFromObject(obj), &v); err != nil {
+			t.Fatal(err)
+		}
+
+		throttled := obj.GetName() == "vh-2" || obj.GetName() == "vh-3"
+		if throttled {
+			if v.Status.UpgradeStatus.Phase != iovv1alpha2.VehiclePhaseIdle {
+				t.Errorf("%s: phase = %s, want Idle while throttled", v.Name, v.Status.UpgradeStatus.Phase)
+			}
+			cond := meta.FindStatusCondition(v.Status.Conditions, iovv1alpha2.ConditionTypeSynced)
+			if cond == nil || cond.Reason != ReasonRolloutThrottled {
+				t.Errorf("%s: expected Synced condition with reason %s, got %+v", v.Name, ReasonRolloutThrottled, cond)
+			}
+			if res.RequeueAfter <= 0 {
+				t.Errorf("%s: throttled vehicle must be requeued", v.Name)
+			}
+		} else if v.Status.UpgradeStatus.Phase != iovv1alpha2.VehiclePhasePending {
+			t.Errorf("%s: phase = %s, want Pending", v.Name, v.Status.UpgradeStatus.Phase)
+		} else if v.Status.UpgradeStatus.StartTime == nil {
+			t.Errorf("%s: admitted vehicle must persist its StartTime", v.Name)
+		}
+	}
+
+	close(recorder.Events)
+	var throttleEvents int
+	for e := range recorder.Events {
+		if strings.Contains(e, ReasonRolloutThrottled) {
+			throttleEvents++
+		}
+	}
+	if throttleEvents != 2 {
+		t.Errorf("got %d %s events, want 2", throttleEvents, ReasonRolloutThrottled)
+	}
+}
+
+func TestReconcileThrottlesRetryWithNewVersion(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	started := &iovv1alpha2.Vehicle{
+		ObjectMeta: metav1.ObjectMeta{Name: "vh-0", Namespace: "default", Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+		Spec:       iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v2.0.0"}}},
+		Status: iovv1alpha2.VehicleStatus{
+			Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v1.0.0"}},
+			UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhaseIdle},
+		},
+	}
+	// The update to v2.0.0 failed, then the user moved the vehicle to v2.0.1.
+	failed := &iovv1alpha2.Vehicle{
+		ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Generation: 2, Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+		Spec:       iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v2.0.1"}}},
+		Status: iovv1alpha2.VehicleStatus{
+			Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v1.0.0"}},
+			UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhaseFailed, RetryCount: 3},
+			Conditions: []metav1.Condition{{
+				Type: iovv1alpha2.ConditionTypeSynced, Status: metav1.ConditionFalse, Reason: "Failed", ObservedGeneration: 1,
+				LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Hour)),
+			}},
+		},
+	}
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(started, failed).WithStatusSubresource(&iovv1alpha2.Vehicle{}).Build()
+	r := NewReconciler(cli, scheme, record.NewFakeRecorder(20), staticSwitch(false), notify.Discard, &options.VehicleOptions{RolloutLimit: 1, RolloutWindow: time.Hour})
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(started)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The retry waits for a slot like any other start, also once the hold recorded the new generation.
+	for i := 0; i < 2; i++ {
+		res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(failed)})
+		if err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+
+		var v iovv1alpha2.Vehicle
+		if err := cli.Get(context.Background(), client.ObjectKeyFromObject(failed), &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.Status.UpgradeStatus.Phase != iovv1alpha2.VehiclePhaseFailed {
+			t.Fatalf("reconcile %d: phase = %s, want Failed while throttled", i, v.Status.UpgradeStatus.Phase)
+		}
+		if cond := meta.FindStatusCondition(v.Status.Conditions, iovv1alpha2.ConditionTypeSynced); cond == nil || cond.Reason != ReasonRolloutThrottled {
+			t.Errorf("reconcile %d: expected Synced condition with reason %s, got %+v", i, ReasonRolloutThrottled, cond)
+		}
+		if res.RequeueAfter <= 0 {
+			t.Errorf("reconcile %d: throttled vehicle must be requeued", i)
+		}
+	}
+}
+
+func TestRolloutSlotReleasedOnFailedStart(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	newVehicle := func(name, model string) *iovv1alpha2.Vehicle {
+		return &iovv1alpha2.Vehicle{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+			Spec:       iovv1alpha2.VehicleSpec{VehicleModelRef: model, Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v2.0.0"}}},
+			Status: iovv1alpha2.VehicleStatus{
+				Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v1.0.0"}},
+				UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhaseIdle},
+			},
+		}
+	}
+
+	t.Run("planning fails", func(t *testing.T) {
+		cli := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if _, ok := obj.(*iovv1alpha2.VehicleModel); ok {
+					return errors.New("apiserver unavailable")
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}).Build()
+		guard := NewRolloutGuard(cli, 1, time.Hour)
+		sub := NewSubStateMachine(cli, record.NewFakeRecorder(10), guard, nil, notify.Discard, RetryBackoff{})
+
+		// Every requeue fails the same way: none of them may use up the single slot.
+		v := newVehicle("vh-0", "model-3")
+		for i := 0; i < 3; i++ {
+			if _, err := sub.Reconcile(context.Background(), v.DeepCopy()); err == nil {
+				t.Fatal("Reconcile() succeeded, want the planning error")
+			}
+		}
+		if ok, _, _ := guard.Admit(context.Background(), newVehicle("vh-1", "")); !ok {
+			t.Error("a failed planning used up the rollout slot")
+		}
+	})
+
+	t.Run("status patch fails", func(t *testing.T) {
+		failed := newVehicle("vh-0", "")
+		cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(failed, newVehicle("vh-1", "")).
+			WithStatusSubresource(&iovv1alpha2.Vehicle{}).
+			WithInterceptorFuncs(interceptor.Funcs{
+				SubResourcePatch: func(ctx context.Context, c client.Client, subResource string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+					if obj.GetName() == failed.Name {
+						return errors.New("conflict")
+					}
+					return c.SubResource(subResource).Patch(ctx, obj, patch, opts...)
+				},
+			}).Build()
+		r := NewReconciler(cli, scheme, record.NewFakeRecorder(10), staticSwitch(false), notify.Discard, &options.VehicleOptions{RolloutLimit: 1, RolloutWindow: time.Hour})
+
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(failed)}); err == nil {
+			t.Fatal("Reconcile() succeeded, want the patch error")
+		}
+		key := client.ObjectKey{Name: "vh-1", Namespace: "default"}
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		var v iovv1alpha2.Vehicle
+		if err := cli.Get(context.Background(), key, &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.Status.UpgradeStatus.Phase != iovv1alpha2.VehiclePhasePending {
+			t.Errorf("phase = %s, want Pending: the failed start must not hold the slot", v.Status.UpgradeStatus.Phase)
+		}
+	})
+}
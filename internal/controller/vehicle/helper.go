@@ -2,6 +2,7 @@ package vehicle
 
 import (
 	"errors"
+	"slices"
 
 	"github.com/looplab/fsm"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -29,6 +30,40 @@ func isFsmRealError(err error) bool {
 	return true
 }
 
+// recordAttempt appends the outcome of the current update attempt to the upgrade history,
+// dropping the oldest entries beyond MaxUpgradeHistory.
+func recordAttempt(v *iovv1alpha2.Vehicle, outcome iovv1alpha2.VehiclePhase, message string) {
+	status := &v.Status.UpgradeStatus
+	status.History = append(status.History, iovv1alpha2.UpgradeAttempt{
+		FromVersion:    v.Status.Profile.Firmware.Version,
+		ToVersion:      v.Spec.Profile.Firmware.Version,
+		Outcome:        outcome,
+		StartTime:      status.StartTime.DeepCopy(),
+		CompletionTime: metav1.Now(),
+		RetryCount:     status.RetryCount,
+		Message:        message,
+	})
+	if extra := len(status.History) - iovv1alpha2.MaxUpgradeHistory; extra > 0 {
+		status.History = slices.Delete(status.History, 0, extra)
+	}
+}
+
+// recordTransition appends a phase transition to the status, dropping the oldest
+// entries beyond MaxPhaseTransitions.
+func recordTransition(v *iovv1alpha2.Vehicle, from, to iovv1alpha2.VehiclePhase, reason, message string) {
+	status := &v.Status.UpgradeStatus
+	status.Transitions = append(status.Transitions, iovv1alpha2.PhaseTransition{
+		From:    from,
+		To:      to,
+		Time:    metav1.Now(),
+		Reason:  reason,
+		Message: message,
+	})
+	if extra := len(status.Transitions) - iovv1alpha2.MaxPhaseTransitions; extra > 0 {
+		status.Transitions = slices.Delete(status.Transitions, 0, extra)
+	}
+}
+
 // --- K8s Condition Helpers ---
 
 // SetCondition 辅助函数，用于设置 Vehicle 的 Condition
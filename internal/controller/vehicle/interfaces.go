@@ -2,12 +2,19 @@ package vehicle
 
 import (
 	"context"
+	"errors"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
 )
 
+// ErrHaltChain can be returned by a SubReconciler to stop the remaining
+// sub-reconcilers from running. Unlike other errors, it is not treated as a
+// failure: the main loop still patches the in-memory changes (e.g., a
+// Ready=False condition) and honors the returned RequeueAfter, if any.
+var ErrHaltChain = errors.New("halt sub-reconciler chain")
+
 // SubReconciler defines the interface for a modular reconciliation step.
 // Each sub-reconciler is responsible for one specific aspect of the Vehicle's logic
 // (e.g., state machine, config management, health checks).
@@ -0,0 +1,121 @@
+package vehicle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+const (
+	// ReasonHeartbeatTimeout is set on the Online condition when the vehicle has not sent a
+	// heartbeat within the offline threshold.
+	ReasonHeartbeatTimeout = "HeartbeatTimeout"
+
+	// ReasonHeartbeatResumed is set on the Online condition when an offline vehicle sends
+	// heartbeats again.
+	ReasonHeartbeatResumed = "HeartbeatResumed"
+
+	// ReasonDisconnected is set on the Online condition when the bridge marked the vehicle
+	// offline because its agent dropped the connection.
+	ReasonDisconnected = "Disconnected"
+)
+
+// OnlineReaper marks vehicles offline when their heartbeats stop.
+// The bridge sets Status.Online on each heartbeat, and clears it only when the agent's will
+// reports a dropped connection, so the reaper periodically sets Online=false on the vehicles
+// whose LastHeartbeatTime is older than Threshold, and follows the will on the Online condition. Connectivity is tracked on its own Online condition, so that it never hides
+// the reason of a failed Ready condition. It implements the manager.Runnable interface to
+// run in the background.
+type OnlineReaper struct {
+	Client    client.Client
+	Log       logr.Logger
+	Threshold time.Duration // e.g., 5 minutes
+	Interval  time.Duration // e.g., 1 minute
+
+	// now is overridable for tests.
+	now func() time.Time
+}
+
+// NewOnlineReaper creates an OnlineReaper that scans every interval for vehicles silent
+// for longer than threshold.
+func NewOnlineReaper(cli client.Client, log logr.Logger, threshold, interval time.Duration) *OnlineReaper {
+	return &OnlineReaper{Client: cli, Log: log, Threshold: threshold, Interval: interval, now: time.Now}
+}
+
+// Start begins the reaper loop.
+// It blocks until the context is cancelled.
+func (r *OnlineReaper) Start(ctx context.Context) error {
+	r.Log.Info("Starting Vehicle online reaper",
+		"threshold", r.Threshold,
+		"interval", r.Interval)
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.reap(ctx)
+		case <-ctx.Done():
+			r.Log.Info("Stopping Vehicle online reaper")
+			return nil
+		}
+	}
+}
+
+// reap marks the vehicles without a recent heartbeat offline, and sets the Online
+// condition of those that came back or that the bridge marked offline.
+func (r *OnlineReaper) reap(ctx context.Context) {
+	var vehicles iovv1alpha2.VehicleList
+	if err := r.Client.List(ctx, &vehicles); err != nil {
+		r.Log.Error(err, "Failed to list Vehicles for the online reaper")
+		return
+	}
+
+	reaped := 0
+	for i := range vehicles.Items {
+		v := &vehicles.Items[i]
+		original := v.DeepCopy()
+		offline := meta.IsStatusConditionFalse(v.Status.Conditions, iovv1alpha2.ConditionTypeOnline)
+		online := meta.IsStatusConditionTrue(v.Status.Conditions, iovv1alpha2.ConditionTypeOnline)
+		stale := v.Status.LastHeartbeatTime != nil && r.now().Sub(v.Status.LastHeartbeatTime.Time) > r.Threshold
+
+		switch {
+		case v.Status.Online && stale:
+			v.Status.Online = false
+			SetCondition(v, iovv1alpha2.ConditionTypeOnline, metav1.ConditionFalse, ReasonHeartbeatTimeout,
+				fmt.Sprintf("No heartbeat since %s", v.Status.LastHeartbeatTime.UTC().Format(time.RFC3339)))
+		case v.Status.Online && offline:
+			SetCondition(v, iovv1alpha2.ConditionTypeOnline, metav1.ConditionTrue, ReasonHeartbeatResumed, "Vehicle sends heartbeats again")
+		case !v.Status.Online && online:
+			SetCondition(v, iovv1alpha2.ConditionTypeOnline, metav1.ConditionFalse, ReasonDisconnected, "Vehicle disconnected")
+		default:
+			continue
+		}
+
+		// The optimistic lock turns a heartbeat that lands meanwhile into a conflict instead of
+		// overwriting it; the vehicle is checked again on the next tick.
+		patch := client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})
+		if err := r.Client.Status().Patch(ctx, v, patch); err != nil {
+			if !apierrors.IsConflict(err) {
+				r.Log.Error(err, "Failed to update Vehicle online status", "name", v.Name, "namespace", v.Namespace)
+			}
+			continue
+		}
+		if !v.Status.Online {
+			reaped++
+		}
+	}
+
+	if reaped > 0 {
+		r.Log.Info("Marked vehicles offline", "count", reaped)
+	}
+}
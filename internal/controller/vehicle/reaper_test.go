@@ -0,0 +1,101 @@
+package vehicle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func TestOnlineReaper(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	heartbeat := metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	// A vehicle with a failure on its Ready condition, which going offline must not hide.
+	failed := metav1.Condition{Type: iovv1alpha2.ConditionTypeReady, Status: metav1.ConditionFalse, Reason: ReasonModelNotFound, Message: "model missing", LastTransitionTime: heartbeat}
+	vehicle := &iovv1alpha2.Vehicle{
+		ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default"},
+		Status:     iovv1alpha2.VehicleStatus{Online: true, LastHeartbeatTime: &heartbeat, Conditions: []metav1.Condition{failed}},
+	}
+	// Online without a heartbeat time: there is nothing to measure the silence from.
+	unknown := &iovv1alpha2.Vehicle{
+		ObjectMeta: metav1.ObjectMeta{Name: "vh-2", Namespace: "default"},
+		Status:     iovv1alpha2.VehicleStatus{Online: true},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vehicle, unknown).
+		WithStatusSubresource(&iovv1alpha2.Vehicle{}).Build()
+	ctx := context.Background()
+
+	now := heartbeat.Add(5 * time.Minute)
+	r := NewOnlineReaper(cli, logr.Discard(), 5*time.Minute, time.Minute)
+	r.now = func() time.Time { return now }
+	get := func(obj *iovv1alpha2.Vehicle) *iovv1alpha2.Vehicle {
+		t.Helper()
+		var v iovv1alpha2.Vehicle
+		if err := cli.Get(ctx, client.ObjectKeyFromObject(obj), &v); err != nil {
+			t.Fatal(err)
+		}
+		return &v
+	}
+
+	// At the threshold, the vehicle is still online.
+	r.reap(ctx)
+	if got := get(vehicle); !got.Status.Online || meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeOnline) != nil {
+		t.Fatalf("vehicle at the threshold: online %v, conditions %+v", got.Status.Online, got.Status.Conditions)
+	}
+
+	// Past it, the vehicle is marked offline.
+	now = now.Add(time.Nanosecond)
+	r.reap(ctx)
+	got := get(vehicle)
+	cond := meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeOnline)
+	if got.Status.Online || cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != ReasonHeartbeatTimeout {
+		t.Fatalf("silent vehicle: online %v, Online condition %+v; want offline with False/%s", got.Status.Online, cond, ReasonHeartbeatTimeout)
+	}
+	if ready := meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeReady); ready == nil || ready.Reason != ReasonModelNotFound {
+		t.Errorf("silent vehicle: Ready condition %+v, want the %s failure kept", ready, ReasonModelNotFound)
+	}
+	if !get(unknown).Status.Online {
+		t.Error("vehicle without a heartbeat time was marked offline")
+	}
+
+	// A new heartbeat brings it back, and the reaper sets the Online condition again.
+	now = now.Add(time.Hour)
+	got.Status.Online = true
+	got.Status.LastHeartbeatTime = &metav1.Time{Time: now}
+	if err := cli.Status().Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	r.reap(ctx)
+	got = get(vehicle)
+	cond = meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeOnline)
+	if !got.Status.Online || cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != ReasonHeartbeatResumed {
+		t.Errorf("vehicle back online: online %v, Online condition %+v; want True/%s", got.Status.Online, cond, ReasonHeartbeatResumed)
+	}
+	if ready := meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeReady); ready == nil || ready.Status != metav1.ConditionFalse || ready.Reason != ReasonModelNotFound {
+		t.Errorf("vehicle back online: Ready condition %+v, want the %s failure kept", ready, ReasonModelNotFound)
+	}
+
+	// The bridge marks it offline when the agent's will arrives, before the heartbeat goes stale.
+	got.Status.Online = false
+	if err := cli.Status().Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	r.reap(ctx)
+	got = get(vehicle)
+	cond = meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeOnline)
+	if got.Status.Online || cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != ReasonDisconnected {
+		t.Errorf("disconnected vehicle: online %v, Online condition %+v; want False/%s", got.Status.Online, cond, ReasonDisconnected)
+	}
+}
@@ -0,0 +1,160 @@
+package vehicle
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/notify"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+// simAgent plays the vehicle agent in a simulation: it settles an OTA command in flight
+// by setting its status.
+type simAgent func(cmd *iovv1alpha2.VehicleCommand)
+
+// simTrace is the outcome of a simulation.
+type simTrace struct {
+	// Phases lists the phases the vehicle went through, starting with the initial one.
+	Phases []iovv1alpha2.VehiclePhase
+	// Steps is the number of reconciles it took.
+	Steps int
+}
+
+func (tr simTrace) String() string {
+	phases := make([]string, len(tr.Phases))
+	for i, p := range tr.Phases {
+		phases[i] = string(p)
+	}
+	return fmt.Sprintf("%s in %d steps", strings.Join(phases, "->"), tr.Steps)
+}
+
+// simulateToCompletion reconciles the vehicle until its OTA reaches a terminal phase
+// (Succeeded or Failed) or maxSteps reconciles. Between reconciles, agent settles the
+// commands still in flight, as the vehicle would. The reconciler's own requeues are not
+// waited for, so retry backoffs and holds simply take more steps.
+func simulateToCompletion(t *testing.T, cli client.Client, r *Reconciler, key client.ObjectKey, agent simAgent, maxSteps int) simTrace {
+	t.Helper()
+	ctx := context.Background()
+
+	phase := func() iovv1alpha2.VehiclePhase {
+		t.Helper()
+		var v iovv1alpha2.Vehicle
+		if err := cli.Get(ctx, key, &v); err != nil {
+			t.Fatal(err)
+		}
+		return v.Status.UpgradeStatus.Phase
+	}
+
+	var trace simTrace
+	if p := phase(); p != "" {
+		trace.Phases = append(trace.Phases, p)
+	}
+	for trace.Steps < maxSteps {
+		trace.Steps++
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("step %d: Reconcile() error = %v", trace.Steps, err)
+		}
+
+		p := phase()
+		if len(trace.Phases) == 0 || trace.Phases[len(trace.Phases)-1] != p {
+			trace.Phases = append(trace.Phases, p)
+		}
+		if p == iovv1alpha2.VehiclePhaseSucceeded || p == iovv1alpha2.VehiclePhaseFailed {
+			return trace
+		}
+
+		var cmds iovv1alpha2.VehicleCommandList
+		if err := cli.List(ctx, &cmds, client.InNamespace(key.Namespace)); err != nil {
+			t.Fatal(err)
+		}
+		for i := range cmds.Items {
+			cmd := &cmds.Items[i]
+			if cmd.Spec.VehicleName != key.Name || commandFinished(cmd.Status.Phase) {
+				continue
+			}
+			agent(cmd)
+			if err := cli.Status().Update(ctx, cmd); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	t.Fatalf("no terminal phase after %d steps: %v", maxSteps, trace)
+	return trace
+}
+
+func commandFinished(phase iovv1alpha2.CommandPhase) bool {
+	return slices.Contains([]iovv1alpha2.CommandPhase{iovv1alpha2.CommandPhaseSucceeded, iovv1alpha2.CommandPhaseFailed, iovv1alpha2.CommandPhaseTimeout}, phase)
+}
+
+// faultyAgent installs every update, except on vehicles whose name ends in 7.
+func faultyAgent(cmd *iovv1alpha2.VehicleCommand) {
+	if strings.HasSuffix(cmd.Spec.VehicleName, "7") {
+		cmd.Status.Phase = iovv1alpha2.CommandPhaseFailed
+		cmd.Status.Message = "simulated install failure"
+		return
+	}
+	cmd.Status.Phase = iovv1alpha2.CommandPhaseSucceeded
+}
+
+func TestSimulateOTA(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		vehicle     string
+		want        string
+		wantVersion string
+		wantOutcome iovv1alpha2.VehiclePhase
+	}{
+		{name: "happy path", vehicle: "vh-1", want: "Idle->Pending->Succeeded in 3 steps", wantVersion: "2.0.0", wantOutcome: iovv1alpha2.VehiclePhaseSucceeded},
+		{name: "install fails", vehicle: "vh-7", want: "Idle->Pending->Failed in 3 steps", wantVersion: "1.0.0", wantOutcome: iovv1alpha2.VehiclePhaseFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vehicle := &iovv1alpha2.Vehicle{
+				ObjectMeta: metav1.ObjectMeta{Name: tt.vehicle, Namespace: "default", Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+				Spec:       iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "2.0.0"}}},
+				Status: iovv1alpha2.VehicleStatus{
+					Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}},
+					UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhaseIdle},
+				},
+			}
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vehicle).
+				WithStatusSubresource(&iovv1alpha2.Vehicle{}, &iovv1alpha2.VehicleCommand{}).Build()
+			r := NewReconciler(cli, scheme, record.NewFakeRecorder(20), staticSwitch(false), notify.Discard, &options.VehicleOptions{})
+			key := client.ObjectKeyFromObject(vehicle)
+
+			trace := simulateToCompletion(t, cli, r, key, faultyAgent, 10)
+			if trace.String() != tt.want {
+				t.Errorf("trace = %v, want %s", trace, tt.want)
+			}
+
+			var got iovv1alpha2.Vehicle
+			if err := cli.Get(context.Background(), key, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.Profile.Firmware.Version != tt.wantVersion {
+				t.Errorf("reported version = %s, want %s", got.Status.Profile.Firmware.Version, tt.wantVersion)
+			}
+			history := got.Status.UpgradeStatus.History
+			if len(history) != 1 || history[0].Outcome != tt.wantOutcome {
+				t.Errorf("history = %+v, want one %s attempt", history, tt.wantOutcome)
+			}
+		})
+	}
+}
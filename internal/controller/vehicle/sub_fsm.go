@@ -4,28 +4,93 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/autopeer-io/autopeer/internal/pkg/notify"
 	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
 )
 
 // SubStateMachine 实现了 SubReconciler 接口
 type SubStateMachine struct {
 	client.Client
+	recorder record.EventRecorder
+
+	// guard throttles fleet-wide OTA starts (Idle -> Pending).
+	guard *RolloutGuard
+
+	// canary holds OTA starts until the model's canaries are healthy on the new firmware.
+	canary *CanaryGate
+
+	// notifier is told about OTA milestones (started, failure threshold, completed).
+	notifier notify.Notifier
+
+	// retry is the automatic retry policy of failed updates.
+	retry RetryBackoff
+}
+
+const (
+	// ReasonBatteryLow is set on the ReadyToInstall condition while the reported battery level is below the OTA policy.
+	ReasonBatteryLow = "BatteryLow"
+
+	// ReasonPreconditionsMet is set on the ReadyToInstall condition once a held update may proceed.
+	ReasonPreconditionsMet = "PreconditionsMet"
+
+	// installRecheck is how often an update held by its install preconditions re-reads the reported state.
+	installRecheck = 30 * time.Second
+)
+
+// RetryBackoff is the automatic retry policy of failed updates: exponential backoff with a ceiling.
+type RetryBackoff struct {
+	// Limit is how many retries a failed update gets before it is given up,
+	// unless the vehicle's OTAPolicy sets a RetryLimit.
+	Limit int32
+
+	// BaseDelay is the wait before the first retry; it doubles with each retry.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the wait between two retries. Zero leaves it uncapped.
+	MaxDelay time.Duration
+}
+
+// limit returns how many automatic retries a failed update of v gets.
+func (b RetryBackoff) limit(v *iovv1alpha2.Vehicle) int32 {
+	if limit := v.Spec.Profile.OTAPolicy.RetryLimit; limit != nil {
+		return max(*limit, 0)
+	}
+	return b.Limit
+}
+
+// delay returns the wait before the retry following retryCount retries, at most MaxDelay.
+// 1st retry (retryCount=0): 2^0 * base, 2nd retry: 2^1 * base, 3rd retry: 2^2 * base...
+func (b RetryBackoff) delay(retryCount int32) time.Duration {
+	ceiling := b.MaxDelay
+	if ceiling <= 0 {
+		ceiling = math.MaxInt64
+	}
+	// Clamp in floating point: with a high retry limit, 2^retryCount * base overflows a Duration,
+	// which would turn the wait negative and retry at once.
+	d := math.Pow(2, float64(max(retryCount, 0))) * float64(b.BaseDelay)
+	if d >= float64(ceiling) {
+		return ceiling
+	}
+	return time.Duration(d)
 }
 
 // NewStateMachine 创建一个新的 state machine sub-reconciler.
-func NewSubStateMachine(cli client.Client) SubReconciler {
-	return &SubStateMachine{Client: cli}
+func NewSubStateMachine(cli client.Client, recorder record.EventRecorder, guard *RolloutGuard, canary *CanaryGate, notifier notify.Notifier, retry RetryBackoff) SubReconciler {
+	return &SubStateMachine{Client: cli, recorder: recorder, guard: guard, canary: canary, notifier: notifier, retry: retry}
 }
 
 // Reconcile 实现了 SubReconciler 接口
@@ -41,17 +106,34 @@ func (s *SubStateMachine) Reconcile(ctx context.Context, v *iovv1alpha2.Vehicle)
 	}
 
 	var err error
-	f := NewFiniteStateMachine(string(v.Status.UpgradeStatus.Phase))
+	from := v.Status.UpgradeStatus.Phase
+	f := NewFiniteStateMachine(string(from))
 
 	// 根据当前状态触发事件
 	switch v.Status.UpgradeStatus.Phase {
 
 	case iovv1alpha2.VehiclePhaseIdle:
-		// (Active) Try to start an update.
+		// (Active) Try to start an update, within the fleet-wide rollout limit.
+		// Automatic retries (Failed -> Pending) are not throttled: the vehicle was admitted when it started.
+		admitted := false
+		if isNewVersion(v) {
+			if result, held, err := s.admitStart(ctx, v); err != nil || held {
+				return result, err
+			}
+			admitted = true
+		}
 		err = f.Event(ctx, EventUpdate, v)
+		if admitted && f.Current() != string(iovv1alpha2.VehiclePhasePending) {
+			s.guard.Release(v)
+		}
 
 	case iovv1alpha2.VehiclePhasePending:
-		err = s.handlePendingPhase(ctx, f, v)
+		var result ctrl.Result
+		result, err = s.handlePendingPhase(ctx, f, v)
+		if result.RequeueAfter > 0 {
+			// Held before the OTA command is created: the phase does not change.
+			return result, nil
+		}
 
 	case iovv1alpha2.VehiclePhaseSucceeded:
 		// (Active) Finalize the successful update.
@@ -68,7 +150,7 @@ func (s *SubStateMachine) Reconcile(ctx context.Context, v *iovv1alpha2.Vehicle)
 		}
 
 		// 1. Check for manual intervention (new firmware version)
-		if failedCond.ObservedGeneration < v.Generation {
+		if newVersionRequested(v) {
 			if !isNewVersion(v) {
 				// --- User wants to CANCEL ---
 				// e.g., Spec changed from v2.0.0 (Failed) -> v1.0.0 (Reported)
@@ -80,24 +162,26 @@ func (s *SubStateMachine) Reconcile(ctx context.Context, v *iovv1alpha2.Vehicle)
 
 			// --- User wants to RETRY with a new version ---
 			// e.g., Spec changed from v2.0.0 (Failed) -> v2.0.1
-			logger.Info("New firmware version specified by user, retrying update immediately.", "newGeneration", v.Generation)
+			// It is a new update: it goes through the same admission as one started from Idle.
+			logger.Info("New firmware version specified by user, retrying update.", "newGeneration", v.Generation)
+			if result, held, err := s.admitStart(ctx, v); err != nil || held {
+				return result, err
+			}
 			err = f.Event(ctx, EventRetry, v) // Trigger Failed -> Pending
+			if f.Current() != string(iovv1alpha2.VehiclePhasePending) {
+				s.guard.Release(v)
+			}
 			break
 		}
 
 		// 2. Check max retry count
-		const maxRetryCount = 5
-		if v.Status.UpgradeStatus.RetryCount >= maxRetryCount {
-			logger.Info("Max retry count reached. Giving up.", "attempts", v.Status.UpgradeStatus.RetryCount, "max", maxRetryCount)
+		if limit := s.retry.limit(v); v.Status.UpgradeStatus.RetryCount >= limit {
+			logger.Info("Max retry count reached. Giving up.", "attempts", v.Status.UpgradeStatus.RetryCount, "max", limit)
 			return ctrl.Result{}, nil // Do nothing
 		}
 
 		// 3. Calculate exponential backoff
-		const baseDelay = 1 * time.Minute
-		// 1st retry (RetryCount=0): 2^0 * 1m = 1m
-		// 2nd retry (RetryCount=1): 2^1 * 1m = 2m
-		// 3rd retry (RetryCount=2): 2^2 * 1m = 4m
-		backoffDuration := time.Duration(math.Pow(2, float64(v.Status.UpgradeStatus.RetryCount))) * baseDelay
+		backoffDuration := s.retry.delay(v.Status.UpgradeStatus.RetryCount)
 
 		elapsed := time.Since(failedCond.LastTransitionTime.Time)
 		if elapsed < backoffDuration {
@@ -122,23 +206,189 @@ func (s *SubStateMachine) Reconcile(ctx context.Context, v *iovv1alpha2.Vehicle)
 
 	// Sync FSM's internal state back to our CRD status.
 	v.Status.UpgradeStatus.Phase = iovv1alpha2.VehiclePhase(f.Current())
+	s.notifyMilestone(ctx, v, from)
 
 	// Return empty result. If the status changed, the main controller's
 	// Patch() will trigger the next Reconcile.
 	return ctrl.Result{}, nil
 }
 
-func (s *SubStateMachine) handlePendingPhase(ctx context.Context, f *FiniteStateMachine, v *iovv1alpha2.Vehicle) error {
+// admitStart runs the admission of an update to a new version: the canary gate, the upgrade
+// path and, last, the rollout slot, so that a failing step before it does not use one up.
+// held reports that the update must wait, for the returned result.
+func (s *SubStateMachine) admitStart(ctx context.Context, v *iovv1alpha2.Vehicle) (ctrl.Result, bool, error) {
+	if result, held, err := s.admitCanary(ctx, v); err != nil || held {
+		return result, held, err
+	}
+	if err := s.planUpgradePath(ctx, v); err != nil {
+		return ctrl.Result{}, false, err
+	}
+	return s.admitRollout(ctx, v)
+}
+
+// newVersionRequested reports whether the desired firmware of the failed vehicle v changed since
+// it failed, so that it starts a new update rather than waiting for an automatic retry.
+// The admission holding such an update sets the Synced condition: a hold keeps it a new update.
+func newVersionRequested(v *iovv1alpha2.Vehicle) bool {
+	cond := meta.FindStatusCondition(v.Status.Conditions, iovv1alpha2.ConditionTypeSynced)
+	if cond == nil {
+		return false
+	}
+	switch cond.Reason {
+	case ReasonRolloutThrottled, ReasonCanarySoaking, ReasonCanaryUnhealthy:
+		return true
+	}
+	return cond.ObservedGeneration < v.Generation
+}
+
+// admitRollout asks the rollout guardrail for a slot. If the vehicle is throttled, it
+// surfaces the wait on the Synced condition (and once as an event) and returns the requeue delay.
+func (s *SubStateMachine) admitRollout(ctx context.Context, v *iovv1alpha2.Vehicle) (ctrl.Result, bool, error) {
+	ok, wait, err := s.guard.Admit(ctx, v)
+	if err != nil || ok {
+		return ctrl.Result{}, false, err
+	}
+
+	// Keep the message constant: a changing message would patch the status and retrigger the reconcile.
+	msg := fmt.Sprintf("Fleet-wide OTA rollout limit reached, waiting for a free slot (annotate with %s=true to override)", iovv1alpha2.RolloutOverrideAnnotation)
+	if cond := meta.FindStatusCondition(v.Status.Conditions, iovv1alpha2.ConditionTypeSynced); cond == nil || cond.Reason != ReasonRolloutThrottled {
+		log.FromContext(ctx).Info("OTA start throttled by rollout guardrail", "targetVersion", v.Spec.Profile.Firmware.Version, "retryAfter", wait)
+		s.recorder.Event(v, corev1.EventTypeWarning, ReasonRolloutThrottled, msg)
+	}
+	SetCondition(v, iovv1alpha2.ConditionTypeSynced, metav1.ConditionFalse, ReasonRolloutThrottled, msg)
+
+	return ctrl.Result{RequeueAfter: wait}, true, nil
+}
+
+// planUpgradePath records the intermediate versions an update must go through when the
+// VehicleModel does not allow installing the desired firmware directly. A direct update has no path.
+func (s *SubStateMachine) planUpgradePath(ctx context.Context, v *iovv1alpha2.Vehicle) error {
+	v.Status.UpgradeStatus.Path = nil
+	v.Status.UpgradeStatus.Step = 0
+
+	from := v.Status.Profile.Firmware.Version
+	if v.Spec.VehicleModelRef == "" || from == "" {
+		return nil
+	}
+
+	var model iovv1alpha2.VehicleModel
+	if err := s.Get(ctx, types.NamespacedName{Namespace: v.Namespace, Name: v.Spec.VehicleModelRef}, &model); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if chain := upgradeChain(&model, from, v.Spec.Profile.Firmware.Version); len(chain) > 1 {
+		log.FromContext(ctx).Info("Firmware update needs intermediate versions", "path", chain)
+		v.Status.UpgradeStatus.Path = chain
+	}
+	return nil
+}
+
+// admitCanary holds the vehicle while the canaries have not proven its desired firmware.
+// Like admitRollout, it surfaces the hold on the Synced condition (and once as an event).
+func (s *SubStateMachine) admitCanary(ctx context.Context, v *iovv1alpha2.Vehicle) (ctrl.Result, bool, error) {
+	verdict, err := s.canary.Check(ctx, v)
+	if err != nil || verdict.reason == "" {
+		return ctrl.Result{}, false, err
+	}
+
+	if cond := meta.FindStatusCondition(v.Status.Conditions, iovv1alpha2.ConditionTypeSynced); cond == nil || cond.Reason != verdict.reason {
+		log.FromContext(ctx).Info("OTA start held by canary gate", "targetVersion", v.Spec.Profile.Firmware.Version, "reason", verdict.message, "retryAfter", verdict.wait)
+		eventType := corev1.EventTypeNormal
+		if verdict.reason == ReasonCanaryUnhealthy {
+			eventType = corev1.EventTypeWarning
+		}
+		s.recorder.Event(v, eventType, verdict.reason, verdict.message)
+	}
+	SetCondition(v, iovv1alpha2.ConditionTypeSynced, metav1.ConditionFalse, verdict.reason, verdict.message)
+
+	return ctrl.Result{RequeueAfter: verdict.wait}, true, nil
+}
+
+// admitInstall holds the OTA command of v while the vehicle reports a battery level below its
+// OTAPolicy.MinBatteryLevel, surfacing the hold on the ReadyToInstall condition (and once as an event).
+// A vehicle that does not report its battery level is let through: the agent checks it before installing.
+// State such as the gear is checked per command, from the RequiredState of the VehicleModel method.
+func (s *SubStateMachine) admitInstall(ctx context.Context, v *iovv1alpha2.Vehicle) (ctrl.Result, bool) {
+	cond := meta.FindStatusCondition(v.Status.Conditions, iovv1alpha2.ConditionTypeReadyToInstall)
+
+	minLevel := v.Spec.Profile.OTAPolicy.MinBatteryLevel
+	raw, reported := v.Status.Properties[iovv1alpha2.PropertyBatteryLevel]
+	level, err := strconv.Atoi(raw)
+	if minLevel == nil || !reported || err != nil || level >= int(*minLevel) {
+		if cond != nil && cond.Status == metav1.ConditionFalse {
+			log.FromContext(ctx).Info("Install preconditions met, resuming update", "batteryLevel", raw)
+			SetCondition(v, iovv1alpha2.ConditionTypeReadyToInstall, metav1.ConditionTrue, ReasonPreconditionsMet, "Vehicle meets the install preconditions")
+		}
+		return ctrl.Result{}, false
+	}
+
+	msg := fmt.Sprintf("Battery level %d%% is below the %d%% required by the OTA policy", level, *minLevel)
+	if cond == nil || cond.Reason != ReasonBatteryLow {
+		log.FromContext(ctx).Info("Update held by install preconditions", "batteryLevel", level, "minBatteryLevel", *minLevel, "retryAfter", installRecheck)
+		s.recorder.Event(v, corev1.EventTypeWarning, ReasonBatteryLow, msg)
+	}
+	SetCondition(v, iovv1alpha2.ConditionTypeReadyToInstall, metav1.ConditionFalse, ReasonBatteryLow, msg)
+
+	return ctrl.Result{RequeueAfter: installRecheck}, true
+}
+
+// notifyMilestone tells the notifier if the transition from the from phase reached a milestone.
+// Delivery runs in the background so that a slow webhook does not stall the reconcile.
+func (s *SubStateMachine) notifyMilestone(ctx context.Context, v *iovv1alpha2.Vehicle, from iovv1alpha2.VehiclePhase) {
+	status := v.Status.UpgradeStatus
+	if status.Phase == from {
+		return
+	}
+
+	e := &notify.Event{
+		Time:      time.Now().UTC(),
+		Namespace: v.Namespace,
+		Vehicle:   v.Name,
+		Model:     v.Spec.VehicleModelRef,
+		Version:   v.Spec.Profile.Firmware.Version,
+		Attempts:  status.RetryCount + 1,
+	}
+
+	switch {
+	case from == iovv1alpha2.VehiclePhaseIdle && status.Phase == iovv1alpha2.VehiclePhasePending:
+		e.Milestone = notify.MilestoneStarted
+		e.Message = fmt.Sprintf("Updating from %s", v.Status.Profile.Firmware.Version)
+	case status.Phase == iovv1alpha2.VehiclePhaseSucceeded:
+		e.Milestone = notify.MilestoneCompleted
+		e.Message = fmt.Sprintf("Version %s is active", e.Version)
+	case status.Phase == iovv1alpha2.VehiclePhaseFailed && status.RetryCount >= s.retry.limit(v):
+		e.Milestone = notify.MilestoneFailureThreshold
+		if cond := meta.FindStatusCondition(v.Status.Conditions, iovv1alpha2.ConditionTypeSynced); cond != nil {
+			e.Message = cond.Message
+		}
+	default:
+		return
+	}
+
+	logger := log.FromContext(ctx)
+	go func() {
+		if err := s.notifier.Notify(context.WithoutCancel(ctx), e); err != nil {
+			logger.Error(err, "Failed to notify OTA milestone", "milestone", e.Milestone)
+		}
+	}()
+}
+
+func (s *SubStateMachine) handlePendingPhase(ctx context.Context, f *FiniteStateMachine, v *iovv1alpha2.Vehicle) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
 	// TODO: FirmwareVersion 可能包含 K8s 资源名称不允许的字符，需要对版本号进行 Slugify 处理或使用 Hash
-	safeVersion := strings.ReplaceAll(v.Spec.Profile.Firmware.Version, "+", "-")
+	targetVersion := upgradeTarget(v)
+	safeVersion := strings.ReplaceAll(targetVersion, "+", "-")
 	cmdName := fmt.Sprintf("ota-%s-%s-%d", v.Name, safeVersion, v.Status.UpgradeStatus.RetryCount)
 
 	var cmd iovv1alpha2.VehicleCommand
 	if err := s.Get(ctx, types.NamespacedName{Namespace: v.Namespace, Name: cmdName}, &cmd); err != nil {
 		if !errors.IsNotFound(err) {
-			return err
+			return ctrl.Result{}, err
+		}
+
+		if result, held := s.admitInstall(ctx, v); held {
+			return result, nil
 		}
 
 		cmd = iovv1alpha2.VehicleCommand{
@@ -153,28 +403,40 @@ func (s *SubStateMachine) handlePendingPhase(ctx context.Context, f *FiniteState
 				VehicleName: v.Name,
 				Method:      "OTA", // TODO: VehicleModel
 				Parameters: map[string]string{
-					"version": v.Spec.Profile.Firmware.Version,
+					"version": targetVersion,
 				},
 			},
 		}
 
-		logger.Info("Creating new OTA Command", "command", cmdName, "targetVersion", v.Spec.Profile.Firmware.Version)
+		logger.Info("Creating new OTA Command", "command", cmdName, "targetVersion", targetVersion)
 		SetCondition(v, iovv1alpha2.ConditionTypeSynced, metav1.ConditionFalse, "Updating", "Creating new OTA Command")
-		return s.Create(ctx, &cmd)
+		return ctrl.Result{}, s.Create(ctx, &cmd)
 	}
 
 	switch cmd.Status.Phase {
 
 	case iovv1alpha2.CommandPhaseSucceeded:
-		return f.Event(ctx, EventSuccess, v)
+		if targetVersion != v.Spec.Profile.Firmware.Version {
+			// An intermediate step is installed: stay Pending and move on to the next one.
+			status := &v.Status.UpgradeStatus
+			v.Status.Profile.Firmware.Version = targetVersion
+			status.Step++
+			status.RetryCount = 0
+
+			logger.Info("Intermediate firmware installed", "version", targetVersion, "step", status.Step, "steps", len(status.Path))
+			msg := fmt.Sprintf("Installed intermediate version %s (step %d/%d)", targetVersion, status.Step, len(status.Path))
+			SetCondition(v, iovv1alpha2.ConditionTypeSynced, metav1.ConditionFalse, "Updating", msg)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, f.Event(ctx, EventSuccess, v)
 
-	case iovv1alpha2.CommandPhaseFailed:
-		return f.Event(ctx, EventFail, v, cmd.Status.Message)
+	case iovv1alpha2.CommandPhaseFailed, iovv1alpha2.CommandPhaseTimeout:
+		return ctrl.Result{}, f.Event(ctx, EventFail, v, cmd.Status.Message)
 
 	default:
 		msg := fmt.Sprintf("Waiting for OTA command. Phase: %s, Message: %s", cmd.Status.Phase, cmd.Status.Message)
 		SetCondition(v, iovv1alpha2.ConditionTypeSynced, metav1.ConditionFalse, "Updating", msg)
 	}
 
-	return nil
+	return ctrl.Result{}, nil
 }
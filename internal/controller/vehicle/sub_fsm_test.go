@@ -0,0 +1,425 @@
+package vehicle
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/notify"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+func TestReconcileMultiStepUpgrade(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	// 3.0.0 can only be installed on 2.0.0, so a vehicle on 1.0.0 goes through 2.0.0 first.
+	model := &iovv1alpha2.VehicleModel{
+		ObjectMeta: metav1.ObjectMeta{Name: "model-3", Namespace: "default"},
+		Spec: iovv1alpha2.VehicleModelSpec{
+			UpgradePaths: []iovv1alpha2.UpgradePath{{Version: "3.0.0", From: []string{"2.0.0"}}},
+		},
+	}
+	vehicle := &iovv1alpha2.Vehicle{
+		ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+		Spec: iovv1alpha2.VehicleSpec{
+			VehicleModelRef: "model-3",
+			Profile:         iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "3.0.0"}},
+		},
+		Status: iovv1alpha2.VehicleStatus{
+			Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}},
+			UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhaseIdle},
+		},
+	}
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(model, vehicle).
+		WithStatusSubresource(&iovv1alpha2.Vehicle{}, &iovv1alpha2.VehicleCommand{}).Build()
+	r := NewReconciler(cli, scheme, record.NewFakeRecorder(20), staticSwitch(false), notify.Discard, &options.VehicleOptions{})
+	ctx := context.Background()
+
+	reconcile := func() *iovv1alpha2.Vehicle {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(vehicle)}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		var v iovv1alpha2.Vehicle
+		if err := cli.Get(ctx, client.ObjectKeyFromObject(vehicle), &v); err != nil {
+			t.Fatal(err)
+		}
+		return &v
+	}
+	commands := func() map[string]string {
+		t.Helper()
+		var list iovv1alpha2.VehicleCommandList
+		if err := cli.List(ctx, &list); err != nil {
+			t.Fatal(err)
+		}
+		versions := make(map[string]string)
+		for _, cmd := range list.Items {
+			versions[cmd.Name] = cmd.Spec.Parameters["version"]
+		}
+		return versions
+	}
+	succeed := func(name string) {
+		t.Helper()
+		var cmd iovv1alpha2.VehicleCommand
+		if err := cli.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, &cmd); err != nil {
+			t.Fatal(err)
+		}
+		cmd.Status.Phase = iovv1alpha2.CommandPhaseSucceeded
+		if err := cli.Status().Update(ctx, &cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Idle -> Pending with the planned chain.
+	v := reconcile()
+	if v.Status.UpgradeStatus.Phase != iovv1alpha2.VehiclePhasePending {
+		t.Fatalf("phase = %s, want Pending", v.Status.UpgradeStatus.Phase)
+	}
+	if got := v.Status.UpgradeStatus.Path; len(got) != 2 || got[0] != "2.0.0" || got[1] != "3.0.0" {
+		t.Fatalf("path = %v, want [2.0.0 3.0.0]", got)
+	}
+
+	// Only the first step is started.
+	reconcile()
+	reconcile()
+	if got := commands(); len(got) != 1 || got["ota-vh-1-2.0.0-0"] != "2.0.0" {
+		t.Fatalf("commands = %v, want only the 2.0.0 step", got)
+	}
+
+	// The first step succeeds: the vehicle stays Pending on the intermediate version.
+	succeed("ota-vh-1-2.0.0-0")
+	v = reconcile()
+	if v.Status.UpgradeStatus.Phase != iovv1alpha2.VehiclePhasePending || v.Status.UpgradeStatus.Step != 1 {
+		t.Fatalf("phase = %s step = %d, want Pending at step 1", v.Status.UpgradeStatus.Phase, v.Status.UpgradeStatus.Step)
+	}
+	if v.Status.Profile.Firmware.Version != "2.0.0" {
+		t.Errorf("reported version = %s, want 2.0.0", v.Status.Profile.Firmware.Version)
+	}
+
+	// The second step starts only now.
+	reconcile()
+	if got := commands(); len(got) != 2 || got["ota-vh-1-3.0.0-0"] != "3.0.0" {
+		t.Fatalf("commands = %v, want the 3.0.0 step after the 2.0.0 one", got)
+	}
+
+	succeed("ota-vh-1-3.0.0-0")
+	v = reconcile()
+	if v.Status.UpgradeStatus.Phase != iovv1alpha2.VehiclePhaseSucceeded {
+		t.Fatalf("phase = %s, want Succeeded", v.Status.UpgradeStatus.Phase)
+	}
+	if v.Status.Profile.Firmware.Version != "3.0.0" || len(v.Status.UpgradeStatus.Path) != 0 {
+		t.Errorf("reported version = %s path = %v, want 3.0.0 and no path", v.Status.Profile.Firmware.Version, v.Status.UpgradeStatus.Path)
+	}
+}
+
+// recordingNotifier passes the notified milestones to a channel.
+type recordingNotifier chan *notify.Event
+
+func (n recordingNotifier) Notify(ctx context.Context, e *notify.Event) error {
+	n <- e
+	return nil
+}
+
+func TestReconcileNotifiesMilestones(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		retryCount    int32
+		commandPhase  iovv1alpha2.CommandPhase
+		wantMilestone notify.Milestone
+		wantMessage   string
+	}{
+		{name: "completion", commandPhase: iovv1alpha2.CommandPhaseSucceeded, wantMilestone: notify.MilestoneCompleted, wantMessage: "Version 2.0.0 is active"},
+		{name: "failure threshold", retryCount: options.NewVehicleOptions().RetryLimit, commandPhase: iovv1alpha2.CommandPhaseFailed, wantMilestone: notify.MilestoneFailureThreshold, wantMessage: "Failed on version 2.0.0: flash error"},
+		{name: "failure with retries left", retryCount: 1, commandPhase: iovv1alpha2.CommandPhaseFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vehicle := &iovv1alpha2.Vehicle{
+				ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+				Spec:       iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "2.0.0"}}},
+				Status: iovv1alpha2.VehicleStatus{
+					Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}},
+					UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhasePending, RetryCount: tt.retryCount},
+				},
+			}
+			cmd := &iovv1alpha2.VehicleCommand{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("ota-vh-1-2.0.0-%d", tt.retryCount), Namespace: "default"},
+				Status:     iovv1alpha2.VehicleCommandStatus{Phase: tt.commandPhase, Message: "flash error"},
+			}
+
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vehicle, cmd).
+				WithStatusSubresource(&iovv1alpha2.Vehicle{}).Build()
+			notifier := make(recordingNotifier, 1)
+			r := NewReconciler(cli, scheme, record.NewFakeRecorder(10), staticSwitch(false), notifier, options.NewVehicleOptions())
+
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(vehicle)}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			if tt.wantMilestone == "" {
+				select {
+				case e := <-notifier:
+					t.Fatalf("unexpected notification %+v", e)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+
+			select {
+			case e := <-notifier:
+				if e.Milestone != tt.wantMilestone || e.Vehicle != "vh-1" || e.Namespace != "default" || e.Version != "2.0.0" || e.Message != tt.wantMessage {
+					t.Errorf("notification = %+v, want %s for vh-1 on 2.0.0 with message %q", e, tt.wantMilestone, tt.wantMessage)
+				}
+				if e.Attempts != tt.retryCount+1 {
+					t.Errorf("attempts = %d, want %d", e.Attempts, tt.retryCount+1)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("no %s notification", tt.wantMilestone)
+			}
+		})
+	}
+}
+
+func TestReconcileHonorsRetryLimit(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		retryCount int32
+		wantPhase  iovv1alpha2.VehiclePhase
+	}{
+		{name: "retries left", retryCount: 1, wantPhase: iovv1alpha2.VehiclePhasePending},
+		{name: "limit reached", retryCount: 2, wantPhase: iovv1alpha2.VehiclePhaseFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vehicle := &iovv1alpha2.Vehicle{
+				ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Generation: 1, Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+				Spec: iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{
+					Firmware:  iovv1alpha2.FirmwareConfig{Version: "2.0.0"},
+					OTAPolicy: iovv1alpha2.OTAPolicy{RetryLimit: ptr.To[int32](2)},
+				}},
+				Status: iovv1alpha2.VehicleStatus{
+					Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}},
+					UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhaseFailed, RetryCount: tt.retryCount},
+					// Failed long enough ago for any backoff to have passed.
+					Conditions: []metav1.Condition{{
+						Type: iovv1alpha2.ConditionTypeSynced, Status: metav1.ConditionFalse, Reason: "SyncFailed",
+						ObservedGeneration: 1, LastTransitionTime: metav1.NewTime(time.Now().Add(-24 * time.Hour)),
+					}},
+				},
+			}
+
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vehicle).
+				WithStatusSubresource(&iovv1alpha2.Vehicle{}).Build()
+			r := NewReconciler(cli, scheme, record.NewFakeRecorder(10), staticSwitch(false), notify.Discard, &options.VehicleOptions{})
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(vehicle)}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			var got iovv1alpha2.Vehicle
+			if err := cli.Get(context.Background(), client.ObjectKeyFromObject(vehicle), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.UpgradeStatus.Phase != tt.wantPhase {
+				t.Errorf("phase = %s, want %s", got.Status.UpgradeStatus.Phase, tt.wantPhase)
+			}
+		})
+	}
+}
+
+func TestReconcileHonorsRetryOptions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &options.VehicleOptions{RetryLimit: 4, RetryBaseDelay: time.Minute, RetryMaxDelay: 5 * time.Minute}
+	tests := []struct {
+		name        string
+		retryCount  int32
+		failedAgo   time.Duration
+		wantPhase   iovv1alpha2.VehiclePhase
+		wantRequeue time.Duration
+	}{
+		// 2^1 * 1m.
+		{name: "backoff", retryCount: 1, wantPhase: iovv1alpha2.VehiclePhaseFailed, wantRequeue: 2 * time.Minute},
+		// 2^3 * 1m is capped to 5m.
+		{name: "backoff capped", retryCount: 3, wantPhase: iovv1alpha2.VehiclePhaseFailed, wantRequeue: 5 * time.Minute},
+		{name: "capped backoff passed", retryCount: 3, failedAgo: 6 * time.Minute, wantPhase: iovv1alpha2.VehiclePhasePending},
+		{name: "limit reached", retryCount: 4, failedAgo: 24 * time.Hour, wantPhase: iovv1alpha2.VehiclePhaseFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vehicle := &iovv1alpha2.Vehicle{
+				ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Generation: 1, Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+				Spec:       iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "2.0.0"}}},
+				Status: iovv1alpha2.VehicleStatus{
+					Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}},
+					UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhaseFailed, RetryCount: tt.retryCount},
+					Conditions: []metav1.Condition{{
+						Type: iovv1alpha2.ConditionTypeSynced, Status: metav1.ConditionFalse, Reason: "SyncFailed",
+						ObservedGeneration: 1, LastTransitionTime: metav1.NewTime(time.Now().Add(-tt.failedAgo)),
+					}},
+				},
+			}
+
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vehicle).
+				WithStatusSubresource(&iovv1alpha2.Vehicle{}).Build()
+			r := NewReconciler(cli, scheme, record.NewFakeRecorder(10), staticSwitch(false), notify.Discard, opts)
+			result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(vehicle)})
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			// The condition's LastTransitionTime has second precision.
+			if result.RequeueAfter > tt.wantRequeue || result.RequeueAfter < tt.wantRequeue-2*time.Second {
+				t.Errorf("RequeueAfter = %s, want about %s", result.RequeueAfter, tt.wantRequeue)
+			}
+
+			var got iovv1alpha2.Vehicle
+			if err := cli.Get(context.Background(), client.ObjectKeyFromObject(vehicle), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.UpgradeStatus.Phase != tt.wantPhase {
+				t.Errorf("phase = %s, want %s", got.Status.UpgradeStatus.Phase, tt.wantPhase)
+			}
+		})
+	}
+}
+
+func TestReconcileRetryBackoffCeiling(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	// A base delay above the ceiling, and retry counts far past the point where 2^n * base overflows.
+	opts := &options.VehicleOptions{RetryLimit: 100, RetryBaseDelay: time.Hour, RetryMaxDelay: 30 * time.Minute}
+	for _, retryCount := range []int32{0, 1, 4, 10, 30, 40, 63, 64, 99} {
+		vehicle := &iovv1alpha2.Vehicle{
+			ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Generation: 1, Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+			Spec:       iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "2.0.0"}}},
+			Status: iovv1alpha2.VehicleStatus{
+				Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}},
+				UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhaseFailed, RetryCount: retryCount},
+				Conditions: []metav1.Condition{{
+					Type: iovv1alpha2.ConditionTypeSynced, Status: metav1.ConditionFalse, Reason: "SyncFailed",
+					ObservedGeneration: 1, LastTransitionTime: metav1.Now(),
+				}},
+			},
+		}
+
+		cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vehicle).
+			WithStatusSubresource(&iovv1alpha2.Vehicle{}).Build()
+		r := NewReconciler(cli, scheme, record.NewFakeRecorder(10), staticSwitch(false), notify.Discard, opts)
+		result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(vehicle)})
+		if err != nil {
+			t.Fatalf("retry %d: Reconcile() error = %v", retryCount, err)
+		}
+		// The vehicle just failed: it always waits, never longer than the ceiling.
+		if result.RequeueAfter <= 0 || result.RequeueAfter > opts.RetryMaxDelay {
+			t.Errorf("retry %d: RequeueAfter = %s, want within (0, %s]", retryCount, result.RequeueAfter, opts.RetryMaxDelay)
+		}
+	}
+}
+
+func TestReconcileHoldsInstallOnLowBattery(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	vehicle := &iovv1alpha2.Vehicle{
+		ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+		Spec: iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{
+			Firmware:  iovv1alpha2.FirmwareConfig{Version: "2.0.0"},
+			OTAPolicy: iovv1alpha2.OTAPolicy{MinBatteryLevel: ptr.To[int32](50)},
+		}},
+		Status: iovv1alpha2.VehicleStatus{
+			Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "1.0.0"}},
+			Properties:    map[string]string{iovv1alpha2.PropertyBatteryLevel: "30"},
+			UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhasePending},
+		},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vehicle).
+		WithStatusSubresource(&iovv1alpha2.Vehicle{}).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := NewReconciler(cli, scheme, recorder, staticSwitch(false), notify.Discard, &options.VehicleOptions{})
+	ctx := context.Background()
+	cmdKey := client.ObjectKey{Namespace: "default", Name: "ota-vh-1-2.0.0-0"}
+
+	reconcile := func() (ctrl.Result, *iovv1alpha2.Vehicle) {
+		t.Helper()
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(vehicle)})
+		if err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		var v iovv1alpha2.Vehicle
+		if err := cli.Get(ctx, client.ObjectKeyFromObject(vehicle), &v); err != nil {
+			t.Fatal(err)
+		}
+		return result, &v
+	}
+
+	// The battery is too low: no OTA command until it recovers.
+	result, got := reconcile()
+	if result.RequeueAfter != installRecheck {
+		t.Errorf("RequeueAfter = %s, want %s", result.RequeueAfter, installRecheck)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeReadyToInstall)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != ReasonBatteryLow {
+		t.Fatalf("ReadyToInstall = %+v, want False/%s", cond, ReasonBatteryLow)
+	}
+	if got.Status.UpgradeStatus.Phase != iovv1alpha2.VehiclePhasePending {
+		t.Errorf("phase = %s, want Pending", got.Status.UpgradeStatus.Phase)
+	}
+	if err := cli.Get(ctx, cmdKey, &iovv1alpha2.VehicleCommand{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("OTA command created while held: %v", err)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("events = %d, want one for the hold", len(recorder.Events))
+	}
+
+	// The vehicle charges: the update proceeds.
+	got.Status.Properties[iovv1alpha2.PropertyBatteryLevel] = "60"
+	if err := cli.Status().Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	result, got = reconcile()
+	if result.RequeueAfter != 0 {
+		t.Errorf("RequeueAfter = %s, want none", result.RequeueAfter)
+	}
+	if cond := meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeReadyToInstall); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Errorf("ReadyToInstall = %+v, want True", cond)
+	}
+	if err := cli.Get(ctx, cmdKey, &iovv1alpha2.VehicleCommand{}); err != nil {
+		t.Errorf("OTA command not created after the battery recovered: %v", err)
+	}
+}
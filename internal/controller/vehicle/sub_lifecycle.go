@@ -0,0 +1,57 @@
+package vehicle
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+const (
+	// ReasonDecommissioned means the vehicle is no longer managed because its Lifecycle is Retired.
+	ReasonDecommissioned = "Decommissioned"
+
+	// ReasonRecommissioned means a decommissioned vehicle is managed again.
+	ReasonRecommissioned = "Recommissioned"
+)
+
+// SubLifecycleGate 实现了 SubReconciler 接口
+// It halts the chain while the vehicle is decommissioned (Lifecycle Retired): the vehicle is
+// no longer validated and the state machine neither creates commands nor advances phases.
+// Unlike deletion, the Vehicle and its status are kept, and setting it Active again resumes management.
+type SubLifecycleGate struct {
+	recorder record.EventRecorder
+}
+
+// NewSubLifecycleGate 创建一个新的 lifecycle gate sub-reconciler.
+func NewSubLifecycleGate(recorder record.EventRecorder) SubReconciler {
+	return &SubLifecycleGate{recorder: recorder}
+}
+
+// Reconcile 实现了 SubReconciler 接口
+func (s *SubLifecycleGate) Reconcile(ctx context.Context, v *iovv1alpha2.Vehicle) (ctrl.Result, error) {
+	cond := meta.FindStatusCondition(v.Status.Conditions, iovv1alpha2.ConditionTypeReady)
+	decommissioned := cond != nil && cond.Reason == ReasonDecommissioned
+
+	if v.Spec.Lifecycle != iovv1alpha2.VehicleLifecycleRetired {
+		if decommissioned {
+			log.FromContext(ctx).Info("Vehicle recommissioned, resuming management", "lifecycle", v.Spec.Lifecycle)
+			SetCondition(v, iovv1alpha2.ConditionTypeReady, metav1.ConditionTrue, ReasonRecommissioned, "Vehicle is managed again")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !decommissioned {
+		msg := "Vehicle is decommissioned: it is no longer updated or sent commands"
+		log.FromContext(ctx).Info("Vehicle decommissioned, holding state machine", "phase", v.Status.UpgradeStatus.Phase)
+		s.recorder.Event(v, corev1.EventTypeNormal, ReasonDecommissioned, msg)
+		SetCondition(v, iovv1alpha2.ConditionTypeReady, metav1.ConditionFalse, ReasonDecommissioned, msg)
+	}
+	return ctrl.Result{}, ErrHaltChain
+}
@@ -0,0 +1,94 @@
+package vehicle
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/notify"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+func TestReconcileDecommissioned(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	v := &iovv1alpha2.Vehicle{
+		ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+		Spec: iovv1alpha2.VehicleSpec{
+			Lifecycle: iovv1alpha2.VehicleLifecycleRetired,
+			Profile:   iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v2.0.0"}},
+		},
+		Status: iovv1alpha2.VehicleStatus{
+			Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v1.0.0"}},
+			UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: iovv1alpha2.VehiclePhaseIdle},
+		},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(v).WithStatusSubresource(v).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := NewReconciler(cli, scheme, recorder, staticSwitch(false), notify.Discard, &options.VehicleOptions{})
+	key := client.ObjectKeyFromObject(v)
+
+	// Reconcile twice: the decommissioning is reported once.
+	for range 2 {
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+
+	// The vehicle is kept with its status, but no OTA is started.
+	var got iovv1alpha2.Vehicle
+	if err := cli.Get(context.Background(), key, &got); err != nil {
+		t.Fatalf("decommissioned vehicle is gone: %v", err)
+	}
+	if got.Status.UpgradeStatus.Phase != iovv1alpha2.VehiclePhaseIdle {
+		t.Errorf("phase advanced to %s while decommissioned", got.Status.UpgradeStatus.Phase)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeReady)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != ReasonDecommissioned {
+		t.Errorf("Ready condition = %+v, want False/%s", cond, ReasonDecommissioned)
+	}
+	var cmds iovv1alpha2.VehicleCommandList
+	if err := cli.List(context.Background(), &cmds); err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds.Items) != 0 {
+		t.Errorf("created %d commands for a decommissioned vehicle", len(cmds.Items))
+	}
+	if n := len(recorder.Events); n != 1 {
+		t.Errorf("got %d events, want 1", n)
+	}
+
+	// Bringing the vehicle back into service resumes the state machine.
+	got.Spec.Lifecycle = iovv1alpha2.VehicleLifecycleActive
+	if err := cli.Update(context.Background(), &got); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile() after recommissioning error = %v", err)
+		}
+	}
+	if err := cli.Get(context.Background(), key, &got); err != nil {
+		t.Fatal(err)
+	}
+	if cond := meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeReady); cond != nil && cond.Reason == ReasonDecommissioned {
+		t.Errorf("Ready condition still %s after recommissioning", cond.Reason)
+	}
+	if err := cli.List(context.Background(), &cmds); err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds.Items) != 1 {
+		t.Errorf("got %d commands after recommissioning, want the OTA command", len(cmds.Items))
+	}
+}
@@ -0,0 +1,69 @@
+package vehicle
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+const (
+	// ReasonMaintenanceMode means the vehicle is excluded from automated commands by Spec.MaintenanceMode.
+	ReasonMaintenanceMode = "MaintenanceMode"
+
+	// ReasonCommandSkipped is the event reason for an OTA not started or advanced because of maintenance mode.
+	ReasonCommandSkipped = "CommandSkipped"
+)
+
+// SubMaintenanceGate 实现了 SubReconciler 接口
+// It halts the chain while the vehicle is in maintenance mode, so that the state
+// machine neither creates commands nor advances phases. Unlike SubPauseGate it
+// applies to one vehicle and is driven by its Spec, so no recheck is needed.
+type SubMaintenanceGate struct {
+	recorder record.EventRecorder
+}
+
+// NewSubMaintenanceGate 创建一个新的 maintenance gate sub-reconciler.
+func NewSubMaintenanceGate(recorder record.EventRecorder) SubReconciler {
+	return &SubMaintenanceGate{recorder: recorder}
+}
+
+// Reconcile 实现了 SubReconciler 接口
+func (s *SubMaintenanceGate) Reconcile(ctx context.Context, v *iovv1alpha2.Vehicle) (ctrl.Result, error) {
+	if !v.Spec.MaintenanceMode {
+		meta.RemoveStatusCondition(&v.Status.Conditions, iovv1alpha2.ConditionTypeMaintenanceMode)
+		return ctrl.Result{}, nil
+	}
+
+	skipped := skippedOTA(v)
+	msg := "Vehicle is in maintenance mode"
+	if skipped != "" {
+		msg = fmt.Sprintf("OTA to %s skipped: vehicle is in maintenance mode", skipped)
+	}
+
+	// Report each skipped OTA once: the message only changes when the desired version does.
+	if cond := meta.FindStatusCondition(v.Status.Conditions, iovv1alpha2.ConditionTypeMaintenanceMode); cond == nil || cond.Message != msg {
+		log.FromContext(ctx).Info("Vehicle in maintenance mode, holding state machine", "phase", v.Status.UpgradeStatus.Phase, "skippedVersion", skipped)
+		if skipped != "" {
+			s.recorder.Event(v, corev1.EventTypeNormal, ReasonCommandSkipped, msg)
+		}
+		SetCondition(v, iovv1alpha2.ConditionTypeMaintenanceMode, metav1.ConditionTrue, ReasonMaintenanceMode, msg)
+	}
+
+	return ctrl.Result{}, ErrHaltChain
+}
+
+// skippedOTA returns the firmware version the state machine would work on, or "" if there is none.
+func skippedOTA(v *iovv1alpha2.Vehicle) string {
+	if isNewVersion(v) || v.Status.UpgradeStatus.Phase == iovv1alpha2.VehiclePhasePending {
+		return v.Spec.Profile.Firmware.Version
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package vehicle
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/notify"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+func TestReconcileMaintenanceMode(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		phase iovv1alpha2.VehiclePhase
+	}{
+		{name: "idle with a new version", phase: iovv1alpha2.VehiclePhaseIdle},
+		{name: "pending without a command", phase: iovv1alpha2.VehiclePhasePending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &iovv1alpha2.Vehicle{
+				ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+				Spec: iovv1alpha2.VehicleSpec{
+					MaintenanceMode: true,
+					Profile:         iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v2.0.0"}},
+				},
+				Status: iovv1alpha2.VehicleStatus{
+					Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v1.0.0"}},
+					UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: tt.phase},
+				},
+			}
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(v).WithStatusSubresource(v).Build()
+			recorder := record.NewFakeRecorder(10)
+			r := NewReconciler(cli, scheme, recorder, staticSwitch(false), notify.Discard, &options.VehicleOptions{})
+			key := client.ObjectKeyFromObject(v)
+
+			// Reconcile twice: the skipped OTA is reported once.
+			for range 2 {
+				if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+					t.Fatalf("Reconcile() error = %v", err)
+				}
+			}
+
+			var got iovv1alpha2.Vehicle
+			if err := cli.Get(context.Background(), key, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.UpgradeStatus.Phase != tt.phase {
+				t.Errorf("phase advanced to %s in maintenance mode", got.Status.UpgradeStatus.Phase)
+			}
+			if !meta.IsStatusConditionTrue(got.Status.Conditions, iovv1alpha2.ConditionTypeMaintenanceMode) {
+				t.Errorf("expected MaintenanceMode=True condition, got %+v", got.Status.Conditions)
+			}
+
+			var cmds iovv1alpha2.VehicleCommandList
+			if err := cli.List(context.Background(), &cmds); err != nil {
+				t.Fatal(err)
+			}
+			if len(cmds.Items) != 0 {
+				t.Errorf("created %d commands in maintenance mode", len(cmds.Items))
+			}
+
+			close(recorder.Events)
+			var skipped int
+			for e := range recorder.Events {
+				if strings.Contains(e, ReasonCommandSkipped) && strings.Contains(e, "v2.0.0") {
+					skipped++
+				}
+			}
+			if skipped != 1 {
+				t.Errorf("got %d %s events, want 1", skipped, ReasonCommandSkipped)
+			}
+
+			// Leaving maintenance mode clears the condition and lets the state machine run again.
+			got.Spec.MaintenanceMode = false
+			if err := cli.Update(context.Background(), &got); err != nil {
+				t.Fatal(err)
+			}
+			r = NewReconciler(cli, scheme, record.NewFakeRecorder(10), staticSwitch(false), notify.Discard, &options.VehicleOptions{})
+			for range 2 {
+				if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+					t.Fatalf("Reconcile() after maintenance error = %v", err)
+				}
+			}
+			if err := cli.Get(context.Background(), key, &got); err != nil {
+				t.Fatal(err)
+			}
+			if meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeMaintenanceMode) != nil {
+				t.Errorf("MaintenanceMode condition not cleared")
+			}
+			if err := cli.List(context.Background(), &cmds); err != nil {
+				t.Fatal(err)
+			}
+			if len(cmds.Items) != 1 {
+				t.Errorf("got %d commands after maintenance, want the OTA command", len(cmds.Items))
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package vehicle
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+const (
+	// ReasonModelNotFound means the referenced VehicleModel does not exist.
+	ReasonModelNotFound = "ModelNotFound"
+	// ReasonModelValidationFailed means the Vehicle Spec violates its VehicleModel.
+	ReasonModelValidationFailed = "ModelValidationFailed"
+	// ReasonModelValidated means the Vehicle Spec conforms to its VehicleModel.
+	ReasonModelValidated = "ModelValidated"
+)
+
+// SubModelValidator 实现了 SubReconciler 接口
+// It validates Vehicle.Spec against the referenced VehicleModel and halts the
+// chain (with Ready=False) if the Spec is not permitted by the model, or if the
+// desired firmware cannot be installed on the running version.
+type SubModelValidator struct {
+	client.Client
+}
+
+// NewSubModelValidator 创建一个新的 model validator sub-reconciler.
+func NewSubModelValidator(cli client.Client) SubReconciler {
+	return &SubModelValidator{Client: cli}
+}
+
+// Reconcile 实现了 SubReconciler 接口
+func (s *SubModelValidator) Reconcile(ctx context.Context, v *iovv1alpha2.Vehicle) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if v.Spec.VehicleModelRef == "" {
+		return ctrl.Result{}, nil
+	}
+
+	var model iovv1alpha2.VehicleModel
+	if err := s.Get(ctx, types.NamespacedName{Namespace: v.Namespace, Name: v.Spec.VehicleModelRef}, &model); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("Referenced VehicleModel not found", "model", v.Spec.VehicleModelRef)
+		msg := fmt.Sprintf("VehicleModel %q not found", v.Spec.VehicleModelRef)
+		SetCondition(v, iovv1alpha2.ConditionTypeReady, metav1.ConditionFalse, ReasonModelNotFound, msg)
+		return ctrl.Result{}, ErrHaltChain
+	}
+
+	if problems := validateAgainstModel(v, &model); len(problems) > 0 {
+		msg := strings.Join(problems, "; ")
+		logger.Info("Vehicle spec rejected by VehicleModel", "model", model.Name, "reason", msg)
+		SetCondition(v, iovv1alpha2.ConditionTypeReady, metav1.ConditionFalse, ReasonModelValidationFailed, msg)
+		return ctrl.Result{}, ErrHaltChain
+	}
+
+	if msg := checkUpgradePath(v, &model); msg != "" {
+		logger.Info("Firmware update blocked by VehicleModel upgrade paths", "model", model.Name, "reason", msg)
+		SetCondition(v, iovv1alpha2.ConditionTypeReady, metav1.ConditionFalse, ReasonUpgradePathBlocked, msg)
+		return ctrl.Result{}, ErrHaltChain
+	}
+
+	// Clear a previous rejection once the Spec (or the model) has been fixed.
+	if cond := meta.FindStatusCondition(v.Status.Conditions, iovv1alpha2.ConditionTypeReady); cond != nil && cond.Status == metav1.ConditionFalse &&
+		(cond.Reason == ReasonModelNotFound || cond.Reason == ReasonModelValidationFailed || cond.Reason == ReasonUpgradePathBlocked) {
+		SetCondition(v, iovv1alpha2.ConditionTypeReady, metav1.ConditionTrue, ReasonModelValidated, "Vehicle conforms to its VehicleModel")
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// validateAgainstModel returns a human-readable list of the Spec fields not permitted by the model.
+func validateAgainstModel(v *iovv1alpha2.Vehicle, model *iovv1alpha2.VehicleModel) []string {
+	var problems []string
+
+	var unknown []string
+	for key := range v.Spec.Properties {
+		if !slices.ContainsFunc(model.Spec.Properties, func(p iovv1alpha2.ModelProperty) bool { return p.Name == key }) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		problems = append(problems, fmt.Sprintf("unknown properties: %s", strings.Join(unknown, ", ")))
+	}
+
+	if version := v.Spec.Profile.Firmware.Version; version != "" && len(model.Spec.FirmwareChannels) > 0 {
+		if !slices.ContainsFunc(model.Spec.FirmwareChannels, func(c iovv1alpha2.FirmwareChannel) bool { return slices.Contains(c.Versions, version) }) {
+			problems = append(problems, fmt.Sprintf("firmware version %q is not published on any channel", version))
+		}
+	}
+
+	policy, bounds := v.Spec.Profile.OTAPolicy, model.Spec.OTAPolicy
+	if policy.MinBatteryLevel != nil && bounds.MinBatteryLevel != nil && *policy.MinBatteryLevel < *bounds.MinBatteryLevel {
+		problems = append(problems, fmt.Sprintf("otaPolicy.minBatteryLevel must be >= %d", *bounds.MinBatteryLevel))
+	}
+	if policy.RetryLimit != nil && bounds.MaxRetryLimit != nil && *policy.RetryLimit > *bounds.MaxRetryLimit {
+		problems = append(problems, fmt.Sprintf("otaPolicy.retryLimit must be <= %d", *bounds.MaxRetryLimit))
+	}
+
+	return problems
+}
@@ -0,0 +1,181 @@
+package vehicle
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func TestSubModelValidator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	model := &iovv1alpha2.VehicleModel{
+		ObjectMeta: metav1.ObjectMeta{Name: "model-3", Namespace: "default"},
+		Spec: iovv1alpha2.VehicleModelSpec{
+			Properties: []iovv1alpha2.ModelProperty{{Name: "ambient_light_color"}},
+			FirmwareChannels: []iovv1alpha2.FirmwareChannel{
+				{Name: "stable", Versions: []string{"1.0.0", "1.1.0"}},
+			},
+		},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(model).Build()
+	sub := NewSubModelValidator(cli)
+
+	tests := []struct {
+		name       string
+		modelRef   string
+		properties map[string]string
+		version    string
+		wantHalt   bool
+		wantReason string
+	}{
+		{name: "no model reference", modelRef: "", properties: map[string]string{"anything": "x"}},
+		{name: "conforming spec", modelRef: "model-3", properties: map[string]string{"ambient_light_color": "blue"}, version: "1.1.0"},
+		{name: "unknown property key", modelRef: "model-3", properties: map[string]string{"turbo_mode": "on"}, wantHalt: true, wantReason: ReasonModelValidationFailed},
+		{name: "unpublished firmware", modelRef: "model-3", version: "9.9.9", wantHalt: true, wantReason: ReasonModelValidationFailed},
+		{name: "missing model", modelRef: "model-y", wantHalt: true, wantReason: ReasonModelNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &iovv1alpha2.Vehicle{
+				ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default"},
+				Spec: iovv1alpha2.VehicleSpec{
+					VehicleModelRef: tt.modelRef,
+					Properties:      tt.properties,
+					Profile:         iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: tt.version}},
+				},
+			}
+
+			_, err := sub.Reconcile(context.Background(), v)
+			if tt.wantHalt != errors.Is(err, ErrHaltChain) {
+				t.Fatalf("Reconcile() error = %v, wantHalt %v", err, tt.wantHalt)
+			}
+			if !tt.wantHalt && err != nil {
+				t.Fatalf("Reconcile() unexpected error: %v", err)
+			}
+
+			cond := meta.FindStatusCondition(v.Status.Conditions, iovv1alpha2.ConditionTypeReady)
+			if !tt.wantHalt {
+				if cond != nil {
+					t.Errorf("unexpected Ready condition: %+v", cond)
+				}
+				return
+			}
+			if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != tt.wantReason {
+				t.Errorf("Ready condition = %+v, want False/%s", cond, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestSubModelValidatorUpgradePaths(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	// 3.0.0 migrates the data partition written by 2.x, so it can only be installed on 2.0.0.
+	// 4.0.0 needs 3.5.0, which was never published, so no vehicle can reach it.
+	model := &iovv1alpha2.VehicleModel{
+		ObjectMeta: metav1.ObjectMeta{Name: "model-3", Namespace: "default"},
+		Spec: iovv1alpha2.VehicleModelSpec{
+			FirmwareChannels: []iovv1alpha2.FirmwareChannel{{Name: "stable", Versions: []string{"1.0.0", "2.0.0", "3.0.0", "4.0.0"}}},
+			UpgradePaths: []iovv1alpha2.UpgradePath{
+				{Version: "3.0.0", From: []string{"2.0.0"}},
+				{Version: "4.0.0", From: []string{"3.5.0"}},
+			},
+		},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(model).Build()
+	sub := NewSubModelValidator(cli)
+
+	tests := []struct {
+		name     string
+		phase    iovv1alpha2.VehiclePhase
+		running  string
+		desired  string
+		wantHalt bool
+	}{
+		{name: "unrestricted target", phase: iovv1alpha2.VehiclePhaseIdle, running: "1.0.0", desired: "2.0.0"},
+		{name: "allowed direct upgrade", phase: iovv1alpha2.VehiclePhaseIdle, running: "2.0.0", desired: "3.0.0"},
+		{name: "reachable through intermediate step", phase: iovv1alpha2.VehiclePhaseIdle, running: "1.0.0", desired: "3.0.0"},
+		{name: "blocked, no path", phase: iovv1alpha2.VehiclePhaseIdle, running: "1.0.0", desired: "4.0.0", wantHalt: true},
+		{name: "blocked retry after failure", phase: iovv1alpha2.VehiclePhaseFailed, running: "1.0.0", desired: "4.0.0", wantHalt: true},
+		{name: "update already in flight", phase: iovv1alpha2.VehiclePhasePending, running: "1.0.0", desired: "4.0.0"},
+		{name: "version not reported yet", phase: iovv1alpha2.VehiclePhaseIdle, desired: "4.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &iovv1alpha2.Vehicle{
+				ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default"},
+				Spec: iovv1alpha2.VehicleSpec{
+					VehicleModelRef: "model-3",
+					Profile:         iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: tt.desired}},
+				},
+				Status: iovv1alpha2.VehicleStatus{
+					Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: tt.running}},
+					UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: tt.phase},
+				},
+			}
+
+			_, err := sub.Reconcile(context.Background(), v)
+			if tt.wantHalt != errors.Is(err, ErrHaltChain) {
+				t.Fatalf("Reconcile() error = %v, wantHalt %v", err, tt.wantHalt)
+			}
+
+			cond := meta.FindStatusCondition(v.Status.Conditions, iovv1alpha2.ConditionTypeReady)
+			if !tt.wantHalt {
+				if cond != nil {
+					t.Errorf("unexpected Ready condition: %+v", cond)
+				}
+				return
+			}
+			if cond == nil || cond.Reason != ReasonUpgradePathBlocked || !strings.Contains(cond.Message, "[3.5.0]") {
+				t.Errorf("Ready condition = %+v, want %s naming the required version 3.5.0", cond, ReasonUpgradePathBlocked)
+			}
+		})
+	}
+}
+
+func TestUpgradeChain(t *testing.T) {
+	model := &iovv1alpha2.VehicleModel{
+		Spec: iovv1alpha2.VehicleModelSpec{
+			UpgradePaths: []iovv1alpha2.UpgradePath{
+				{Version: "2.0.0", From: []string{"1.5.0"}},
+				{Version: "3.0.0", From: []string{"2.0.0", "2.5.0"}},
+				{Version: "4.0.0", From: []string{"3.5.0"}},
+				{Version: "3.5.0", From: []string{"4.0.0"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		from, to string
+		want     []string
+	}{
+		{from: "1.5.0", to: "2.0.0", want: []string{"2.0.0"}},
+		{from: "1.0.0", to: "2.0.0", want: []string{"1.5.0", "2.0.0"}},
+		{from: "1.0.0", to: "3.0.0", want: []string{"2.5.0", "3.0.0"}},
+		{from: "1.0.0", to: "4.0.0", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := upgradeChain(model, tt.from, tt.to); !slices.Equal(got, tt.want) {
+			t.Errorf("upgradeChain(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
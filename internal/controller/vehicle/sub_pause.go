@@ -0,0 +1,45 @@
+package vehicle
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/autopeer-io/autopeer/internal/controller/pause"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// ReasonMaintenance means reconciliation is paused by the cluster-wide pause switch.
+const ReasonMaintenance = "Maintenance"
+
+// SubPauseGate 实现了 SubReconciler 接口
+// It halts the chain while the global pause switch is set, so that the state
+// machine neither creates commands nor advances phases. Sub-reconcilers before
+// it keep aggregating status.
+type SubPauseGate struct {
+	pause pause.Switch
+}
+
+// NewSubPauseGate 创建一个新的 pause gate sub-reconciler.
+func NewSubPauseGate(sw pause.Switch) SubReconciler {
+	return &SubPauseGate{pause: sw}
+}
+
+// Reconcile 实现了 SubReconciler 接口
+func (s *SubPauseGate) Reconcile(ctx context.Context, v *iovv1alpha2.Vehicle) (ctrl.Result, error) {
+	if !s.pause.Paused(ctx) {
+		meta.RemoveStatusCondition(&v.Status.Conditions, iovv1alpha2.ConditionTypePaused)
+		return ctrl.Result{}, nil
+	}
+
+	if !meta.IsStatusConditionTrue(v.Status.Conditions, iovv1alpha2.ConditionTypePaused) {
+		log.FromContext(ctx).Info("Reconciliation paused, holding state machine", "phase", v.Status.UpgradeStatus.Phase)
+		SetCondition(v, iovv1alpha2.ConditionTypePaused, metav1.ConditionTrue, ReasonMaintenance, "Reconciliation is paused cluster-wide")
+	}
+
+	// The switch is not watched: come back later to notice a resume.
+	return ctrl.Result{RequeueAfter: pause.RecheckInterval}, ErrHaltChain
+}
@@ -0,0 +1,92 @@
+package vehicle
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/autopeer-io/autopeer/internal/controller/pause"
+	"github.com/autopeer-io/autopeer/internal/pkg/notify"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+type staticSwitch bool
+
+func (s staticSwitch) Paused(context.Context) bool { return bool(s) }
+
+func TestReconcilePaused(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		phase iovv1alpha2.VehiclePhase
+	}{
+		{name: "idle with a new version", phase: iovv1alpha2.VehiclePhaseIdle},
+		{name: "pending without a command", phase: iovv1alpha2.VehiclePhasePending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &iovv1alpha2.Vehicle{
+				ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Finalizers: []string{iovv1alpha2.VehicleFinalizer}},
+				Spec:       iovv1alpha2.VehicleSpec{Profile: iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v2.0.0"}}},
+				Status: iovv1alpha2.VehicleStatus{
+					Profile:       iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v1.0.0"}},
+					UpgradeStatus: iovv1alpha2.UpgradeStatus{Phase: tt.phase},
+				},
+			}
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(v).WithStatusSubresource(v).Build()
+			r := NewReconciler(cli, scheme, record.NewFakeRecorder(10), staticSwitch(true), notify.Discard, options.NewVehicleOptions())
+
+			res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(v)})
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			if res.RequeueAfter != pause.RecheckInterval {
+				t.Errorf("RequeueAfter = %v, want %v", res.RequeueAfter, pause.RecheckInterval)
+			}
+
+			var got iovv1alpha2.Vehicle
+			if err := cli.Get(context.Background(), client.ObjectKeyFromObject(v), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.UpgradeStatus.Phase != tt.phase {
+				t.Errorf("phase advanced to %s while paused", got.Status.UpgradeStatus.Phase)
+			}
+			if !meta.IsStatusConditionTrue(got.Status.Conditions, iovv1alpha2.ConditionTypePaused) {
+				t.Errorf("expected Paused=True condition, got %+v", got.Status.Conditions)
+			}
+
+			var cmds iovv1alpha2.VehicleCommandList
+			if err := cli.List(context.Background(), &cmds); err != nil {
+				t.Fatal(err)
+			}
+			if len(cmds.Items) != 0 {
+				t.Errorf("created %d commands while paused", len(cmds.Items))
+			}
+
+			// Resuming clears the condition and lets the state machine run again.
+			r = NewReconciler(cli, scheme, record.NewFakeRecorder(10), staticSwitch(false), notify.Discard, options.NewVehicleOptions())
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(v)}); err != nil {
+				t.Fatalf("Reconcile() after resume error = %v", err)
+			}
+			if err := cli.Get(context.Background(), client.ObjectKeyFromObject(v), &got); err != nil {
+				t.Fatal(err)
+			}
+			if meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypePaused) != nil {
+				t.Errorf("Paused condition not cleared after resume")
+			}
+		})
+	}
+}
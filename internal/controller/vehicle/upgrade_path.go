@@ -0,0 +1,108 @@
+package vehicle
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// ReasonUpgradePathBlocked means the desired firmware cannot be installed on the running version.
+const ReasonUpgradePathBlocked = "UpgradePathBlocked"
+
+// upgradeSources returns the versions that may upgrade directly to version, or nil if any may.
+func upgradeSources(model *iovv1alpha2.VehicleModel, version string) []string {
+	i := slices.IndexFunc(model.Spec.UpgradePaths, func(p iovv1alpha2.UpgradePath) bool { return p.Version == version })
+	if i < 0 {
+		return nil
+	}
+	return model.Spec.UpgradePaths[i].From
+}
+
+// directUpgradeAllowed reports whether a vehicle running from may install to directly.
+func directUpgradeAllowed(model *iovv1alpha2.VehicleModel, from, to string) bool {
+	sources := upgradeSources(model, to)
+	return sources == nil || slices.Contains(sources, from)
+}
+
+// upgradeChain returns the shortest sequence of versions that takes a vehicle running from
+// to the firmware to, ending with to, or nil if the compatibility matrix allows none.
+// Intermediate steps are limited to the versions published on the model's channels,
+// or to the versions named in the matrix if the model has no channels.
+func upgradeChain(model *iovv1alpha2.VehicleModel, from, to string) []string {
+	if directUpgradeAllowed(model, from, to) {
+		return []string{to}
+	}
+
+	var candidates []string
+	if len(model.Spec.FirmwareChannels) > 0 {
+		for _, c := range model.Spec.FirmwareChannels {
+			candidates = append(candidates, c.Versions...)
+		}
+	} else {
+		for _, p := range model.Spec.UpgradePaths {
+			candidates = append(candidates, p.Version)
+			candidates = append(candidates, p.From...)
+		}
+	}
+
+	candidates = append(candidates, to)
+
+	// Breadth-first search, so the first chain found has the fewest steps.
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, next := range candidates {
+			if _, seen := prev[next]; seen || !directUpgradeAllowed(model, current, next) {
+				continue
+			}
+			prev[next] = current
+			if next != to {
+				queue = append(queue, next)
+				continue
+			}
+
+			var chain []string
+			for version := to; version != from; version = prev[version] {
+				chain = append(chain, version)
+			}
+			slices.Reverse(chain)
+			return chain
+		}
+	}
+
+	return nil
+}
+
+// checkUpgradePath returns why the update to the desired firmware must not start, or "" if it may.
+// Only an update that is about to start is checked: one in flight was admitted when it started,
+// and a vehicle that has not reported its version yet cannot be matched against the model.
+// An update that needs intermediate versions is allowed; the state machine installs them in turn.
+func checkUpgradePath(v *iovv1alpha2.Vehicle, model *iovv1alpha2.VehicleModel) string {
+	phase := v.Status.UpgradeStatus.Phase
+	if phase != iovv1alpha2.VehiclePhaseIdle && phase != iovv1alpha2.VehiclePhaseFailed {
+		return ""
+	}
+
+	from, to := v.Status.Profile.Firmware.Version, v.Spec.Profile.Firmware.Version
+	if from == "" || !isNewVersion(v) || upgradeChain(model, from, to) != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("firmware %q cannot be reached from %q; it can only be installed on [%s]",
+		to, from, strings.Join(upgradeSources(model, to), ", "))
+}
+
+// upgradeTarget returns the version the current step of the update installs.
+// A planned chain is only followed while it still ends at the desired version.
+func upgradeTarget(v *iovv1alpha2.Vehicle) string {
+	path, step := v.Status.UpgradeStatus.Path, int(v.Status.UpgradeStatus.Step)
+	if n := len(path); n > 0 && step < n && path[n-1] == v.Spec.Profile.Firmware.Version {
+		return path[step]
+	}
+	return v.Spec.Profile.Firmware.Version
+}
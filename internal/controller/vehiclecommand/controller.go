@@ -2,10 +2,15 @@ package vehiclecommand
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -13,7 +18,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/autopeer-io/autopeer/internal/controller/pause"
+	"github.com/autopeer-io/autopeer/internal/pkg/audit"
 	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+	"github.com/autopeer-io/autopeer/pkg/options"
 )
 
 // Reconciler reconciles a VehicleCommand object
@@ -24,30 +32,60 @@ type Reconciler struct {
 
 	runners []manager.Runnable
 
-	// subReconcilers is the list of logic processors
+	// gcRetention and gcInterval configure the GarbageCollector.
+	gcRetention time.Duration
+	gcInterval  time.Duration
+
+	// pause is the cluster-wide maintenance switch.
+	pause pause.Switch
+
+	// audit records every phase transition made by this controller.
+	audit audit.Sink
+
+	// subReconcilers is the list of logic processors that advance the command.
+	// They are skipped while reconciliation is paused.
 	subReconcilers []SubReconciler
+
+	// observers only aggregate status and also run while paused.
+	observers []SubReconciler
+
+	// quality derives per-vehicle connection quality from command timing; nil disables it.
+	quality *QualityTracker
 }
 
 // NewReconciler creates a new Reconciler for VehicleCommand.
-func NewReconciler(cli client.Client, sche *runtime.Scheme, recorder record.EventRecorder, hubAddr string) *Reconciler {
+func NewReconciler(cli client.Client, sche *runtime.Scheme, recorder record.EventRecorder, sw pause.Switch, sink audit.Sink, opts *options.VehicleCommandOptions, hubAddr string, hubOpts ...HubClientOption) *Reconciler {
 	// Initialize the Hub Client
-	hubClient := NewGrpcHubClient(hubAddr)
+	hubClient := NewGrpcHubClient(hubAddr, hubOpts...)
 
 	return &Reconciler{
-		Client:   cli,
-		Scheme:   sche,
-		Recorder: recorder,
-		runners:  []manager.Runnable{hubClient},
+		Client:      cli,
+		Scheme:      sche,
+		Recorder:    recorder,
+		runners:     []manager.Runnable{hubClient},
+		gcRetention: opts.GCRetention,
+		gcInterval:  opts.GCInterval,
+		pause:       sw,
+		audit:       sink,
 		// Register the pipeline steps
 		subReconcilers: []SubReconciler{
+			NewGroupReconciler(cli, sche),
+			NewPreconditionReconciler(cli),
 			NewSenderReconciler(hubClient),
+			NewResendReconciler(hubClient, opts.AckDeadline, opts.MaxResends),
+			NewTimeoutReconciler(),
+		},
+		observers: []SubReconciler{
+			NewTimestampReconciler(),
 		},
+		quality: NewQualityTracker(opts.QualityMaxVehicles),
 	}
 }
 
 //+kubebuilder:rbac:groups=iov.autopeer.io,resources=vehiclecommands,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=iov.autopeer.io,resources=vehiclecommands/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=iov.autopeer.io,resources=vehiclecommands/finalizers,verbs=update
+//+kubebuilder:rbac:groups=iov.autopeer.io,resources=vehicles;vehiclemodels;vehiclegroups;commandtemplates,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile handles the lifecycle of a VehicleCommand.
@@ -69,12 +107,46 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	// This ensures the object has a valid Phase before entering SubReconcilers
 	if cmd.Status.Phase == "" {
 		logger.Info("Initializing VehicleCommand status")
+
+		// Resolve the template first: the spec patch returns the object without in-memory status changes.
+		var templateErr error
+		if cmd.Spec.TemplateRef != "" {
+			templateErr = r.applyTemplate(ctx, &cmd)
+			if templateErr != nil && !apierrors.IsNotFound(templateErr) {
+				logger.Error(templateErr, "Failed to resolve command template", "template", cmd.Spec.TemplateRef)
+				return ctrl.Result{}, templateErr
+			}
+		}
+
+		// Then the OTA policy the agent enforces; an override is audited with the initial transition.
+		var override string
+		var policyErr error
+		if templateErr == nil {
+			override, policyErr = r.applyOTAPolicy(ctx, &cmd)
+			if policyErr != nil && !errors.Is(policyErr, errInvalidOTAPolicy) {
+				logger.Error(policyErr, "Failed to resolve OTA policy", "vehicle", cmd.Spec.VehicleName)
+				return ctrl.Result{}, policyErr
+			}
+		}
+
+		now := metav1.Now()
 		cmd.Status.Phase = iovv1alpha2.CommandPhasePending
 		cmd.Status.Message = "Command created, waiting to be sent"
+		if override != "" {
+			cmd.Status.Message = fmt.Sprintf("Command created with %s, waiting to be sent", override)
+		}
+		cmd.Status.StartTime = &now
+		if templateErr != nil {
+			MarkFailed(&cmd, fmt.Sprintf("CommandTemplate %q not found", cmd.Spec.TemplateRef))
+		}
+		if policyErr != nil {
+			MarkFailed(&cmd, policyErr.Error())
+		}
 		if err := r.Status().Update(ctx, &cmd); err != nil {
 			logger.Error(err, "Failed to initialize status")
 			return ctrl.Result{}, err
 		}
+		r.recordTransition(ctx, "", &cmd)
 		// Status update triggers immediate requeue
 		return ctrl.Result{}, nil
 	}
@@ -84,10 +156,19 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	originalCmd := cmd.DeepCopy()
 
 	// 5. Run SubReconcilers
+	// While paused, only the observers run and the command stays in its phase.
 	var aggregatedResult ctrl.Result
-	for _, sub := range r.subReconcilers {
-		res, err := sub.Reconcile(ctx, &cmd)
-		if err != nil {
+	advancing := r.subReconcilers
+	if r.pause.Paused(ctx) {
+		logger.Info("Reconciliation paused, not advancing command", "phase", cmd.Status.Phase)
+		advancing = nil
+		aggregatedResult = ctrl.Result{RequeueAfter: pause.RecheckInterval}
+	}
+
+	steps := slices.Concat(advancing, r.observers)
+	for i := 0; i < len(steps); i++ {
+		res, err := steps[i].Reconcile(ctx, &cmd)
+		if err != nil && !errors.Is(err, ErrHaltChain) {
 			// If a step fails, record an event and return error
 			logger.Error(err, "Sub-reconciler failed")
 			r.Recorder.Event(&cmd, corev1.EventTypeWarning, "ReconcileFailed", err.Error())
@@ -100,6 +181,11 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 				aggregatedResult = res
 			}
 		}
+
+		// Skip the remaining advancing steps, the observers still run.
+		if errors.Is(err, ErrHaltChain) && i < len(advancing) {
+			i = len(advancing) - 1
+		}
 	}
 
 	// 6. Apply Status Patch
@@ -115,23 +201,57 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			return ctrl.Result{}, err
 		}
 
+		// Export the latencies of the timestamps recorded in this cycle.
+		// Group commands are never delivered; their per-vehicle commands are observed instead.
+		if cmd.Spec.GroupRef == "" {
+			observeLatencies(originalCmd, &cmd)
+			r.quality.Observe(originalCmd, &cmd)
+		}
+
 		// Emit events for phase transitions
 		if originalCmd.Status.Phase != cmd.Status.Phase {
+			r.recordTransition(ctx, originalCmd.Status.Phase, &cmd)
 			r.Recorder.Eventf(&cmd, corev1.EventTypeNormal, "PhaseChanged",
 				"Phase transitioned from %s to %s", originalCmd.Status.Phase, cmd.Status.Phase)
 		}
+		if originalCmd.Status.Phase != cmd.Status.Phase && cmd.Status.Phase == iovv1alpha2.CommandPhaseTimeout {
+			r.Recorder.Event(&cmd, corev1.EventTypeWarning, "CommandTimeout", cmd.Status.Message)
+		}
 	}
 
 	return aggregatedResult, nil
 }
 
+// recordTransition writes an audit record for a persisted phase transition.
+// A failing sink is logged but does not fail the reconcile: the transition has already happened.
+func (r *Reconciler) recordTransition(ctx context.Context, from iovv1alpha2.CommandPhase, cmd *iovv1alpha2.VehicleCommand) {
+	err := r.audit.Record(ctx, &audit.Record{
+		Actor:     audit.ActorCommandController,
+		Namespace: cmd.Namespace,
+		Command:   cmd.Name,
+		Vehicle:   cmd.Spec.VehicleName,
+		Method:    cmd.Spec.Method,
+		FromPhase: string(from),
+		ToPhase:   string(cmd.Status.Phase),
+		Message:   cmd.Status.Message,
+	})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to audit command transition", "command", cmd.Name, "phase", cmd.Status.Phase)
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *Reconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &iovv1alpha2.VehicleCommand{}, VehicleNameIndex, IndexVehicleName); err != nil {
+		return err
+	}
+
 	gc := &GarbageCollector{
 		Client:            mgr.GetClient(),
+		Reader:            mgr.GetAPIReader(),
 		Log:               mgr.GetLogger().WithName("gc-vehicle-command"),
-		RetentionDuration: 30 * 24 * time.Hour, // Configurable via options later
-		CleanupInterval:   1 * time.Hour,       // Check every hour
+		RetentionDuration: r.gcRetention,
+		CleanupInterval:   r.gcInterval,
 	}
 
 	r.runners = append(r.runners, gc)
@@ -144,5 +264,6 @@ func (r *Reconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) err
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&iovv1alpha2.VehicleCommand{}).
+		Owns(&iovv1alpha2.VehicleCommand{}).
 		Complete(r)
 }
@@ -0,0 +1,146 @@
+package vehiclecommand
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/autopeer-io/autopeer/internal/controller/pause"
+	"github.com/autopeer-io/autopeer/internal/pkg/audit"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+type staticSwitch bool
+
+func (s staticSwitch) Paused(context.Context) bool { return bool(s) }
+
+// countingReconciler records how often it ran.
+type countingReconciler struct{ calls int }
+
+func (c *countingReconciler) Reconcile(ctx context.Context, cmd *iovv1alpha2.VehicleCommand) (ctrl.Result, error) {
+	c.calls++
+	return ctrl.Result{}, nil
+}
+
+func TestReconcilePaused(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		paused      bool
+		wantAdvance int
+	}{
+		{name: "paused", paused: true, wantAdvance: 0},
+		{name: "running", paused: false, wantAdvance: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &iovv1alpha2.VehicleCommand{
+				ObjectMeta: metav1.ObjectMeta{Name: "ota-vh-1", Namespace: "default"},
+				Status:     iovv1alpha2.VehicleCommandStatus{Phase: iovv1alpha2.CommandPhasePending},
+			}
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cmd).WithStatusSubresource(cmd).Build()
+
+			sender, observer := &countingReconciler{}, &countingReconciler{}
+			r := &Reconciler{
+				Client:         cli,
+				Scheme:         scheme,
+				Recorder:       record.NewFakeRecorder(10),
+				pause:          staticSwitch(tt.paused),
+				audit:          audit.Discard,
+				subReconcilers: []SubReconciler{sender},
+				observers:      []SubReconciler{observer},
+			}
+
+			res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cmd)})
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			if sender.calls != tt.wantAdvance {
+				t.Errorf("advancing sub-reconciler ran %d times, want %d", sender.calls, tt.wantAdvance)
+			}
+			if observer.calls != 1 {
+				t.Errorf("observer ran %d times, want 1", observer.calls)
+			}
+			if tt.paused && res.RequeueAfter != pause.RecheckInterval {
+				t.Errorf("RequeueAfter = %v, want %v", res.RequeueAfter, pause.RecheckInterval)
+			}
+		})
+	}
+}
+
+// recordingSink keeps audit records in memory.
+type recordingSink struct{ records []audit.Record }
+
+func (s *recordingSink) Record(ctx context.Context, r *audit.Record) error {
+	s.records = append(s.records, *r)
+	return nil
+}
+
+// sendingReconciler moves a Pending command to Sent, like the SenderReconciler does.
+type sendingReconciler struct{}
+
+func (sendingReconciler) Reconcile(ctx context.Context, cmd *iovv1alpha2.VehicleCommand) (ctrl.Result, error) {
+	if cmd.Status.Phase == iovv1alpha2.CommandPhasePending {
+		MarkSent(cmd, "Command sent to vehicle")
+	}
+	return ctrl.Result{}, nil
+}
+
+func TestReconcileAuditsTransitions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &iovv1alpha2.VehicleCommand{
+		ObjectMeta: metav1.ObjectMeta{Name: "ota-vh-1", Namespace: "default"},
+		Spec:       iovv1alpha2.VehicleCommandSpec{VehicleName: "vh-1", Method: "OTA"},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cmd).WithStatusSubresource(cmd).Build()
+
+	sink := &recordingSink{}
+	r := &Reconciler{
+		Client:         cli,
+		Scheme:         scheme,
+		Recorder:       record.NewFakeRecorder(10),
+		pause:          staticSwitch(false),
+		audit:          sink,
+		subReconcilers: []SubReconciler{sendingReconciler{}},
+	}
+
+	// Initialize, send, and a no-op reconcile that must not be audited.
+	for i := 0; i < 3; i++ {
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cmd)}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+
+	want := []struct{ from, to string }{
+		{"", string(iovv1alpha2.CommandPhasePending)},
+		{string(iovv1alpha2.CommandPhasePending), string(iovv1alpha2.CommandPhaseSent)},
+	}
+	if len(sink.records) != len(want) {
+		t.Fatalf("got %d audit records, want %d: %+v", len(sink.records), len(want), sink.records)
+	}
+	for i, w := range want {
+		got := sink.records[i]
+		if got.FromPhase != w.from || got.ToPhase != w.to {
+			t.Errorf("record %d: %s -> %s, want %s -> %s", i, got.FromPhase, got.ToPhase, w.from, w.to)
+		}
+		if got.Actor != audit.ActorCommandController || got.Command != "ota-vh-1" || got.Vehicle != "vh-1" || got.Method != "OTA" {
+			t.Errorf("record %d has wrong who/what: %+v", i, got)
+		}
+	}
+}
@@ -13,12 +13,19 @@ import (
 // GarbageCollector handles the periodic cleanup of stale VehicleCommand resources.
 // It implements the manager.Runnable interface to run in the background.
 type GarbageCollector struct {
-	Client            client.Client
+	Client client.Client
+	// Reader is used for the paginated List. It should read from the API server
+	// directly, because the informer cache does not support Continue tokens.
+	Reader            client.Reader
 	Log               logr.Logger
 	RetentionDuration time.Duration // e.g., 30 days
 	CleanupInterval   time.Duration // e.g., 1 hour
+	PageSize          int64         // defaults to defaultGCPageSize
 }
 
+// defaultGCPageSize bounds how many VehicleCommands are held in memory per List call.
+const defaultGCPageSize = 500
+
 // Start begins the garbage collection loop.
 // It blocks until the context is cancelled.
 func (gc *GarbageCollector) Start(ctx context.Context) error {
@@ -41,23 +48,52 @@ func (gc *GarbageCollector) Start(ctx context.Context) error {
 }
 
 // cleanup performs the actual list and delete logic.
+// VehicleCommands are listed page by page so that memory stays bounded
+// even with millions of records.
 func (gc *GarbageCollector) cleanup(ctx context.Context) {
 	gc.Log.V(1).Info("Running scheduled cleanup for VehicleCommands")
 
-	// List all VehicleCommands
-	// Note: In a production environment with millions of records, consider using
-	// Pagination (Continue/Limit) or Listing with specific labels to reduce memory footprint.
-	// For now, listing from the specialized controller-runtime cache is efficient enough.
-	cmdList := &iovv1alpha2.VehicleCommandList{}
-	if err := gc.Client.List(ctx, cmdList); err != nil {
-		gc.Log.Error(err, "Failed to list VehicleCommands for GC")
-		return
+	pageSize := gc.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultGCPageSize
 	}
 
 	threshold := time.Now().Add(-gc.RetentionDuration)
 	deletedCount := 0
+	continueToken := ""
+
+	for {
+		// Stop between pages if the manager is shutting down.
+		if ctx.Err() != nil {
+			gc.Log.Info("GC cycle interrupted", "deleted_count", deletedCount)
+			return
+		}
+
+		cmdList := &iovv1alpha2.VehicleCommandList{}
+		if err := gc.Reader.List(ctx, cmdList, client.Limit(pageSize), client.Continue(continueToken)); err != nil {
+			gc.Log.Error(err, "Failed to list VehicleCommands for GC")
+			return
+		}
 
-	for _, cmd := range cmdList.Items {
+		deletedCount += gc.deleteStale(ctx, cmdList.Items, threshold)
+
+		continueToken = cmdList.Continue
+		if continueToken == "" {
+			break
+		}
+	}
+
+	if deletedCount > 0 {
+		gc.Log.Info("Completed GC cycle", "deleted_count", deletedCount)
+	}
+}
+
+// deleteStale deletes the terminal commands that finished before threshold
+// and returns how many were deleted.
+func (gc *GarbageCollector) deleteStale(ctx context.Context, items []iovv1alpha2.VehicleCommand, threshold time.Time) int {
+	deletedCount := 0
+
+	for _, cmd := range items {
 		// 1. Skip if the command is not in a terminal state.
 		// We assume strictly that only finished commands should be deleted.
 		if !isTerminalState(&cmd) {
@@ -65,11 +101,13 @@ func (gc *GarbageCollector) cleanup(ctx context.Context) {
 		}
 
 		// 2. Check the timestamp.
-		// Using CompletionTime is better, but if nil, fallback to CreationTimestamp.
-		// Here we assume Status.LastUpdateTime is maintained, or use CreationTimestamp as a fallback.
+		// Retention is measured from when the command finished, so a long-running
+		// command is not deleted right after it completes. Fall back to
+		// CreationTimestamp for commands without a CompletionTime.
 		checkTime := cmd.CreationTimestamp.Time
-		// If you have a specific Status.FinishedAt field, use it here:
-		// if cmd.Status.FinishedAt != nil { checkTime = cmd.Status.FinishedAt.Time }
+		if cmd.Status.CompletionTime != nil {
+			checkTime = cmd.Status.CompletionTime.Time
+		}
 
 		if checkTime.Before(threshold) {
 			// Perform deletion
@@ -85,14 +123,13 @@ func (gc *GarbageCollector) cleanup(ctx context.Context) {
 		}
 	}
 
-	if deletedCount > 0 {
-		gc.Log.Info("Completed GC cycle", "deleted_count", deletedCount)
-	}
+	return deletedCount
 }
 
 // isTerminalState determines if the command has finished its lifecycle.
 func isTerminalState(cmd *iovv1alpha2.VehicleCommand) bool {
 	phase := cmd.Status.Phase
 	return phase == iovv1alpha2.CommandPhaseSucceeded ||
-		phase == iovv1alpha2.CommandPhaseFailed
+		phase == iovv1alpha2.CommandPhaseFailed ||
+		phase == iovv1alpha2.CommandPhaseTimeout
 }
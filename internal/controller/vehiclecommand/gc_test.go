@@ -0,0 +1,154 @@
+package vehiclecommand
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func newCommand(name string, created time.Time, phase iovv1alpha2.CommandPhase, completed *time.Time) *iovv1alpha2.VehicleCommand {
+	cmd := &iovv1alpha2.VehicleCommand{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Status: iovv1alpha2.VehicleCommandStatus{Phase: phase},
+	}
+	if completed != nil {
+		t := metav1.NewTime(*completed)
+		cmd.Status.CompletionTime = &t
+	}
+	return cmd
+}
+
+func TestGarbageCollectorCleanup(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	day := 24 * time.Hour
+	yesterday := now.Add(-day)
+	longAgo := now.Add(-35 * day)
+
+	tests := []struct {
+		cmd         *iovv1alpha2.VehicleCommand
+		wantDeleted bool
+	}{
+		// Created 40 days ago but completed yesterday: still within retention.
+		{cmd: newCommand("old-recently-completed", now.Add(-40*day), iovv1alpha2.CommandPhaseSucceeded, &yesterday), wantDeleted: false},
+		{cmd: newCommand("old-completed", now.Add(-40*day), iovv1alpha2.CommandPhaseFailed, &longAgo), wantDeleted: true},
+		// No CompletionTime: falls back to CreationTimestamp.
+		{cmd: newCommand("old-no-completion", now.Add(-40*day), iovv1alpha2.CommandPhaseTimeout, nil), wantDeleted: true},
+		{cmd: newCommand("old-still-running", now.Add(-40*day), iovv1alpha2.CommandPhaseRunning, nil), wantDeleted: false},
+	}
+
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	for _, tt := range tests {
+		builder = builder.WithObjects(tt.cmd)
+	}
+	cli := builder.Build()
+
+	gc := &GarbageCollector{Client: cli, Reader: cli, Log: logr.Discard(), RetentionDuration: 30 * day}
+	gc.cleanup(context.Background())
+
+	for _, tt := range tests {
+		err := cli.Get(context.Background(), client.ObjectKeyFromObject(tt.cmd), &iovv1alpha2.VehicleCommand{})
+		if deleted := apierrors.IsNotFound(err); deleted != tt.wantDeleted {
+			t.Errorf("%s: deleted = %v, want %v (err: %v)", tt.cmd.Name, deleted, tt.wantDeleted, err)
+		}
+	}
+}
+
+// pagingReader serves List calls page by page, honoring Limit and Continue,
+// which the fake client ignores.
+type pagingReader struct {
+	client.Reader
+	pages int
+}
+
+func (r *pagingReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	all := &iovv1alpha2.VehicleCommandList{}
+	if err := r.Reader.List(ctx, all); err != nil {
+		return err
+	}
+
+	start := 0
+	if listOpts.Continue != "" {
+		start, _ = strconv.Atoi(listOpts.Continue)
+	}
+	end := min(start+int(listOpts.Limit), len(all.Items))
+
+	out := list.(*iovv1alpha2.VehicleCommandList)
+	out.Items = all.Items[start:end]
+	out.Continue = ""
+	if end < len(all.Items) {
+		out.Continue = strconv.Itoa(end)
+	}
+	r.pages++
+	return nil
+}
+
+func TestGarbageCollectorCleanupPaginated(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Now().Add(-40 * 24 * time.Hour)
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	for i := 0; i < 25; i++ {
+		builder = builder.WithObjects(newCommand(fmt.Sprintf("stale-%02d", i), created, iovv1alpha2.CommandPhaseSucceeded, nil))
+	}
+	for i := 0; i < 5; i++ {
+		builder = builder.WithObjects(newCommand(fmt.Sprintf("running-%02d", i), created, iovv1alpha2.CommandPhaseRunning, nil))
+	}
+	cli := builder.Build()
+
+	// Deleting while paging shifts the offsets of the stub reader, so read from a snapshot.
+	snapshot := fake.NewClientBuilder().WithScheme(scheme).WithLists(listAll(t, cli)).Build()
+	reader := &pagingReader{Reader: snapshot}
+
+	gc := &GarbageCollector{Client: cli, Reader: reader, Log: logr.Discard(), RetentionDuration: 30 * 24 * time.Hour, PageSize: 10}
+	gc.cleanup(context.Background())
+
+	if reader.pages != 3 {
+		t.Errorf("listed %d pages, want 3", reader.pages)
+	}
+
+	remaining := listAll(t, cli)
+	if len(remaining.Items) != 5 {
+		t.Fatalf("%d commands remain, want the 5 running ones", len(remaining.Items))
+	}
+	for _, cmd := range remaining.Items {
+		if cmd.Status.Phase != iovv1alpha2.CommandPhaseRunning {
+			t.Errorf("unexpected remaining command %s in phase %s", cmd.Name, cmd.Status.Phase)
+		}
+	}
+}
+
+func listAll(t *testing.T, cli client.Client) *iovv1alpha2.VehicleCommandList {
+	t.Helper()
+
+	list := &iovv1alpha2.VehicleCommandList{}
+	if err := cli.List(context.Background(), list); err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
@@ -1,7 +1,9 @@
 package vehiclecommand
 
 import (
+	"github.com/autopeer-io/autopeer/internal/pkg/metrics"
 	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -11,6 +13,7 @@ func MarkSent(cmd *iovv1alpha2.VehicleCommand, msg string) {
 	cmd.Status.Phase = iovv1alpha2.CommandPhaseSent
 	cmd.Status.Message = msg
 	cmd.Status.LastUpdateTime = &now
+	cmd.Status.SentTime = &now
 }
 
 // MarkFailed updates the command status to Failed, records error message and completion time.
@@ -30,3 +33,42 @@ func MarkSucceeded(cmd *iovv1alpha2.VehicleCommand) {
 	cmd.Status.LastUpdateTime = &now
 	cmd.Status.CompletionTime = &now
 }
+
+// MarkTimeout updates the command status to Timeout, records the reason and completion time.
+func MarkTimeout(cmd *iovv1alpha2.VehicleCommand, msg string) {
+	now := metav1.Now()
+	cmd.Status.Phase = iovv1alpha2.CommandPhaseTimeout
+	cmd.Status.Message = msg
+	cmd.Status.LastUpdateTime = &now
+	cmd.Status.CompletionTime = &now
+}
+
+// setReadyCondition sets the Ready condition of the command.
+func setReadyCondition(cmd *iovv1alpha2.VehicleCommand, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&cmd.Status.Conditions, metav1.Condition{
+		Type:               iovv1alpha2.ConditionTypeReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: cmd.Generation,
+	})
+}
+
+// observeLatencies records the pipeline latencies for every timestamp that was
+// set between old and cur. Each latency is therefore observed exactly once per command.
+func observeLatencies(old, cur *iovv1alpha2.VehicleCommand) {
+	method := cur.Spec.Method
+	st := cur.Status
+
+	if old.Status.SentTime == nil && st.SentTime != nil && st.StartTime != nil {
+		metrics.CommandDispatchLatency.WithLabelValues(method).Observe(st.SentTime.Sub(st.StartTime.Time).Seconds())
+	}
+
+	if old.Status.AcknowledgeTime == nil && st.AcknowledgeTime != nil && st.SentTime != nil {
+		metrics.CommandAckLatency.WithLabelValues(method).Observe(st.AcknowledgeTime.Sub(st.SentTime.Time).Seconds())
+	}
+
+	if old.Status.CompletionTime == nil && st.CompletionTime != nil && st.StartTime != nil {
+		metrics.CommandTotalLatency.WithLabelValues(method, string(st.Phase)).Observe(st.CompletionTime.Sub(st.StartTime.Time).Seconds())
+	}
+}
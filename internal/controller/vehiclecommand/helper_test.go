@@ -0,0 +1,63 @@
+package vehiclecommand
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/metrics"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func sampleCount(t *testing.T, o prometheus.Observer) uint64 {
+	t.Helper()
+
+	var m dto.Metric
+	if err := o.(prometheus.Metric).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestObserveLatencies(t *testing.T) {
+	const method = "TestObserveLatencies"
+	start := metav1.NewTime(time.Now().Add(-3 * time.Second))
+
+	cmd := &iovv1alpha2.VehicleCommand{
+		Spec:   iovv1alpha2.VehicleCommandSpec{Method: method},
+		Status: iovv1alpha2.VehicleCommandStatus{Phase: iovv1alpha2.CommandPhasePending, StartTime: &start},
+	}
+
+	// Pending -> Sent
+	old := cmd.DeepCopy()
+	MarkSent(cmd, "sent")
+	observeLatencies(old, cmd)
+
+	if got := sampleCount(t, metrics.CommandDispatchLatency.WithLabelValues(method)); got != 1 {
+		t.Errorf("dispatch latency sample count = %d, want 1", got)
+	}
+
+	// Sent -> Succeeded, timestamps filled in by the TimestampReconciler.
+	old = cmd.DeepCopy()
+	cmd.Status.Phase = iovv1alpha2.CommandPhaseSucceeded
+	if _, err := NewTimestampReconciler().Reconcile(t.Context(), cmd); err != nil {
+		t.Fatal(err)
+	}
+	observeLatencies(old, cmd)
+
+	if got := sampleCount(t, metrics.CommandAckLatency.WithLabelValues(method)); got != 1 {
+		t.Errorf("ack latency sample count = %d, want 1", got)
+	}
+	if got := sampleCount(t, metrics.CommandTotalLatency.WithLabelValues(method, string(iovv1alpha2.CommandPhaseSucceeded))); got != 1 {
+		t.Errorf("total latency sample count = %d, want 1", got)
+	}
+
+	// No new timestamps: nothing is observed twice.
+	observeLatencies(cmd.DeepCopy(), cmd)
+	if got := sampleCount(t, metrics.CommandDispatchLatency.WithLabelValues(method)); got != 1 {
+		t.Errorf("dispatch latency observed again, sample count = %d", got)
+	}
+}
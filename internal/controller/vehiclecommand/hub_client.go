@@ -2,11 +2,16 @@ package vehiclecommand
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	pb "github.com/autopeer-io/autopeer/api/proto/v1"
@@ -21,16 +26,63 @@ type HubClient interface {
 	SendCommand(ctx context.Context, req *pb.SendCommandRequest) (*pb.SendCommandResponse, error)
 }
 
+// ErrHubUnavailable is returned when the Hub cannot be reached, either because
+// all retries were exhausted or because the circuit breaker is open.
+var ErrHubUnavailable = errors.New("hub unavailable")
+
+const (
+	defaultHubMaxRetries     = 3
+	defaultHubTimeout        = grpcmiddleware.DefaultRPCTimeout
+	defaultHubBaseBackoff    = 200 * time.Millisecond
+	defaultHubMaxBackoff     = 5 * time.Second
+	defaultBreakerThreshold  = 5
+	defaultBreakerOpenPeriod = 30 * time.Second
+)
+
+// HubClientOption configures a GrpcHubClient.
+type HubClientOption func(*GrpcHubClient)
+
+// WithMaxRetries sets how many times a failed call is retried before giving up.
+func WithMaxRetries(n int) HubClientOption {
+	return func(c *GrpcHubClient) {
+		c.maxRetries = n
+	}
+}
+
+// WithTimeout sets the deadline of a single call attempt.
+func WithTimeout(d time.Duration) HubClientOption {
+	return func(c *GrpcHubClient) {
+		c.timeout = d
+	}
+}
+
+// WithBackoff sets the initial and the maximum delay between retries.
+func WithBackoff(base, max time.Duration) HubClientOption {
+	return func(c *GrpcHubClient) {
+		c.baseBackoff = base
+		c.maxBackoff = max
+	}
+}
+
 // GrpcHubClient is the real implementation using gRPC.
+// Calls are retried with capped exponential backoff and guarded by a circuit breaker,
+// so a Hub outage fails fast instead of piling up blocked reconciles.
 type GrpcHubClient struct {
 	client pb.HubServiceClient
 	conn   *grpc.ClientConn
+
+	maxRetries  int
+	timeout     time.Duration
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+
+	breaker *circuitBreaker
 }
 
 var _ HubClient = (*GrpcHubClient)(nil)
 
 // NewGrpcHubClient creates a new GrpcHubClient.
-func NewGrpcHubClient(addr string) *GrpcHubClient {
+func NewGrpcHubClient(addr string, opts ...HubClientOption) *GrpcHubClient {
 	// Establish connection
 	conn, err := grpc.NewClient(addr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
@@ -41,16 +93,72 @@ func NewGrpcHubClient(addr string) *GrpcHubClient {
 		panic(fmt.Sprintf("FATAL: failed to initialize gRPC client for hub addr '%s': %v", addr, err))
 	}
 
-	return &GrpcHubClient{
-		client: pb.NewHubServiceClient(conn),
-		conn:   conn,
+	c := &GrpcHubClient{
+		client:      pb.NewHubServiceClient(conn),
+		conn:        conn,
+		maxRetries:  defaultHubMaxRetries,
+		timeout:     defaultHubTimeout,
+		baseBackoff: defaultHubBaseBackoff,
+		maxBackoff:  defaultHubMaxBackoff,
+		breaker:     &circuitBreaker{threshold: defaultBreakerThreshold, openPeriod: defaultBreakerOpenPeriod, now: time.Now},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
+// SendCommand forwards the command to the Hub.
+// Transient failures are retried; if the Hub stays unreachable the returned error wraps ErrHubUnavailable.
 func (c *GrpcHubClient) SendCommand(ctx context.Context, req *pb.SendCommandRequest) (*pb.SendCommandResponse, error) {
+	if !c.breaker.allow() {
+		return nil, fmt.Errorf("%w: circuit breaker open", ErrHubUnavailable)
+	}
+
+	var lastErr error
+	backoff := c.baseBackoff
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			backoff = min(backoff*2, c.maxBackoff)
+		}
+
+		resp, err := c.send(ctx, req)
+		if err == nil {
+			c.breaker.success()
+			return resp, nil
+		}
+		if !isRetryable(err) {
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	c.breaker.failure()
+	return nil, fmt.Errorf("%w after %d attempts: %w", ErrHubUnavailable, c.maxRetries+1, lastErr)
+}
+
+func (c *GrpcHubClient) send(ctx context.Context, req *pb.SendCommandRequest) (*pb.SendCommandResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
 	return c.client.SendCommand(ctx, req)
 }
 
+// isRetryable reports whether the gRPC error indicates a transient Hub problem.
+func isRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
 // Start is manages the lifecycle of the gRPC connection.
 func (c *GrpcHubClient) Start(ctx context.Context) error {
 	logger := log.FromContext(ctx)
@@ -97,3 +205,46 @@ func (c *GrpcHubClient) updateMetric(state connectivity.State) {
 		metrics.HubConnectivityStatus.Set(0)
 	}
 }
+
+// circuitBreaker opens after `threshold` consecutive failed calls and rejects calls
+// for `openPeriod`. After that a single trial call is let through (half-open).
+type circuitBreaker struct {
+	mu         sync.Mutex
+	threshold  int
+	openPeriod time.Duration
+	failures   int
+	openUntil  time.Time
+
+	// now is overridable for tests.
+	now func() time.Time
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.now().Before(b.openUntil) {
+		return false
+	}
+	// Half-open: let this call probe the Hub and keep the others out until it reports back.
+	b.openUntil = b.now().Add(b.openPeriod)
+	return true
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.openPeriod)
+	}
+}
@@ -0,0 +1,105 @@
+package vehiclecommand
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/autopeer-io/autopeer/api/proto/v1"
+)
+
+// stubHub rejects the first `failures` calls with codes.Unavailable.
+type stubHub struct {
+	pb.UnimplementedHubServiceServer
+	failures int32
+	calls    atomic.Int32
+}
+
+func (s *stubHub) SendCommand(ctx context.Context, req *pb.SendCommandRequest) (*pb.SendCommandResponse, error) {
+	if s.calls.Add(1) <= s.failures {
+		return nil, status.Error(codes.Unavailable, "hub is starting")
+	}
+	return &pb.SendCommandResponse{Accepted: true}, nil
+}
+
+func startStubHub(t *testing.T, hub *stubHub) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterHubServiceServer(srv, hub)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	return lis.Addr().String()
+}
+
+func TestGrpcHubClientRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int32
+		wantErr   error
+		wantCalls int32
+	}{
+		{name: "succeeds without retry", failures: 0, wantCalls: 1},
+		{name: "recovers after transient failures", failures: 2, wantCalls: 3},
+		{name: "gives up after max retries", failures: 10, wantErr: ErrHubUnavailable, wantCalls: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := &stubHub{failures: tt.failures}
+			c := NewGrpcHubClient(startStubHub(t, hub),
+				WithMaxRetries(3),
+				WithTimeout(time.Second),
+				WithBackoff(time.Millisecond, 5*time.Millisecond),
+			)
+			defer c.conn.Close()
+
+			resp, err := c.SendCommand(context.Background(), &pb.SendCommandRequest{CommandName: "cmd-1"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SendCommand() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !resp.Accepted {
+				t.Errorf("SendCommand() not accepted")
+			}
+			if got := hub.calls.Load(); got != tt.wantCalls {
+				t.Errorf("hub received %d calls, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGrpcHubClientCircuitBreaker(t *testing.T) {
+	hub := &stubHub{failures: 1 << 30}
+	c := NewGrpcHubClient(startStubHub(t, hub),
+		WithMaxRetries(0),
+		WithBackoff(time.Millisecond, time.Millisecond),
+	)
+	defer c.conn.Close()
+
+	for i := 0; i < defaultBreakerThreshold; i++ {
+		if _, err := c.SendCommand(context.Background(), &pb.SendCommandRequest{}); !errors.Is(err, ErrHubUnavailable) {
+			t.Fatalf("call %d: error = %v, want ErrHubUnavailable", i, err)
+		}
+	}
+
+	// The breaker is open now: calls fail fast without reaching the Hub.
+	before := hub.calls.Load()
+	if _, err := c.SendCommand(context.Background(), &pb.SendCommandRequest{}); !errors.Is(err, ErrHubUnavailable) {
+		t.Fatalf("error = %v, want ErrHubUnavailable", err)
+	}
+	if got := hub.calls.Load(); got != before {
+		t.Errorf("hub received %d calls while breaker open, want %d", got, before)
+	}
+}
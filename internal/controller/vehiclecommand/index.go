@@ -0,0 +1,21 @@
+package vehiclecommand
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// VehicleNameIndex is the cache index of VehicleCommands by Spec.VehicleName.
+// List the commands of a vehicle with client.MatchingFields{VehicleNameIndex: name}.
+const VehicleNameIndex = "spec.vehicleName"
+
+// IndexVehicleName extracts the VehicleNameIndex value of a VehicleCommand.
+// Group commands have no vehicle and are not indexed; their per-member commands are.
+func IndexVehicleName(obj client.Object) []string {
+	cmd, ok := obj.(*iovv1alpha2.VehicleCommand)
+	if !ok || cmd.Spec.VehicleName == "" {
+		return nil
+	}
+	return []string{cmd.Spec.VehicleName}
+}
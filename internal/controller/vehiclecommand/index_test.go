@@ -0,0 +1,53 @@
+package vehiclecommand
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func TestVehicleNameIndex(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	newCmd := func(name, vehicle, group string) *iovv1alpha2.VehicleCommand {
+		return &iovv1alpha2.VehicleCommand{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       iovv1alpha2.VehicleCommandSpec{VehicleName: vehicle, GroupRef: group, Method: "Reboot"},
+		}
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).
+		WithIndex(&iovv1alpha2.VehicleCommand{}, VehicleNameIndex, IndexVehicleName).
+		WithObjects(
+			newCmd("reboot-1", "vh-1", ""),
+			newCmd("reboot-2", "vh-2", ""),
+			newCmd("reboot-3", "vh-1", ""),
+			newCmd("reboot-fleet", "", "fleet"),
+		).Build()
+
+	var list iovv1alpha2.VehicleCommandList
+	if err := cli.List(context.Background(), &list, client.InNamespace("default"), client.MatchingFields{VehicleNameIndex: "vh-1"}); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, cmd := range list.Items {
+		got = append(got, cmd.Name)
+	}
+	slices.Sort(got)
+	if want := []string{"reboot-1", "reboot-3"}; !slices.Equal(got, want) {
+		t.Errorf("commands of vh-1 = %v, want %v", got, want)
+	}
+
+	if keys := IndexVehicleName(newCmd("reboot-fleet", "", "fleet")); len(keys) != 0 {
+		t.Errorf("group command indexed under %v", keys)
+	}
+}
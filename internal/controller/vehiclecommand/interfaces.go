@@ -2,12 +2,19 @@ package vehiclecommand
 
 import (
 	"context"
+	"errors"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
 )
 
+// ErrHaltChain can be returned by a SubReconciler to stop the remaining
+// sub-reconcilers from advancing the command. It is not treated as a failure:
+// the observers still run, the in-memory changes are patched and the returned
+// RequeueAfter, if any, is honored.
+var ErrHaltChain = errors.New("halt sub-reconciler chain")
+
 // SubReconciler defines the interface for a modular reconciliation step.
 // It operates on the in-memory VehicleCommand object.
 // It should return a ctrl.Result if it wants to request a requeue (e.g. for exponential backoff),
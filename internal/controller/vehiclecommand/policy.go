@@ -0,0 +1,77 @@
+package vehiclecommand
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+const (
+	// methodOTA is the method of firmware update commands.
+	methodOTA = "OTA"
+
+	// ReasonOTAPolicyOverride is the event reason of an OTA command overriding its vehicle's OTAPolicy.
+	ReasonOTAPolicyOverride = "OTAPolicyOverride"
+)
+
+// errInvalidOTAPolicy is returned for OTA policy parameters the agent could not enforce.
+var errInvalidOTAPolicy = errors.New("invalid OTA policy parameter")
+
+// applyOTAPolicy resolves the OTA policy an OTA command is executed with and persists it in the
+// command's Parameters, where the agent reads it. Parameters the command leaves unset are filled
+// from the vehicle's OTAPolicy; a value that differs from the policy is a one-off override
+// (e.g. an emergency patch ignoring the battery minimum). It returns a description of the
+// overrides, which the caller records with the command's initial transition.
+// Commands whose vehicle does not exist are left as is: they fail when they are dispatched.
+func (r *Reconciler) applyOTAPolicy(ctx context.Context, cmd *iovv1alpha2.VehicleCommand) (string, error) {
+	if cmd.Spec.Method != methodOTA || cmd.Spec.VehicleName == "" {
+		return "", nil
+	}
+
+	var vehicle iovv1alpha2.Vehicle
+	if err := r.Get(ctx, client.ObjectKey{Namespace: cmd.Namespace, Name: cmd.Spec.VehicleName}, &vehicle); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	policy := vehicle.Spec.Profile.OTAPolicy.MinBatteryLevel
+
+	raw, set := cmd.Spec.Parameters[iovv1alpha2.OTAParamMinBatteryLevel]
+	if !set {
+		if policy == nil {
+			return "", nil
+		}
+		original := cmd.DeepCopy()
+		if cmd.Spec.Parameters == nil {
+			cmd.Spec.Parameters = make(map[string]string)
+		}
+		cmd.Spec.Parameters[iovv1alpha2.OTAParamMinBatteryLevel] = strconv.Itoa(int(*policy))
+		return "", r.Patch(ctx, cmd, client.MergeFrom(original))
+	}
+
+	level, err := strconv.Atoi(raw)
+	if err != nil || level < 0 || level > 100 {
+		return "", fmt.Errorf("%w: %s must be a percentage between 0 and 100, got %q", errInvalidOTAPolicy, iovv1alpha2.OTAParamMinBatteryLevel, raw)
+	}
+	if policy != nil && int(*policy) == level {
+		return "", nil
+	}
+
+	vehiclePolicy := "unset"
+	if policy != nil {
+		vehiclePolicy = strconv.Itoa(int(*policy))
+	}
+	override := fmt.Sprintf("OTA policy override %s=%d (vehicle policy: %s)", iovv1alpha2.OTAParamMinBatteryLevel, level, vehiclePolicy)
+	log.FromContext(ctx).Info("OTA command overrides the vehicle's OTA policy", "vehicle", vehicle.Name, "parameter", iovv1alpha2.OTAParamMinBatteryLevel, "value", level, "vehiclePolicy", vehiclePolicy)
+	r.Recorder.Event(cmd, corev1.EventTypeWarning, ReasonOTAPolicyOverride, override)
+	return override, nil
+}
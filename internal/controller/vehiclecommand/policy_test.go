@@ -0,0 +1,99 @@
+package vehiclecommand
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func TestReconcileAppliesOTAPolicy(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	vehicle := &iovv1alpha2.Vehicle{
+		ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default"},
+		Spec: iovv1alpha2.VehicleSpec{
+			Profile: iovv1alpha2.VehicleProfile{OTAPolicy: iovv1alpha2.OTAPolicy{MinBatteryLevel: ptr.To[int32](50)}},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		param        string // minBatteryLevel set on the command, "" for none
+		wantParam    string
+		wantPhase    iovv1alpha2.CommandPhase
+		wantOverride bool
+	}{
+		{name: "vehicle policy", method: "OTA", wantParam: "50", wantPhase: iovv1alpha2.CommandPhasePending},
+		{name: "same as policy", method: "OTA", param: "50", wantParam: "50", wantPhase: iovv1alpha2.CommandPhasePending},
+		{name: "override", method: "OTA", param: "0", wantParam: "0", wantPhase: iovv1alpha2.CommandPhasePending, wantOverride: true},
+		{name: "invalid override", method: "OTA", param: "low", wantParam: "low", wantPhase: iovv1alpha2.CommandPhaseFailed},
+		{name: "not an OTA", method: "Reboot", wantPhase: iovv1alpha2.CommandPhasePending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &iovv1alpha2.VehicleCommand{
+				ObjectMeta: metav1.ObjectMeta{Name: "cmd-vh-1", Namespace: "default"},
+				Spec:       iovv1alpha2.VehicleCommandSpec{VehicleName: "vh-1", Method: tt.method, Parameters: map[string]string{"version": "v2.0.0"}},
+			}
+			if tt.param != "" {
+				cmd.Spec.Parameters[iovv1alpha2.OTAParamMinBatteryLevel] = tt.param
+			}
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vehicle, cmd).WithStatusSubresource(cmd).Build()
+
+			sink := &recordingSink{}
+			recorder := record.NewFakeRecorder(10)
+			r := &Reconciler{
+				Client:   cli,
+				Scheme:   scheme,
+				Recorder: recorder,
+				pause:    staticSwitch(false),
+				audit:    sink,
+			}
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cmd)}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			var got iovv1alpha2.VehicleCommand
+			if err := cli.Get(context.Background(), client.ObjectKeyFromObject(cmd), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.Phase != tt.wantPhase {
+				t.Errorf("phase = %s (%s), want %s", got.Status.Phase, got.Status.Message, tt.wantPhase)
+			}
+			if param := got.Spec.Parameters[iovv1alpha2.OTAParamMinBatteryLevel]; param != tt.wantParam {
+				t.Errorf("%s = %q, want %q", iovv1alpha2.OTAParamMinBatteryLevel, param, tt.wantParam)
+			}
+
+			// The override is audited with the initial transition and surfaced as a warning event.
+			if len(sink.records) != 1 {
+				t.Fatalf("got %d audit records, want 1", len(sink.records))
+			}
+			audited := strings.Contains(sink.records[0].Message, "OTA policy override minBatteryLevel=0 (vehicle policy: 50)")
+			if audited != tt.wantOverride {
+				t.Errorf("audit message = %q, want override recorded: %v", sink.records[0].Message, tt.wantOverride)
+			}
+			var warned bool
+			for len(recorder.Events) > 0 {
+				warned = warned || strings.Contains(<-recorder.Events, ReasonOTAPolicyOverride)
+			}
+			if warned != tt.wantOverride {
+				t.Errorf("override event = %v, want %v", warned, tt.wantOverride)
+			}
+		})
+	}
+}
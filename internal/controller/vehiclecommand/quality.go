@@ -0,0 +1,166 @@
+package vehiclecommand
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/metrics"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// Gains of the exponential moving averages, as used for TCP's smoothed RTT (RFC 6298)
+// and RTP's interarrival jitter (RFC 3550).
+const (
+	latencyGain = 1.0 / 8
+	jitterGain  = 1.0 / 16
+	dropGain    = 1.0 / 8
+)
+
+// ConnectionQuality summarizes how reliably a vehicle's link delivers commands.
+type ConnectionQuality struct {
+	// Latency is the smoothed time from sending a command to its acknowledgement.
+	Latency time.Duration
+
+	// Jitter is the smoothed difference between consecutive acknowledgement times.
+	Jitter time.Duration
+
+	// DropRate is the smoothed share of sends the vehicle did not acknowledge (0..1).
+	DropRate float64
+
+	// Samples is the number of sends observed.
+	Samples int
+}
+
+// vehicleKey identifies a vehicle across namespaces.
+type vehicleKey struct {
+	namespace string
+	name      string
+}
+
+type qualityEntry struct {
+	key     vehicleKey
+	quality ConnectionQuality
+
+	// lastLatency is the latest unsmoothed sample, the reference for the next jitter sample.
+	lastLatency time.Duration
+	acked       bool
+}
+
+// QualityTracker derives per-vehicle connection quality from command timing and exports it as metrics.
+// It keeps only the maxVehicles most recently active vehicles, which bounds the metrics' cardinality.
+type QualityTracker struct {
+	mu          sync.Mutex
+	maxVehicles int
+	entries     map[vehicleKey]*list.Element
+	lru         *list.List
+}
+
+// NewQualityTracker creates a tracker for up to maxVehicles vehicles. It returns nil, which
+// disables tracking, if maxVehicles is zero.
+func NewQualityTracker(maxVehicles int) *QualityTracker {
+	if maxVehicles <= 0 {
+		return nil
+	}
+	return &QualityTracker{
+		maxVehicles: maxVehicles,
+		entries:     make(map[vehicleKey]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+// Observe records the sends of cur whose outcome became known between old and cur:
+// re-sent attempts count as drops once the command is acknowledged or given up.
+func (t *QualityTracker) Observe(old, cur *iovv1alpha2.VehicleCommand) {
+	if t == nil {
+		return
+	}
+
+	st := cur.Status
+	key := vehicleKey{namespace: cur.Namespace, name: cur.Spec.VehicleName}
+
+	switch {
+	case old.Status.AcknowledgeTime == nil && st.AcknowledgeTime != nil && st.SentTime != nil:
+		// Attribute the acknowledgement to the latest send; the earlier ones were dropped.
+		sent := st.SentTime
+		if st.LastResendTime != nil {
+			sent = st.LastResendTime
+		}
+		t.record(key, int(st.ResendCount), st.AcknowledgeTime.Sub(sent.Time), true)
+
+	case old.Status.CompletionTime == nil && st.CompletionTime != nil && st.SentTime != nil && st.AcknowledgeTime == nil:
+		// The command ended without the vehicle ever answering.
+		t.record(key, int(st.ResendCount)+1, 0, false)
+	}
+}
+
+// Get returns the connection quality of a vehicle, if it is tracked.
+func (t *QualityTracker) Get(namespace, name string) (ConnectionQuality, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	elem, ok := t.entries[vehicleKey{namespace: namespace, name: name}]
+	if !ok {
+		return ConnectionQuality{}, false
+	}
+	return elem.Value.(*qualityEntry).quality, true
+}
+
+// record applies drops unacknowledged sends and, if acked, one acknowledged send with the given latency.
+func (t *QualityTracker) record(key vehicleKey, drops int, latency time.Duration, acked bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e := t.touch(key)
+	q := &e.quality
+
+	for range drops {
+		q.DropRate += dropGain * (1 - q.DropRate)
+		q.Samples++
+	}
+
+	if acked {
+		q.DropRate -= dropGain * q.DropRate
+		if !e.acked {
+			q.Latency = latency
+			e.acked = true
+		} else {
+			q.Latency += time.Duration(latencyGain * float64(latency-q.Latency))
+			q.Jitter += time.Duration(jitterGain * float64(absDuration(latency-e.lastLatency)-q.Jitter))
+		}
+		e.lastLatency = latency
+		q.Samples++
+	}
+
+	metrics.VehicleConnectionLatency.WithLabelValues(key.namespace, key.name).Set(q.Latency.Seconds())
+	metrics.VehicleConnectionJitter.WithLabelValues(key.namespace, key.name).Set(q.Jitter.Seconds())
+	metrics.VehicleConnectionDropRatio.WithLabelValues(key.namespace, key.name).Set(q.DropRate)
+}
+
+// touch returns the entry of key, creating it and evicting the least recently active vehicle if needed.
+func (t *QualityTracker) touch(key vehicleKey) *qualityEntry {
+	if elem, ok := t.entries[key]; ok {
+		t.lru.MoveToFront(elem)
+		return elem.Value.(*qualityEntry)
+	}
+
+	if t.lru.Len() >= t.maxVehicles {
+		oldest := t.lru.Back()
+		evicted := t.lru.Remove(oldest).(*qualityEntry).key
+		delete(t.entries, evicted)
+		metrics.VehicleConnectionLatency.DeleteLabelValues(evicted.namespace, evicted.name)
+		metrics.VehicleConnectionJitter.DeleteLabelValues(evicted.namespace, evicted.name)
+		metrics.VehicleConnectionDropRatio.DeleteLabelValues(evicted.namespace, evicted.name)
+	}
+
+	e := &qualityEntry{key: key}
+	t.entries[key] = t.lru.PushFront(e)
+	return e
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
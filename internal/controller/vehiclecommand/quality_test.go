@@ -0,0 +1,119 @@
+package vehiclecommand
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+var qualityEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ackedCommand returns the before and after states of a command to vehicle
+// that was acknowledged latency after its last send, following resends re-sends.
+func ackedCommand(vehicle string, resends int32, latency time.Duration) (*iovv1alpha2.VehicleCommand, *iovv1alpha2.VehicleCommand) {
+	sent := metav1.NewTime(qualityEpoch)
+	old := &iovv1alpha2.VehicleCommand{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+		Spec:       iovv1alpha2.VehicleCommandSpec{VehicleName: vehicle},
+		Status:     iovv1alpha2.VehicleCommandStatus{SentTime: &sent, ResendCount: resends},
+	}
+	last := sent
+	if resends > 0 {
+		last = metav1.NewTime(qualityEpoch.Add(time.Duration(resends) * 30 * time.Second))
+		old.Status.LastResendTime = &last
+	}
+
+	cur := old.DeepCopy()
+	ack := metav1.NewTime(last.Add(latency))
+	cur.Status.AcknowledgeTime = &ack
+	return old, cur
+}
+
+func TestQualityTrackerLatencyAndJitter(t *testing.T) {
+	tracker := NewQualityTracker(10)
+
+	// Round trips of 100ms, 300ms, 100ms.
+	for _, ms := range []int{100, 300, 100} {
+		tracker.Observe(ackedCommand("vh-1", 0, time.Duration(ms)*time.Millisecond))
+	}
+
+	q, ok := tracker.Get("default", "vh-1")
+	if !ok {
+		t.Fatal("vehicle not tracked")
+	}
+
+	// latency: 100 -> 100+(300-100)/8 = 125 -> 125+(100-125)/8 = 121.875
+	if want := 121875 * time.Microsecond; q.Latency != want {
+		t.Errorf("Latency = %v, want %v", q.Latency, want)
+	}
+	// jitter: 0 -> 200/16 = 12.5 -> 12.5+(200-12.5)/16 = 24.21875
+	if want := 24218750 * time.Nanosecond; q.Jitter != want {
+		t.Errorf("Jitter = %v, want %v", q.Jitter, want)
+	}
+	if q.DropRate != 0 || q.Samples != 3 {
+		t.Errorf("DropRate = %v, Samples = %d; want 0, 3", q.DropRate, q.Samples)
+	}
+}
+
+func TestQualityTrackerDropRate(t *testing.T) {
+	tracker := NewQualityTracker(10)
+
+	// Acknowledged after two re-sends: two drops, then a delivery.
+	tracker.Observe(ackedCommand("vh-1", 2, 200*time.Millisecond))
+
+	// Never acknowledged: the send and its re-send were both dropped.
+	sent := metav1.NewTime(qualityEpoch)
+	old := &iovv1alpha2.VehicleCommand{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+		Spec:       iovv1alpha2.VehicleCommandSpec{VehicleName: "vh-1"},
+		Status:     iovv1alpha2.VehicleCommandStatus{SentTime: &sent, ResendCount: 1},
+	}
+	failed := old.DeepCopy()
+	MarkFailed(failed, "not acknowledged")
+	tracker.Observe(old, failed)
+
+	// Re-observing the same state change is a no-op.
+	tracker.Observe(failed, failed)
+
+	q, _ := tracker.Get("default", "vh-1")
+	want := 0.0
+	for _, dropped := range []bool{true, true, false, true, true} {
+		if dropped {
+			want += dropGain * (1 - want)
+		} else {
+			want -= dropGain * want
+		}
+	}
+	if math.Abs(q.DropRate-want) > 1e-9 || q.Samples != 5 {
+		t.Errorf("DropRate = %v, Samples = %d; want %v, 5", q.DropRate, q.Samples, want)
+	}
+	if q.Latency != 200*time.Millisecond {
+		t.Errorf("Latency = %v, want the latency of the last send", q.Latency)
+	}
+}
+
+func TestQualityTrackerBoundsVehicles(t *testing.T) {
+	tracker := NewQualityTracker(2)
+
+	tracker.Observe(ackedCommand("vh-1", 0, time.Second))
+	tracker.Observe(ackedCommand("vh-2", 0, time.Second))
+	tracker.Observe(ackedCommand("vh-1", 0, time.Second)) // vh-1 is now the most recent
+	tracker.Observe(ackedCommand("vh-3", 0, time.Second))
+
+	if _, ok := tracker.Get("default", "vh-2"); ok {
+		t.Error("least recently active vehicle was not evicted")
+	}
+	for _, v := range []string{"vh-1", "vh-3"} {
+		if _, ok := tracker.Get("default", v); !ok {
+			t.Errorf("%s was evicted", v)
+		}
+	}
+
+	if NewQualityTracker(0) != nil {
+		t.Error("a zero limit should disable tracking")
+	}
+}
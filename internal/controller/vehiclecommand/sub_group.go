@@ -0,0 +1,125 @@
+package vehiclecommand
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// GroupReconciler handles commands targeting a VehicleGroup (Spec.GroupRef). It creates one
+// command per group member and aggregates their phases into the group command.
+// Group commands are never sent themselves, so it halts the chain for them.
+type GroupReconciler struct {
+	Client client.Client
+	Scheme *runtime.Scheme
+}
+
+var _ SubReconciler = (*GroupReconciler)(nil)
+
+func NewGroupReconciler(cli client.Client, sche *runtime.Scheme) *GroupReconciler {
+	return &GroupReconciler{Client: cli, Scheme: sche}
+}
+
+// Reconcile implements the SubReconciler interface.
+func (g *GroupReconciler) Reconcile(ctx context.Context, cmd *iovv1alpha2.VehicleCommand) (ctrl.Result, error) {
+	if cmd.Spec.GroupRef == "" {
+		return ctrl.Result{}, nil
+	}
+
+	switch cmd.Status.Phase {
+	case iovv1alpha2.CommandPhasePending:
+		if err := g.fanOut(ctx, cmd); err != nil {
+			return ctrl.Result{}, err
+		}
+	case iovv1alpha2.CommandPhaseSent:
+		if err := g.aggregate(ctx, cmd); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, ErrHaltChain
+}
+
+// fanOut creates the per-member commands from the members the group controller materialized.
+// Creating is idempotent, so a failed reconcile can simply be retried.
+func (g *GroupReconciler) fanOut(ctx context.Context, cmd *iovv1alpha2.VehicleCommand) error {
+	var group iovv1alpha2.VehicleGroup
+	if err := g.Client.Get(ctx, client.ObjectKey{Namespace: cmd.Namespace, Name: cmd.Spec.GroupRef}, &group); err != nil {
+		if apierrors.IsNotFound(err) {
+			MarkFailed(cmd, fmt.Sprintf("VehicleGroup %q not found", cmd.Spec.GroupRef))
+			return nil
+		}
+		return err
+	}
+	if len(group.Status.Members) == 0 {
+		MarkFailed(cmd, fmt.Sprintf("VehicleGroup %q has no members", cmd.Spec.GroupRef))
+		return nil
+	}
+
+	for _, vehicle := range group.Status.Members {
+		child := &iovv1alpha2.VehicleCommand{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%s", cmd.Name, vehicle),
+				Namespace: cmd.Namespace,
+				Labels:    map[string]string{iovv1alpha2.GroupCommandLabel: cmd.Name},
+			},
+			Spec: *cmd.Spec.DeepCopy(),
+		}
+		child.Spec.GroupRef = ""
+		child.Spec.VehicleName = vehicle
+		if err := controllerutil.SetControllerReference(cmd, child, g.Scheme); err != nil {
+			return err
+		}
+		if err := g.Client.Create(ctx, child); err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create command for vehicle %s: %w", vehicle, err)
+		}
+	}
+
+	log.FromContext(ctx).Info("Fanned out group command", "group", group.Name, "members", len(group.Status.Members))
+	cmd.Status.GroupMembers = int32(len(group.Status.Members))
+	MarkSent(cmd, fmt.Sprintf("Created %d commands for VehicleGroup %s", len(group.Status.Members), group.Name))
+	return nil
+}
+
+// aggregate finishes the group command once all per-member commands have finished.
+// It succeeds only if all of them succeeded. The per-member commands are listed from the
+// cache, which may not hold all of them yet right after the fan-out: the group command
+// waits until it sees as many as were created.
+func (g *GroupReconciler) aggregate(ctx context.Context, cmd *iovv1alpha2.VehicleCommand) error {
+	var children iovv1alpha2.VehicleCommandList
+	if err := g.Client.List(ctx, &children, client.InNamespace(cmd.Namespace), client.MatchingLabels{iovv1alpha2.GroupCommandLabel: cmd.Name}); err != nil {
+		return err
+	}
+
+	var finished, succeeded int
+	for i := range children.Items {
+		if isTerminalState(&children.Items[i]) {
+			finished++
+		}
+		if children.Items[i].Status.Phase == iovv1alpha2.CommandPhaseSucceeded {
+			succeeded++
+		}
+	}
+
+	total := len(children.Items)
+	switch {
+	case total == 0 || total < int(cmd.Status.GroupMembers):
+		cmd.Status.Message = fmt.Sprintf("%d of %d commands observed", total, cmd.Status.GroupMembers)
+	case finished < total:
+		cmd.Status.Message = fmt.Sprintf("%d of %d commands finished", finished, total)
+	case succeeded == total:
+		MarkSucceeded(cmd)
+	default:
+		MarkFailed(cmd, fmt.Sprintf("%d of %d commands did not succeed", total-succeeded, total))
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package vehiclecommand
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func TestGroupReconcilerFanOut(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	group := &iovv1alpha2.VehicleGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "berlin", Namespace: "default"},
+		Status:     iovv1alpha2.VehicleGroupStatus{Members: []string{"vh-1", "vh-2"}, MemberCount: 2},
+	}
+	empty := &iovv1alpha2.VehicleGroup{ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "default"}}
+
+	tests := []struct {
+		name         string
+		groupRef     string
+		wantPhase    iovv1alpha2.CommandPhase
+		wantChildren int
+	}{
+		{name: "fan out", groupRef: "berlin", wantPhase: iovv1alpha2.CommandPhaseSent, wantChildren: 2},
+		{name: "missing group", groupRef: "typo", wantPhase: iovv1alpha2.CommandPhaseFailed},
+		{name: "no members", groupRef: "empty", wantPhase: iovv1alpha2.CommandPhaseFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &iovv1alpha2.VehicleCommand{
+				ObjectMeta: metav1.ObjectMeta{Name: "reboot", Namespace: "default", UID: "cmd-uid"},
+				Spec:       iovv1alpha2.VehicleCommandSpec{GroupRef: tt.groupRef, Method: "Reboot"},
+				Status:     iovv1alpha2.VehicleCommandStatus{Phase: iovv1alpha2.CommandPhasePending},
+			}
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(group, empty).Build()
+
+			_, err := NewGroupReconciler(cli, scheme).Reconcile(context.Background(), cmd)
+			if !errors.Is(err, ErrHaltChain) {
+				t.Fatalf("Reconcile() error = %v, want ErrHaltChain", err)
+			}
+			if cmd.Status.Phase != tt.wantPhase {
+				t.Errorf("phase = %s (%s), want %s", cmd.Status.Phase, cmd.Status.Message, tt.wantPhase)
+			}
+			if cmd.Status.GroupMembers != int32(tt.wantChildren) {
+				t.Errorf("group members = %d, want %d", cmd.Status.GroupMembers, tt.wantChildren)
+			}
+
+			var children iovv1alpha2.VehicleCommandList
+			if err := cli.List(context.Background(), &children, client.MatchingLabels{iovv1alpha2.GroupCommandLabel: "reboot"}); err != nil {
+				t.Fatal(err)
+			}
+			if len(children.Items) != tt.wantChildren {
+				t.Fatalf("children = %d, want %d", len(children.Items), tt.wantChildren)
+			}
+			for _, c := range children.Items {
+				if c.Spec.GroupRef != "" || c.Spec.VehicleName == "" || c.Spec.Method != "Reboot" {
+					t.Errorf("child %s has spec %+v", c.Name, c.Spec)
+				}
+				if !metav1.IsControlledBy(&c, cmd) {
+					t.Errorf("child %s is not controlled by the group command", c.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupReconcilerAggregate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		members   int32
+		phases    []iovv1alpha2.CommandPhase
+		wantPhase iovv1alpha2.CommandPhase
+	}{
+		{name: "in progress", members: 2, phases: []iovv1alpha2.CommandPhase{iovv1alpha2.CommandPhaseSucceeded, iovv1alpha2.CommandPhaseSent}, wantPhase: iovv1alpha2.CommandPhaseSent},
+		{name: "all succeeded", members: 2, phases: []iovv1alpha2.CommandPhase{iovv1alpha2.CommandPhaseSucceeded, iovv1alpha2.CommandPhaseSucceeded}, wantPhase: iovv1alpha2.CommandPhaseSucceeded},
+		{name: "partial failure", members: 2, phases: []iovv1alpha2.CommandPhase{iovv1alpha2.CommandPhaseSucceeded, iovv1alpha2.CommandPhaseTimeout}, wantPhase: iovv1alpha2.CommandPhaseFailed},
+		// Right after the fan-out, the cache may not hold the per-member commands yet.
+		{name: "empty cache", members: 2, wantPhase: iovv1alpha2.CommandPhaseSent},
+		{name: "lagging cache", members: 3, phases: []iovv1alpha2.CommandPhase{iovv1alpha2.CommandPhaseSucceeded, iovv1alpha2.CommandPhaseSucceeded}, wantPhase: iovv1alpha2.CommandPhaseSent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(scheme)
+			for i, phase := range tt.phases {
+				builder = builder.WithObjects(&iovv1alpha2.VehicleCommand{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "reboot-" + string(rune('a'+i)),
+						Namespace: "default",
+						Labels:    map[string]string{iovv1alpha2.GroupCommandLabel: "reboot"},
+					},
+					Status: iovv1alpha2.VehicleCommandStatus{Phase: phase},
+				})
+			}
+			cli := builder.Build()
+
+			cmd := &iovv1alpha2.VehicleCommand{
+				ObjectMeta: metav1.ObjectMeta{Name: "reboot", Namespace: "default"},
+				Spec:       iovv1alpha2.VehicleCommandSpec{GroupRef: "berlin", Method: "Reboot"},
+				Status:     iovv1alpha2.VehicleCommandStatus{Phase: iovv1alpha2.CommandPhaseSent, GroupMembers: tt.members},
+			}
+			if _, err := NewGroupReconciler(cli, scheme).Reconcile(context.Background(), cmd); !errors.Is(err, ErrHaltChain) {
+				t.Fatalf("Reconcile() error = %v, want ErrHaltChain", err)
+			}
+			if cmd.Status.Phase != tt.wantPhase {
+				t.Errorf("phase = %s (%s), want %s", cmd.Status.Phase, cmd.Status.Message, tt.wantPhase)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package vehiclecommand
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+const (
+	// ReasonWaitingForVehicleState is set on the Ready condition while a command waits for its execution window.
+	ReasonWaitingForVehicleState = "WaitingForVehicleState"
+
+	// defaultMaxWaitSeconds applies to methods that don't set MaxWaitSeconds.
+	defaultMaxWaitSeconds int32 = 3600
+
+	// vehicleStateRecheck is how often a waiting command re-reads the reported vehicle state.
+	vehicleStateRecheck = 30 * time.Second
+)
+
+// PreconditionReconciler holds Pending commands back until the vehicle reports the
+// state required by the method's entry in its VehicleModel (e.g., parked).
+// Commands of a decommissioned vehicle (Lifecycle Retired) fail instead of being dispatched.
+type PreconditionReconciler struct {
+	Reader client.Reader
+
+	// now is overridable for tests.
+	now func() time.Time
+}
+
+var _ SubReconciler = (*PreconditionReconciler)(nil)
+
+func NewPreconditionReconciler(reader client.Reader) *PreconditionReconciler {
+	return &PreconditionReconciler{Reader: reader, now: time.Now}
+}
+
+// Reconcile implements the SubReconciler interface.
+func (p *PreconditionReconciler) Reconcile(ctx context.Context, cmd *iovv1alpha2.VehicleCommand) (ctrl.Result, error) {
+	// 1. Filter: Only process commands in 'Pending' phase
+	if cmd.Status.Phase != iovv1alpha2.CommandPhasePending {
+		return ctrl.Result{}, nil
+	}
+
+	vehicle, method, err := p.lookup(ctx, cmd)
+	if err != nil || vehicle == nil {
+		return ctrl.Result{}, err
+	}
+	if vehicle.Spec.Lifecycle == iovv1alpha2.VehicleLifecycleRetired {
+		log.FromContext(ctx).Info("Not dispatching command to a decommissioned vehicle", "vehicle", vehicle.Name)
+		MarkFailed(cmd, fmt.Sprintf("Vehicle %s is decommissioned", vehicle.Name))
+		return ctrl.Result{}, ErrHaltChain
+	}
+	if method == nil || len(method.RequiredState) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	// 2. The vehicle is in the required state: let the command through
+	unmet := unmetState(method.RequiredState, vehicle.Status.Properties)
+	if len(unmet) == 0 {
+		if cond := meta.FindStatusCondition(cmd.Status.Conditions, iovv1alpha2.ConditionTypeReady); cond != nil && cond.Reason == ReasonWaitingForVehicleState {
+			setReadyCondition(cmd, metav1.ConditionTrue, "VehicleStateReady", "Vehicle reports the required state")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// 3. Waited too long: give up instead of dispatching at an unsafe moment
+	maxWait := time.Duration(ptr.Deref(method.MaxWaitSeconds, defaultMaxWaitSeconds)) * time.Second
+	waitingSince := cmd.CreationTimestamp.Time
+	if cmd.Status.StartTime != nil {
+		waitingSince = cmd.Status.StartTime.Time
+	}
+	remaining := maxWait - p.now().Sub(waitingSince)
+	want := strings.Join(unmet, ", ")
+	if remaining <= 0 {
+		log.FromContext(ctx).Info("Vehicle did not reach the required state in time", "required", want, "maxWait", maxWait)
+		MarkFailed(cmd, fmt.Sprintf("Vehicle did not report %s within %s", want, maxWait))
+		return ctrl.Result{}, ErrHaltChain
+	}
+
+	// 4. Keep waiting
+	setReadyCondition(cmd, metav1.ConditionFalse, ReasonWaitingForVehicleState, fmt.Sprintf("Waiting for the vehicle to report %s", want))
+	return ctrl.Result{RequeueAfter: min(vehicleStateRecheck, remaining)}, ErrHaltChain
+}
+
+// lookup returns the command's vehicle and the model's entry for the command method.
+// A missing vehicle, model or entry means there is no precondition to enforce; the
+// vehicle is nil only if it is missing.
+func (p *PreconditionReconciler) lookup(ctx context.Context, cmd *iovv1alpha2.VehicleCommand) (*iovv1alpha2.Vehicle, *iovv1alpha2.CommandMethod, error) {
+	var vehicle iovv1alpha2.Vehicle
+	if err := p.Reader.Get(ctx, client.ObjectKey{Namespace: cmd.Namespace, Name: cmd.Spec.VehicleName}, &vehicle); err != nil {
+		return nil, nil, client.IgnoreNotFound(err)
+	}
+	if vehicle.Spec.VehicleModelRef == "" {
+		return &vehicle, nil, nil
+	}
+
+	var model iovv1alpha2.VehicleModel
+	if err := p.Reader.Get(ctx, client.ObjectKey{Namespace: cmd.Namespace, Name: vehicle.Spec.VehicleModelRef}, &model); err != nil {
+		if apierrors.IsNotFound(err) {
+			return &vehicle, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	i := slices.IndexFunc(model.Spec.Methods, func(m iovv1alpha2.CommandMethod) bool { return m.Name == cmd.Spec.Method })
+	if i < 0 {
+		return &vehicle, nil, nil
+	}
+	return &vehicle, &model.Spec.Methods[i], nil
+}
+
+// unmetState returns the required "key=value" pairs the reported properties don't satisfy, sorted.
+func unmetState(required, reported map[string]string) []string {
+	var unmet []string
+	for k, v := range required {
+		if got, ok := reported[k]; !ok || got != v {
+			unmet = append(unmet, k+"="+v)
+		}
+	}
+	slices.Sort(unmet)
+	return unmet
+}
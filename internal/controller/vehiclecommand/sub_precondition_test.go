@@ -0,0 +1,164 @@
+package vehiclecommand
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/audit"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func TestReconcileWaitsForVehicleState(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	model := &iovv1alpha2.VehicleModel{
+		ObjectMeta: metav1.ObjectMeta{Name: "model-3", Namespace: "default"},
+		Spec: iovv1alpha2.VehicleModelSpec{
+			Methods: []iovv1alpha2.CommandMethod{{Name: "Reboot", RequiredState: map[string]string{"gear": "P"}}},
+		},
+	}
+	vehicle := &iovv1alpha2.Vehicle{
+		ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default"},
+		Spec:       iovv1alpha2.VehicleSpec{VehicleModelRef: "model-3"},
+		Status:     iovv1alpha2.VehicleStatus{Properties: map[string]string{"gear": "D"}},
+	}
+	now := metav1.Now()
+	cmd := &iovv1alpha2.VehicleCommand{
+		ObjectMeta: metav1.ObjectMeta{Name: "reboot-vh-1", Namespace: "default"},
+		Spec:       iovv1alpha2.VehicleCommandSpec{VehicleName: "vh-1", Method: "Reboot"},
+		Status:     iovv1alpha2.VehicleCommandStatus{Phase: iovv1alpha2.CommandPhasePending, StartTime: &now},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(model, vehicle, cmd).WithStatusSubresource(vehicle, cmd).Build()
+
+	sender, observer := &countingReconciler{}, &countingReconciler{}
+	r := &Reconciler{
+		Client:         cli,
+		Scheme:         scheme,
+		Recorder:       record.NewFakeRecorder(10),
+		pause:          staticSwitch(false),
+		audit:          audit.Discard,
+		subReconcilers: []SubReconciler{NewPreconditionReconciler(cli), sender},
+		observers:      []SubReconciler{observer},
+	}
+	reconcile := func() ctrl.Result {
+		t.Helper()
+		res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cmd)})
+		if err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		return res
+	}
+
+	// Driving: the command waits and is not dispatched.
+	res := reconcile()
+	if sender.calls != 0 {
+		t.Fatalf("command dispatched while the vehicle is driving")
+	}
+	if observer.calls != 1 {
+		t.Errorf("observer ran %d times, want 1", observer.calls)
+	}
+	if res.RequeueAfter != vehicleStateRecheck {
+		t.Errorf("RequeueAfter = %v, want %v", res.RequeueAfter, vehicleStateRecheck)
+	}
+	var got iovv1alpha2.VehicleCommand
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(cmd), &got); err != nil {
+		t.Fatal(err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeReady)
+	if got.Status.Phase != iovv1alpha2.CommandPhasePending || cond == nil || cond.Reason != ReasonWaitingForVehicleState {
+		t.Fatalf("waiting command: phase %s, Ready condition %+v", got.Status.Phase, cond)
+	}
+
+	// Parked: the command is dispatched.
+	vehicle.Status.Properties["gear"] = "P"
+	if err := cli.Status().Update(context.Background(), vehicle); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	if sender.calls != 1 {
+		t.Errorf("sender ran %d times after the vehicle parked, want 1", sender.calls)
+	}
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(cmd), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, iovv1alpha2.ConditionTypeReady) {
+		t.Errorf("Ready condition should be cleared once the vehicle parked, got %+v", got.Status.Conditions)
+	}
+}
+
+func TestPreconditionReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		method      string
+		gear        string
+		lifecycle   iovv1alpha2.VehicleLifecycle
+		waited      time.Duration
+		wantHalt    bool
+		wantPhase   iovv1alpha2.CommandPhase
+		wantRequeue time.Duration
+	}{
+		{name: "no precondition", method: "Ping", gear: "D", wantPhase: iovv1alpha2.CommandPhasePending},
+		{name: "state reached", method: "Reboot", gear: "P", wantPhase: iovv1alpha2.CommandPhasePending},
+		{name: "waiting", method: "Reboot", gear: "D", waited: time.Minute, wantHalt: true, wantPhase: iovv1alpha2.CommandPhasePending, wantRequeue: vehicleStateRecheck},
+		{name: "requeue at max wait", method: "Reboot", gear: "D", waited: 590 * time.Second, wantHalt: true, wantPhase: iovv1alpha2.CommandPhasePending, wantRequeue: 10 * time.Second},
+		{name: "max wait exceeded", method: "Reboot", gear: "D", waited: 11 * time.Minute, wantHalt: true, wantPhase: iovv1alpha2.CommandPhaseFailed},
+		{name: "decommissioned", method: "Ping", gear: "P", lifecycle: iovv1alpha2.VehicleLifecycleRetired, wantHalt: true, wantPhase: iovv1alpha2.CommandPhaseFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := &iovv1alpha2.VehicleModel{
+				ObjectMeta: metav1.ObjectMeta{Name: "model-3", Namespace: "default"},
+				Spec: iovv1alpha2.VehicleModelSpec{
+					Methods: []iovv1alpha2.CommandMethod{{Name: "Reboot", RequiredState: map[string]string{"gear": "P"}, MaxWaitSeconds: ptr.To[int32](600)}},
+				},
+			}
+			vehicle := &iovv1alpha2.Vehicle{
+				ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default"},
+				Spec:       iovv1alpha2.VehicleSpec{VehicleModelRef: "model-3", Lifecycle: tt.lifecycle},
+				Status:     iovv1alpha2.VehicleStatus{Properties: map[string]string{"gear": tt.gear}},
+			}
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(model, vehicle).Build()
+
+			start := metav1.NewTime(now.Add(-tt.waited))
+			cmd := &iovv1alpha2.VehicleCommand{
+				ObjectMeta: metav1.ObjectMeta{Name: "cmd", Namespace: "default"},
+				Spec:       iovv1alpha2.VehicleCommandSpec{VehicleName: "vh-1", Method: tt.method},
+				Status:     iovv1alpha2.VehicleCommandStatus{Phase: iovv1alpha2.CommandPhasePending, StartTime: &start},
+			}
+
+			p := &PreconditionReconciler{Reader: cli, now: func() time.Time { return now }}
+			res, err := p.Reconcile(context.Background(), cmd)
+			if tt.wantHalt != errors.Is(err, ErrHaltChain) {
+				t.Fatalf("Reconcile() error = %v, wantHalt %v", err, tt.wantHalt)
+			}
+			if cmd.Status.Phase != tt.wantPhase {
+				t.Errorf("phase = %s, want %s", cmd.Status.Phase, tt.wantPhase)
+			}
+			if res.RequeueAfter != tt.wantRequeue {
+				t.Errorf("RequeueAfter = %v, want %v", res.RequeueAfter, tt.wantRequeue)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package vehiclecommand
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/metrics"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// ReasonNotAcknowledged is the Ready condition reason of a command that was never acknowledged.
+const ReasonNotAcknowledged = "NotAcknowledged"
+
+// ResendReconciler re-sends commands the vehicle has not acknowledged within the ack deadline.
+// MQTT QoS1 normally covers this, but a message can still be lost when the vehicle
+// drops off the network before the broker delivers it.
+type ResendReconciler struct {
+	HubClient HubClient
+
+	// ackDeadline is how long to wait for an acknowledgement; zero disables re-sending.
+	ackDeadline time.Duration
+	maxResends  int32
+
+	// now is overridable for tests.
+	now func() time.Time
+}
+
+var _ SubReconciler = (*ResendReconciler)(nil)
+
+func NewResendReconciler(hubClient HubClient, ackDeadline time.Duration, maxResends int32) *ResendReconciler {
+	return &ResendReconciler{
+		HubClient:   hubClient,
+		ackDeadline: ackDeadline,
+		maxResends:  maxResends,
+		now:         time.Now,
+	}
+}
+
+// Reconcile implements the SubReconciler interface.
+func (r *ResendReconciler) Reconcile(ctx context.Context, cmd *iovv1alpha2.VehicleCommand) (ctrl.Result, error) {
+	// 1. Filter: Only commands still waiting for the vehicle's acknowledgement
+	if r.ackDeadline <= 0 || cmd.Status.Phase != iovv1alpha2.CommandPhaseSent || cmd.Status.SentTime == nil {
+		return ctrl.Result{}, nil
+	}
+
+	// 2. Deadline not reached yet: requeue exactly when it would be
+	lastSent := cmd.Status.SentTime
+	if cmd.Status.LastResendTime != nil {
+		lastSent = cmd.Status.LastResendTime
+	}
+	remaining := r.ackDeadline - r.now().Sub(lastSent.Time)
+	if remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	logger := log.FromContext(ctx)
+
+	// 3. Resends exhausted
+	if cmd.Status.ResendCount >= r.maxResends {
+		logger.Info("Command not acknowledged, giving up", "resends", cmd.Status.ResendCount)
+		MarkFailed(cmd, fmt.Sprintf("Vehicle did not acknowledge the command after %d resends", cmd.Status.ResendCount))
+		setReadyCondition(cmd, metav1.ConditionFalse, ReasonNotAcknowledged, cmd.Status.Message)
+		return ctrl.Result{}, nil
+	}
+
+	// 4. Re-send
+	resp, err := r.HubClient.SendCommand(ctx, newSendRequest(cmd))
+	if err != nil {
+		logger.Error(err, "Failed to resend command to Hub")
+		metrics.CommandSentTotal.WithLabelValues("failure", string(cmd.Spec.Method)).Inc()
+
+		// Keep waiting for the Hub, the command stays Sent and may still be acknowledged.
+		if errors.Is(err, ErrHubUnavailable) {
+			return ctrl.Result{RequeueAfter: hubUnavailableRequeue}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if !resp.Accepted {
+		logger.Info("Hub rejected the resent command", "reason", resp.Message)
+		metrics.CommandSentTotal.WithLabelValues("rejected", string(cmd.Spec.Method)).Inc()
+		MarkFailed(cmd, fmt.Sprintf("Hub rejected: %s", resp.Message))
+		return ctrl.Result{}, nil
+	}
+
+	metrics.CommandSentTotal.WithLabelValues("resent", string(cmd.Spec.Method)).Inc()
+	markResent(cmd, r.now(), r.maxResends)
+	logger.Info("Command not acknowledged in time, resent", "resends", cmd.Status.ResendCount, "ackDeadline", r.ackDeadline)
+
+	return ctrl.Result{RequeueAfter: r.ackDeadline}, nil
+}
+
+// markResent records one more resend of a command that stays Sent.
+func markResent(cmd *iovv1alpha2.VehicleCommand, at time.Time, maxResends int32) {
+	now := metav1.NewTime(at)
+	cmd.Status.ResendCount++
+	cmd.Status.LastResendTime = &now
+	cmd.Status.LastUpdateTime = &now
+	cmd.Status.Message = fmt.Sprintf("Not acknowledged in time, resent (%d/%d)", cmd.Status.ResendCount, maxResends)
+}
@@ -0,0 +1,125 @@
+package vehiclecommand
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	pb "github.com/autopeer-io/autopeer/api/proto/v1"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// countingHubClient accepts every command and counts the calls.
+type countingHubClient struct{ calls int }
+
+func (c *countingHubClient) Start(ctx context.Context) error { return nil }
+
+func (c *countingHubClient) SendCommand(ctx context.Context, req *pb.SendCommandRequest) (*pb.SendCommandResponse, error) {
+	c.calls++
+	return &pb.SendCommandResponse{Accepted: true}, nil
+}
+
+func newSentCommand(sentAt time.Time) *iovv1alpha2.VehicleCommand {
+	sentTime := metav1.NewTime(sentAt)
+	return &iovv1alpha2.VehicleCommand{
+		ObjectMeta: metav1.ObjectMeta{Name: "ota-1", Namespace: "default"},
+		Spec:       iovv1alpha2.VehicleCommandSpec{VehicleName: "vin-1", Method: "OTA"},
+		Status:     iovv1alpha2.VehicleCommandStatus{Phase: iovv1alpha2.CommandPhaseSent, SentTime: &sentTime},
+	}
+}
+
+func TestResendReconcilerResendThenAck(t *testing.T) {
+	now := time.Now()
+	hub := &countingHubClient{}
+	r := NewResendReconciler(hub, 30*time.Second, 3)
+	r.now = func() time.Time { return now }
+	cmd := newSentCommand(now)
+
+	// Within the deadline: wait for the remaining time.
+	now = now.Add(10 * time.Second)
+	res, err := r.Reconcile(context.Background(), cmd)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if hub.calls != 0 || res.RequeueAfter != 20*time.Second {
+		t.Fatalf("calls = %d, RequeueAfter = %v; want no resend and 20s", hub.calls, res.RequeueAfter)
+	}
+
+	// Deadline missed: resend and wait a full deadline again.
+	now = now.Add(25 * time.Second)
+	res, err = r.Reconcile(context.Background(), cmd)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if hub.calls != 1 || cmd.Status.ResendCount != 1 {
+		t.Fatalf("calls = %d, ResendCount = %d; want 1 resend", hub.calls, cmd.Status.ResendCount)
+	}
+	if cmd.Status.Phase != iovv1alpha2.CommandPhaseSent || res.RequeueAfter != 30*time.Second {
+		t.Fatalf("phase = %s, RequeueAfter = %v; want Sent and 30s", cmd.Status.Phase, res.RequeueAfter)
+	}
+	if cmd.Status.LastResendTime == nil || !cmd.Status.LastResendTime.Time.Equal(now) {
+		t.Errorf("LastResendTime = %v, want %v", cmd.Status.LastResendTime, now)
+	}
+
+	// The deadline is now measured from the resend.
+	now = now.Add(10 * time.Second)
+	if res, _ = r.Reconcile(context.Background(), cmd); hub.calls != 1 || res.RequeueAfter != 20*time.Second {
+		t.Fatalf("calls = %d, RequeueAfter = %v; want no resend and 20s", hub.calls, res.RequeueAfter)
+	}
+
+	// The vehicle acknowledges: nothing left to resend.
+	cmd.Status.Phase = iovv1alpha2.CommandPhaseAcknowledged
+	now = now.Add(time.Minute)
+	res, err = r.Reconcile(context.Background(), cmd)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if hub.calls != 1 || cmd.Status.ResendCount != 1 || res.RequeueAfter != 0 {
+		t.Errorf("calls = %d, ResendCount = %d, RequeueAfter = %v; want acknowledged command left alone",
+			hub.calls, cmd.Status.ResendCount, res.RequeueAfter)
+	}
+}
+
+func TestResendReconcilerExhausted(t *testing.T) {
+	now := time.Now()
+	hub := &countingHubClient{}
+	r := NewResendReconciler(hub, 30*time.Second, 2)
+	r.now = func() time.Time { return now }
+	cmd := newSentCommand(now)
+
+	for i := 0; i < 3; i++ {
+		now = now.Add(31 * time.Second)
+		if _, err := r.Reconcile(context.Background(), cmd); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+
+	if hub.calls != 2 || cmd.Status.ResendCount != 2 {
+		t.Errorf("calls = %d, ResendCount = %d; want 2 resends", hub.calls, cmd.Status.ResendCount)
+	}
+	if cmd.Status.Phase != iovv1alpha2.CommandPhaseFailed || cmd.Status.CompletionTime == nil {
+		t.Fatalf("phase = %s, want Failed with CompletionTime", cmd.Status.Phase)
+	}
+	cond := meta.FindStatusCondition(cmd.Status.Conditions, iovv1alpha2.ConditionTypeReady)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != ReasonNotAcknowledged {
+		t.Errorf("Ready condition = %+v, want False/%s", cond, ReasonNotAcknowledged)
+	}
+}
+
+func TestResendReconcilerDisabled(t *testing.T) {
+	now := time.Now()
+	hub := &countingHubClient{}
+	r := NewResendReconciler(hub, 0, 3)
+	cmd := newSentCommand(now.Add(-time.Hour))
+
+	res, err := r.Reconcile(context.Background(), cmd)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if hub.calls != 0 || res.RequeueAfter != 0 || cmd.Status.Phase != iovv1alpha2.CommandPhaseSent {
+		t.Errorf("calls = %d, RequeueAfter = %v, phase = %s; want resend disabled", hub.calls, res.RequeueAfter, cmd.Status.Phase)
+	}
+}
@@ -2,9 +2,13 @@ package vehiclecommand
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -13,6 +17,9 @@ import (
 	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
 )
 
+// hubUnavailableRequeue is how long a Pending command waits before retrying an unreachable Hub.
+const hubUnavailableRequeue = 15 * time.Second
+
 // SenderReconciler is responsible for sending pending commands to the Hub.
 type SenderReconciler struct {
 	HubClient HubClient
@@ -37,12 +44,7 @@ func (s *SenderReconciler) Reconcile(ctx context.Context, cmd *iovv1alpha2.Vehic
 	logger.Info("Processing Pending command", "command", cmd.Spec.Method, "vehicle", cmd.Spec.VehicleName)
 
 	// 2. Construct the gRPC request
-	req := &pb.SendCommandRequest{
-		CommandName: cmd.Name,
-		VehicleId:   cmd.Spec.VehicleName,
-		CommandType: cmd.Spec.Method,
-		Parameters:  cmd.Spec.Parameters,
-	}
+	req := newSendRequest(cmd)
 
 	// 3. Call Hub via interface
 	start := time.Now()
@@ -52,10 +54,23 @@ func (s *SenderReconciler) Reconcile(ctx context.Context, cmd *iovv1alpha2.Vehic
 	if err != nil {
 		logger.Error(err, "Failed to send command to Hub")
 		metrics.CommandSentTotal.WithLabelValues("failure", string(cmd.Spec.Method)).Inc()
+
+		// The Hub is unreachable: keep the command Pending and surface it as a condition
+		// instead of flapping the phase. The status patch is kept by returning no error.
+		if errors.Is(err, ErrHubUnavailable) {
+			setReadyCondition(cmd, metav1.ConditionFalse, "HubUnreachable", err.Error())
+			return ctrl.Result{RequeueAfter: hubUnavailableRequeue}, nil
+		}
+
 		// Return error to trigger exponential backoff requeue by controller-runtime
 		return ctrl.Result{}, err
 	}
 
+	// The Hub answered, clear a previous HubUnreachable condition.
+	if meta.IsStatusConditionFalse(cmd.Status.Conditions, iovv1alpha2.ConditionTypeReady) {
+		setReadyCondition(cmd, metav1.ConditionTrue, "HubReachable", "Hub is reachable")
+	}
+
 	// 4. Handle Hub Rejection
 	if !resp.Accepted {
 		logger.Info("Hub rejected the command", "reason", resp.Message)
@@ -74,3 +89,17 @@ func (s *SenderReconciler) Reconcile(ctx context.Context, cmd *iovv1alpha2.Vehic
 
 	return ctrl.Result{}, nil
 }
+
+// defaultPriority is the priority of a command that doesn't set Spec.Priority (Normal).
+const defaultPriority int32 = 1
+
+// newSendRequest builds the Hub request for a command.
+func newSendRequest(cmd *iovv1alpha2.VehicleCommand) *pb.SendCommandRequest {
+	return &pb.SendCommandRequest{
+		CommandName: cmd.Name,
+		VehicleId:   cmd.Spec.VehicleName,
+		CommandType: cmd.Spec.Method,
+		Parameters:  cmd.Spec.Parameters,
+		Priority:    ptr.Deref(cmd.Spec.Priority, defaultPriority),
+	}
+}
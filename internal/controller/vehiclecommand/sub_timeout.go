@@ -0,0 +1,51 @@
+package vehiclecommand
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// TimeoutReconciler enforces Spec.TimeoutSeconds on commands that have been sent
+// but have not reached a terminal phase yet.
+type TimeoutReconciler struct {
+	// now is overridable for tests.
+	now func() time.Time
+}
+
+var _ SubReconciler = (*TimeoutReconciler)(nil)
+
+func NewTimeoutReconciler() *TimeoutReconciler {
+	return &TimeoutReconciler{now: time.Now}
+}
+
+// Reconcile implements the SubReconciler interface.
+func (t *TimeoutReconciler) Reconcile(ctx context.Context, cmd *iovv1alpha2.VehicleCommand) (ctrl.Result, error) {
+	// 1. Filter: Only in-flight commands with a timeout and a known SentTime
+	switch cmd.Status.Phase {
+	case iovv1alpha2.CommandPhaseSent, iovv1alpha2.CommandPhaseAcknowledged, iovv1alpha2.CommandPhaseRunning:
+	default:
+		return ctrl.Result{}, nil
+	}
+	if cmd.Spec.TimeoutSeconds == nil || cmd.Status.SentTime == nil {
+		return ctrl.Result{}, nil
+	}
+
+	// 2. Not expired yet: requeue exactly when it would expire
+	timeout := time.Duration(*cmd.Spec.TimeoutSeconds) * time.Second
+	remaining := timeout - t.now().Sub(cmd.Status.SentTime.Time)
+	if remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	// 3. Expired
+	log.FromContext(ctx).Info("Command timed out", "phase", cmd.Status.Phase, "timeout", timeout)
+	MarkTimeout(cmd, fmt.Sprintf("Command did not complete within %s (last phase: %s)", timeout, cmd.Status.Phase))
+
+	return ctrl.Result{}, nil
+}
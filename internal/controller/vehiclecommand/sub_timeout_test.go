@@ -0,0 +1,58 @@
+package vehiclecommand
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func TestTimeoutReconciler(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		phase       iovv1alpha2.CommandPhase
+		sentAgo     time.Duration
+		wantPhase   iovv1alpha2.CommandPhase
+		wantRequeue bool
+	}{
+		{name: "sent and expired", phase: iovv1alpha2.CommandPhaseSent, sentAgo: 2 * time.Minute, wantPhase: iovv1alpha2.CommandPhaseTimeout},
+		{name: "running and expired", phase: iovv1alpha2.CommandPhaseRunning, sentAgo: 2 * time.Minute, wantPhase: iovv1alpha2.CommandPhaseTimeout},
+		{name: "sent within deadline", phase: iovv1alpha2.CommandPhaseSent, sentAgo: 10 * time.Second, wantPhase: iovv1alpha2.CommandPhaseSent, wantRequeue: true},
+		{name: "terminal phase untouched", phase: iovv1alpha2.CommandPhaseSucceeded, sentAgo: 2 * time.Minute, wantPhase: iovv1alpha2.CommandPhaseSucceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sentTime := metav1.NewTime(now.Add(-tt.sentAgo))
+			cmd := &iovv1alpha2.VehicleCommand{
+				Spec:   iovv1alpha2.VehicleCommandSpec{TimeoutSeconds: ptr.To[int32](60)},
+				Status: iovv1alpha2.VehicleCommandStatus{Phase: tt.phase, SentTime: &sentTime},
+			}
+
+			r := &TimeoutReconciler{now: func() time.Time { return now }}
+			res, err := r.Reconcile(context.Background(), cmd)
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			if cmd.Status.Phase != tt.wantPhase {
+				t.Errorf("phase = %s, want %s", cmd.Status.Phase, tt.wantPhase)
+			}
+			if tt.wantPhase == iovv1alpha2.CommandPhaseTimeout && (cmd.Status.CompletionTime == nil || cmd.Status.Message == "") {
+				t.Errorf("timeout should set CompletionTime and Message, got %+v", cmd.Status)
+			}
+			if got := res.RequeueAfter > 0; got != tt.wantRequeue {
+				t.Errorf("RequeueAfter = %v, wantRequeue %v", res.RequeueAfter, tt.wantRequeue)
+			}
+			if tt.wantRequeue && res.RequeueAfter != 50*time.Second {
+				t.Errorf("RequeueAfter = %v, want remaining 50s", res.RequeueAfter)
+			}
+		})
+	}
+}
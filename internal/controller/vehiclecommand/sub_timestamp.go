@@ -0,0 +1,39 @@
+package vehiclecommand
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// TimestampReconciler fills in AcknowledgeTime and CompletionTime for phase changes
+// reported by the Hub, which only patches the phase and message.
+type TimestampReconciler struct{}
+
+var _ SubReconciler = (*TimestampReconciler)(nil)
+
+func NewTimestampReconciler() *TimestampReconciler {
+	return &TimestampReconciler{}
+}
+
+// Reconcile implements the SubReconciler interface.
+func (t *TimestampReconciler) Reconcile(ctx context.Context, cmd *iovv1alpha2.VehicleCommand) (ctrl.Result, error) {
+	now := metav1.Now()
+
+	switch cmd.Status.Phase {
+	case iovv1alpha2.CommandPhaseAcknowledged, iovv1alpha2.CommandPhaseRunning, iovv1alpha2.CommandPhaseSucceeded:
+		// The vehicle answered, so it has received the command.
+		if cmd.Status.AcknowledgeTime == nil && cmd.Status.SentTime != nil {
+			cmd.Status.AcknowledgeTime = &now
+		}
+	}
+
+	if isTerminalState(cmd) && cmd.Status.CompletionTime == nil {
+		cmd.Status.CompletionTime = &now
+	}
+
+	return ctrl.Result{}, nil
+}
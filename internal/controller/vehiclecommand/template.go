@@ -0,0 +1,48 @@
+package vehiclecommand
+
+import (
+	"context"
+	"maps"
+
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// applyTemplate resolves the command's CommandTemplate into its Spec and persists the result,
+// so later edits of the template do not change a command that is already in flight.
+// It returns a NotFound error if the template does not exist.
+func (r *Reconciler) applyTemplate(ctx context.Context, cmd *iovv1alpha2.VehicleCommand) error {
+	var tmpl iovv1alpha2.CommandTemplate
+	if err := r.Get(ctx, client.ObjectKey{Namespace: cmd.Namespace, Name: cmd.Spec.TemplateRef}, &tmpl); err != nil {
+		return err
+	}
+
+	original := cmd.DeepCopy()
+	resolveTemplate(&cmd.Spec, &tmpl.Spec)
+
+	log.FromContext(ctx).Info("Resolved command template", "template", tmpl.Name, "method", cmd.Spec.Method)
+	return r.Patch(ctx, cmd, client.MergeFrom(original))
+}
+
+// resolveTemplate fills the fields spec leaves unset from tmpl.
+// Fields set on the command take precedence; Parameters are merged key by key.
+func resolveTemplate(spec *iovv1alpha2.VehicleCommandSpec, tmpl *iovv1alpha2.CommandTemplateSpec) {
+	if spec.Method == "" {
+		spec.Method = tmpl.Method
+	}
+	if spec.Priority == nil && tmpl.Priority != nil {
+		spec.Priority = ptr.To(*tmpl.Priority)
+	}
+	if spec.TimeoutSeconds == nil && tmpl.TimeoutSeconds != nil {
+		spec.TimeoutSeconds = ptr.To(*tmpl.TimeoutSeconds)
+	}
+
+	if len(tmpl.Parameters) > 0 {
+		params := maps.Clone(tmpl.Parameters)
+		maps.Copy(params, spec.Parameters)
+		spec.Parameters = params
+	}
+}
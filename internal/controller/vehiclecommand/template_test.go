@@ -0,0 +1,166 @@
+package vehiclecommand
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/autopeer-io/autopeer/internal/pkg/audit"
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func TestResolveTemplate(t *testing.T) {
+	tmpl := iovv1alpha2.CommandTemplateSpec{
+		Method:         "UploadLogs",
+		Parameters:     map[string]string{"level": "info", "since": "1h"},
+		Priority:       ptr.To[int32](0),
+		TimeoutSeconds: ptr.To[int32](600),
+	}
+
+	tests := []struct {
+		name       string
+		spec       iovv1alpha2.VehicleCommandSpec
+		wantMethod string
+		wantParams map[string]string
+		wantPrio   int32
+		wantTO     int32
+	}{
+		{
+			name:       "template only",
+			spec:       iovv1alpha2.VehicleCommandSpec{},
+			wantMethod: "UploadLogs",
+			wantParams: map[string]string{"level": "info", "since": "1h"},
+			wantPrio:   0,
+			wantTO:     600,
+		},
+		{
+			name: "command overrides",
+			spec: iovv1alpha2.VehicleCommandSpec{
+				Parameters:     map[string]string{"level": "debug", "component": "modem"},
+				Priority:       ptr.To[int32](2),
+				TimeoutSeconds: ptr.To[int32](60),
+			},
+			wantMethod: "UploadLogs",
+			wantParams: map[string]string{"level": "debug", "since": "1h", "component": "modem"},
+			wantPrio:   2,
+			wantTO:     60,
+		},
+		{
+			name:       "command method wins",
+			spec:       iovv1alpha2.VehicleCommandSpec{Method: "UploadCrashDumps"},
+			wantMethod: "UploadCrashDumps",
+			wantParams: map[string]string{"level": "info", "since": "1h"},
+			wantPrio:   0,
+			wantTO:     600,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := tt.spec
+			resolveTemplate(&spec, &tmpl)
+
+			if spec.Method != tt.wantMethod {
+				t.Errorf("Method = %q, want %q", spec.Method, tt.wantMethod)
+			}
+			if !maps.Equal(spec.Parameters, tt.wantParams) {
+				t.Errorf("Parameters = %v, want %v", spec.Parameters, tt.wantParams)
+			}
+			if ptr.Deref(spec.Priority, -1) != tt.wantPrio {
+				t.Errorf("Priority = %v, want %d", spec.Priority, tt.wantPrio)
+			}
+			if ptr.Deref(spec.TimeoutSeconds, -1) != tt.wantTO {
+				t.Errorf("TimeoutSeconds = %v, want %d", spec.TimeoutSeconds, tt.wantTO)
+			}
+		})
+	}
+
+	// Resolving must not alias the template's parameters.
+	spec := iovv1alpha2.VehicleCommandSpec{}
+	resolveTemplate(&spec, &tmpl)
+	spec.Parameters["level"] = "trace"
+	if tmpl.Parameters["level"] != "info" {
+		t.Errorf("resolving modified the template parameters: %v", tmpl.Parameters)
+	}
+}
+
+func TestReconcileResolvesTemplate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &iovv1alpha2.CommandTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "upload-logs", Namespace: "default"},
+		Spec:       iovv1alpha2.CommandTemplateSpec{Method: "UploadLogs", Parameters: map[string]string{"level": "info"}},
+	}
+
+	tests := []struct {
+		name      string
+		ref       string
+		wantPhase iovv1alpha2.CommandPhase
+		wantSpec  iovv1alpha2.VehicleCommandSpec
+	}{
+		{
+			name:      "resolved",
+			ref:       "upload-logs",
+			wantPhase: iovv1alpha2.CommandPhasePending,
+			wantSpec: iovv1alpha2.VehicleCommandSpec{
+				VehicleName: "vh-1", TemplateRef: "upload-logs", Method: "UploadLogs",
+				Parameters: map[string]string{"level": "info", "since": "24h"},
+			},
+		},
+		{
+			name:      "missing template",
+			ref:       "typo",
+			wantPhase: iovv1alpha2.CommandPhaseFailed,
+			wantSpec: iovv1alpha2.VehicleCommandSpec{
+				VehicleName: "vh-1", TemplateRef: "typo",
+				Parameters: map[string]string{"since": "24h"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &iovv1alpha2.VehicleCommand{
+				ObjectMeta: metav1.ObjectMeta{Name: "logs-vh-1", Namespace: "default"},
+				Spec: iovv1alpha2.VehicleCommandSpec{
+					VehicleName: "vh-1", TemplateRef: tt.ref,
+					Parameters: map[string]string{"since": "24h"},
+				},
+			}
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tmpl, cmd).WithStatusSubresource(cmd).Build()
+
+			r := &Reconciler{
+				Client:   cli,
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+				pause:    staticSwitch(false),
+				audit:    audit.Discard,
+			}
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cmd)}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			var got iovv1alpha2.VehicleCommand
+			if err := cli.Get(context.Background(), client.ObjectKeyFromObject(cmd), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.Phase != tt.wantPhase {
+				t.Errorf("phase = %s (%s), want %s", got.Status.Phase, got.Status.Message, tt.wantPhase)
+			}
+			if got.Spec.Method != tt.wantSpec.Method || !maps.Equal(got.Spec.Parameters, tt.wantSpec.Parameters) {
+				t.Errorf("spec = %+v, want %+v", got.Spec, tt.wantSpec)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package vehiclecommand
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// MethodAuthorizer is a validating webhook that restricts command methods to user groups.
+// Kubernetes RBAC decides who may create VehicleCommands at all; it cannot tell an OTA from a Reboot.
+//
+// It validates VehicleCommands, including group commands, and ScheduledVehicleCommands, whose
+// VehicleCommands the controller creates later under its own identity. A command that leaves
+// its method to a CommandTemplate is checked against the template's method, and the templates
+// themselves are validated too, so an approved template cannot be switched to a restricted
+// method afterwards. The commands the controller creates for schedules and groups need its
+// service account in the allowed groups.
+type MethodAuthorizer struct {
+	// reader looks up the CommandTemplates; it should read from the API server, so a template
+	// created right before the command is found.
+	reader client.Reader
+
+	// allowlist maps a method to the groups allowed to create its commands.
+	// Methods not listed are not restricted.
+	allowlist map[string][]string
+}
+
+var _ admission.CustomValidator = (*MethodAuthorizer)(nil)
+
+func NewMethodAuthorizer(reader client.Reader, allowlist map[string][]string) *MethodAuthorizer {
+	return &MethodAuthorizer{reader: reader, allowlist: allowlist}
+}
+
+// SetupWebhookWithManager registers the webhooks with the manager's webhook server.
+func (a *MethodAuthorizer) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&iovv1alpha2.VehicleCommand{}).
+		WithValidator(a).
+		Complete(); err != nil {
+		return err
+	}
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&iovv1alpha2.ScheduledVehicleCommand{}).
+		WithValidator(a).
+		Complete(); err != nil {
+		return err
+	}
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&iovv1alpha2.CommandTemplate{}).
+		WithValidator(a).
+		Complete()
+}
+
+// ValidateCreate implements admission.CustomValidator.
+func (a *MethodAuthorizer) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	ref, err := methodRefOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	method, err := a.effectiveMethod(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return nil, a.authorize(ctx, ref, method)
+}
+
+// ValidateUpdate implements admission.CustomValidator.
+// Only a change of the effective method is checked: status updates and the controller's own
+// patches, such as filling in the method of the command's template, go through.
+func (a *MethodAuthorizer) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldRef, err := methodRefOf(oldObj)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := methodRefOf(newObj)
+	if err != nil {
+		return nil, err
+	}
+	if ref.method == oldRef.method && ref.templateRef == oldRef.templateRef {
+		return nil, nil
+	}
+	method, err := a.effectiveMethod(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	// A template that disappeared leaves the old method unknown: the new one is checked.
+	if oldMethod, err := a.effectiveMethod(ctx, oldRef); err == nil && oldMethod == method {
+		return nil, nil
+	}
+	return nil, a.authorize(ctx, ref, method)
+}
+
+// ValidateDelete implements admission.CustomValidator.
+func (a *MethodAuthorizer) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// methodRef is what MethodAuthorizer checks of a VehicleCommand, ScheduledVehicleCommand or CommandTemplate.
+type methodRef struct {
+	resource    string
+	namespace   string
+	name        string
+	method      string
+	templateRef string
+}
+
+func methodRefOf(obj runtime.Object) (methodRef, error) {
+	switch o := obj.(type) {
+	case *iovv1alpha2.VehicleCommand:
+		return methodRef{resource: "vehiclecommands", namespace: o.Namespace, name: o.Name, method: o.Spec.Method, templateRef: o.Spec.TemplateRef}, nil
+	case *iovv1alpha2.ScheduledVehicleCommand:
+		return methodRef{resource: "scheduledvehiclecommands", namespace: o.Namespace, name: o.Name, method: o.Spec.Method, templateRef: o.Spec.TemplateRef}, nil
+	case *iovv1alpha2.CommandTemplate:
+		return methodRef{resource: "commandtemplates", namespace: o.Namespace, name: o.Name, method: o.Spec.Method}, nil
+	default:
+		return methodRef{}, fmt.Errorf("expected a VehicleCommand, ScheduledVehicleCommand or CommandTemplate, got %T", obj)
+	}
+}
+
+// effectiveMethod returns the method the command runs: its own, or else its template's.
+// The template must exist, as the controller would otherwise resolve whatever template of
+// that name is created later.
+func (a *MethodAuthorizer) effectiveMethod(ctx context.Context, ref methodRef) (string, error) {
+	if ref.method != "" || ref.templateRef == "" {
+		return ref.method, nil
+	}
+
+	var tmpl iovv1alpha2.CommandTemplate
+	if err := a.reader.Get(ctx, client.ObjectKey{Namespace: ref.namespace, Name: ref.templateRef}, &tmpl); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", apierrors.NewForbidden(iovv1alpha2.GroupVersion.WithResource(ref.resource).GroupResource(), ref.name,
+				fmt.Errorf("CommandTemplate %q not found, its method cannot be authorized", ref.templateRef))
+		}
+		return "", err
+	}
+	return tmpl.Spec.Method, nil
+}
+
+// authorize rejects the command unless method is unrestricted or the requesting user is in one of its groups.
+func (a *MethodAuthorizer) authorize(ctx context.Context, ref methodRef, method string) error {
+	allowed, ok := a.allowlist[method]
+	if !ok {
+		return nil
+	}
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	user := req.UserInfo
+	if slices.ContainsFunc(user.Groups, func(g string) bool { return slices.Contains(allowed, g) }) {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("Rejected command of a restricted method", "resource", ref.resource, "command", ref.name, "method", method, "user", user.Username)
+	return apierrors.NewForbidden(iovv1alpha2.GroupVersion.WithResource(ref.resource).GroupResource(), ref.name,
+		fmt.Errorf("user %q may not create %s commands: restricted to groups %s", user.Username, method, strings.Join(allowed, ", ")))
+}
@@ -0,0 +1,128 @@
+package vehiclecommand
+
+import (
+	"context"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func TestMethodAuthorizer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&iovv1alpha2.CommandTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly-ota", Namespace: "default"},
+		Spec:       iovv1alpha2.CommandTemplateSpec{Method: "OTA"},
+	}).Build()
+	authorizer := NewMethodAuthorizer(cli, map[string][]string{"OTA": {"senior-ops", "system:serviceaccounts:autopeer-io"}})
+
+	newCmd := func(method, templateRef string) *iovv1alpha2.VehicleCommand {
+		return &iovv1alpha2.VehicleCommand{
+			ObjectMeta: metav1.ObjectMeta{Name: "cmd-vh-1", Namespace: "default"},
+			Spec:       iovv1alpha2.VehicleCommandSpec{VehicleName: "vh-1", Method: method, TemplateRef: templateRef},
+		}
+	}
+	newGroupCmd := func(method, templateRef string) *iovv1alpha2.VehicleCommand {
+		return &iovv1alpha2.VehicleCommand{
+			ObjectMeta: metav1.ObjectMeta{Name: "cmd-berlin", Namespace: "default"},
+			Spec:       iovv1alpha2.VehicleCommandSpec{GroupRef: "berlin", Method: method, TemplateRef: templateRef},
+		}
+	}
+	newSchedule := func(method, templateRef string) *iovv1alpha2.ScheduledVehicleCommand {
+		return &iovv1alpha2.ScheduledVehicleCommand{
+			ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "default"},
+			Spec:       iovv1alpha2.ScheduledVehicleCommandSpec{Schedule: "@daily", Method: method, TemplateRef: templateRef},
+		}
+	}
+	newTemplate := func(method string) *iovv1alpha2.CommandTemplate {
+		return &iovv1alpha2.CommandTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "nightly-ota", Namespace: "default"},
+			Spec:       iovv1alpha2.CommandTemplateSpec{Method: method},
+		}
+	}
+	requestBy := func(user string, groups ...string) context.Context {
+		return admission.NewContextWithRequest(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			UserInfo: authenticationv1.UserInfo{Username: user, Groups: append(groups, "system:authenticated")},
+		}})
+	}
+	alice := requestBy("alice", "senior-ops")
+	bob := requestBy("bob", "ops")
+	controller := requestBy("system:serviceaccount:autopeer-io:controller-manager", "system:serviceaccounts", "system:serviceaccounts:autopeer-io")
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		obj        runtime.Object
+		wantDenied bool
+	}{
+		{name: "allowed group", ctx: alice, obj: newCmd("OTA", "")},
+		{name: "controller service account", ctx: controller, obj: newCmd("OTA", "")},
+		{name: "denied group", ctx: bob, obj: newCmd("OTA", ""), wantDenied: true},
+		{name: "unrestricted method", ctx: bob, obj: newCmd("Reboot", "")},
+		{name: "restricted template, allowed group", ctx: alice, obj: newCmd("", "nightly-ota")},
+		{name: "restricted template, denied group", ctx: bob, obj: newCmd("", "nightly-ota"), wantDenied: true},
+		{name: "method overrides the template", ctx: bob, obj: newCmd("Reboot", "nightly-ota")},
+		{name: "missing template", ctx: alice, obj: newCmd("", "typo"), wantDenied: true},
+		{name: "group command, denied group", ctx: bob, obj: newGroupCmd("OTA", ""), wantDenied: true},
+		{name: "group command with restricted template, denied group", ctx: bob, obj: newGroupCmd("", "nightly-ota"), wantDenied: true},
+		{name: "schedule, allowed group", ctx: alice, obj: newSchedule("OTA", "")},
+		{name: "schedule, denied group", ctx: bob, obj: newSchedule("OTA", ""), wantDenied: true},
+		{name: "schedule with restricted template, denied group", ctx: bob, obj: newSchedule("", "nightly-ota"), wantDenied: true},
+		{name: "schedule of an unrestricted method", ctx: bob, obj: newSchedule("Reboot", "")},
+		{name: "template of a restricted method, allowed group", ctx: alice, obj: newTemplate("OTA")},
+		{name: "template of a restricted method, denied group", ctx: bob, obj: newTemplate("OTA"), wantDenied: true},
+		{name: "template of an unrestricted method", ctx: bob, obj: newTemplate("Reboot")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := authorizer.ValidateCreate(tt.ctx, tt.obj)
+			if tt.wantDenied != apierrors.IsForbidden(err) || (!tt.wantDenied && err != nil) {
+				t.Errorf("ValidateCreate() error = %v, wantDenied %v", err, tt.wantDenied)
+			}
+		})
+	}
+
+	suspended := newSchedule("OTA", "")
+	suspended.Spec.Suspend = true
+
+	updates := []struct {
+		name       string
+		ctx        context.Context
+		oldObj     runtime.Object
+		newObj     runtime.Object
+		wantDenied bool
+	}{
+		{name: "same method", ctx: bob, oldObj: newCmd("OTA", ""), newObj: newCmd("OTA", "")},
+		{name: "to a restricted method", ctx: bob, oldObj: newCmd("Reboot", ""), newObj: newCmd("OTA", ""), wantDenied: true},
+		{name: "to a restricted template", ctx: bob, oldObj: newCmd("Reboot", ""), newObj: newCmd("", "nightly-ota"), wantDenied: true},
+		// The controller fills in the method of the template the command was authorized for.
+		{name: "template resolved", ctx: requestBy("system:serviceaccount:other:controller"), oldObj: newCmd("", "nightly-ota"), newObj: newCmd("OTA", "nightly-ota")},
+		{name: "schedule to a restricted method", ctx: bob, oldObj: newSchedule("Reboot", ""), newObj: newSchedule("OTA", ""), wantDenied: true},
+		{name: "schedule to a restricted template", ctx: bob, oldObj: newSchedule("Reboot", ""), newObj: newSchedule("", "nightly-ota"), wantDenied: true},
+		{name: "schedule suspended", ctx: bob, oldObj: newSchedule("OTA", ""), newObj: suspended},
+		// The commands referring to the template would inherit its new method.
+		{name: "template to a restricted method", ctx: bob, oldObj: newTemplate("Reboot"), newObj: newTemplate("OTA"), wantDenied: true},
+		{name: "template to a restricted method, allowed group", ctx: alice, oldObj: newTemplate("Reboot"), newObj: newTemplate("OTA")},
+		{name: "template to an unrestricted method", ctx: bob, oldObj: newTemplate("OTA"), newObj: newTemplate("Reboot")},
+	}
+
+	for _, tt := range updates {
+		t.Run("update "+tt.name, func(t *testing.T) {
+			_, err := authorizer.ValidateUpdate(tt.ctx, tt.oldObj, tt.newObj)
+			if tt.wantDenied != apierrors.IsForbidden(err) || (!tt.wantDenied && err != nil) {
+				t.Errorf("ValidateUpdate() error = %v, wantDenied %v", err, tt.wantDenied)
+			}
+		})
+	}
+}
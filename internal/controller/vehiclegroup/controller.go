@@ -0,0 +1,144 @@
+package vehiclegroup
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+const (
+	// ReasonMembersResolved means every listed vehicle exists and the selector is valid.
+	ReasonMembersResolved = "MembersResolved"
+
+	// ReasonVehiclesNotFound means some vehicles listed in Spec.Vehicles do not exist. They are not members.
+	ReasonVehiclesNotFound = "VehiclesNotFound"
+
+	// ReasonInvalidSelector means Spec.Selector cannot be parsed.
+	ReasonInvalidSelector = "InvalidSelector"
+)
+
+// Reconciler keeps the member list of a VehicleGroup in its status.
+type Reconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// NewReconciler creates a new Reconciler for VehicleGroup.
+func NewReconciler(cli client.Client, sche *runtime.Scheme) *Reconciler {
+	return &Reconciler{Client: cli, Scheme: sche}
+}
+
+//+kubebuilder:rbac:groups=iov.autopeer.io,resources=vehiclegroups,verbs=get;list;watch
+//+kubebuilder:rbac:groups=iov.autopeer.io,resources=vehiclegroups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=iov.autopeer.io,resources=vehicles,verbs=get;list;watch
+
+// Reconcile recomputes the members of a VehicleGroup.
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var group iovv1alpha2.VehicleGroup
+	if err := r.Get(ctx, req.NamespacedName, &group); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	original := group.DeepCopy()
+
+	var vehicles iovv1alpha2.VehicleList
+	if err := r.List(ctx, &vehicles, client.InNamespace(group.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	members, missing, err := Members(&group, vehicles.Items)
+	switch {
+	case err != nil:
+		// Keep the last known members: a typo in the selector must not empty the group.
+		setReadyCondition(&group, metav1.ConditionFalse, ReasonInvalidSelector, err.Error())
+	case len(missing) > 0:
+		group.Status.Members = members
+		setReadyCondition(&group, metav1.ConditionFalse, ReasonVehiclesNotFound,
+			fmt.Sprintf("Listed vehicles not found: %s", strings.Join(missing, ", ")))
+	default:
+		group.Status.Members = members
+		setReadyCondition(&group, metav1.ConditionTrue, ReasonMembersResolved, fmt.Sprintf("%d members", len(members)))
+	}
+	group.Status.MemberCount = int32(len(group.Status.Members))
+	group.Status.ObservedGeneration = group.Generation
+
+	if equality.Semantic.DeepEqual(original.Status, group.Status) {
+		return ctrl.Result{}, nil
+	}
+	log.FromContext(ctx).Info("Updating group members", "members", group.Status.MemberCount)
+	return ctrl.Result{}, r.Status().Patch(ctx, &group, client.MergeFrom(original))
+}
+
+// Members computes the sorted member names of group among vehicles, and the listed
+// vehicles that do not exist. It fails if the selector is invalid.
+func Members(group *iovv1alpha2.VehicleGroup, vehicles []iovv1alpha2.Vehicle) (members, missing []string, err error) {
+	selector := labels.Nothing()
+	if group.Spec.Selector != nil {
+		if selector, err = metav1.LabelSelectorAsSelector(group.Spec.Selector); err != nil {
+			return nil, nil, fmt.Errorf("invalid selector: %w", err)
+		}
+	}
+
+	existing := make(map[string]struct{}, len(vehicles))
+	for _, v := range vehicles {
+		existing[v.Name] = struct{}{}
+		if selector.Matches(labels.Set(v.Labels)) || slices.Contains(group.Spec.Vehicles, v.Name) {
+			members = append(members, v.Name)
+		}
+	}
+	for _, name := range group.Spec.Vehicles {
+		if _, ok := existing[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	slices.Sort(members)
+	slices.Sort(missing)
+	return members, missing, nil
+}
+
+func setReadyCondition(group *iovv1alpha2.VehicleGroup, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&group.Status.Conditions, metav1.Condition{
+		Type:               iovv1alpha2.ConditionTypeReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: group.Generation,
+	})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Any vehicle change requeues the groups of its namespace, since labels decide membership.
+func (r *Reconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&iovv1alpha2.VehicleGroup{}).
+		Watches(&iovv1alpha2.Vehicle{}, handler.EnqueueRequestsFromMapFunc(r.groupsInNamespace)).
+		Complete(r)
+}
+
+func (r *Reconciler) groupsInNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	var groups iovv1alpha2.VehicleGroupList
+	if err := r.List(ctx, &groups, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list vehicle groups", "namespace", obj.GetNamespace())
+		return nil
+	}
+
+	reqs := make([]reconcile.Request, 0, len(groups.Items))
+	for _, g := range groups.Items {
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&g)})
+	}
+	return reqs
+}
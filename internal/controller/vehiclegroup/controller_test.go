@@ -0,0 +1,110 @@
+package vehiclegroup
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func vehicle(name, city string) iovv1alpha2.Vehicle {
+	return iovv1alpha2.Vehicle{ObjectMeta: metav1.ObjectMeta{
+		Name: name, Namespace: "default", Labels: map[string]string{"city": city},
+	}}
+}
+
+func TestMembers(t *testing.T) {
+	vehicles := []iovv1alpha2.Vehicle{vehicle("vh-3", "berlin"), vehicle("vh-1", "berlin"), vehicle("vh-2", "munich")}
+	berlin := &metav1.LabelSelector{MatchLabels: map[string]string{"city": "berlin"}}
+
+	tests := []struct {
+		name        string
+		spec        iovv1alpha2.VehicleGroupSpec
+		wantMembers []string
+		wantMissing []string
+		wantErr     bool
+	}{
+		{name: "empty", spec: iovv1alpha2.VehicleGroupSpec{}},
+		{name: "selector", spec: iovv1alpha2.VehicleGroupSpec{Selector: berlin}, wantMembers: []string{"vh-1", "vh-3"}},
+		{name: "list", spec: iovv1alpha2.VehicleGroupSpec{Vehicles: []string{"vh-2"}}, wantMembers: []string{"vh-2"}},
+		{
+			name:        "selector and list",
+			spec:        iovv1alpha2.VehicleGroupSpec{Selector: berlin, Vehicles: []string{"vh-2", "vh-1"}},
+			wantMembers: []string{"vh-1", "vh-2", "vh-3"},
+		},
+		{
+			name:        "missing listed vehicle",
+			spec:        iovv1alpha2.VehicleGroupSpec{Vehicles: []string{"vh-9", "vh-2"}},
+			wantMembers: []string{"vh-2"},
+			wantMissing: []string{"vh-9"},
+		},
+		{
+			name: "invalid selector",
+			spec: iovv1alpha2.VehicleGroupSpec{Selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "city", Operator: "Near"}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members, missing, err := Members(&iovv1alpha2.VehicleGroup{Spec: tt.spec}, vehicles)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Members() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(members, tt.wantMembers) {
+				t.Errorf("members = %v, want %v", members, tt.wantMembers)
+			}
+			if !slices.Equal(missing, tt.wantMissing) {
+				t.Errorf("missing = %v, want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	vh1, vh2 := vehicle("vh-1", "berlin"), vehicle("vh-2", "munich")
+	group := &iovv1alpha2.VehicleGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "berlin", Namespace: "default", Generation: 2},
+		Spec: iovv1alpha2.VehicleGroupSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"city": "berlin"}},
+			Vehicles: []string{"vh-9"},
+		},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(&vh1, &vh2, group).WithStatusSubresource(group).Build()
+
+	r := NewReconciler(cli, scheme)
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(group)}); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	var got iovv1alpha2.VehicleGroup
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(group), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got.Status.Members, []string{"vh-1"}) || got.Status.MemberCount != 1 {
+		t.Errorf("members = %v (%d), want [vh-1]", got.Status.Members, got.Status.MemberCount)
+	}
+	if got.Status.ObservedGeneration != 2 {
+		t.Errorf("observedGeneration = %d, want 2", got.Status.ObservedGeneration)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, iovv1alpha2.ConditionTypeReady)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != ReasonVehiclesNotFound {
+		t.Errorf("Ready condition = %+v, want False/%s", cond, ReasonVehiclesNotFound)
+	}
+}
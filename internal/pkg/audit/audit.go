@@ -0,0 +1,102 @@
+// Package audit records VehicleCommand dispatches and outcomes for compliance.
+//
+// Every record answers who (Actor), what (the command and its phase transition)
+// and when (Time). Records are only ever appended; sinks must not rewrite or
+// drop earlier records. The sink is pluggable: implement Sink to ship records to
+// object storage or a message topic instead of a local file.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Actors recorded by the components that write command transitions.
+const (
+	ActorCommandController = "autopeer-command-controller"
+	ActorVehicleAgent      = "vehicle-agent"
+)
+
+// Record is one audited VehicleCommand transition.
+type Record struct {
+	// Time is when the transition was persisted.
+	Time time.Time `json:"time"`
+
+	// Actor is the component that performed the transition.
+	Actor string `json:"actor"`
+
+	// Namespace and Command identify the VehicleCommand.
+	Namespace string `json:"namespace,omitempty"`
+	Command   string `json:"command"`
+
+	// Vehicle and Method describe the command, if known to the actor.
+	Vehicle string `json:"vehicle,omitempty"`
+	Method  string `json:"method,omitempty"`
+
+	// FromPhase is empty if the actor does not know the previous phase.
+	FromPhase string `json:"fromPhase,omitempty"`
+	ToPhase   string `json:"toPhase"`
+
+	// Message is the status message that came with the transition.
+	Message string `json:"message,omitempty"`
+}
+
+// Sink receives audit records.
+type Sink interface {
+	Record(ctx context.Context, r *Record) error
+}
+
+// Discard is a Sink that drops all records. It is used when auditing is disabled.
+var Discard Sink = discard{}
+
+type discard struct{}
+
+func (discard) Record(context.Context, *Record) error { return nil }
+
+// JSONSink writes one JSON object per line to an append-only writer.
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONSink creates a Sink writing JSON lines to w.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+// Record implements Sink. A zero Time is set to the current time.
+func (s *JSONSink) Record(ctx context.Context, r *Record) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// NewSink returns the sink for an audit log path:
+// "" disables auditing, "-" writes to stdout, anything else is a file opened in append-only mode.
+func NewSink(path string) (Sink, error) {
+	switch path {
+	case "":
+		return Discard, nil
+	case "-":
+		return NewJSONSink(os.Stdout), nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return NewJSONSink(f), nil
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSinkAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	// Two sinks on the same file, e.g. across a restart: neither may truncate the other's records.
+	for _, phase := range []string{"Sent", "Succeeded"} {
+		sink, err := NewSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Record(context.Background(), &Record{Actor: ActorCommandController, Command: "ota-vh-1", ToPhase: phase}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var phases []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid audit line %q: %v", scanner.Text(), err)
+		}
+		if r.Time.IsZero() {
+			t.Errorf("record without time: %s", scanner.Text())
+		}
+		phases = append(phases, r.ToPhase)
+	}
+
+	if len(phases) != 2 || phases[0] != "Sent" || phases[1] != "Succeeded" {
+		t.Errorf("audit log phases = %v, want [Sent Succeeded]", phases)
+	}
+}
+
+func TestNewSinkDisabled(t *testing.T) {
+	sink, err := NewSink("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sink != Discard {
+		t.Errorf("empty path should disable auditing")
+	}
+}
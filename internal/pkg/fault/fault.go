@@ -0,0 +1,114 @@
+// Package fault implements opt-in fault injection for resilience (chaos) testing.
+//
+// Faults are declared as "<point>=<fault>" rules, where point names an
+// injection point (e.g. "storage.presign") and fault is one of:
+//
+//	error           fail the call with ErrInjected
+//	delay:<dur>     sleep for <dur> (e.g. "delay:2s"), then continue normally
+//	timeout         block until the context is done and return its error
+//
+// A nil *Injector is valid and never injects anything.
+package fault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInjected is returned by an "error" fault.
+var ErrInjected = errors.New("injected fault")
+
+// Injection points used across the project.
+const (
+	PointStoragePresign     = "storage.presign"
+	PointStorageCheckBucket = "storage.check-bucket"
+	PointMQTTPublish        = "mqtt.publish"
+)
+
+type kind string
+
+const (
+	kindError   kind = "error"
+	kindDelay   kind = "delay"
+	kindTimeout kind = "timeout"
+)
+
+type fault struct {
+	kind  kind
+	delay time.Duration
+}
+
+// Injector injects the configured faults at named points.
+type Injector struct {
+	faults map[string]fault
+}
+
+// NewInjector parses the rules and returns an Injector.
+func NewInjector(rules []string) (*Injector, error) {
+	i := &Injector{faults: make(map[string]fault, len(rules))}
+
+	for _, rule := range rules {
+		point, spec, ok := strings.Cut(rule, "=")
+		if !ok || point == "" {
+			return nil, fmt.Errorf("invalid fault rule %q: expected <point>=<fault>", rule)
+		}
+
+		f, err := parseFault(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fault rule %q: %w", rule, err)
+		}
+		i.faults[point] = f
+	}
+
+	return i, nil
+}
+
+func parseFault(spec string) (fault, error) {
+	name, arg, _ := strings.Cut(spec, ":")
+
+	switch kind(name) {
+	case kindError, kindTimeout:
+		return fault{kind: kind(name)}, nil
+	case kindDelay:
+		d, err := time.ParseDuration(arg)
+		if err != nil || d <= 0 {
+			return fault{}, fmt.Errorf("delay needs a positive duration, got %q", arg)
+		}
+		return fault{kind: kindDelay, delay: d}, nil
+	default:
+		return fault{}, fmt.Errorf("unknown fault %q", name)
+	}
+}
+
+// Inject applies the fault configured for point, if any.
+// It returns nil when no fault is configured or after a delay fault has elapsed.
+func (i *Injector) Inject(ctx context.Context, point string) error {
+	if i == nil {
+		return nil
+	}
+
+	f, ok := i.faults[point]
+	if !ok {
+		return nil
+	}
+
+	switch f.kind {
+	case kindError:
+		return fmt.Errorf("%w at %s", ErrInjected, point)
+	case kindDelay:
+		select {
+		case <-time.After(f.delay):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	case kindTimeout:
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	return nil
+}
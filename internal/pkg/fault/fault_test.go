@@ -0,0 +1,61 @@
+package fault
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInjector(t *testing.T) {
+	inj, err := NewInjector([]string{
+		PointStoragePresign + "=error",
+		PointMQTTPublish + "=delay:20ms",
+		PointStorageCheckBucket + "=timeout",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		point    string
+		wantErr  error
+		minDelay time.Duration
+	}{
+		{point: PointStoragePresign, wantErr: ErrInjected},
+		{point: PointMQTTPublish, minDelay: 20 * time.Millisecond},
+		{point: PointStorageCheckBucket, wantErr: context.DeadlineExceeded, minDelay: 50 * time.Millisecond},
+		{point: "unknown.point"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.point, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := inj.Inject(ctx, tt.point)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Inject() error = %v, want %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed < tt.minDelay {
+				t.Errorf("Inject() returned after %v, want at least %v", elapsed, tt.minDelay)
+			}
+		})
+	}
+}
+
+func TestNilInjector(t *testing.T) {
+	var inj *Injector
+	if err := inj.Inject(context.Background(), PointStoragePresign); err != nil {
+		t.Errorf("nil Injector injected %v", err)
+	}
+}
+
+func TestNewInjectorInvalidRules(t *testing.T) {
+	for _, rule := range []string{"storage.presign", "=error", "storage.presign=explode", "mqtt.publish=delay:soon"} {
+		if _, err := NewInjector([]string{rule}); err == nil {
+			t.Errorf("NewInjector(%q) expected error", rule)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package metrics
 
 import (
+	"net/http"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
@@ -34,6 +37,123 @@ var (
 		},
 		[]string{"type"}, // type: OTA/Reboot
 	)
+
+	// CommandDispatchLatency 记录命令从被 Controller 接收 (StartTime) 到发送至 Hub (SentTime) 的耗时
+	CommandDispatchLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "autopeer_vehiclecommand_dispatch_latency_seconds",
+			Help:    "Time from VehicleCommand start to being sent to Hub (SentTime - StartTime).",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"type"},
+	)
+
+	// CommandAckLatency 记录命令从发送到车端确认 (AcknowledgeTime) 的耗时，反映网络与连接健康度
+	CommandAckLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "autopeer_vehiclecommand_ack_latency_seconds",
+			Help:    "Time from VehicleCommand sent to acknowledged by the vehicle (AcknowledgeTime - SentTime).",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 12), // 0.1s ~ 204.8s
+		},
+		[]string{"type"},
+	)
+
+	// CommandTotalLatency 记录命令端到端耗时 (SLA 窗口)
+	CommandTotalLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "autopeer_vehiclecommand_total_latency_seconds",
+			Help:    "Time from VehicleCommand start to a terminal phase (CompletionTime - StartTime).",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 14), // 1s ~ 8192s
+		},
+		[]string{"type", "phase"},
+	)
+
+	// VehicleConnectionLatency 记录每辆车命令确认往返时间的平滑值
+	// 只导出最近活跃的有限数量车辆, 以限制 vehicle 标签的基数
+	VehicleConnectionLatency = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "autopeer_vehicle_connection_latency_seconds",
+			Help: "Smoothed time for a vehicle to acknowledge a command, for the most recently active vehicles.",
+		},
+		[]string{"namespace", "vehicle"},
+	)
+
+	// VehicleConnectionJitter 记录每辆车确认往返时间的平滑波动
+	VehicleConnectionJitter = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "autopeer_vehicle_connection_jitter_seconds",
+			Help: "Smoothed variation of a vehicle's command acknowledgement time, for the most recently active vehicles.",
+		},
+		[]string{"namespace", "vehicle"},
+	)
+
+	// VehicleConnectionDropRatio 记录每辆车未被确认 (需要重发) 的命令发送比例
+	VehicleConnectionDropRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "autopeer_vehicle_connection_drop_ratio",
+			Help: "Smoothed share of command sends a vehicle did not acknowledge, for the most recently active vehicles.",
+		},
+		[]string{"namespace", "vehicle"},
+	)
+
+	// VehicleFSMGuardCancellations 记录 Vehicle 状态机的 Guard 取消状态转换的次数, 按事件与原因区分
+	// 用于排查车辆为何停留在当前阶段
+	VehicleFSMGuardCancellations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "autopeer_vehicle_fsm_guard_cancellations_total",
+			Help: "Total number of vehicle state machine transitions canceled by a guard.",
+		},
+		[]string{"event", "reason"},
+	)
+
+	// PipelineUpdatesReceived 记录推入 Bridge StatusPipeline 的状态更新总数
+	PipelineUpdatesReceived = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "autopeer_pipeline_updates_received_total",
+			Help: "Total number of vehicle status updates pushed to the status pipeline.",
+		},
+	)
+
+	// PipelineUpdatesDropped 记录因通道已满而被丢弃 (Load Shedding) 的状态更新总数
+	PipelineUpdatesDropped = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "autopeer_pipeline_updates_dropped_total",
+			Help: "Total number of vehicle status updates dropped because the status pipeline was full.",
+		},
+	)
+
+	// PipelineFlushErrors 记录 flush 时写入 K8s 失败的次数
+	PipelineFlushErrors = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "autopeer_pipeline_flush_errors_total",
+			Help: "Total number of failed vehicle status patches during status pipeline flushes.",
+		},
+	)
+
+	// PipelineUpdatesDeadLettered 记录重试耗尽后被放弃的状态更新总数
+	PipelineUpdatesDeadLettered = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "autopeer_pipeline_updates_dead_lettered_total",
+			Help: "Total number of vehicle status updates dropped after exhausting flush retries.",
+		},
+	)
+
+	// PipelineBufferSize 记录每次 flush 时合并后的待写入车辆数
+	PipelineBufferSize = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "autopeer_pipeline_buffer_size",
+			Help: "Number of merged vehicle status updates in the status pipeline at the last flush.",
+		},
+	)
+
+	// PipelineFlushDuration 记录一次 flush 写入 K8s 的耗时
+	PipelineFlushDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "autopeer_pipeline_flush_duration_seconds",
+			Help:    "Time spent flushing the status pipeline to Kubernetes.",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
 )
 
 // init 函数会自动将这些指标注册到 controller-runtime 的全局 Registry 中
@@ -42,4 +162,22 @@ func init() {
 	metrics.Registry.MustRegister(HubConnectivityStatus)
 	metrics.Registry.MustRegister(CommandSentTotal)
 	metrics.Registry.MustRegister(CommandLatency)
+	metrics.Registry.MustRegister(CommandDispatchLatency)
+	metrics.Registry.MustRegister(CommandAckLatency)
+	metrics.Registry.MustRegister(CommandTotalLatency)
+	metrics.Registry.MustRegister(VehicleConnectionLatency)
+	metrics.Registry.MustRegister(VehicleConnectionJitter)
+	metrics.Registry.MustRegister(VehicleConnectionDropRatio)
+	metrics.Registry.MustRegister(VehicleFSMGuardCancellations)
+	metrics.Registry.MustRegister(PipelineUpdatesReceived)
+	metrics.Registry.MustRegister(PipelineUpdatesDropped)
+	metrics.Registry.MustRegister(PipelineFlushErrors)
+	metrics.Registry.MustRegister(PipelineUpdatesDeadLettered)
+	metrics.Registry.MustRegister(PipelineBufferSize)
+	metrics.Registry.MustRegister(PipelineFlushDuration)
+}
+
+// Handler 返回暴露上述指标的 HTTP Handler，供不运行 controller-runtime Manager 的进程 (如 Bridge) 使用
+func Handler() http.Handler {
+	return promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
 }
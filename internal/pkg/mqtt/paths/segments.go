@@ -13,6 +13,16 @@ const (
 	// Payload: { "requestID": "...", "downloadURL": "..." }
 	// Pattern: {root}/ota/response/{vehicleID}
 	OTAResponse = "ota/response"
+
+	// RequestResponse is the topic segment for answering vehicle-initiated requests.
+	// Payload: { "requestID": "...", "accepted": true, "commandName": "..." }
+	// Pattern: {root}/request/response/{vehicleID}
+	RequestResponse = "request/response"
+
+	// ResultUploadResponse is the topic segment for delivering command result upload URLs.
+	// Payload: { "requestID": "...", "uploadURL": "...", "objectKey": "..." }
+	// Pattern: {root}/result/response/{vehicleID}
+	ResultUploadResponse = "result/response"
 )
 
 // Upstream: Edge -> Cloud (Requests & Status Reports)
@@ -22,7 +32,9 @@ const (
 	Register = "register"
 
 	// Online is the topic segment for reporting vehicle online/offline status.
-	// Payload: { "online": true/false, "timestamp": ... }
+	// It is also the agent's last-will topic: the broker publishes the agent's
+	// offline status here as soon as its connection drops.
+	// Payload: { "online": true/false, "reason": "UnexpectedDisconnect" }
 	// Pattern: {root}/online/{vehicleID}
 	Online = "online"
 
@@ -39,4 +51,19 @@ const (
 	// Payload: { "percentage": 50, "status": "installing", "message": "..." }
 	// Pattern: {root}/ota/progress/{vehicleID}
 	OTAProgress = "ota/progress"
+
+	// Telemetry is the topic segment for reporting the values of dynamic properties.
+	// Payload: { "properties": { "battery_level": "80" }, "timestamp": ... }
+	// Pattern: {root}/telemetry/{vehicleID}
+	Telemetry = "telemetry"
+
+	// Request is the topic segment for vehicle-initiated requests (e.g. "send me my latest config").
+	// Payload: { "requestID": "...", "type": "Config", "parameters": { ... } }
+	// Pattern: {root}/request/{vehicleID}
+	Request = "request"
+
+	// ResultUploadRequest is the topic segment for requesting an upload URL for a command result.
+	// Payload: { "requestID": "...", "commandName": "..." }
+	// Pattern: {root}/result/request/{vehicleID}
+	ResultUploadRequest = "result/request"
 )
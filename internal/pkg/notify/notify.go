@@ -0,0 +1,170 @@
+// Package notify tells operators about OTA rollout milestones, e.g. through a Slack webhook.
+//
+// Notifications are best effort and at least once: a milestone may be notified again
+// if the controller retries the reconcile that reached it. The notifier is pluggable:
+// implement Notifier to send milestones anywhere else than an HTTP webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/autopeer-io/autopeer/pkg/options"
+)
+
+// Milestone is a point in a vehicle's OTA worth telling operators about.
+type Milestone string
+
+const (
+	// MilestoneStarted means the vehicle started updating to a new firmware version.
+	MilestoneStarted Milestone = "Started"
+
+	// MilestoneFailureThreshold means the update failed on every allowed attempt and was given up.
+	MilestoneFailureThreshold Milestone = "FailureThreshold"
+
+	// MilestoneCompleted means the vehicle runs the new firmware version.
+	MilestoneCompleted Milestone = "Completed"
+)
+
+// Event is one notified milestone.
+type Event struct {
+	// Time is when the milestone was reached.
+	Time time.Time `json:"time"`
+
+	Milestone Milestone `json:"milestone"`
+
+	// Namespace and Vehicle identify the Vehicle; Model is its VehicleModel, if any.
+	Namespace string `json:"namespace"`
+	Vehicle   string `json:"vehicle"`
+	Model     string `json:"model,omitempty"`
+
+	// Version is the firmware version the update installs.
+	Version string `json:"version"`
+
+	// Attempts is how many times the update was tried.
+	Attempts int32 `json:"attempts"`
+
+	// Message describes the milestone, e.g. the last failure.
+	Message string `json:"message,omitempty"`
+}
+
+// Notifier receives milestones.
+type Notifier interface {
+	Notify(ctx context.Context, e *Event) error
+}
+
+// Discard is a Notifier that drops all milestones. It is used when notifications are disabled.
+var Discard Notifier = discard{}
+
+type discard struct{}
+
+func (discard) Notify(context.Context, *Event) error { return nil }
+
+// Webhook posts milestones to an HTTP endpoint.
+// The body is the Event as JSON, or the output of a text/template executed on the Event.
+// Failed deliveries (transport errors and 5xx responses) are retried with a linear backoff.
+type Webhook struct {
+	url      string
+	tmpl     *template.Template
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+// NewWebhook creates a Webhook posting to url. An empty body template sends the Event as JSON.
+// retries is how many times a failed delivery is repeated.
+func NewWebhook(url, bodyTemplate string, retries int, timeout time.Duration) (*Webhook, error) {
+	w := &Webhook{
+		url:      url,
+		client:   &http.Client{Timeout: timeout},
+		attempts: retries + 1,
+		backoff:  time.Second,
+	}
+
+	if bodyTemplate != "" {
+		tmpl, err := template.New("webhook").Parse(bodyTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook template: %w", err)
+		}
+		w.tmpl = tmpl
+	}
+
+	return w, nil
+}
+
+// Notify implements Notifier.
+func (w *Webhook) Notify(ctx context.Context, e *Event) error {
+	body, err := w.render(e)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		retryable, err := w.post(ctx, body)
+		if err == nil || !retryable || attempt >= w.attempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * w.backoff):
+		}
+	}
+}
+
+func (w *Webhook) render(e *Event) ([]byte, error) {
+	if w.tmpl == nil {
+		return json.Marshal(e)
+	}
+
+	var buf bytes.Buffer
+	if err := w.tmpl.Execute(&buf, e); err != nil {
+		return nil, fmt.Errorf("failed to render webhook template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// post delivers one request. It reports whether a failure may succeed when retried.
+func (w *Webhook) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode >= 500, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return false, nil
+}
+
+// NewNotifier returns the notifier configured by opts: Discard if no webhook URL is set.
+func NewNotifier(opts *options.NotifyOptions) (Notifier, error) {
+	if opts.WebhookURL == "" {
+		return Discard, nil
+	}
+
+	var bodyTemplate string
+	if opts.TemplateFile != "" {
+		data, err := os.ReadFile(opts.TemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read webhook template: %w", err)
+		}
+		bodyTemplate = string(data)
+	}
+
+	return NewWebhook(opts.WebhookURL, bodyTemplate, opts.Retries, opts.Timeout)
+}
@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookPayload(t *testing.T) {
+	event := &Event{
+		Time:      time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Milestone: MilestoneCompleted,
+		Namespace: "default",
+		Vehicle:   "vh-1",
+		Version:   "2.0.0",
+		Attempts:  1,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name: "json",
+			want: `{"time":"2026-01-02T03:04:05Z","milestone":"Completed","namespace":"default","vehicle":"vh-1","version":"2.0.0","attempts":1}`,
+		},
+		{
+			name:     "template",
+			template: `{"text":"{{.Vehicle}}: {{.Milestone}} ({{.Version}})"}`,
+			want:     `{"text":"vh-1: Completed (2.0.0)"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				got = string(body)
+			}))
+			defer srv.Close()
+
+			w, err := NewWebhook(srv.URL, tt.template, 0, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Notify(context.Background(), event); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantRequests int
+		wantErr      bool
+	}{
+		{name: "recovers from server errors", statuses: []int{503, 502, 200}, wantRequests: 3},
+		{name: "gives up after the retries", statuses: []int{503, 503, 503}, wantRequests: 3, wantErr: true},
+		{name: "client errors are not retried", statuses: []int{400}, wantRequests: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statuses[min(requests, len(tt.statuses)-1)])
+				requests++
+			}))
+			defer srv.Close()
+
+			w, err := NewWebhook(srv.URL, "", 2, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.backoff = time.Millisecond
+
+			err = w.Notify(context.Background(), &Event{Milestone: MilestoneStarted})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
@@ -3,3 +3,9 @@ package util
 import "errors"
 
 var ErrNotFound = errors.New("errors not found")
+
+// ErrAlreadyExists is returned when creating a resource that has been created concurrently.
+var ErrAlreadyExists = errors.New("errors already exists")
+
+// ErrStale is returned when an update is older than the stored state and was not applied.
+var ErrStale = errors.New("errors stale update")
@@ -0,0 +1,133 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+// ConversionDataAnnotation holds the fields of a Vehicle that the other API version has no
+// counterpart for, as JSON, so that a round trip through that version does not lose them.
+const ConversionDataAnnotation = "iov.autopeer.io/conversion-data"
+
+// spokeData holds the v1alpha1 fields v1alpha2 has no counterpart for.
+type spokeData struct {
+	Description string `json:"description,omitempty"`
+	Message     string `json:"message,omitempty"`
+}
+
+// hubData holds the v1alpha2 Vehicle, whose fields v1alpha1 mostly has no counterpart for.
+type hubData struct {
+	Spec   iovv1alpha2.VehicleSpec   `json:"spec"`
+	Status iovv1alpha2.VehicleStatus `json:"status"`
+}
+
+var _ conversion.Convertible = (*Vehicle)(nil)
+
+// ConvertTo converts this Vehicle to the v1alpha2 hub version.
+func (src *Vehicle) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*iovv1alpha2.Vehicle)
+	if !ok {
+		return fmt.Errorf("expected a v1alpha2 Vehicle, got %T", dstRaw)
+	}
+
+	// Restore the v1alpha2 fields saved by ConvertFrom, then overwrite those v1alpha1 has.
+	var restored hubData
+	annotations, err := popConversionData(src.Annotations, &restored)
+	if err != nil {
+		return err
+	}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Annotations = annotations
+	dst.Spec = restored.Spec
+	dst.Status = restored.Status
+
+	dst.Spec.Profile.Firmware.Version = src.Spec.FirmwareVersion
+	dst.Status.Online = src.Status.Online
+	dst.Status.LastHeartbeatTime = src.Status.LastSeenTime.DeepCopy()
+	dst.Status.Profile.Firmware.Version = src.Status.ReportedFirmwareVersion
+	dst.Status.UpgradeStatus.Phase = iovv1alpha2.VehiclePhase(src.Status.Phase)
+	dst.Status.UpgradeStatus.RetryCount = src.Status.RetryCount
+	dst.Status.Conditions = nil
+	for _, c := range src.Status.Conditions {
+		dst.Status.Conditions = append(dst.Status.Conditions, *c.DeepCopy())
+	}
+
+	data := spokeData{Description: src.Spec.Description, Message: src.Status.Message}
+	if data == (spokeData{}) {
+		return nil
+	}
+	dst.Annotations, err = pushConversionData(dst.Annotations, data)
+	return err
+}
+
+// ConvertFrom converts the v1alpha2 hub version to this Vehicle.
+func (dst *Vehicle) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*iovv1alpha2.Vehicle)
+	if !ok {
+		return fmt.Errorf("expected a v1alpha2 Vehicle, got %T", srcRaw)
+	}
+
+	// Restore the v1alpha1 fields saved by ConvertTo.
+	var restored spokeData
+	annotations, err := popConversionData(src.Annotations, &restored)
+	if err != nil {
+		return err
+	}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Annotations = annotations
+
+	dst.Spec = VehicleSpec{
+		Description:     restored.Description,
+		FirmwareVersion: src.Spec.Profile.Firmware.Version,
+	}
+	dst.Status = VehicleStatus{
+		Online:                  src.Status.Online,
+		Phase:                   VehiclePhase(src.Status.UpgradeStatus.Phase),
+		ReportedFirmwareVersion: src.Status.Profile.Firmware.Version,
+		Message:                 restored.Message,
+		RetryCount:              src.Status.UpgradeStatus.RetryCount,
+		LastSeenTime:            src.Status.LastHeartbeatTime.DeepCopy(),
+	}
+	for _, c := range src.Status.Conditions {
+		dst.Status.Conditions = append(dst.Status.Conditions, *c.DeepCopy())
+	}
+
+	dst.Annotations, err = pushConversionData(dst.Annotations, hubData{Spec: src.Spec, Status: src.Status})
+	return err
+}
+
+// popConversionData decodes the conversion data of annotations into data, if any,
+// and returns a copy of annotations without it.
+func popConversionData(annotations map[string]string, data any) (map[string]string, error) {
+	raw, ok := annotations[ConversionDataAnnotation]
+	if !ok {
+		return maps.Clone(annotations), nil
+	}
+	if err := json.Unmarshal([]byte(raw), data); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", ConversionDataAnnotation, err)
+	}
+	annotations = maps.Clone(annotations)
+	delete(annotations, ConversionDataAnnotation)
+	if len(annotations) == 0 {
+		return nil, nil
+	}
+	return annotations, nil
+}
+
+// pushConversionData returns annotations with data encoded as the conversion data.
+func pushConversionData(annotations map[string]string, data any) (map[string]string, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if annotations == nil {
+		annotations = make(map[string]string, 1)
+	}
+	annotations[ConversionDataAnnotation] = string(raw)
+	return annotations, nil
+}
@@ -0,0 +1,128 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+
+	iovv1alpha2 "github.com/autopeer-io/autopeer/pkg/apis/iov/v1alpha2"
+)
+
+func TestVehicleIsConvertible(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := iovv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := conversion.IsConvertible(scheme, &iovv1alpha2.Vehicle{})
+	if err != nil || !ok {
+		t.Errorf("IsConvertible() = %v, %v; want the Vehicle versions to convert through the hub", ok, err)
+	}
+}
+
+func TestVehicleConversionRoundTripFromSpoke(t *testing.T) {
+	seen := metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	src := &Vehicle{
+		ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Labels: map[string]string{"fleet": "a"}},
+		Spec:       VehicleSpec{Description: "test bench", FirmwareVersion: "v2.0.0"},
+		Status: VehicleStatus{
+			Online:                  true,
+			Phase:                   VehiclePhaseFailed,
+			ReportedFirmwareVersion: "v1.0.0",
+			Message:                 "download failed",
+			RetryCount:              2,
+			LastSeenTime:            &seen,
+			Conditions:              []metav1.Condition{{Type: ConditionTypeReady, Status: metav1.ConditionFalse, Reason: "Failed"}},
+		},
+	}
+
+	var hub iovv1alpha2.Vehicle
+	if err := src.DeepCopy().ConvertTo(&hub); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+	if hub.Spec.Profile.Firmware.Version != "v2.0.0" || hub.Status.Profile.Firmware.Version != "v1.0.0" {
+		t.Errorf("firmware = %q/%q, want v2.0.0/v1.0.0", hub.Spec.Profile.Firmware.Version, hub.Status.Profile.Firmware.Version)
+	}
+	if got := hub.Status.UpgradeStatus; got.Phase != iovv1alpha2.VehiclePhaseFailed || got.RetryCount != 2 {
+		t.Errorf("upgrade status = %+v, want Failed after 2 retries", got)
+	}
+	if !hub.Status.Online || !hub.Status.LastHeartbeatTime.Equal(&seen) || len(hub.Status.Conditions) != 1 {
+		t.Errorf("status = %+v", hub.Status)
+	}
+
+	var got Vehicle
+	if err := got.ConvertFrom(&hub); err != nil {
+		t.Fatalf("ConvertFrom() error = %v", err)
+	}
+	// The hub fields v1alpha1 has no counterpart for are kept for the way back.
+	if _, ok := got.Annotations[ConversionDataAnnotation]; !ok {
+		t.Errorf("annotations = %v, want the conversion data", got.Annotations)
+	}
+	delete(got.Annotations, ConversionDataAnnotation)
+	if !equality.Semantic.DeepEqual(&got, src) {
+		t.Errorf("round trip = %+v, want %+v", got, src)
+	}
+}
+
+func TestVehicleConversionRoundTripFromHub(t *testing.T) {
+	started := metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	src := &iovv1alpha2.Vehicle{
+		ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Namespace: "default", Annotations: map[string]string{"owner": "ops"}},
+		Spec: iovv1alpha2.VehicleSpec{
+			VIN:             "1HGCM82633A004352",
+			Lifecycle:       iovv1alpha2.VehicleLifecycleActive,
+			VehicleModelRef: "model-3",
+			Profile: iovv1alpha2.VehicleProfile{
+				Firmware:  iovv1alpha2.FirmwareConfig{Version: "v2.0.0", DownloadURL: "s3://fw/v2.0.0", Checksum: "sha256:abc"},
+				OTAPolicy: iovv1alpha2.OTAPolicy{MinBatteryLevel: ptr.To[int32](40)},
+			},
+			Properties:      map[string]string{"drive_mode": "eco"},
+			MaintenanceMode: true,
+		},
+		Status: iovv1alpha2.VehicleStatus{
+			Online:     true,
+			Profile:    iovv1alpha2.VehicleProfile{Firmware: iovv1alpha2.FirmwareConfig{Version: "v1.0.0"}},
+			Properties: map[string]string{"gear": "P"},
+			UpgradeStatus: iovv1alpha2.UpgradeStatus{
+				Phase:       iovv1alpha2.VehiclePhasePending,
+				RetryCount:  1,
+				StartTime:   &started,
+				Transitions: []iovv1alpha2.PhaseTransition{{From: iovv1alpha2.VehiclePhaseIdle, To: iovv1alpha2.VehiclePhasePending, Time: started, Reason: "event_update"}},
+			},
+		},
+	}
+
+	var spoke Vehicle
+	if err := spoke.ConvertFrom(src.DeepCopy()); err != nil {
+		t.Fatalf("ConvertFrom() error = %v", err)
+	}
+	if spoke.Spec.FirmwareVersion != "v2.0.0" || spoke.Status.ReportedFirmwareVersion != "v1.0.0" || spoke.Status.Phase != VehiclePhasePending || spoke.Status.RetryCount != 1 {
+		t.Errorf("v1alpha1 vehicle = %+v", spoke)
+	}
+
+	// A v1alpha1 client changes the desired version: the v1alpha2-only fields survive.
+	spoke.Spec.FirmwareVersion = "v3.0.0"
+	var got iovv1alpha2.Vehicle
+	if err := spoke.ConvertTo(&got); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+	want := src.DeepCopy()
+	want.Spec.Profile.Firmware.Version = "v3.0.0"
+	if !equality.Semantic.DeepEqual(&got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestVehicleConversionInvalidData(t *testing.T) {
+	src := &Vehicle{ObjectMeta: metav1.ObjectMeta{Name: "vh-1", Annotations: map[string]string{ConversionDataAnnotation: "{"}}}
+	if err := src.ConvertTo(&iovv1alpha2.Vehicle{}); err == nil {
+		t.Error("ConvertTo() with invalid conversion data succeeded, want error")
+	}
+}
@@ -0,0 +1,60 @@
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CommandTemplateSpec holds a reusable command. VehicleCommands reference it through
+// Spec.TemplateRef and only need to set what differs.
+type CommandTemplateSpec struct {
+	// Description is a human-readable summary of what the command does.
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Method is the name of the operation to execute (e.g., "Reboot", "UploadLogs").
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Method string `json:"method"`
+
+	// Parameters are the default input arguments. A command's own parameters override them key by key.
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// Priority is the default priority (see VehicleCommandSpec.Priority).
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=2
+	Priority *int32 `json:"priority,omitempty"`
+
+	// TimeoutSeconds is the default timeout (see VehicleCommandSpec.TimeoutSeconds).
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Method",type="string",JSONPath=".spec.method",description="Command Method"
+//+kubebuilder:printcolumn:name="Description",type="string",JSONPath=".spec.description",description="Template Description"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// CommandTemplate is the Schema for the commandtemplates API
+type CommandTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CommandTemplateSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CommandTemplateList contains a list of CommandTemplate
+type CommandTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CommandTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CommandTemplate{}, &CommandTemplateList{})
+}
@@ -0,0 +1,108 @@
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ScheduledCommandLabel is set on the VehicleCommands created by a ScheduledVehicleCommand
+// and holds the name of the ScheduledVehicleCommand.
+const ScheduledCommandLabel = "iov.autopeer.io/scheduled-command"
+
+// ScheduledVehicleCommandSpec defines a command that recurs on a schedule, like a CronJob.
+// On every scheduled time, one VehicleCommand is created per vehicle matching Selector.
+// +kubebuilder:validation:XValidation:rule="(has(self.method) && self.method != '') || (has(self.templateRef) && self.templateRef != '')",message="either method or templateRef must be set"
+type ScheduledVehicleCommandSpec struct {
+	// Schedule is a standard five-field cron expression (minute hour day-of-month month day-of-week),
+	// or one of @yearly, @monthly, @weekly, @daily, @hourly. It is evaluated in UTC.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Suspend stops creating commands while true. Commands already created are not affected.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// StartingDeadlineSeconds is how late a missed scheduled time may still be acted on,
+	// e.g. after a controller outage. Older scheduled times are skipped.
+	// If unset, the most recent missed time is always acted on.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+
+	// Selector selects the target vehicles (in the same namespace) by label.
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// Method is the name of the operation to execute. It may be omitted if TemplateRef is set.
+	// +optional
+	Method string `json:"method,omitempty"`
+
+	// TemplateRef references a CommandTemplate (in the same namespace), see VehicleCommandSpec.TemplateRef.
+	// +optional
+	TemplateRef string `json:"templateRef,omitempty"`
+
+	// Priority of the created commands, see VehicleCommandSpec.Priority.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=2
+	Priority *int32 `json:"priority,omitempty"`
+
+	// Parameters contains the input arguments for the method.
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// TimeoutSeconds of the created commands, see VehicleCommandSpec.TimeoutSeconds.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// ScheduledVehicleCommandStatus defines the observed state of ScheduledVehicleCommand.
+type ScheduledVehicleCommandStatus struct {
+	// LastScheduleTime is the last scheduled time commands were created for.
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// LastScheduledCount is how many commands were created at LastScheduleTime.
+	// +optional
+	LastScheduledCount int32 `json:"lastScheduledCount,omitempty"`
+
+	// NextScheduleTime is the next time commands will be created, unless suspended.
+	// +optional
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+
+	// Conditions represent the latest available observations of the schedule (e.g., Ready).
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Schedule",type="string",JSONPath=".spec.schedule",description="Cron Schedule"
+//+kubebuilder:printcolumn:name="Method",type="string",JSONPath=".spec.method",description="Command Method"
+//+kubebuilder:printcolumn:name="Suspend",type="boolean",JSONPath=".spec.suspend",description="Whether the Schedule is Suspended"
+//+kubebuilder:printcolumn:name="Last Schedule",type="date",JSONPath=".status.lastScheduleTime"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ScheduledVehicleCommand is the Schema for the scheduledvehiclecommands API
+type ScheduledVehicleCommand struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScheduledVehicleCommandSpec   `json:"spec,omitempty"`
+	Status ScheduledVehicleCommandStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ScheduledVehicleCommandList contains a list of ScheduledVehicleCommand
+type ScheduledVehicleCommandList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ScheduledVehicleCommand `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ScheduledVehicleCommand{}, &ScheduledVehicleCommandList{})
+}
@@ -0,0 +1,4 @@
+package v1alpha2
+
+// Hub marks Vehicle as the conversion hub: other versions convert to and from v1alpha2.
+func (*Vehicle) Hub() {}
@@ -8,6 +8,14 @@ import (
 // VehicleFinalizer allows the controller to clean up resources (e.g. remove from EMQX authentication) before deletion.
 const VehicleFinalizer = "iov.autopeer.io/vehicle-finalizer"
 
+// RolloutOverrideAnnotation set to "true" lets a vehicle start an OTA even when the
+// fleet-wide rollout guardrail is throttling.
+const RolloutOverrideAnnotation = "iov.autopeer.io/rollout-override"
+
+// CanaryLabel set to "true" marks a vehicle as a canary: other vehicles of its model only start
+// an OTA to the version it runs once it has run that version healthily for the canary soak period.
+const CanaryLabel = "iov.autopeer.io/canary"
+
 // VehicleLifecycle defines the administrative intent for the vehicle's existence.
 // +kubebuilder:validation:Enum=Inventory;Active;Retired
 type VehicleLifecycle string
@@ -21,8 +29,9 @@ const (
 	// This is the default state for a working vehicle.
 	VehicleLifecycleActive VehicleLifecycle = "Active"
 
-	// VehicleLifecycleRetired means the vehicle is permanently decommissioned.
-	// The controller should revoke access credentials and stop processing data.
+	// VehicleLifecycleRetired means the vehicle is decommissioned. The controllers stop managing it:
+	// no OTA is started or advanced, its commands are not dispatched and its requests are refused.
+	// Unlike deleting the Vehicle, the resource, its status and its command history are kept.
 	VehicleLifecycleRetired VehicleLifecycle = "Retired"
 )
 
@@ -41,8 +50,8 @@ type VehicleSpec struct {
 	// +kubebuilder:default="Active"
 	Lifecycle VehicleLifecycle `json:"lifecycle,omitempty"`
 
-	// VehicleModelRef references the definition of the vehicle model (e.g., "tesla-model-3-v1").
-	// This allows future validation of supported properties.
+	// VehicleModelRef references the VehicleModel (in the same namespace) of this vehicle (e.g., "tesla-model-3-v1").
+	// The controller validates Properties, firmware version and OTAPolicy against it.
 	// +optional
 	VehicleModelRef string `json:"vehicleModelRef,omitempty"`
 
@@ -61,6 +70,12 @@ type VehicleSpec struct {
 	// Used for non-core, model-specific features (e.g., "ambient_light_color": "blue").
 	// +optional
 	Properties map[string]string `json:"properties,omitempty"`
+
+	// MaintenanceMode excludes the vehicle from automated commands, e.g. while it is in a service bay:
+	// no OTA is started or advanced and vehicle-initiated requests are refused.
+	// Its status is still tracked.
+	// +optional
+	MaintenanceMode bool `json:"maintenanceMode,omitempty"`
 }
 
 // AccessConfig defines the connectivity parameters.
@@ -122,12 +137,17 @@ type FirmwareConfig struct {
 type OTAPolicy struct {
 	// MinBatteryLevel defines the minimum battery percentage (0-100) required to start an OTA.
 	// This is a POLICY, not the current battery level.
+	// A single OTA command may override it with its "minBatteryLevel" parameter.
+	// The controller does not start installing while the reported battery_level property is below it.
 	// +kubebuilder:validation:Minimum=30
 	// +kubebuilder:validation:Maximum=100
 	// +optional
 	MinBatteryLevel *int32 `json:"minBatteryLevel,omitempty"`
 
-	// RetryLimit defines how many times the agent should retry a failed update.
+	// RetryLimit defines how many times a failed update is retried automatically before it is given up.
+	// Defaults to the controller's --vehicle.retry-limit (5); 0 disables automatic retries.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
 	// +optional
 	RetryLimit *int32 `json:"retryLimit,omitempty"`
 }
@@ -157,8 +177,25 @@ const (
 
 	// ConditionTypeSynced indicates if the Vehicle's reported state matches the desired Spec.
 	ConditionTypeSynced = "Synced"
+
+	// ConditionTypePaused is set while reconciliation is paused cluster-wide for maintenance.
+	ConditionTypePaused = "Paused"
+
+	// ConditionTypeMaintenanceMode is set while the vehicle is in maintenance mode (Spec.MaintenanceMode).
+	ConditionTypeMaintenanceMode = "MaintenanceMode"
+
+	// ConditionTypeReadyToInstall is False while an update is held because the vehicle does not meet
+	// the safety preconditions for installation (e.g., battery below OTAPolicy.MinBatteryLevel).
+	ConditionTypeReadyToInstall = "ReadyToInstall"
+
+	// ConditionTypeOnline is False while the vehicle has not sent a heartbeat within the offline
+	// threshold, and True again once it does.
+	ConditionTypeOnline = "Online"
 )
 
+// PropertyBatteryLevel is the reported property (Status.Properties) holding the battery percentage (0-100).
+const PropertyBatteryLevel = "battery_level"
+
 // VehicleStatus defines the observed state of Vehicle.
 type VehicleStatus struct {
 	// Online status derived from heartbeats.
@@ -202,9 +239,88 @@ type UpgradeStatus struct {
 	// +optional
 	RetryCount int32 `json:"retryCount,omitempty"`
 
+	// StartTime is when the current update was started (Idle -> Pending). Retries keep it.
+	// The rollout guardrail rebuilds its window from it after a controller restart.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// Path lists the firmware versions a multi-step update installs, in order.
+	// The last one is the desired version. Empty for a direct update.
+	// +optional
+	Path []string `json:"path,omitempty"`
+
+	// Step is the index in Path of the version being installed.
+	// +optional
+	Step int32 `json:"step,omitempty"`
+
 	// LastError stores the last failure reason for debugging.
 	// +optional
 	LastError string `json:"lastError,omitempty"`
+
+	// History lists the most recent finished update attempts, oldest first.
+	// It keeps at most MaxUpgradeHistory entries, dropping the oldest, to bound the object size.
+	// +optional
+	// +kubebuilder:validation:MaxItems=10
+	History []UpgradeAttempt `json:"history,omitempty"`
+
+	// Transitions lists the most recent phase transitions of the state machine, oldest first.
+	// It keeps at most MaxPhaseTransitions entries, dropping the oldest, to bound the object size.
+	// +optional
+	// +kubebuilder:validation:MaxItems=20
+	Transitions []PhaseTransition `json:"transitions,omitempty"`
+}
+
+// MaxUpgradeHistory is the number of update attempts kept in UpgradeStatus.History.
+const MaxUpgradeHistory = 10
+
+// MaxPhaseTransitions is the number of phase transitions kept in UpgradeStatus.Transitions.
+const MaxPhaseTransitions = 20
+
+// PhaseTransition records a change of UpgradeStatus.Phase.
+type PhaseTransition struct {
+	// From is the phase the vehicle left.
+	From VehiclePhase `json:"from"`
+
+	// To is the phase the vehicle entered.
+	To VehiclePhase `json:"to"`
+
+	// Time is when the transition happened.
+	Time metav1.Time `json:"time"`
+
+	// Reason is the state machine event that caused the transition, e.g. event_retry.
+	Reason string `json:"reason"`
+
+	// Message describes the transition, e.g. the failure reason.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// UpgradeAttempt records the outcome of an update attempt.
+type UpgradeAttempt struct {
+	// FromVersion is the firmware version reported when the attempt ended.
+	// +optional
+	FromVersion string `json:"fromVersion,omitempty"`
+
+	// ToVersion is the desired firmware version of the attempt.
+	ToVersion string `json:"toVersion"`
+
+	// Outcome is the terminal phase of the attempt: Succeeded or Failed.
+	Outcome VehiclePhase `json:"outcome"`
+
+	// StartTime is when the update was started. Retries of a failed update keep it.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the attempt reached its outcome.
+	CompletionTime metav1.Time `json:"completionTime"`
+
+	// RetryCount is the number of retries the attempt was.
+	// +optional
+	RetryCount int32 `json:"retryCount,omitempty"`
+
+	// Message describes the outcome, e.g. the failure reason.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -4,17 +4,38 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// GroupCommandLabel is set on the per-vehicle commands created for a group command (Spec.GroupRef)
+// and holds the name of the group command.
+const GroupCommandLabel = "iov.autopeer.io/group-command"
+
+// OTAParamMinBatteryLevel is the OTA command parameter holding the minimum battery percentage
+// the agent requires before installing. The controller fills it from the vehicle's OTAPolicy;
+// a value set on the command overrides the policy for that command only, and is audited.
+const OTAParamMinBatteryLevel = "minBatteryLevel"
+
 // VehicleCommandSpec defines the desired command execution.
+// +kubebuilder:validation:XValidation:rule="(has(self.method) && self.method != '') || (has(self.templateRef) && self.templateRef != '')",message="either method or templateRef must be set"
+// +kubebuilder:validation:XValidation:rule="(has(self.vehicleName) && self.vehicleName != '') != (has(self.groupRef) && self.groupRef != '')",message="exactly one of vehicleName or groupRef must be set"
 type VehicleCommandSpec struct {
 	// VehicleName is the name of the target Vehicle resource in the same namespace.
-	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:MinLength=1
-	VehicleName string `json:"vehicleName"`
+	// +optional
+	VehicleName string `json:"vehicleName,omitempty"`
+
+	// GroupRef targets every member of a VehicleGroup (in the same namespace) instead of a single vehicle.
+	// The controller creates one command per member and aggregates their outcome into this command.
+	// +optional
+	GroupRef string `json:"groupRef,omitempty"`
 
 	// Method is the name of the operation to execute (e.g., "Reboot", "OTA", "OpenTrunk").
-	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:MinLength=1
-	Method string `json:"method"`
+	// It may be omitted if TemplateRef is set.
+	// +optional
+	Method string `json:"method,omitempty"`
+
+	// TemplateRef references a CommandTemplate (in the same namespace) providing the defaults
+	// for Method, Parameters, Priority and TimeoutSeconds. Fields set on the command take precedence,
+	// Parameters are merged key by key. The controller resolves the template once, when the command is created.
+	// +optional
+	TemplateRef string `json:"templateRef,omitempty"`
 
 	// RequestID allow external systems (like BFF) to trace the command.
 	// Ideally maps to OpenTelemetry TraceID or a UUID.
@@ -79,6 +100,7 @@ type VehicleCommandStatus struct {
 	// WARNING: Do NOT store large binaries or logs here.
 	// Use strictly for references (e.g., {"status": "ok", "report_url": "s3://bucket/log.txt"}).
 	// Etcd limit is 1.5MB, keeping this small is crucial.
+	// Outputs the vehicle uploaded to object storage are referenced by the "objectKey" entry.
 	// +optional
 	Result map[string]string `json:"result,omitempty"`
 
@@ -101,11 +123,25 @@ type VehicleCommandStatus struct {
 	// +optional
 	AcknowledgeTime *metav1.Time `json:"acknowledgeTime,omitempty"`
 
+	// ResendCount is how many times the command was re-sent because the vehicle did not acknowledge it in time.
+	// +optional
+	ResendCount int32 `json:"resendCount,omitempty"`
+
+	// LastResendTime is when the command was last re-sent. The acknowledgement deadline is
+	// measured from it, or from SentTime if the command was never re-sent.
+	// +optional
+	LastResendTime *metav1.Time `json:"lastResendTime,omitempty"`
+
 	// CompletionTime marks when the command reached a terminal state (Succeeded, Failed, or Timeout).
 	// This timestamp effectively closes the SLA window for the operation.
 	// +optional
 	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
 
+	// GroupMembers is the number of per-vehicle commands created for a group command (Spec.GroupRef).
+	// The group command finishes only once that many of them have finished.
+	// +optional
+	GroupMembers int32 `json:"groupMembers,omitempty"`
+
 	// Conditions provide a detailed history of the command's progress (e.g., Downloaded, Verified).
 	// +optional
 	// +patchMergeKey=type
@@ -0,0 +1,72 @@
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VehicleGroupSpec defines which vehicles belong to a group.
+// A vehicle is a member if it matches Selector or is listed in Vehicles.
+type VehicleGroupSpec struct {
+	// Description is a human-readable summary of the group (e.g., "Berlin test fleet").
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Selector selects member vehicles (in the same namespace) by label.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Vehicles lists member vehicles by name.
+	// +optional
+	// +listType=set
+	Vehicles []string `json:"vehicles,omitempty"`
+}
+
+// VehicleGroupStatus holds the materialized membership of a group.
+type VehicleGroupStatus struct {
+	// ObservedGeneration is the generation of the spec the members were computed from.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Members are the names of the vehicles currently in the group, sorted.
+	// +optional
+	Members []string `json:"members,omitempty"`
+
+	// MemberCount is the number of Members.
+	// +optional
+	MemberCount int32 `json:"memberCount,omitempty"`
+
+	// Conditions represent the latest available observations of the group (e.g., Ready).
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Members",type="integer",JSONPath=".status.memberCount",description="Number of Member Vehicles"
+//+kubebuilder:printcolumn:name="Description",type="string",JSONPath=".spec.description",description="Group Description"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// VehicleGroup is the Schema for the vehiclegroups API
+type VehicleGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VehicleGroupSpec   `json:"spec,omitempty"`
+	Status VehicleGroupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// VehicleGroupList contains a list of VehicleGroup
+type VehicleGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VehicleGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VehicleGroup{}, &VehicleGroupList{})
+}
@@ -0,0 +1,142 @@
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VehicleModelSpec defines the schema shared by all vehicles of a model.
+// Vehicles reference it through Spec.VehicleModelRef.
+type VehicleModelSpec struct {
+	// Description is a human-readable summary of the model (e.g., "Model 3, 2024 HW4").
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Properties lists the dynamic extension attributes a vehicle of this model may carry.
+	// Keys in Vehicle.Spec.Properties that are not listed here are rejected.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Properties []ModelProperty `json:"properties,omitempty"`
+
+	// FirmwareChannels lists the release channels and the versions they ship.
+	// If set, Vehicle.Spec.Profile.Firmware.Version must belong to one of them.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	FirmwareChannels []FirmwareChannel `json:"firmwareChannels,omitempty"`
+
+	// OTAPolicy defines the bounds a Vehicle's OTAPolicy must stay within.
+	// +optional
+	OTAPolicy OTAPolicyBounds `json:"otaPolicy,omitempty"`
+
+	// UpgradePaths is the firmware compatibility matrix. A version listed here may only be
+	// installed directly on the versions in its From list; versions not listed may be
+	// installed on any version.
+	// +optional
+	// +listType=map
+	// +listMapKey=version
+	UpgradePaths []UpgradePath `json:"upgradePaths,omitempty"`
+
+	// Methods declares when the command methods of this model may be dispatched.
+	// Methods not listed here are dispatched as soon as they are created.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Methods []CommandMethod `json:"methods,omitempty"`
+}
+
+// ModelProperty describes one supported dynamic property.
+type ModelProperty struct {
+	// Name is the property key as used in Vehicle.Spec.Properties.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Description explains the meaning of the property.
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+// FirmwareChannel groups the firmware versions published on one release channel.
+type FirmwareChannel struct {
+	// Name of the channel (e.g., "stable", "beta").
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Versions are the firmware versions available on this channel.
+	// +optional
+	Versions []string `json:"versions,omitempty"`
+}
+
+// UpgradePath restricts which firmware versions may upgrade directly to a version.
+type UpgradePath struct {
+	// Version is the target firmware version.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+
+	// From lists the versions that may upgrade directly to Version.
+	// A vehicle running any other version must first upgrade to one of them.
+	// +kubebuilder:validation:MinItems=1
+	From []string `json:"from"`
+}
+
+// CommandMethod describes the execution window of one command method.
+type CommandMethod struct {
+	// Name is the command method as used in VehicleCommand.Spec.Method (e.g., "Reboot").
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// RequiredState lists the reported properties (Vehicle.Status.Properties) and the values
+	// they must have before a command is dispatched (e.g., {"gear": "P"}).
+	// +optional
+	RequiredState map[string]string `json:"requiredState,omitempty"`
+
+	// MaxWaitSeconds is how long a command may wait for RequiredState before it fails.
+	// Defaults to 3600.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxWaitSeconds *int32 `json:"maxWaitSeconds,omitempty"`
+}
+
+// OTAPolicyBounds constrains the OTAPolicy values a Vehicle may request.
+type OTAPolicyBounds struct {
+	// MinBatteryLevel is the lowest MinBatteryLevel a vehicle may configure.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	MinBatteryLevel *int32 `json:"minBatteryLevel,omitempty"`
+
+	// MaxRetryLimit is the highest RetryLimit a vehicle may configure.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxRetryLimit *int32 `json:"maxRetryLimit,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Description",type="string",JSONPath=".spec.description",description="Model Description"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// VehicleModel is the Schema for the vehiclemodels API
+type VehicleModel struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec VehicleModelSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// VehicleModelList contains a list of VehicleModel
+type VehicleModelList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VehicleModel `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VehicleModel{}, &VehicleModelList{})
+}
@@ -0,0 +1,123 @@
+package log
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const logImportPath = "github.com/autopeer-io/autopeer/pkg/log"
+
+// formatVerb matches printf verbs such as %s, %d or %v.
+var formatVerb = regexp.MustCompile(`%[-+# 0-9.]*[sdvqxXtfeg]`)
+
+// TestStructuredLogCalls is a vet-style check over the whole module: the package-level
+// log functions take key/value pairs, not printf arguments, so a message must not
+// contain format verbs and the key/value list must have an even length.
+func TestStructuredLogCalls(t *testing.T) {
+	root := filepath.Join("..", "..")
+	fset := token.NewFileSet()
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); name == "vendor" || name == "_output" || (strings.HasPrefix(name, ".") && path != root) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+
+		pkgName := logPackageName(file)
+		if pkgName == "" {
+			return nil
+		}
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				if problem := checkLogCall(call, pkgName); problem != "" {
+					t.Errorf("%s: %s", fset.Position(call.Pos()), problem)
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// logPackageName returns the name under which this package is imported by file, or "".
+func logPackageName(file *ast.File) string {
+	for _, imp := range file.Imports {
+		if path, _ := strconv.Unquote(imp.Path.Value); path == logImportPath {
+			if imp.Name != nil {
+				return imp.Name.Name
+			}
+			return "log"
+		}
+	}
+	return ""
+}
+
+func checkLogCall(call *ast.CallExpr, pkgName string) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	if ident, ok := sel.X.(*ast.Ident); !ok || ident.Name != pkgName {
+		return ""
+	}
+
+	var msgIndex int
+	switch sel.Sel.Name {
+	case "Debug", "Info", "Warn":
+		msgIndex = 0
+	case "Error":
+		msgIndex = 1
+	default:
+		return ""
+	}
+	if len(call.Args) <= msgIndex || call.Ellipsis.IsValid() {
+		return ""
+	}
+
+	if lit, ok := call.Args[msgIndex].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		if msg, _ := strconv.Unquote(lit.Value); formatVerb.MatchString(strings.ReplaceAll(msg, "%%", "")) {
+			return "log." + sel.Sel.Name + " message contains a format verb; pass values as key/value pairs"
+		}
+	}
+
+	kvs := call.Args[msgIndex+1:]
+	for _, arg := range kvs {
+		// zap.Field values are self-describing and do not need a key.
+		if c, ok := arg.(*ast.CallExpr); ok {
+			if s, ok := c.Fun.(*ast.SelectorExpr); ok {
+				if x, ok := s.X.(*ast.Ident); ok && x.Name == "zap" {
+					return ""
+				}
+			}
+		}
+	}
+	if len(kvs)%2 != 0 {
+		return "log." + sel.Sel.Name + " has an odd number of key/value arguments"
+	}
+
+	return ""
+}
@@ -5,7 +5,6 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net/url"
-	"strings"
 	"sync"
 	"time"
 
@@ -13,12 +12,28 @@ import (
 	"github.com/eclipse/paho.golang/paho"
 
 	"github.com/autopeer-io/autopeer/pkg/log"
+	"github.com/autopeer-io/autopeer/pkg/mqtt/topic"
 )
 
 type pahoClient struct {
+	// mu guards cfg and cm, which Reload replaces.
+	mu  sync.RWMutex
 	cfg *ClientConfig
 	cm  *autopaho.ConnectionManager
 
+	// runCtx is the context passed to Start. Connections opened by Reload live as long as it.
+	runCtx context.Context
+
+	// reloadMu serializes Reload calls.
+	reloadMu sync.Mutex
+
+	// inflight counts the running handlers, so Reload can wait for them to finish. idle is
+	// closed when the count drops to zero. inflightMu only guards both and is never held
+	// while waiting, so the router does not block on a draining Reload.
+	inflightMu sync.Mutex
+	inflight   int
+	idle       chan struct{}
+
 	// subscriptions holds the registered handlers.
 	// Key: topic filter (string), Value: subscriptionEntry
 	subscriptions sync.Map
@@ -49,23 +64,95 @@ func NewClient(cfg *ClientConfig) (Client, error) {
 }
 
 func (c *pahoClient) Start(ctx context.Context) error {
-	brokerURL, _ := url.Parse(c.cfg.BrokerURL) // Already validated
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cm, err := c.connect(ctx, c.cfg)
+	if err != nil {
+		return err
+	}
+	c.runCtx = ctx
+	c.cm = cm
+	return nil
+}
+
+func (c *pahoClient) Disconnect(ctx context.Context) {
+	if cm := c.conn(); cm != nil {
+		_ = cm.Disconnect(ctx)
+		log.Info("MQTT Client disconnected")
+	}
+}
+
+// Reload replaces the configuration, e.g. to rotate credentials or switch brokers.
+// If the client is started, it disconnects, waits for the running handlers to finish,
+// and connects again with the new configuration. Registered subscriptions are kept and
+// sent again once the new connection is up.
+func (c *pahoClient) Reload(ctx context.Context, cfg *ClientConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("mqtt config is required")
+	}
+	setDefaultConfig(cfg)
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid mqtt config: %w", err)
+	}
+
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	c.mu.Lock()
+	old := c.cm
+	if old == nil {
+		// Not started yet: Start picks up the new configuration.
+		c.cfg = cfg
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+
+	log.Info("Reloading MQTT Client", "broker", cfg.BrokerURL, "clientID", cfg.ClientID)
+
+	// No message is delivered once the old connection is down; drain the ones being handled.
+	// If ctx expires first, reconnect anyway: staying offline is worse than handlers of the
+	// old connection overlapping with those of the new one.
+	if err := old.Disconnect(ctx); err != nil {
+		log.Warn("MQTT Client did not shut down in time", "error", err.Error())
+	} else if err := c.drain(ctx); err != nil {
+		log.Warn("MQTT handlers still running, reconnecting anyway", "error", err.Error())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cm, err := c.connect(c.runCtx, cfg)
+	if err != nil {
+		return err
+	}
+	c.cfg = cfg
+	c.cm = cm
+	return nil
+}
+
+// connect starts a connection manager for cfg; it runs until ctx is done or it is disconnected.
+func (c *pahoClient) connect(ctx context.Context, cfg *ClientConfig) (*autopaho.ConnectionManager, error) {
+	log.Info("Starting MQTT Client", "broker", cfg.BrokerURL, "clientID", cfg.ClientID)
+	return autopaho.NewConnection(ctx, c.pahoConfig(cfg))
+}
+
+func (c *pahoClient) pahoConfig(cfg *ClientConfig) autopaho.ClientConfig {
+	brokerURL, _ := url.Parse(cfg.BrokerURL) // Already validated
 
-	pahoCfg := autopaho.ClientConfig{
+	return autopaho.ClientConfig{
 		ServerUrls:                    []*url.URL{brokerURL},
-		KeepAlive:                     c.cfg.KeepAlive,
-		CleanStartOnInitialConnection: c.cfg.CleanStart,
-		SessionExpiryInterval:         c.cfg.SessionExpiry,
+		KeepAlive:                     cfg.KeepAlive,
+		CleanStartOnInitialConnection: cfg.CleanStart,
+		SessionExpiryInterval:         cfg.SessionExpiry,
 		ReconnectBackoff:              autopaho.NewConstantBackoff(3 * time.Second),
-		ConnectTimeout:                c.cfg.ConnectTimeout,
-		ConnectUsername:               c.cfg.Username,
-		ConnectPassword:               []byte(c.cfg.Password),
-		TlsCfg: &tls.Config{
-			InsecureSkipVerify: c.cfg.InsecureSkipVerify,
-		},
-		WillMessage: c.willMessage(),
+		ConnectTimeout:                cfg.ConnectTimeout,
+		ConnectUsername:               cfg.Username,
+		ConnectPassword:               []byte(cfg.Password),
+		TlsCfg:                        tlsConfig(cfg),
+		WillMessage:                   willMessage(cfg),
 		ClientConfig: paho.ClientConfig{
-			ClientID:           c.cfg.ClientID,
+			ClientID:           cfg.ClientID,
 			OnClientError:      c.onClientError,
 			OnServerDisconnect: c.onServerDisconnect,
 			OnPublishReceived: []func(paho.PublishReceived) (bool, error){
@@ -75,33 +162,81 @@ func (c *pahoClient) Start(ctx context.Context) error {
 		OnConnectionUp: c.onConnectionUp,
 		OnConnectError: c.onConnectError,
 	}
+}
 
-	log.Info("Starting MQTT Client", "broker", c.cfg.BrokerURL, "clientID", c.cfg.ClientID)
+// tlsConfig returns the TLS configuration of cfg, presenting its client certificate if it has one.
+func tlsConfig(cfg *ClientConfig) *tls.Config {
+	tlsCfg := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CertFile != "" {
+		certFile, keyFile := cfg.CertFile, cfg.KeyFile
+		tlsCfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, err
+			}
+			return &cert, nil
+		}
+	}
+	return tlsCfg
+}
 
-	cm, err := autopaho.NewConnection(ctx, pahoCfg)
-	if err != nil {
-		return err
+// drain waits until no handler is running, or ctx is done.
+func (c *pahoClient) drain(ctx context.Context) error {
+	c.inflightMu.Lock()
+	if c.inflight == 0 {
+		c.inflightMu.Unlock()
+		return nil
+	}
+	idle := c.idle
+	c.inflightMu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	c.cm = cm
-	return nil
 }
 
-func (c *pahoClient) Disconnect(ctx context.Context) {
-	if c.cm != nil {
-		_ = c.cm.Disconnect(ctx)
-		log.Info("MQTT Client disconnected")
+// handlerStarted counts a handler that is about to run.
+func (c *pahoClient) handlerStarted() {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+	if c.inflight == 0 {
+		c.idle = make(chan struct{})
+	}
+	c.inflight++
+}
+
+// handlerDone counts a handler that returned.
+func (c *pahoClient) handlerDone() {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+	c.inflight--
+	if c.inflight == 0 {
+		close(c.idle)
 	}
 }
 
+// conn returns the current connection manager, or nil if the client is not started.
+func (c *pahoClient) conn() *autopaho.ConnectionManager {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cm
+}
+
 func (c *pahoClient) Publish(ctx context.Context, topic string, qos int, retain bool, payload []byte) error {
-	if c.cm == nil {
+	cm := c.conn()
+	if cm == nil {
 		return fmt.Errorf("client not started")
 	}
 
 	// Check connection status to avoid immediate error if possible,
 	// although paho handles offline buffering if configured.
 	// Here we simply delegate.
-	_, err := c.cm.Publish(ctx, &paho.Publish{
+	_, err := cm.Publish(ctx, &paho.Publish{
 		Topic:   topic,
 		QoS:     byte(qos),
 		Retain:  retain,
@@ -112,7 +247,8 @@ func (c *pahoClient) Publish(ctx context.Context, topic string, qos int, retain
 }
 
 func (c *pahoClient) Subscribe(ctx context.Context, topic string, qos int, handler MessageHandler) error {
-	if c.cm == nil {
+	cm := c.conn()
+	if cm == nil {
 		return fmt.Errorf("client not started")
 	}
 
@@ -128,7 +264,7 @@ func (c *pahoClient) Subscribe(ctx context.Context, topic string, qos int, handl
 	// If not connected, OnConnectionUp will handle it later.
 	// Note: We don't strictly check IsConnected() because autopaho might be in a reconnecting state.
 	// Attempting to subscribe usually works or queues up.
-	_, err := c.cm.Subscribe(ctx, &paho.Subscribe{
+	_, err := cm.Subscribe(ctx, &paho.Subscribe{
 		Subscriptions: []paho.SubscribeOptions{
 			{Topic: topic, QoS: byte(qos)},
 		},
@@ -142,23 +278,25 @@ func (c *pahoClient) Subscribe(ctx context.Context, topic string, qos int, handl
 }
 
 func (c *pahoClient) Unsubscribe(ctx context.Context, topic string) error {
-	if c.cm == nil {
+	cm := c.conn()
+	if cm == nil {
 		return fmt.Errorf("client not started")
 	}
 
 	c.subscriptions.Delete(topic)
 
-	_, err := c.cm.Unsubscribe(ctx, &paho.Unsubscribe{
+	_, err := cm.Unsubscribe(ctx, &paho.Unsubscribe{
 		Topics: []string{topic},
 	})
 	return err
 }
 
 func (c *pahoClient) AwaitConnection(ctx context.Context) error {
-	if c.cm == nil {
+	cm := c.conn()
+	if cm == nil {
 		return fmt.Errorf("client not started")
 	}
-	return c.cm.AwaitConnection(ctx)
+	return cm.AwaitConnection(ctx)
 }
 
 func (c *pahoClient) IsConnected() bool {
@@ -213,10 +351,12 @@ func (c *pahoClient) router(p paho.PublishReceived) (bool, error) {
 	matched := false
 	c.subscriptions.Range(func(key, value any) bool {
 		entry := value.(subscriptionEntry)
-		if topicsMatch(topicFilter(entry.topic), p.Packet.Topic) {
+		if topic.Match(entry.topic, p.Packet.Topic) {
 			// Execute handler in a separate goroutine to avoid blocking the reader loop
 			// Or execute inline if logic is fast. Given "go" keyword is cheap:
+			c.handlerStarted()
 			go func(h MessageHandler) {
+				defer c.handlerDone()
 				// Create a background context or derive one with timeout
 				h(context.Background(), p.Packet.Topic, p.Packet.Payload)
 			}(entry.handler)
@@ -232,56 +372,14 @@ func (c *pahoClient) router(p paho.PublishReceived) (bool, error) {
 	return true, nil // Always acknowledge reception
 }
 
-func (c *pahoClient) willMessage() *paho.WillMessage {
-	if c.cfg.WillTopic == "" {
+func willMessage(cfg *ClientConfig) *paho.WillMessage {
+	if cfg.WillTopic == "" {
 		return nil
 	}
 	return &paho.WillMessage{
-		Topic:   c.cfg.WillTopic,
-		Payload: c.cfg.WillPayload,
-		QoS:     c.cfg.WillQoS,
-		Retain:  c.cfg.WillRetain,
-	}
-}
-
-// topicsMatch checks if a topic matches a filter (supports wildcards + and #).
-func topicsMatch(filter, topic string) bool {
-	// This is a simplified matcher. Paho often has internal ones, but for transparency:
-	if filter == topic {
-		return true
-	}
-
-	// If simple equality fails, check for wildcards.
-	// Optimization: if no wildcards, we are done.
-	if !strings.Contains(filter, "+") && !strings.Contains(filter, "#") {
-		return false
-	}
-
-	filterParts := strings.Split(filter, "/")
-	topicParts := strings.Split(topic, "/")
-
-	for i, part := range filterParts {
-		if part == "#" {
-			return true
-		}
-		if i >= len(topicParts) {
-			return false
-		}
-		if part != "+" && part != topicParts[i] {
-			return false
-		}
-	}
-
-	return len(filterParts) == len(topicParts)
-}
-
-func topicFilter(filter string) string {
-	if strings.HasPrefix(filter, "$share/") {
-		// Format: $share/<group>/<topic>
-		parts := strings.SplitN(filter, "/", 3)
-		if len(parts) == 3 {
-			return parts[2]
-		}
+		Topic:   cfg.WillTopic,
+		Payload: cfg.WillPayload,
+		QoS:     cfg.WillQoS,
+		Retain:  cfg.WillRetain,
 	}
-	return filter
 }
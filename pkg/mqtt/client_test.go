@@ -0,0 +1,183 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/autopeer-io/autopeer/pkg/mqtt/topic"
+)
+
+func TestSharedSubscriptionRouting(t *testing.T) {
+	tests := []struct {
+		root      string
+		wantTopic string
+		published string
+	}{
+		{root: "iov/v1", wantTopic: "$share/hub/iov/v1/command/ack/+", published: "iov/v1/command/ack/VH1"},
+		{root: "/iov/v1", wantTopic: "$share/hub//iov/v1/command/ack/+", published: "/iov/v1/command/ack/VH1"},
+		{root: "", wantTopic: "$share/hub//command/ack/+", published: "/command/ack/VH1"},
+	}
+
+	for _, tt := range tests {
+		builder := topic.NewBuilder(tt.root)
+		shared := builder.Shared("hub").BuildWildcard("command", "ack")
+		if shared != tt.wantTopic {
+			t.Errorf("root %q: shared subscription = %q, want %q", tt.root, shared, tt.wantTopic)
+		}
+
+		// The router must match a shared subscription exactly like the plain one.
+		if !topic.Match(shared, tt.published) {
+			t.Errorf("root %q: %q is not routed to %q", tt.root, tt.published, shared)
+		}
+		if unrelated := builder.Build("register", "VH1"); topic.Match(shared, unrelated) {
+			t.Errorf("root %q: unrelated topic routed to %q", tt.root, shared)
+		}
+	}
+}
+
+func TestReloadBeforeStart(t *testing.T) {
+	cli, err := NewClient(&ClientConfig{BrokerURL: "tcp://localhost:1883", ClientID: "test", Username: "old", Password: "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cli.(*pahoClient)
+	ctx := context.Background()
+
+	if err := c.Reload(ctx, nil); err == nil {
+		t.Error("Reload(nil) succeeded, want error")
+	}
+	if err := c.Reload(ctx, &ClientConfig{}); err == nil {
+		t.Error("Reload without broker succeeded, want error")
+	}
+	if c.cfg.Username != "old" {
+		t.Errorf("rejected config was applied: username = %q", c.cfg.Username)
+	}
+
+	if err := c.Reload(ctx, &ClientConfig{BrokerURL: "tcp://other:1883", ClientID: "test", Username: "new", Password: "rotated"}); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if c.cm != nil {
+		t.Error("Reload started a client that was not started")
+	}
+	got := c.pahoConfig(c.cfg)
+	if got.ServerUrls[0].Host != "other:1883" || got.ConnectUsername != "new" || string(got.ConnectPassword) != "rotated" {
+		t.Errorf("connection config = %v %q %q, want the reloaded one", got.ServerUrls, got.ConnectUsername, got.ConnectPassword)
+	}
+	if got.KeepAlive != 60 {
+		t.Errorf("KeepAlive = %d, want the default", got.KeepAlive)
+	}
+}
+
+func TestReloadPreservesSubscriptions(t *testing.T) {
+	// Nothing listens on the broker address: the connections retry in the background,
+	// which is enough to exercise the reload.
+	cli, err := NewClient(&ClientConfig{BrokerURL: "tcp://127.0.0.1:1", ClientID: "test", Username: "old", ConnectTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cli.(*pahoClient)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Subscribe fails while offline, but the handler stays registered for the next connection.
+	received := make(chan string, 1)
+	release := make(chan struct{})
+	_ = c.Subscribe(ctx, "iov/v1/command/+", 1, func(_ context.Context, topic string, _ []byte) {
+		<-release
+		received <- topic
+	})
+
+	// A message is being handled when the reload starts: the reload waits for it.
+	c.router(paho.PublishReceived{Packet: &paho.Publish{Topic: "iov/v1/command/VH1"}})
+	old := c.conn()
+	reloaded := make(chan error, 1)
+	go func() {
+		reloaded <- c.Reload(ctx, &ClientConfig{BrokerURL: "tcp://127.0.0.1:1", ClientID: "test", Username: "new", Password: "rotated"})
+	}()
+	select {
+	case err := <-reloaded:
+		t.Fatalf("Reload() returned before the running handler finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	if err := <-reloaded; err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if topic := <-received; topic != "iov/v1/command/VH1" {
+		t.Errorf("in-flight message topic = %q", topic)
+	}
+
+	if c.conn() == old {
+		t.Error("Reload kept the old connection")
+	}
+	if c.cfg.Username != "new" || c.cfg.Password != "rotated" {
+		t.Errorf("credentials = %q/%q, want the reloaded ones", c.cfg.Username, c.cfg.Password)
+	}
+	if _, ok := c.subscriptions.Load("iov/v1/command/+"); !ok {
+		t.Fatal("subscription lost on reload")
+	}
+
+	// The preserved handler still receives messages.
+	c.router(paho.PublishReceived{Packet: &paho.Publish{Topic: "iov/v1/command/VH2"}})
+	select {
+	case topic := <-received:
+		if topic != "iov/v1/command/VH2" {
+			t.Errorf("topic = %q, want iov/v1/command/VH2", topic)
+		}
+	case <-time.After(time.Second):
+		t.Error("handler not called after reload")
+	}
+	c.Disconnect(context.Background())
+}
+
+func TestRouterDoesNotWaitForDrain(t *testing.T) {
+	cli, err := NewClient(&ClientConfig{BrokerURL: "tcp://localhost:1883", ClientID: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cli.(*pahoClient)
+
+	release := make(chan struct{})
+	received := make(chan string, 2)
+	c.subscriptions.Store("iov/v1/command/+", subscriptionEntry{topic: "iov/v1/command/+", qos: 1, handler: func(_ context.Context, topic string, _ []byte) {
+		if topic == "iov/v1/command/slow" {
+			<-release
+		}
+		received <- topic
+	}})
+
+	// A slow handler of the old connection outlives the drain of a reload.
+	c.router(paho.PublishReceived{Packet: &paho.Publish{Topic: "iov/v1/command/slow"}})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.drain(ctx); err == nil {
+		t.Fatal("drain() returned while a handler is running")
+	}
+
+	// The new connection keeps delivering meanwhile.
+	routed := make(chan struct{})
+	go func() {
+		c.router(paho.PublishReceived{Packet: &paho.Publish{Topic: "iov/v1/command/fast"}})
+		close(routed)
+	}()
+	select {
+	case <-routed:
+	case <-time.After(time.Second):
+		t.Fatal("router blocked behind the timed-out drain")
+	}
+	if topic := <-received; topic != "iov/v1/command/fast" {
+		t.Errorf("first handled message = %q, want the fast one", topic)
+	}
+
+	close(release)
+	<-received
+	if err := c.drain(context.Background()); err != nil {
+		t.Errorf("drain() error = %v once all handlers returned", err)
+	}
+}
@@ -2,10 +2,31 @@ package mqtt
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
+	"slices"
 	"time"
 )
 
+// Errors returned by ClientConfig.Validate.
+var (
+	// ErrMissingBrokerURL means BrokerURL is empty.
+	ErrMissingBrokerURL = errors.New("broker url is required")
+
+	// ErrInvalidBrokerScheme means the BrokerURL scheme is not one of tcp, ssl, ws or wss.
+	ErrInvalidBrokerScheme = errors.New("invalid broker url scheme")
+
+	// ErrMissingClientID means ClientID is empty although CleanStart is false:
+	// the broker can only resume a session by its client ID.
+	ErrMissingClientID = errors.New("client id is required without clean start")
+
+	// ErrTLSCertWithoutKey means only one of CertFile and KeyFile is set.
+	ErrTLSCertWithoutKey = errors.New("tls client certificate and key must be set together")
+)
+
+// brokerSchemes are the BrokerURL schemes the client can connect with.
+var brokerSchemes = []string{"tcp", "ssl", "ws", "wss"}
+
 // ClientConfig holds the configuration for creating a new MQTT Client.
 type ClientConfig struct {
 	BrokerURL string
@@ -30,6 +51,11 @@ type ClientConfig struct {
 	// MUST be true for Autopeer's self-signed certs environment.
 	InsecureSkipVerify bool
 
+	// CertFile and KeyFile are the PEM client certificate and key for mutual TLS.
+	// They are read on each TLS handshake, so rotated files are picked up on reconnect.
+	CertFile string
+	KeyFile  string
+
 	// Last Will and Testament (LWT) settings
 	WillTopic   string
 	WillPayload []byte
@@ -53,12 +79,23 @@ func setDefaultConfig(cfg *ClientConfig) {
 }
 
 // Validate checks if the configuration is valid.
+// Its errors wrap the Err* sentinels of this package, for errors.Is.
 func (c *ClientConfig) Validate() error {
 	if c.BrokerURL == "" {
-		return errors.New("broker url is required")
+		return ErrMissingBrokerURL
 	}
-	if _, err := url.Parse(c.BrokerURL); err != nil {
+	u, err := url.Parse(c.BrokerURL)
+	if err != nil {
 		return err
 	}
+	if !slices.Contains(brokerSchemes, u.Scheme) {
+		return fmt.Errorf("%w %q: must be one of %v", ErrInvalidBrokerScheme, u.Scheme, brokerSchemes)
+	}
+	if c.ClientID == "" && !c.CleanStart {
+		return ErrMissingClientID
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return ErrTLSCertWithoutKey
+	}
 	return nil
 }
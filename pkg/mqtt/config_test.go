@@ -0,0 +1,43 @@
+package mqtt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClientConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ClientConfig
+		wantErr error
+	}{
+		{name: "valid", cfg: ClientConfig{BrokerURL: "ssl://broker:8883", ClientID: "agent-1", CertFile: "tls.crt", KeyFile: "tls.key"}},
+		{name: "server assigned client id", cfg: ClientConfig{BrokerURL: "wss://broker/mqtt", CleanStart: true}},
+		{name: "missing broker url", cfg: ClientConfig{ClientID: "agent-1"}, wantErr: ErrMissingBrokerURL},
+		{name: "invalid broker scheme", cfg: ClientConfig{BrokerURL: "http://broker:1883", ClientID: "agent-1"}, wantErr: ErrInvalidBrokerScheme},
+		{name: "broker without scheme", cfg: ClientConfig{BrokerURL: "broker:1883", ClientID: "agent-1"}, wantErr: ErrInvalidBrokerScheme},
+		{name: "missing client id", cfg: ClientConfig{BrokerURL: "tcp://broker:1883"}, wantErr: ErrMissingClientID},
+		{name: "cert without key", cfg: ClientConfig{BrokerURL: "ssl://broker:8883", ClientID: "agent-1", CertFile: "tls.crt"}, wantErr: ErrTLSCertWithoutKey},
+		{name: "key without cert", cfg: ClientConfig{BrokerURL: "ssl://broker:8883", ClientID: "agent-1", KeyFile: "tls.key"}, wantErr: ErrTLSCertWithoutKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// NewClient keeps the sentinel in its wrapped error.
+	if _, err := NewClient(&ClientConfig{BrokerURL: "tcp://broker:1883"}); !errors.Is(err, ErrMissingClientID) {
+		t.Errorf("NewClient() error = %v, want ErrMissingClientID", err)
+	}
+}
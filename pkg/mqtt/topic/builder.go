@@ -20,19 +20,24 @@ func NewBuilder(root string) *Builder {
 	}
 }
 
+// Namespace returns a NEW Builder whose topics live below "<root>/<namespace>".
+// It isolates tenants sharing a broker: an ACL granting a tenant's clients only
+// BuildMultiWildcard() of its namespaced builder ("<root>/<namespace>/#") keeps
+// them from reading or publishing other tenants' messages.
+// An empty namespace returns the builder unchanged.
+func (b *Builder) Namespace(namespace string) *Builder {
+	if namespace == "" {
+		return b
+	}
+	return &Builder{root: b.root + "/" + namespace}
+}
+
 // Shared returns a NEW Builder instance with the shared subscription prefix.
 // It uses the "Immutable Pattern" to avoid side effects on the original builder.
+// Every topic it builds is "$share/<group>/" followed by the topic the original builder
+// would build, so stripping the prefix yields the filter the broker matches against.
 func (b *Builder) Shared(groupName string) *Builder {
-	cleanRoot := strings.TrimPrefix(b.root, "/")
-
-	var newRoot string
-	if cleanRoot == "" {
-		newRoot = fmt.Sprintf("$share/%s", groupName)
-	} else {
-		newRoot = fmt.Sprintf("$share/%s/%s", groupName, cleanRoot)
-	}
-
-	return &Builder{root: newRoot}
+	return &Builder{root: fmt.Sprintf("$share/%s/%s", groupName, b.root)}
 }
 
 // Build constructs a topic path by joining the root and provided segments.
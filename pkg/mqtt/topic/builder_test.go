@@ -0,0 +1,59 @@
+package topic
+
+import "testing"
+
+func TestBuilderNamespace(t *testing.T) {
+	b := NewBuilder("iov/v1/")
+	tenant := b.Namespace("tenant-a")
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "flat", got: b.Build("command", "VH1"), want: "iov/v1/command/VH1"},
+		{name: "namespaced", got: tenant.Build("command", "VH1"), want: "iov/v1/tenant-a/command/VH1"},
+		{name: "namespaced wildcard", got: tenant.BuildWildcard("command", "ack"), want: "iov/v1/tenant-a/command/ack/+"},
+		{name: "namespaced shared", got: tenant.Shared("hub").BuildWildcard("online"), want: "$share/hub/iov/v1/tenant-a/online/+"},
+		{name: "tenant ACL", got: tenant.BuildMultiWildcard(), want: "iov/v1/tenant-a/#"},
+		{name: "empty namespace", got: b.Namespace("").Build("online", "VH1"), want: "iov/v1/online/VH1"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Deriving a namespaced builder must not change the original.
+	if got := b.Build("online"); got != "iov/v1/online" {
+		t.Errorf("original builder changed: %q", got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tenantA := NewBuilder("iov/v1").Namespace("tenant-a")
+	tenantB := NewBuilder("iov/v1").Namespace("tenant-b")
+
+	tests := []struct {
+		filter string
+		name   string
+		want   bool
+	}{
+		{filter: tenantA.BuildWildcard("command", "ack"), name: tenantA.Build("command", "ack", "VH1"), want: true},
+		{filter: tenantA.BuildWildcard("command", "ack"), name: tenantB.Build("command", "ack", "VH1"), want: false},
+		{filter: tenantA.Shared("hub").BuildWildcard("online"), name: tenantA.Build("online", "VH1"), want: true},
+		{filter: tenantA.Shared("hub").BuildWildcard("online"), name: tenantB.Build("online", "VH1"), want: false},
+		{filter: tenantA.BuildMultiWildcard(), name: tenantA.Build("ota", "request", "VH1"), want: true},
+		{filter: tenantA.BuildMultiWildcard(), name: tenantB.Build("ota", "request", "VH1"), want: false},
+		{filter: tenantA.BuildMultiWildcard(), name: "iov/v1/tenant-a", want: true},
+		{filter: "iov/v1/+/online/+", name: tenantB.Build("online", "VH1"), want: true},
+		{filter: "iov/v1/online/+", name: tenantA.Build("online", "VH1"), want: false},
+		{filter: "iov/v1/online/+", name: "iov/v1/online", want: false},
+		{filter: "#", name: "$SYS/brokers", want: false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.filter, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.filter, tt.name, got, tt.want)
+		}
+	}
+}
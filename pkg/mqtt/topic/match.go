@@ -0,0 +1,33 @@
+package topic
+
+import "strings"
+
+// sharedPrefix marks a shared subscription ("$share/<group>/<filter>").
+const sharedPrefix = "$share/"
+
+// Match reports whether a topic name matches a subscription filter, following the
+// MQTT wildcard rules. A shared subscription filter matches like the filter it wraps.
+// Topics starting with "$" (broker internals) are not matched by a leading wildcard.
+func Match(filter, name string) bool {
+	if rest, ok := strings.CutPrefix(filter, sharedPrefix); ok {
+		_, filter, _ = strings.Cut(rest, "/")
+	}
+	if strings.HasPrefix(name, "$") && (strings.HasPrefix(filter, Wildcard) || strings.HasPrefix(filter, MultiWildcard)) {
+		return false
+	}
+
+	levels := strings.Split(name, "/")
+	filters := strings.Split(filter, "/")
+	for i, f := range filters {
+		switch {
+		case f == MultiWildcard:
+			// "#" also matches the parent level ("a/#" matches "a").
+			return true
+		case i >= len(levels):
+			return false
+		case f != Wildcard && f != levels[i]:
+			return false
+		}
+	}
+	return len(filters) == len(levels)
+}
@@ -17,6 +17,11 @@ type Client interface {
 	// Disconnect cleanly closes the connection.
 	Disconnect(ctx context.Context)
 
+	// Reload applies a new configuration, e.g. rotated credentials or another broker.
+	// A started client reconnects after its running handlers finish; registered
+	// subscriptions are kept and sent again on the new connection.
+	Reload(ctx context.Context, cfg *ClientConfig) error
+
 	// Publish sends a message to the specified topic.
 	Publish(ctx context.Context, topic string, qos int, retain bool, payload []byte) error
 
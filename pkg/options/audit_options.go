@@ -0,0 +1,25 @@
+package options
+
+import (
+	"github.com/spf13/pflag"
+)
+
+var _ IOptions = (*AuditOptions)(nil)
+
+// AuditOptions configures the audit log of VehicleCommand dispatches and outcomes.
+type AuditOptions struct {
+	// Path is the append-only audit log file. Empty disables auditing, "-" writes to stdout.
+	Path string `json:"path" mapstructure:"path"`
+}
+
+func NewAuditOptions() *AuditOptions {
+	return &AuditOptions{}
+}
+
+func (o *AuditOptions) Validate() []error {
+	return []error{}
+}
+
+func (o *AuditOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
+	fs.StringVar(&o.Path, "audit.path", o.Path, "Append-only file receiving one JSON audit record per VehicleCommand transition. Empty disables auditing, '-' writes to stdout")
+}
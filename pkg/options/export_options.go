@@ -0,0 +1,60 @@
+package options
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+var _ IOptions = (*ExportOptions)(nil)
+
+// Supported fleet snapshot formats.
+const (
+	// ExportFormatJSON writes the snapshot as a single JSON document.
+	ExportFormatJSON = "json"
+
+	// ExportFormatNDJSON writes one JSON line per vehicle, which most analytics tools load directly.
+	ExportFormatNDJSON = "ndjson"
+)
+
+// ExportOptions configures the periodic export of fleet status snapshots to object storage.
+type ExportOptions struct {
+	// Interval is how often a snapshot is written. Zero disables the export.
+	Interval time.Duration `json:"interval" mapstructure:"interval"`
+
+	// Format is the snapshot encoding ("json" or "ndjson").
+	Format string `json:"format" mapstructure:"format"`
+
+	// Prefix is prepended to the object key of every snapshot.
+	Prefix string `json:"prefix" mapstructure:"prefix"`
+}
+
+func NewExportOptions() *ExportOptions {
+	return &ExportOptions{
+		Format: ExportFormatJSON,
+		Prefix: "snapshots/fleet/",
+	}
+}
+
+func (o *ExportOptions) Validate() []error {
+	errors := []error{}
+
+	if o.Interval < 0 {
+		errors = append(errors, fmt.Errorf("--export.interval must not be negative"))
+	}
+
+	switch o.Format {
+	case ExportFormatJSON, ExportFormatNDJSON:
+	default:
+		errors = append(errors, fmt.Errorf("--export.format must be one of: %s, %s", ExportFormatJSON, ExportFormatNDJSON))
+	}
+
+	return errors
+}
+
+func (o *ExportOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
+	fs.DurationVar(&o.Interval, "export.interval", o.Interval, "How often a fleet status snapshot is written to object storage. 0 disables the export")
+	fs.StringVar(&o.Format, "export.format", o.Format, "Fleet snapshot format, one of: json, ndjson")
+	fs.StringVar(&o.Prefix, "export.prefix", o.Prefix, "Object key prefix of fleet snapshots")
+}
@@ -0,0 +1,38 @@
+package options
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+var _ IOptions = (*FaultOptions)(nil)
+
+// FaultOptions configures fault injection for resilience testing.
+// It must never be enabled in production.
+type FaultOptions struct {
+	// Enabled turns fault injection on. Rules are ignored unless it is set.
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+
+	// Rules are "<point>=<fault>" pairs, e.g. "storage.presign=error" or "mqtt.publish=delay:2s".
+	Rules []string `json:"rules" mapstructure:"rules"`
+}
+
+func NewFaultOptions() *FaultOptions {
+	return &FaultOptions{}
+}
+
+func (o *FaultOptions) Validate() []error {
+	errors := []error{}
+
+	if o.Enabled && len(o.Rules) == 0 {
+		errors = append(errors, fmt.Errorf("--fault.rules must not be empty when --fault.enabled is set"))
+	}
+
+	return errors
+}
+
+func (o *FaultOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
+	fs.BoolVar(&o.Enabled, "fault.enabled", o.Enabled, "Enable fault injection for chaos testing. Never enable in production")
+	fs.StringSliceVar(&o.Rules, "fault.rules", o.Rules, "Fault rules as <point>=<fault> (faults: error, delay:<duration>, timeout; points: storage.presign, storage.check-bucket, mqtt.publish)")
+}
@@ -1,9 +1,12 @@
 package options
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/autopeer-io/autopeer/pkg/mqtt"
+	"github.com/autopeer-io/autopeer/pkg/mqtt/topic"
 	"github.com/spf13/pflag"
 )
 
@@ -27,9 +30,28 @@ type MqttOptions struct {
 	// In this mode, TLS is susceptible to man-in-the-middle attacks. This should be used only for testing.
 	InsecureSkipVerify bool `json:"insecure-skip-verify" mapstructure:"insecure-skip-verify"`
 
+	// CertFile and KeyFile are the client certificate and key for mutual TLS with the broker.
+	CertFile string `json:"cert-file" mapstructure:"cert-file"`
+	KeyFile  string `json:"key-file" mapstructure:"key-file"`
+
 	// Topic Topology definition
 	// Using prefixes allows us to construct topics like: {TopicRoot}/{XXX}
 	TopicRoot string `json:"topic-root" mapstructure:"topic-root"`
+
+	// TopicNamespace isolates a tenant on a shared broker: all topics move below
+	// {TopicRoot}/{TopicNamespace}, so broker ACLs can be keyed per namespace.
+	// The hub and its agents must use the same value. Empty keeps the flat layout.
+	TopicNamespace string `json:"topic-namespace" mapstructure:"topic-namespace"`
+
+	// SharedGroup is the shared subscription group of the hub ($share/<group>/...).
+	// The broker delivers each message to only one subscriber of a group, so hub replicas
+	// split the load instead of all processing every message. Empty disables sharing.
+	// The agent does not use it.
+	SharedGroup string `json:"shared-group" mapstructure:"shared-group"`
+
+	// HandlerTimeout bounds how long the hub works on a single incoming message,
+	// so a slow apiserver cannot pile up handler goroutines. The agent does not use it.
+	HandlerTimeout time.Duration `json:"handler-timeout" mapstructure:"handler-timeout"`
 }
 
 // NewMqttOptions creates a new MqttOptions with default values.
@@ -44,6 +66,8 @@ func NewMqttOptions() *MqttOptions {
 		CleanStart:         true,
 		InsecureSkipVerify: true,
 		TopicRoot:          "iov/v1",
+		SharedGroup:        "autopeer-bridge",
+		HandlerTimeout:     10 * time.Second,
 	}
 }
 
@@ -56,6 +80,18 @@ func (o *MqttOptions) Validate() []error {
 
 	errors := []error{}
 
+	if strings.ContainsAny(o.SharedGroup, "/+#") {
+		errors = append(errors, fmt.Errorf("--mqtt.shared-group must not contain '/', '+' or '#'"))
+	}
+
+	if strings.ContainsAny(o.TopicNamespace, "/+#$") {
+		errors = append(errors, fmt.Errorf("--mqtt.topic-namespace must not contain '/', '+', '#' or '$'"))
+	}
+
+	if o.HandlerTimeout <= 0 {
+		errors = append(errors, fmt.Errorf("--mqtt.handler-timeout must be greater than 0"))
+	}
+
 	return errors
 }
 
@@ -70,9 +106,19 @@ func (o *MqttOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
 	fs.DurationVar(&o.ConnectTimeout, "mqtt.connect-timeout", o.ConnectTimeout, "Timeout for establishing MQTT connection.")
 	fs.Uint32Var(&o.SessionExpiry, "mqtt.session-expiry", o.SessionExpiry, "MQTT Session Expiry Interval in seconds.")
 	fs.BoolVar(&o.InsecureSkipVerify, "mqtt.insecure-skip-verify", o.InsecureSkipVerify, "If true, skips the TLS certificate verification.")
+	fs.StringVar(&o.CertFile, "mqtt.cert-file", o.CertFile, "PEM client certificate for mutual TLS with the broker. Requires --mqtt.key-file.")
+	fs.StringVar(&o.KeyFile, "mqtt.key-file", o.KeyFile, "PEM private key of --mqtt.cert-file.")
 
 	// Topics
 	fs.StringVar(&o.TopicRoot, "mqtt.topic-root", o.TopicRoot, "Topic prefix for sending commands.")
+	fs.StringVar(&o.TopicNamespace, "mqtt.topic-namespace", o.TopicNamespace, "Tenant namespace inserted after the topic root, isolating tenants on a shared broker. Must match between the hub and its agents.")
+	fs.StringVar(&o.SharedGroup, "mqtt.shared-group", o.SharedGroup, "Shared subscription group of the hub, so each message is processed by only one replica. Empty disables shared subscriptions.")
+	fs.DurationVar(&o.HandlerTimeout, "mqtt.handler-timeout", o.HandlerTimeout, "Deadline for the hub to handle a single incoming message.")
+}
+
+// ToTopicBuilder returns the builder of the configured topic layout, namespaced if TopicNamespace is set.
+func (o *MqttOptions) ToTopicBuilder() *topic.Builder {
+	return topic.NewBuilder(o.TopicRoot).Namespace(o.TopicNamespace)
 }
 
 func (o *MqttOptions) ToClientConfig() *mqtt.ClientConfig {
@@ -86,5 +132,7 @@ func (o *MqttOptions) ToClientConfig() *mqtt.ClientConfig {
 		ConnectTimeout:     o.ConnectTimeout,
 		CleanStart:         o.CleanStart,
 		InsecureSkipVerify: o.InsecureSkipVerify,
+		CertFile:           o.CertFile,
+		KeyFile:            o.KeyFile,
 	}
 }
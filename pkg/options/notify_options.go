@@ -0,0 +1,54 @@
+package options
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+var _ IOptions = (*NotifyOptions)(nil)
+
+// NotifyOptions configures the webhook notified about OTA rollout milestones.
+type NotifyOptions struct {
+	// WebhookURL receives a POST per milestone. Empty disables notifications.
+	WebhookURL string `json:"webhook-url" mapstructure:"webhook-url"`
+
+	// TemplateFile is a Go text/template rendering the request body from the milestone,
+	// e.g. a Slack message. Empty sends the milestone as JSON.
+	TemplateFile string `json:"template-file" mapstructure:"template-file"`
+
+	// Retries is how many times a failed delivery is repeated.
+	Retries int `json:"retries" mapstructure:"retries"`
+
+	// Timeout bounds a single delivery.
+	Timeout time.Duration `json:"timeout" mapstructure:"timeout"`
+}
+
+func NewNotifyOptions() *NotifyOptions {
+	return &NotifyOptions{
+		Retries: 3,
+		Timeout: 5 * time.Second,
+	}
+}
+
+func (o *NotifyOptions) Validate() []error {
+	errors := []error{}
+
+	if o.Retries < 0 {
+		errors = append(errors, fmt.Errorf("--notify.retries must not be negative"))
+	}
+
+	if o.WebhookURL != "" && o.Timeout <= 0 {
+		errors = append(errors, fmt.Errorf("--notify.timeout must be greater than 0"))
+	}
+
+	return errors
+}
+
+func (o *NotifyOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
+	fs.StringVar(&o.WebhookURL, "notify.webhook-url", o.WebhookURL, "URL receiving a POST per OTA milestone (started, failure threshold, completed). Empty disables notifications")
+	fs.StringVar(&o.TemplateFile, "notify.template-file", o.TemplateFile, "Go text/template file rendering the webhook body from the milestone. Empty sends the milestone as JSON")
+	fs.IntVar(&o.Retries, "notify.retries", o.Retries, "How many times a failed webhook delivery is repeated")
+	fs.DurationVar(&o.Timeout, "notify.timeout", o.Timeout, "Timeout of a single webhook delivery")
+}
@@ -0,0 +1,111 @@
+package options
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+var _ IOptions = (*OTAOptions)(nil)
+
+// OTAOptions configures how the agent downloads and verifies firmware.
+type OTAOptions struct {
+	// PublicKeyFile is a PEM encoded Ed25519 public key of the firmware publisher.
+	// If set, every download must carry a valid signature; if empty, only the checksum is verified.
+	PublicKeyFile string `json:"public-key-file" mapstructure:"public-key-file"`
+
+	// CAFile is a PEM bundle of additional CAs trusted for firmware downloads, e.g. for a private registry.
+	CAFile string `json:"ca-file" mapstructure:"ca-file"`
+
+	// InsecureSkipVerify disables TLS verification of the download server. Only meant for local testing.
+	InsecureSkipVerify bool `json:"insecure-skip-verify" mapstructure:"insecure-skip-verify"`
+
+	// URLTimeout bounds the wait for the hub to answer a firmware URL request.
+	URLTimeout time.Duration `json:"url-timeout" mapstructure:"url-timeout"`
+
+	// URLTTL is the lifetime the agent requests for firmware download URLs, in whole seconds.
+	// Zero leaves it to the hub, which also clamps it to its own bounds.
+	URLTTL time.Duration `json:"url-ttl" mapstructure:"url-ttl"`
+
+	// DownloadTimeout bounds downloading (and, for a delta, rebuilding) the firmware image.
+	DownloadTimeout time.Duration `json:"download-timeout" mapstructure:"download-timeout"`
+
+	// InstallTimeout bounds writing the image to the inactive slot and switching to it.
+	InstallTimeout time.Duration `json:"install-timeout" mapstructure:"install-timeout"`
+
+	// RebootTimeout bounds requesting the reboot into the new slot.
+	RebootTimeout time.Duration `json:"reboot-timeout" mapstructure:"reboot-timeout"`
+
+	// BootConfirmTimeout is the watchdog window for the new firmware to pass its health check after the reboot.
+	// If it does not, the agent switches back to the previous slot.
+	BootConfirmTimeout time.Duration `json:"boot-confirm-timeout" mapstructure:"boot-confirm-timeout"`
+
+	// StateDir holds the agent state that must survive the reboot into new firmware.
+	StateDir string `json:"state-dir" mapstructure:"state-dir"`
+}
+
+func NewOTAOptions() *OTAOptions {
+	return &OTAOptions{
+		URLTimeout:      15 * time.Second,
+		DownloadTimeout: 10 * time.Minute,
+		InstallTimeout:  10 * time.Minute,
+		RebootTimeout:   1 * time.Minute,
+
+		BootConfirmTimeout: 5 * time.Minute,
+		StateDir:           "/var/lib/autopeer",
+	}
+}
+
+func (o *OTAOptions) Validate() []error {
+	errors := []error{}
+
+	if o.InsecureSkipVerify && o.CAFile != "" {
+		errors = append(errors, fmt.Errorf("--ota.ca-file and --ota.insecure-skip-verify are mutually exclusive"))
+	}
+
+	if o.URLTimeout <= 0 {
+		errors = append(errors, fmt.Errorf("--ota.url-timeout must be greater than 0"))
+	}
+
+	if o.URLTTL < 0 {
+		errors = append(errors, fmt.Errorf("--ota.url-ttl must not be negative"))
+	} else if o.URLTTL%time.Second != 0 {
+		errors = append(errors, fmt.Errorf("--ota.url-ttl must be a whole number of seconds"))
+	}
+
+	if o.DownloadTimeout <= 0 {
+		errors = append(errors, fmt.Errorf("--ota.download-timeout must be greater than 0"))
+	}
+
+	if o.InstallTimeout <= 0 {
+		errors = append(errors, fmt.Errorf("--ota.install-timeout must be greater than 0"))
+	}
+
+	if o.RebootTimeout <= 0 {
+		errors = append(errors, fmt.Errorf("--ota.reboot-timeout must be greater than 0"))
+	}
+
+	if o.BootConfirmTimeout <= 0 {
+		errors = append(errors, fmt.Errorf("--ota.boot-confirm-timeout must be greater than 0"))
+	}
+
+	if o.StateDir == "" {
+		errors = append(errors, fmt.Errorf("--ota.state-dir must not be empty"))
+	}
+
+	return errors
+}
+
+func (o *OTAOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
+	fs.StringVar(&o.PublicKeyFile, "ota.public-key-file", o.PublicKeyFile, "PEM encoded Ed25519 public key used to verify firmware signatures. If empty, signatures are not verified")
+	fs.StringVar(&o.CAFile, "ota.ca-file", o.CAFile, "PEM encoded CA bundle trusted for firmware downloads in addition to the system roots")
+	fs.BoolVar(&o.InsecureSkipVerify, "ota.insecure-skip-verify", o.InsecureSkipVerify, "If true, skips TLS verification of the firmware download server. Only for local testing")
+	fs.DurationVar(&o.URLTimeout, "ota.url-timeout", o.URLTimeout, "How long to wait for the hub to answer a firmware URL request")
+	fs.DurationVar(&o.URLTTL, "ota.url-ttl", o.URLTTL, "Lifetime to request for firmware download URLs. If 0, the hub's default is used; the hub clamps it to its bounds")
+	fs.DurationVar(&o.DownloadTimeout, "ota.download-timeout", o.DownloadTimeout, "How long downloading and verifying the firmware may take")
+	fs.DurationVar(&o.InstallTimeout, "ota.install-timeout", o.InstallTimeout, "How long installing the firmware to the inactive slot may take")
+	fs.DurationVar(&o.RebootTimeout, "ota.reboot-timeout", o.RebootTimeout, "How long requesting the reboot into the new slot may take")
+	fs.DurationVar(&o.BootConfirmTimeout, "ota.boot-confirm-timeout", o.BootConfirmTimeout, "How long new firmware may take to pass its health check after the reboot before the agent rolls back to the previous slot")
+	fs.StringVar(&o.StateDir, "ota.state-dir", o.StateDir, "Directory for the agent state that must survive a reboot, such as the update awaiting boot confirmation")
+}
@@ -0,0 +1,51 @@
+package options
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+var _ IOptions = (*PipelineOptions)(nil)
+
+// PipelineOptions configures the buffered pipeline that writes vehicle status to Kubernetes.
+//
+// The flush interval trades freshness for apiserver load: a longer interval merges more
+// heartbeats of the same vehicle into one patch, but Vehicle status lags further behind.
+// When MaxFlushInterval is greater than MinFlushInterval, the pipeline adapts between the
+// two: it widens the interval while flushes are large and narrows it again when traffic is quiet.
+type PipelineOptions struct {
+	// MinFlushInterval is the flush interval used when traffic is low.
+	MinFlushInterval time.Duration `json:"min-flush-interval" mapstructure:"min-flush-interval"`
+
+	// MaxFlushInterval is the upper bound the interval can grow to under load.
+	// Setting it equal to MinFlushInterval disables adaptation.
+	MaxFlushInterval time.Duration `json:"max-flush-interval" mapstructure:"max-flush-interval"`
+}
+
+func NewPipelineOptions() *PipelineOptions {
+	return &PipelineOptions{
+		MinFlushInterval: 1 * time.Second,
+		MaxFlushInterval: 1 * time.Second,
+	}
+}
+
+func (o *PipelineOptions) Validate() []error {
+	errors := []error{}
+
+	if o.MinFlushInterval <= 0 {
+		errors = append(errors, fmt.Errorf("--pipeline.min-flush-interval must be greater than 0"))
+	}
+
+	if o.MaxFlushInterval < o.MinFlushInterval {
+		errors = append(errors, fmt.Errorf("--pipeline.max-flush-interval must not be less than --pipeline.min-flush-interval"))
+	}
+
+	return errors
+}
+
+func (o *PipelineOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
+	fs.DurationVar(&o.MinFlushInterval, "pipeline.min-flush-interval", o.MinFlushInterval, "How often buffered vehicle status is flushed to Kubernetes when traffic is low")
+	fs.DurationVar(&o.MaxFlushInterval, "pipeline.max-flush-interval", o.MaxFlushInterval, "Upper bound for the flush interval under load; a value above --pipeline.min-flush-interval enables the adaptive interval")
+}
@@ -1,6 +1,9 @@
 package options
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/spf13/pflag"
 )
 
@@ -13,6 +16,17 @@ type S3Options struct {
 	UseSSL          bool   `json:"use-ssl" mapstructure:"use-ssl"`
 	BucketName      string `json:"bucket-name" mapstructure:"bucket-name"`
 	Region          string `json:"region" mapstructure:"region"`
+
+	// MaxURLExpiry is the upper bound for the lifetime of presigned download URLs.
+	// Agents may request a custom TTL, but it is always clamped to this value.
+	MaxURLExpiry time.Duration `json:"max-url-expiry" mapstructure:"max-url-expiry"`
+
+	// CheckBucketAttempts is how many times the bucket check is tried at startup
+	// before giving up. It smooths out boot ordering when storage comes up late.
+	CheckBucketAttempts int `json:"check-bucket-attempts" mapstructure:"check-bucket-attempts"`
+
+	// CheckBucketInterval is the delay between two startup bucket checks.
+	CheckBucketInterval time.Duration `json:"check-bucket-interval" mapstructure:"check-bucket-interval"`
 }
 
 func NewS3Options() *S3Options {
@@ -23,13 +37,27 @@ func NewS3Options() *S3Options {
 		UseSSL:          true,
 		BucketName:      "firmware",
 		Region:          "us-east-1",
+		MaxURLExpiry:    24 * time.Hour,
+
+		CheckBucketAttempts: 5,
+		CheckBucketInterval: 3 * time.Second,
 	}
 }
 
 func (o *S3Options) Validate() []error {
 	errors := []error{}
 
-	// some validate
+	if o.MaxURLExpiry <= 0 {
+		errors = append(errors, fmt.Errorf("--s3.max-url-expiry must be greater than 0"))
+	}
+
+	if o.CheckBucketAttempts < 1 {
+		errors = append(errors, fmt.Errorf("--s3.check-bucket-attempts must be at least 1"))
+	}
+
+	if o.CheckBucketInterval <= 0 {
+		errors = append(errors, fmt.Errorf("--s3.check-bucket-interval must be greater than 0"))
+	}
 
 	return errors
 }
@@ -41,4 +69,7 @@ func (o *S3Options) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
 	fs.BoolVar(&o.UseSSL, "s3.use-ssl", o.UseSSL, "Enable SSL for S3 connection")
 	fs.StringVar(&o.BucketName, "s3.bucket-name", o.BucketName, "S3 bucket name for firmware storage")
 	fs.StringVar(&o.Region, "s3.region", o.Region, "S3 region")
+	fs.DurationVar(&o.MaxURLExpiry, "s3.max-url-expiry", o.MaxURLExpiry, "Maximum lifetime of presigned firmware download URLs")
+	fs.IntVar(&o.CheckBucketAttempts, "s3.check-bucket-attempts", o.CheckBucketAttempts, "Number of bucket checks at startup before giving up")
+	fs.DurationVar(&o.CheckBucketInterval, "s3.check-bucket-interval", o.CheckBucketInterval, "Delay between startup bucket checks")
 }
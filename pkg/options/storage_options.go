@@ -0,0 +1,77 @@
+package options
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+var _ IOptions = (*StorageOptions)(nil)
+
+// Supported object storage backends.
+const (
+	StorageBackendS3         = "s3"
+	StorageBackendFileSystem = "filesystem"
+)
+
+// StorageOptions selects the firmware storage backend and configures the
+// local-filesystem backend used by edge/air-gapped deployments without S3.
+type StorageOptions struct {
+	// Backend is the storage implementation to use ("s3" or "filesystem").
+	Backend string `json:"backend" mapstructure:"backend"`
+
+	// RootDir is the directory firmware files are served from (filesystem backend only).
+	RootDir string `json:"root-dir" mapstructure:"root-dir"`
+
+	// PublicURL is the externally reachable base URL of the hub HTTP server,
+	// used to build download links (filesystem backend only).
+	PublicURL string `json:"public-url" mapstructure:"public-url"`
+
+	// SigningKey is the HMAC secret used to sign expiring download tokens (filesystem backend only).
+	SigningKey string `json:"signing-key" mapstructure:"signing-key"`
+}
+
+// NewStorageOptions creates a StorageOptions with default values.
+func NewStorageOptions() *StorageOptions {
+	return &StorageOptions{
+		Backend:   StorageBackendS3,
+		RootDir:   "/var/lib/autopeer/firmware",
+		PublicURL: "http://bridge.autopeer-io.svc:8001",
+	}
+}
+
+// Validate is used to parse and validate the parameters entered by the user at
+// the command line when the program starts.
+func (o *StorageOptions) Validate() []error {
+	if o == nil {
+		return nil
+	}
+
+	errors := []error{}
+
+	switch o.Backend {
+	case StorageBackendS3:
+	case StorageBackendFileSystem:
+		if o.RootDir == "" {
+			errors = append(errors, fmt.Errorf("--storage.root-dir is required for the %s backend", o.Backend))
+		}
+		if o.PublicURL == "" {
+			errors = append(errors, fmt.Errorf("--storage.public-url is required for the %s backend", o.Backend))
+		}
+		if o.SigningKey == "" {
+			errors = append(errors, fmt.Errorf("--storage.signing-key is required for the %s backend", o.Backend))
+		}
+	default:
+		errors = append(errors, fmt.Errorf("unsupported storage backend %q", o.Backend))
+	}
+
+	return errors
+}
+
+// AddFlags adds flags for StorageOptions to the specified FlagSet.
+func (o *StorageOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
+	fs.StringVar(&o.Backend, "storage.backend", o.Backend, "Firmware storage backend, one of: s3, filesystem.")
+	fs.StringVar(&o.RootDir, "storage.root-dir", o.RootDir, "Directory holding firmware files (filesystem backend).")
+	fs.StringVar(&o.PublicURL, "storage.public-url", o.PublicURL, "Externally reachable base URL of the hub HTTP server (filesystem backend).")
+	fs.StringVar(&o.SigningKey, "storage.signing-key", o.SigningKey, "HMAC secret used to sign download tokens (filesystem backend).")
+}
@@ -0,0 +1,106 @@
+package options
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+var _ IOptions = (*VehicleOptions)(nil)
+
+// VehicleOptions configures the Vehicle controller.
+type VehicleOptions struct {
+	// RolloutLimit is how many vehicles may start an OTA within RolloutWindow, fleet-wide.
+	// Vehicles above the limit wait unless they carry the rollout override annotation. 0 disables the guardrail.
+	RolloutLimit int `json:"rollout-limit" mapstructure:"rollout-limit"`
+
+	// RolloutWindow is the sliding window RolloutLimit applies to.
+	RolloutWindow time.Duration `json:"rollout-window" mapstructure:"rollout-window"`
+
+	// CanarySoak is how long the canaries of a model (vehicles labelled iov.autopeer.io/canary=true)
+	// must run a firmware version healthily before the rest of the model's fleet starts updating to it.
+	// 0 disables the canary gate.
+	CanarySoak time.Duration `json:"canary-soak" mapstructure:"canary-soak"`
+
+	// RetryLimit is how many automatic retries a failed OTA gets, unless the vehicle's OTAPolicy
+	// sets a RetryLimit. 0 disables automatic retries.
+	RetryLimit int32 `json:"retry-limit" mapstructure:"retry-limit"`
+
+	// RetryBaseDelay is the wait before the first retry of a failed OTA; it doubles with each retry.
+	RetryBaseDelay time.Duration `json:"retry-base-delay" mapstructure:"retry-base-delay"`
+
+	// RetryMaxDelay caps the wait between two retries of a failed OTA.
+	RetryMaxDelay time.Duration `json:"retry-max-delay" mapstructure:"retry-max-delay"`
+
+	// OfflineThreshold is how long a vehicle may go without a heartbeat before it is marked offline.
+	// 0 disables the online reaper.
+	OfflineThreshold time.Duration `json:"offline-threshold" mapstructure:"offline-threshold"`
+
+	// OfflineScanInterval is how often the online reaper looks for vehicles without recent heartbeats.
+	OfflineScanInterval time.Duration `json:"offline-scan-interval" mapstructure:"offline-scan-interval"`
+}
+
+func NewVehicleOptions() *VehicleOptions {
+	return &VehicleOptions{
+		RolloutLimit:  100,
+		RolloutWindow: 10 * time.Minute,
+		CanarySoak:    30 * time.Minute,
+
+		RetryLimit:     5,
+		RetryBaseDelay: 1 * time.Minute,
+		RetryMaxDelay:  30 * time.Minute,
+
+		OfflineThreshold:    5 * time.Minute,
+		OfflineScanInterval: 1 * time.Minute,
+	}
+}
+
+func (o *VehicleOptions) Validate() []error {
+	errors := []error{}
+
+	if o.RolloutLimit < 0 {
+		errors = append(errors, fmt.Errorf("--vehicle.rollout-limit must not be negative"))
+	}
+
+	if o.RolloutLimit > 0 && o.RolloutWindow <= 0 {
+		errors = append(errors, fmt.Errorf("--vehicle.rollout-window must be greater than 0"))
+	}
+
+	if o.CanarySoak < 0 {
+		errors = append(errors, fmt.Errorf("--vehicle.canary-soak must not be negative"))
+	}
+
+	if o.RetryLimit < 0 {
+		errors = append(errors, fmt.Errorf("--vehicle.retry-limit must not be negative"))
+	}
+
+	if o.RetryBaseDelay <= 0 {
+		errors = append(errors, fmt.Errorf("--vehicle.retry-base-delay must be greater than 0"))
+	}
+
+	if o.RetryMaxDelay < o.RetryBaseDelay {
+		errors = append(errors, fmt.Errorf("--vehicle.retry-max-delay must not be less than --vehicle.retry-base-delay"))
+	}
+
+	if o.OfflineThreshold < 0 {
+		errors = append(errors, fmt.Errorf("--vehicle.offline-threshold must not be negative"))
+	}
+
+	if o.OfflineThreshold > 0 && o.OfflineScanInterval <= 0 {
+		errors = append(errors, fmt.Errorf("--vehicle.offline-scan-interval must be greater than 0"))
+	}
+
+	return errors
+}
+
+func (o *VehicleOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
+	fs.IntVar(&o.RolloutLimit, "vehicle.rollout-limit", o.RolloutLimit, "How many vehicles may start an OTA within --vehicle.rollout-window across the fleet (0 disables the guardrail)")
+	fs.DurationVar(&o.RolloutWindow, "vehicle.rollout-window", o.RolloutWindow, "The sliding window of the OTA rollout guardrail")
+	fs.DurationVar(&o.CanarySoak, "vehicle.canary-soak", o.CanarySoak, "How long the canaries of a model must run a firmware version healthily before the rest of the fleet updates to it (0 disables the canary gate)")
+	fs.Int32Var(&o.RetryLimit, "vehicle.retry-limit", o.RetryLimit, "How many automatic retries a failed OTA gets, unless the vehicle's OTA policy sets a retry limit (0 disables automatic retries)")
+	fs.DurationVar(&o.RetryBaseDelay, "vehicle.retry-base-delay", o.RetryBaseDelay, "The wait before the first retry of a failed OTA; it doubles with each retry")
+	fs.DurationVar(&o.RetryMaxDelay, "vehicle.retry-max-delay", o.RetryMaxDelay, "The maximum wait between two retries of a failed OTA")
+	fs.DurationVar(&o.OfflineThreshold, "vehicle.offline-threshold", o.OfflineThreshold, "How long a vehicle may go without a heartbeat before it is marked offline (0 disables the online reaper)")
+	fs.DurationVar(&o.OfflineScanInterval, "vehicle.offline-scan-interval", o.OfflineScanInterval, "How often vehicles are checked for missing heartbeats")
+}
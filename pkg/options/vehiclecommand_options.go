@@ -0,0 +1,105 @@
+package options
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+var _ IOptions = (*VehicleCommandOptions)(nil)
+
+// VehicleCommandOptions configures the VehicleCommand controller.
+type VehicleCommandOptions struct {
+	// GCRetention is how long a finished VehicleCommand is kept before it is garbage collected.
+	GCRetention time.Duration `json:"gc-retention" mapstructure:"gc-retention"`
+
+	// GCInterval is how often the garbage collector scans for stale VehicleCommands.
+	GCInterval time.Duration `json:"gc-interval" mapstructure:"gc-interval"`
+
+	// AckDeadline is how long a Sent command waits for the vehicle to acknowledge it before being re-sent.
+	// Zero disables re-sending.
+	AckDeadline time.Duration `json:"ack-deadline" mapstructure:"ack-deadline"`
+
+	// MaxResends is how many times an unacknowledged command is re-sent before it fails.
+	MaxResends int32 `json:"max-resends" mapstructure:"max-resends"`
+
+	// QualityMaxVehicles is how many of the most recently active vehicles export connection-quality metrics.
+	// It bounds the cardinality of the per-vehicle series. Zero disables the metrics.
+	QualityMaxVehicles int `json:"quality-max-vehicles" mapstructure:"quality-max-vehicles"`
+
+	// MethodGroups restricts command methods to user groups, one METHOD=group1,group2 entry per method.
+	// Only members of the listed groups may create commands of a listed method, directly, through a
+	// CommandTemplate or through a ScheduledVehicleCommand, and only they may create or change a
+	// CommandTemplate of a listed method; other methods are not restricted.
+	// Non-empty, it enables the VehicleCommand, ScheduledVehicleCommand and CommandTemplate validating webhooks.
+	MethodGroups []string `json:"method-groups" mapstructure:"method-groups"`
+}
+
+func NewVehicleCommandOptions() *VehicleCommandOptions {
+	return &VehicleCommandOptions{
+		GCRetention: 30 * 24 * time.Hour,
+		GCInterval:  1 * time.Hour,
+		AckDeadline: 30 * time.Second,
+		MaxResends:  3,
+
+		QualityMaxVehicles: 1000,
+	}
+}
+
+func (o *VehicleCommandOptions) Validate() []error {
+	errors := []error{}
+
+	if o.GCRetention <= 0 {
+		errors = append(errors, fmt.Errorf("--vehiclecommand.gc-retention must be greater than 0"))
+	}
+
+	if o.GCInterval <= 0 {
+		errors = append(errors, fmt.Errorf("--vehiclecommand.gc-interval must be greater than 0"))
+	}
+
+	if o.AckDeadline < 0 {
+		errors = append(errors, fmt.Errorf("--vehiclecommand.ack-deadline must not be negative"))
+	}
+
+	if o.MaxResends < 0 {
+		errors = append(errors, fmt.Errorf("--vehiclecommand.max-resends must not be negative"))
+	}
+
+	if o.QualityMaxVehicles < 0 {
+		errors = append(errors, fmt.Errorf("--vehiclecommand.quality-max-vehicles must not be negative"))
+	}
+
+	if _, err := o.MethodAllowlist(); err != nil {
+		errors = append(errors, err)
+	}
+
+	return errors
+}
+
+func (o *VehicleCommandOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
+	fs.DurationVar(&o.GCRetention, "vehiclecommand.gc-retention", o.GCRetention, "How long finished VehicleCommands are kept before being garbage collected")
+	fs.DurationVar(&o.GCInterval, "vehiclecommand.gc-interval", o.GCInterval, "How often the VehicleCommand garbage collector runs")
+	fs.DurationVar(&o.AckDeadline, "vehiclecommand.ack-deadline", o.AckDeadline, "How long a sent command waits for the vehicle's acknowledgement before being re-sent (0 disables re-sending)")
+	fs.Int32Var(&o.MaxResends, "vehiclecommand.max-resends", o.MaxResends, "How many times an unacknowledged command is re-sent before it is marked Failed")
+	fs.IntVar(&o.QualityMaxVehicles, "vehiclecommand.quality-max-vehicles", o.QualityMaxVehicles, "How many of the most recently active vehicles export connection-quality metrics (0 disables them)")
+	fs.StringArrayVar(&o.MethodGroups, "vehiclecommand.method-groups", o.MethodGroups, "Restrict a command method to user groups, as METHOD=group1,group2 (repeatable). "+
+		"Service accounts that create commands of the method, such as the controller's for OTA, must be in one of the groups")
+}
+
+// MethodAllowlist parses MethodGroups into the groups allowed to create commands of each method.
+func (o *VehicleCommandOptions) MethodAllowlist() (map[string][]string, error) {
+	allowlist := make(map[string][]string, len(o.MethodGroups))
+	for _, entry := range o.MethodGroups {
+		method, groups, ok := strings.Cut(entry, "=")
+		if !ok || method == "" || groups == "" {
+			return nil, fmt.Errorf("--vehiclecommand.method-groups must be in the form METHOD=group1,group2, got %q", entry)
+		}
+		if _, ok := allowlist[method]; ok {
+			return nil, fmt.Errorf("--vehiclecommand.method-groups lists method %q more than once", method)
+		}
+		allowlist[method] = strings.Split(groups, ",")
+	}
+	return allowlist, nil
+}
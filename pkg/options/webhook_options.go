@@ -0,0 +1,46 @@
+package options
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+var _ IOptions = (*WebhookOptions)(nil)
+
+// WebhookOptions configures the admission webhook server of the controller manager.
+// The server only runs if a webhook is enabled.
+type WebhookOptions struct {
+	// Port is the port the webhook server listens on.
+	Port int `json:"port" mapstructure:"port"`
+
+	// CertDir is the directory holding the serving certificate (tls.crt and tls.key).
+	CertDir string `json:"cert-dir" mapstructure:"cert-dir"`
+
+	// Conversion enables the Vehicle conversion webhook between iov v1alpha1 and v1alpha2.
+	// The Vehicle CRD must also be set to the Webhook conversion strategy.
+	Conversion bool `json:"conversion" mapstructure:"conversion"`
+}
+
+func NewWebhookOptions() *WebhookOptions {
+	return &WebhookOptions{
+		Port:    9443,
+		CertDir: "/tmp/k8s-webhook-server/serving-certs",
+	}
+}
+
+func (o *WebhookOptions) Validate() []error {
+	errors := []error{}
+
+	if o.Port <= 0 || o.Port > 65535 {
+		errors = append(errors, fmt.Errorf("--webhook.port must be between 1 and 65535"))
+	}
+
+	return errors
+}
+
+func (o *WebhookOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
+	fs.IntVar(&o.Port, "webhook.port", o.Port, "The port the admission webhook server listens on")
+	fs.StringVar(&o.CertDir, "webhook.cert-dir", o.CertDir, "The directory holding the webhook serving certificate (tls.crt and tls.key)")
+	fs.BoolVar(&o.Conversion, "webhook.conversion", o.Conversion, "Serve the Vehicle conversion webhook between iov v1alpha1 and v1alpha2")
+}